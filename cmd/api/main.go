@@ -8,6 +8,7 @@ import (
 	"stock-simulation-backend/internal/adapters/middleware"
 	mysqlRepo "stock-simulation-backend/internal/adapters/repositories/mysql"
 	"stock-simulation-backend/internal/config"
+	"stock-simulation-backend/internal/core/domain"
 	"stock-simulation-backend/internal/core/services"
 
 	"github.com/gin-gonic/gin"
@@ -63,24 +64,45 @@ func main() {
 	portfolioRepo := mysqlRepo.NewPortfolioRepository(db)
 	historicalPriceRepo := mysqlRepo.NewHistoricalPriceRepository(db)
 	advancedOrderRepo := mysqlRepo.NewAdvancedOrderRepository(db)
+	shortPositionRepo := mysqlRepo.NewShortPositionRepository(db)
+	marginAccountRepo := mysqlRepo.NewMarginAccountRepository(db)
+	algoOrderRepo := mysqlRepo.NewAlgoOrderRepository(db)
+	contingentOrderRepo := mysqlRepo.NewContingentOrderRepository(db)
+	idempotencyRepo := mysqlRepo.NewIdempotencyRepository(db)
+	riskLimitRepo := mysqlRepo.NewRiskLimitRepository(db)
+	unitOfWork := mysqlRepo.NewUnitOfWork(db)
 
 	// Initialize services
 	log.Printf("⚙️ Initializing services...")
-	userService := services.NewUserService(userRepo)
+	userService := services.NewUserService(userRepo, advancedOrderRepo)
 	stockService := services.NewStockService(stockRepo)
-	transactionService := services.NewTransactionService(transactionRepo, portfolioRepo, stockRepo, userRepo)
-	portfolioService := services.NewPortfolioService(portfolioRepo, stockRepo)
+	riskLimits := domain.RiskLimits{
+		MaxOrderNotional:         cfg.Risk.MaxOrderNotional,
+		MaxPositionConcentration: cfg.Risk.MaxPositionConcentration,
+		MaxOrdersPerDay:          cfg.Risk.MaxOrdersPerDay,
+		DailyLossLimit:           cfg.Risk.DailyLossLimit,
+		PriceBandPercent:         cfg.Risk.PriceBandPercent,
+	}
+	riskManager := services.NewRiskManager(advancedOrderRepo, transactionRepo, userRepo, portfolioRepo, shortPositionRepo, marginAccountRepo, stockRepo, historicalPriceRepo, riskLimitRepo, riskLimits)
+	quoteCache := services.NewQuoteCache(historicalPriceRepo)
+	transactionService := services.NewTransactionService(unitOfWork, transactionRepo, portfolioRepo, stockRepo, userRepo, riskManager, quoteCache)
+	portfolioService := services.NewPortfolioService(portfolioRepo, shortPositionRepo, stockRepo, userRepo, advancedOrderRepo)
 	chartService := services.NewChartService(historicalPriceRepo)
-	advancedOrderService := services.NewAdvancedOrderService(advancedOrderRepo, stockRepo, portfolioRepo, userRepo, transactionService)
-	commissionService := services.NewCommissionService()
+	commissionService := services.NewCommissionService(stockRepo, historicalPriceRepo)
 
 	// Initialize real-time service with Redis support
 	log.Printf("🔄 Initializing real-time services...")
 	realTimeService := services.NewRealTimeService(redisService)
 	realTimeService.Start()
 
+	orderBookService := services.NewOrderBookService(advancedOrderRepo, stockRepo, realTimeService, quoteCache)
+	advancedOrderService := services.NewAdvancedOrderService(unitOfWork, advancedOrderRepo, stockRepo, portfolioRepo, userRepo, shortPositionRepo, marginAccountRepo, algoOrderRepo, historicalPriceRepo, transactionService, commissionService, riskManager, realTimeService, orderBookService, quoteCache)
+	marginService := services.NewMarginService(unitOfWork, marginAccountRepo, portfolioRepo, stockRepo, userRepo, advancedOrderService, realTimeService)
+	idempotencyService := services.NewIdempotencyService(idempotencyRepo, redisService)
+	contingentOrderService := services.NewContingentOrderService(contingentOrderRepo, stockRepo, historicalPriceRepo, advancedOrderService)
+
 	// Initialize price simulator service with Redis and WebSocket support
-	priceSimulator := services.NewPriceSimulatorService(stockRepo, historicalPriceRepo, realTimeService, redisService)
+	priceSimulator := services.NewPriceSimulatorService(stockRepo, historicalPriceRepo, realTimeService, redisService, advancedOrderService, marginService, contingentOrderService, quoteCache)
 
 	// Start automatic price simulation in all environments
 	log.Printf("📈 Starting automatic price simulation...")
@@ -93,8 +115,12 @@ func main() {
 	log.Printf("🎛️ Initializing handlers...")
 	userHandler := handlers.NewUserHandler(userService)
 	stockHandler := handlers.NewStockHandler(stockService)
+	orderBookHandler := handlers.NewOrderBookHandler(orderBookService)
 	transactionHandler := handlers.NewTransactionHandler(transactionService)
 	portfolioHandler := handlers.NewPortfolioHandler(portfolioService)
+	marginHandler := handlers.NewMarginHandler(marginService)
+	riskHandler := handlers.NewRiskHandler(riskManager)
+	contingentOrderHandler := handlers.NewContingentOrderHandler(contingentOrderService)
 	chartHandler := handlers.NewChartHandler(chartService)
 
 	// Use the full advanced order handler
@@ -129,7 +155,7 @@ func main() {
 		}
 
 		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		c.Header("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Requested-With")
+		c.Header("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Requested-With, Idempotency-Key")
 		c.Header("Access-Control-Allow-Credentials", "true")
 
 		if c.Request.Method == "OPTIONS" {
@@ -184,8 +210,21 @@ func main() {
 		})
 	})
 
-	// WebSocket endpoint (before other routes)
-	router.GET("/api/v1/ws", gin.WrapH(http.HandlerFunc(realTimeService.HandleWebSocket)))
+	// WebSocket endpoint (before other routes). Browsers cannot set headers on
+	// a WebSocket handshake, so an optional ?token= authenticates the
+	// connection for the user's own order updates.
+	router.GET("/api/v1/ws", func(c *gin.Context) {
+		userID := 0
+		if token := c.Query("token"); token != "" {
+			id, err := middleware.ValidateToken(token)
+			if err != nil {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+				return
+			}
+			userID = id
+		}
+		realTimeService.HandleUserWebSocket(c.Writer, c.Request, userID)
+	})
 
 	// Public routes
 	public := router.Group("/api/v1")
@@ -195,6 +234,7 @@ func main() {
 		public.GET("/stocks", stockHandler.GetAllStocks)
 		public.GET("/stocks/top", stockHandler.GetTopStocks)
 		public.GET("/stocks/:symbol", stockHandler.GetStockBySymbol)
+		public.GET("/stocks/:symbol/orderbook", orderBookHandler.GetOrderBook)
 		public.GET("/leaderboard", userHandler.GetLeaderboard)
 
 		// Chart routes (public)
@@ -262,14 +302,17 @@ func main() {
 	// Protected routes
 	protected := router.Group("/api/v1")
 	protected.Use(middleware.Auth())
+
+	// Trading writes replay their first response when retried with the same Idempotency-Key
+	idempotent := middleware.Idempotency(idempotencyService)
 	{
 		// User routes
 		protected.GET("/profile", userHandler.GetProfile)
 		protected.PUT("/profile", userHandler.UpdateProfile)
 
 		// Transaction routes
-		protected.POST("/transactions/buy", transactionHandler.BuyStock)
-		protected.POST("/transactions/sell", transactionHandler.SellStock)
+		protected.POST("/transactions/buy", idempotent, transactionHandler.BuyStock)
+		protected.POST("/transactions/sell", idempotent, transactionHandler.SellStock)
 		protected.GET("/transactions", transactionHandler.GetUserTransactions)
 
 		// Portfolio routes
@@ -277,16 +320,42 @@ func main() {
 		protected.GET("/portfolio/performance", portfolioHandler.GetPortfolioPerformance)
 		protected.GET("/portfolio/value", portfolioHandler.GetPortfolioValue)
 		protected.GET("/portfolio/summary", portfolioHandler.GetPortfolioSummary)
+		protected.POST("/portfolio/rebalance", idempotent, advancedOrderHandler.RebalancePortfolio)
+
+		// Margin account routes
+		protected.GET("/account/margin", marginHandler.GetMarginAccount)
+		protected.PUT("/account/margin", marginHandler.UpdateMarginAccount)
+
+		// Risk routes
+		protected.GET("/account/risk", riskHandler.GetRiskReport)
+		protected.GET("/account/risk/limits", riskHandler.GetRiskLimits)
+		protected.PUT("/account/risk/limits", riskHandler.UpdateRiskLimits)
 
 		// Advanced Order routes
-		protected.POST("/orders", advancedOrderHandler.CreateOrder)
-		protected.POST("/orders/oco", advancedOrderHandler.CreateOCOOrder)
+		protected.POST("/orders", idempotent, advancedOrderHandler.CreateOrder)
+		protected.POST("/orders/oco", idempotent, advancedOrderHandler.CreateOCOOrder)
+		protected.GET("/orders/oco/:groupId", advancedOrderHandler.GetOCOGroup)
+		protected.POST("/orders/bracket", idempotent, advancedOrderHandler.CreateBracketOrder)
+		protected.POST("/orders/algo", idempotent, advancedOrderHandler.CreateAlgoOrder)
+		protected.GET("/orders/algo/:id", advancedOrderHandler.GetAlgoOrder)
+		protected.POST("/orders/algo/:id/pause", idempotent, advancedOrderHandler.PauseAlgoOrder)
+		protected.POST("/orders/algo/:id/resume", idempotent, advancedOrderHandler.ResumeAlgoOrder)
+		protected.POST("/orders/contingent", idempotent, contingentOrderHandler.CreateContingentOrder)
+		protected.GET("/orders/contingent", contingentOrderHandler.GetContingentOrders)
+		protected.GET("/orders/contingent/:id", contingentOrderHandler.GetContingentOrder)
+		protected.PUT("/orders/contingent/:id", idempotent, contingentOrderHandler.UpdateContingentOrder)
+		protected.DELETE("/orders/contingent/:id", idempotent, contingentOrderHandler.CancelContingentOrder)
 		protected.GET("/orders", advancedOrderHandler.GetUserOrders)
+		protected.GET("/orders/search", advancedOrderHandler.SearchOrders)
 		protected.GET("/orders/active", advancedOrderHandler.GetActiveOrders)
 		protected.GET("/orders/:id", advancedOrderHandler.GetOrderByID)
-		protected.PUT("/orders/:id", advancedOrderHandler.ModifyOrder)
-		protected.DELETE("/orders/:id", advancedOrderHandler.CancelOrder)
-		protected.POST("/orders/cancel-all", advancedOrderHandler.CancelAllOrders)
+		protected.GET("/orders/:id/executions", advancedOrderHandler.GetOrderExecutions)
+		protected.GET("/orders/:id/events", advancedOrderHandler.GetOrderEvents)
+		protected.GET("/orders/:id/versions", advancedOrderHandler.GetOrderVersions)
+		protected.PUT("/orders/:id", idempotent, advancedOrderHandler.ModifyOrder)
+		protected.DELETE("/orders/:id", idempotent, advancedOrderHandler.CancelOrder)
+		protected.POST("/orders/cancel-all", idempotent, advancedOrderHandler.CancelAllOrders)
+		protected.POST("/orders/bulk-cancel", idempotent, advancedOrderHandler.BulkCancelOrders)
 		protected.GET("/orders/statistics", advancedOrderHandler.GetOrderStatistics)
 		protected.GET("/orders/execution-metrics", advancedOrderHandler.GetExecutionMetrics)
 		protected.GET("/orders/slippage/:symbol", advancedOrderHandler.GetSlippageAnalysis)
@@ -1,9 +1,11 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -232,6 +234,9 @@ func (h *AdvancedOrderHandler) CreateOrder(c *gin.Context) {
 	order, err := h.orderService.CreateOrder(userID, &request)
 	if err != nil {
 		fmt.Printf("🔥 Order creation failed: %v\n", err)
+		if writeRiskRejection(c, err) {
+			return
+		}
 		c.JSON(http.StatusInternalServerError, ErrorResponse{
 			Error:   "Failed to create order",
 			Message: err.Error(),
@@ -270,6 +275,7 @@ func (h *AdvancedOrderHandler) CreateOrder(c *gin.Context) {
 // @Param oco body OCOOrderRequest true "OCO order details"
 // @Success 201 {object} OCOOrderResponse
 // @Failure 400 {object} ErrorResponse
+// @Failure 422 {object} RiskRejectionResponse
 // @Router /orders/oco [post]
 func (h *AdvancedOrderHandler) CreateOCOOrder(c *gin.Context) {
 	userID := getUserIDFromContext(c)
@@ -297,6 +303,9 @@ func (h *AdvancedOrderHandler) CreateOCOOrder(c *gin.Context) {
 		&request.LinkedOrder,
 	)
 	if err != nil {
+		if writeRiskRejection(c, err) {
+			return
+		}
 		c.JSON(http.StatusInternalServerError, ErrorResponse{
 			Error:   "Failed to create OCO order",
 			Message: err.Error(),
@@ -313,8 +322,213 @@ func (h *AdvancedOrderHandler) CreateOCOOrder(c *gin.Context) {
 	c.JSON(http.StatusCreated, response)
 }
 
+// @Summary Create bracket order
+// @Description Create an entry order with take-profit and stop-loss exits that activate when the entry fills
+// @Tags orders
+// @Accept json
+// @Produce json
+// @Param order body domain.OrderRequest true "Bracket order details (price omitted for a market entry)"
+// @Success 201 {object} BracketOrderResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 422 {object} ErrorResponse
+// @Router /orders/bracket [post]
+func (h *AdvancedOrderHandler) CreateBracketOrder(c *gin.Context) {
+	userID := getUserIDFromContext(c)
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{
+			Error: "Unauthorized",
+		})
+		return
+	}
+
+	var request domain.OrderRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, ValidationErrorResponse{
+			Error:   "Invalid request",
+			Message: "Please check your bracket order parameters",
+			Details: parseValidationErrors(err),
+		})
+		return
+	}
+	request.OrderType = domain.OrderTypeBracket
+
+	if err := h.orderService.ValidateOrder(userID, &request); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, ErrorResponse{
+			Error:   "Order validation failed",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	bracket, err := h.orderService.CreateBracketOrder(userID, &request)
+	if err != nil {
+		if writeRiskRejection(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error:   "Failed to create bracket order",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, BracketOrderResponse{
+		Bracket: *bracket,
+		Message: "Bracket order created successfully",
+	})
+}
+
+// @Summary Get OCO group
+// @Description Get both legs of a One-Cancels-Other group and the group's status
+// @Tags orders
+// @Param groupId path int true "OCO group ID"
+// @Success 200 {object} OCOGroupResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /orders/oco/{groupId} [get]
+func (h *AdvancedOrderHandler) CreateAlgoOrder(c *gin.Context) {
+	userID := getUserIDFromContext(c)
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{
+			Error: "Unauthorized",
+		})
+		return
+	}
+
+	var request domain.OrderRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, ValidationErrorResponse{
+			Error:   "Invalid request",
+			Message: "Please check your algo order parameters",
+			Details: parseValidationErrors(err),
+		})
+		return
+	}
+	if request.OrderType != domain.OrderTypeVWAP {
+		request.OrderType = domain.OrderTypeTWAP
+	}
+
+	if err := h.orderService.ValidateOrder(userID, &request); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, ErrorResponse{
+			Error:   "Order validation failed",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	algo, err := h.orderService.CreateAlgoOrder(userID, &request)
+	if err != nil {
+		if writeRiskRejection(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error:   "Failed to create algo order",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, AlgoOrderResponse{
+		Algo:    *algo,
+		Message: fmt.Sprintf("%s order created successfully", algo.Order.OrderType),
+	})
+}
+
+func (h *AdvancedOrderHandler) GetAlgoOrder(c *gin.Context) {
+	userID := getUserIDFromContext(c)
+	orderID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error: "Invalid order ID",
+		})
+		return
+	}
+
+	algo, err := h.orderService.GetAlgoOrder(userID, orderID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{
+			Error:   "Algo order not found",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, AlgoOrderResponse{Algo: *algo})
+}
+
+func (h *AdvancedOrderHandler) PauseAlgoOrder(c *gin.Context) {
+	userID := getUserIDFromContext(c)
+	orderID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error: "Invalid order ID",
+		})
+		return
+	}
+
+	algo, err := h.orderService.PauseAlgoOrder(userID, orderID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "Failed to pause algo order",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, AlgoOrderResponse{
+		Algo:    *algo,
+		Message: "Algo order paused",
+	})
+}
+
+func (h *AdvancedOrderHandler) ResumeAlgoOrder(c *gin.Context) {
+	userID := getUserIDFromContext(c)
+	orderID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error: "Invalid order ID",
+		})
+		return
+	}
+
+	algo, err := h.orderService.ResumeAlgoOrder(userID, orderID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "Failed to resume algo order",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, AlgoOrderResponse{
+		Algo:    *algo,
+		Message: "Algo order resumed",
+	})
+}
+
+func (h *AdvancedOrderHandler) GetOCOGroup(c *gin.Context) {
+	userID := getUserIDFromContext(c)
+	groupID, err := strconv.Atoi(c.Param("groupId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error: "Invalid OCO group ID",
+		})
+		return
+	}
+
+	group, err := h.orderService.GetOCOGroup(userID, groupID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{
+			Error:   "OCO group not found",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, OCOGroupResponse{Group: *group})
+}
+
 // @Summary Modify an existing order
-// @Description Modify price, quantity, or other parameters of an active order
+// @Description Amend the price, quantity, expiry or other terms of a working order. The order moves to its next version and is validated and risk checked again; quantity cannot go below what has filled.
 // @Tags orders
 // @Accept json
 // @Produce json
@@ -322,6 +536,8 @@ func (h *AdvancedOrderHandler) CreateOCOOrder(c *gin.Context) {
 // @Param modifications body services.OrderModificationRequest true "Order modifications"
 // @Success 200 {object} OrderResponse
 // @Failure 400 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 422 {object} RiskRejectionResponse
 // @Router /orders/{id} [put]
 func (h *AdvancedOrderHandler) ModifyOrder(c *gin.Context) {
 	userID := getUserIDFromContext(c)
@@ -345,6 +561,16 @@ func (h *AdvancedOrderHandler) ModifyOrder(c *gin.Context) {
 
 	modifiedOrder, err := h.orderService.ModifyOrder(userID, orderID, &request)
 	if err != nil {
+		if writeRiskRejection(c, err) {
+			return
+		}
+		if errors.Is(err, domain.ErrOrderVersionConflict) {
+			c.JSON(http.StatusConflict, ErrorResponse{
+				Error:   "Order has changed",
+				Message: err.Error(),
+			})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, ErrorResponse{
 			Error:   "Failed to modify order",
 			Message: err.Error(),
@@ -423,8 +649,100 @@ func (h *AdvancedOrderHandler) CancelAllOrders(c *gin.Context) {
 	c.JSON(http.StatusOK, response)
 }
 
+// @Summary Bulk cancel orders
+// @Description Cancel up to 100 orders in one request. Every distinct ID gets its own result: CANCELLED, NOT_FOUND or NOT_CANCELLABLE.
+// @Tags orders
+// @Accept json
+// @Produce json
+// @Param request body BulkCancelRequest true "Orders to cancel"
+// @Success 200 {object} BulkCancelResponse
+// @Failure 400 {object} ValidationErrorResponse
+// @Router /orders/bulk-cancel [post]
+func (h *AdvancedOrderHandler) BulkCancelOrders(c *gin.Context) {
+	userID := getUserIDFromContext(c)
+
+	var request BulkCancelRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, ValidationErrorResponse{
+			Error:   "Invalid request",
+			Details: parseValidationErrors(err),
+		})
+		return
+	}
+
+	results, err := h.orderService.BulkCancelOrders(userID, request.OrderIDs)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error:   "Failed to cancel orders",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	response := BulkCancelResponse{Results: results}
+	for _, result := range results {
+		if result.Status == services.BulkCancelStatusCancelled {
+			response.Cancelled++
+		} else {
+			response.Failed++
+		}
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// @Summary Rebalance portfolio
+// @Description Move long holdings to target weights, in percent of the portfolio's value, with cash under the CASH key. Sells go first, then buys, as market orders. ALL_OR_NONE (the default) settles the basket as one unit, so every order fills in full or none trades; BEST_EFFORT sends every order. Drifts within tolerance_percent points are skipped, and dry_run previews the trades without sending them.
+// @Tags portfolio
+// @Accept json
+// @Produce json
+// @Param request body domain.RebalanceRequest true "Target weights"
+// @Success 200 {object} RebalanceResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 422 {object} RiskRejectionResponse
+// @Router /portfolio/rebalance [post]
+func (h *AdvancedOrderHandler) RebalancePortfolio(c *gin.Context) {
+	userID := getUserIDFromContext(c)
+
+	var request domain.RebalanceRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, ValidationErrorResponse{
+			Error:   "Invalid request",
+			Details: parseValidationErrors(err),
+		})
+		return
+	}
+
+	rebalance, err := h.orderService.Rebalance(userID, &request)
+	if err != nil {
+		if writeRiskRejection(c, err) {
+			return
+		}
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "Failed to rebalance portfolio",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	message := "Portfolio rebalanced"
+	switch rebalance.Status {
+	case domain.RebalanceStatusPreview:
+		message = "Rebalance preview; no orders were sent"
+	case domain.RebalanceStatusPartial:
+		message = "Portfolio partly rebalanced"
+	case domain.RebalanceStatusAborted:
+		message = "Rebalance rolled back: " + rebalance.Error
+	}
+
+	c.JSON(http.StatusOK, RebalanceResponse{
+		Rebalance: *rebalance,
+		Message:   message,
+	})
+}
+
 // @Summary Get user orders
-// @Description Get orders for the authenticated user with filtering and pagination
+// @Description Get orders for the authenticated user with filtering and pagination; takes the same filters as /orders/search
 // @Tags orders
 // @Param status query string false "Order status filter"
 // @Param symbol query string false "Symbol filter"
@@ -434,42 +752,43 @@ func (h *AdvancedOrderHandler) CancelAllOrders(c *gin.Context) {
 // @Param limit query int false "Number of results per page" default(20)
 // @Param offset query int false "Number of results to skip" default(0)
 // @Success 200 {object} OrderListResponse
+// @Failure 400 {object} ErrorResponse
 // @Router /orders [get]
 func (h *AdvancedOrderHandler) GetUserOrders(c *gin.Context) {
 	fmt.Printf("🟦 GetUserOrders called - Method: %s, Path: %s\n", c.Request.Method, c.Request.URL.Path)
-	userID := getUserIDFromContext(c)
-	
-	// Parse query parameters
-	criteria := &repositories.OrderSearchCriteria{
-		UserID: &userID,
-		Limit:  getIntQuery(c, "limit", 20),
-		Offset: getIntQuery(c, "offset", 0),
-	}
-
-	if status := c.Query("status"); status != "" {
-		orderStatus := domain.OrderStatus(status)
-		criteria.Status = &orderStatus
-	}
-
-	if symbol := c.Query("symbol"); symbol != "" {
-		criteria.Symbol = &symbol
-	}
-
-	if orderType := c.Query("order_type"); orderType != "" {
-		ot := domain.OrderType(orderType)
-		criteria.OrderType = &ot
-	}
+	h.SearchOrders(c)
+}
 
-	if startDate := c.Query("start_date"); startDate != "" {
-		if t, err := time.Parse(time.RFC3339, startDate); err == nil {
-			criteria.StartDate = &t
-		}
-	}
+// @Summary Search orders
+// @Description Search the authenticated user's orders by any combination of filters, a page at a time
+// @Tags orders
+// @Param symbol query string false "Symbol"
+// @Param order_type query string false "Order type"
+// @Param status query string false "Order status"
+// @Param side query string false "Order side"
+// @Param start_date query string false "Placed at or after (RFC3339 format)"
+// @Param end_date query string false "Placed at or before (RFC3339 format)"
+// @Param min_price query number false "Lowest limit price"
+// @Param max_price query number false "Highest limit price"
+// @Param min_quantity query int false "Smallest quantity"
+// @Param max_quantity query int false "Largest quantity"
+// @Param sort_by query string false "created_at, updated_at, executed_at, price or quantity" default(created_at)
+// @Param sort_order query string false "ASC or DESC" default(DESC)
+// @Param limit query int false "Number of results per page, at most 100" default(20)
+// @Param offset query int false "Number of results to skip" default(0)
+// @Success 200 {object} OrderListResponse
+// @Failure 400 {object} ErrorResponse
+// @Router /orders/search [get]
+func (h *AdvancedOrderHandler) SearchOrders(c *gin.Context) {
+	userID := getUserIDFromContext(c)
 
-	if endDate := c.Query("end_date"); endDate != "" {
-		if t, err := time.Parse(time.RFC3339, endDate); err == nil {
-			criteria.EndDate = &t
-		}
+	criteria, err := parseOrderSearchCriteria(c, userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "Invalid search criteria",
+			Message: err.Error(),
+		})
+		return
 	}
 
 	result, err := h.orderService.SearchOrders(userID, criteria)
@@ -492,6 +811,103 @@ func (h *AdvancedOrderHandler) GetUserOrders(c *gin.Context) {
 	c.JSON(http.StatusOK, response)
 }
 
+// parseOrderSearchCriteria reads the search filters from the query string,
+// scoped to the caller's own orders
+func parseOrderSearchCriteria(c *gin.Context, userID int) (*repositories.OrderSearchCriteria, error) {
+	criteria := &repositories.OrderSearchCriteria{
+		UserID:    &userID,
+		SortBy:    c.DefaultQuery("sort_by", "created_at"),
+		SortOrder: strings.ToUpper(c.DefaultQuery("sort_order", "DESC")),
+	}
+
+	if symbol := c.Query("symbol"); symbol != "" {
+		criteria.Symbol = &symbol
+	}
+	if orderType := c.Query("order_type"); orderType != "" {
+		ot := domain.OrderType(orderType)
+		criteria.OrderType = &ot
+	}
+	if status := c.Query("status"); status != "" {
+		orderStatus := domain.OrderStatus(status)
+		criteria.Status = &orderStatus
+	}
+	if side := c.Query("side"); side != "" {
+		orderSide := domain.OrderSide(side)
+		criteria.Side = &orderSide
+	}
+
+	parseTime := func(value string) (time.Time, error) { return time.Parse(time.RFC3339, value) }
+	parseFloat := func(value string) (float64, error) { return strconv.ParseFloat(value, 64) }
+
+	var err error
+	if criteria.StartDate, err = optionalQuery(c, "start_date", parseTime); err != nil {
+		return nil, err
+	}
+	if criteria.EndDate, err = optionalQuery(c, "end_date", parseTime); err != nil {
+		return nil, err
+	}
+	if criteria.MinPrice, err = optionalQuery(c, "min_price", parseFloat); err != nil {
+		return nil, err
+	}
+	if criteria.MaxPrice, err = optionalQuery(c, "max_price", parseFloat); err != nil {
+		return nil, err
+	}
+	if criteria.MinQuantity, err = optionalQuery(c, "min_quantity", strconv.Atoi); err != nil {
+		return nil, err
+	}
+	if criteria.MaxQuantity, err = optionalQuery(c, "max_quantity", strconv.Atoi); err != nil {
+		return nil, err
+	}
+
+	limit, err := optionalQuery(c, "limit", strconv.Atoi)
+	if err != nil {
+		return nil, err
+	}
+	offset, err := optionalQuery(c, "offset", strconv.Atoi)
+	if err != nil {
+		return nil, err
+	}
+	criteria.Limit = repositories.DefaultOrderSearchLimit
+	if limit != nil {
+		criteria.Limit = *limit
+	}
+	if offset != nil {
+		criteria.Offset = *offset
+	}
+
+	switch {
+	case !repositories.OrderSortFields[criteria.SortBy]:
+		return nil, fmt.Errorf("cannot sort by %s", criteria.SortBy)
+	case criteria.SortOrder != "ASC" && criteria.SortOrder != "DESC":
+		return nil, fmt.Errorf("sort_order must be ASC or DESC")
+	case criteria.Limit < 1 || criteria.Limit > repositories.MaxOrderSearchLimit:
+		return nil, fmt.Errorf("limit must be between 1 and %d", repositories.MaxOrderSearchLimit)
+	case criteria.Offset < 0:
+		return nil, fmt.Errorf("offset cannot be negative")
+	case criteria.StartDate != nil && criteria.EndDate != nil && criteria.EndDate.Before(*criteria.StartDate):
+		return nil, fmt.Errorf("end_date is before start_date")
+	case criteria.MinPrice != nil && criteria.MaxPrice != nil && *criteria.MaxPrice < *criteria.MinPrice:
+		return nil, fmt.Errorf("max_price is below min_price")
+	case criteria.MinQuantity != nil && criteria.MaxQuantity != nil && *criteria.MaxQuantity < *criteria.MinQuantity:
+		return nil, fmt.Errorf("max_quantity is below min_quantity")
+	}
+
+	return criteria, nil
+}
+
+// optionalQuery parses a query parameter, giving nil when it is absent
+func optionalQuery[T any](c *gin.Context, key string, parse func(string) (T, error)) (*T, error) {
+	value := c.Query(key)
+	if value == "" {
+		return nil, nil
+	}
+	parsed, err := parse(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %s", key, value)
+	}
+	return &parsed, nil
+}
+
 // @Summary Get order by ID
 // @Description Get detailed information about a specific order
 // @Tags orders
@@ -525,6 +941,102 @@ func (h *AdvancedOrderHandler) GetOrderByID(c *gin.Context) {
 	c.JSON(http.StatusOK, response)
 }
 
+// @Summary Get order executions
+// @Description Get the individual fills of an order, oldest first
+// @Tags orders
+// @Param id path int true "Order ID"
+// @Success 200 {object} OrderExecutionsResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /orders/{id}/executions [get]
+func (h *AdvancedOrderHandler) GetOrderExecutions(c *gin.Context) {
+	userID := getUserIDFromContext(c)
+	orderID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error: "Invalid order ID",
+		})
+		return
+	}
+
+	executions, err := h.orderService.GetOrderExecutions(userID, orderID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{
+			Error:   "Order not found",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, OrderExecutionsResponse{
+		Executions: executions,
+		Total:      len(executions),
+	})
+}
+
+// @Summary Get order events
+// @Description Get every status change of an order with its reason and actor, oldest first
+// @Tags orders
+// @Param id path int true "Order ID"
+// @Success 200 {object} OrderEventsResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /orders/{id}/events [get]
+func (h *AdvancedOrderHandler) GetOrderEvents(c *gin.Context) {
+	userID := getUserIDFromContext(c)
+	orderID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error: "Invalid order ID",
+		})
+		return
+	}
+
+	events, err := h.orderService.GetOrderEvents(userID, orderID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{
+			Error:   "Order not found",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, OrderEventsResponse{
+		Events: events,
+		Total:  len(events),
+	})
+}
+
+// @Summary Get order versions
+// @Description Get the terms of every version of an amended order, oldest first
+// @Tags orders
+// @Param id path int true "Order ID"
+// @Success 200 {object} OrderVersionsResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /orders/{id}/versions [get]
+func (h *AdvancedOrderHandler) GetOrderVersions(c *gin.Context) {
+	userID := getUserIDFromContext(c)
+	orderID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error: "Invalid order ID",
+		})
+		return
+	}
+
+	versions, err := h.orderService.GetOrderVersions(userID, orderID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{
+			Error:   "Order not found",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, OrderVersionsResponse{
+		Versions: versions,
+		Total:    len(versions),
+	})
+}
+
 // @Summary Get active orders
 // @Description Get all active orders for the authenticated user
 // @Tags orders
@@ -577,12 +1089,19 @@ func (h *AdvancedOrderHandler) GetOrderStatistics(c *gin.Context) {
 // @Summary Get execution metrics
 // @Description Get detailed execution performance metrics
 // @Tags orders
-// @Param timeframe query string false "Timeframe (day, week, month, year)" default(month)
+// @Param timeframe query string false "Timeframe (day, week, month, year, all)" default(month)
 // @Success 200 {object} ExecutionMetricsResponse
 // @Router /orders/execution-metrics [get]
 func (h *AdvancedOrderHandler) GetExecutionMetrics(c *gin.Context) {
 	userID := getUserIDFromContext(c)
 	timeframe := c.DefaultQuery("timeframe", "month")
+	if _, ok := domain.TimeframeStart(timeframe, time.Now()); !ok {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "Invalid timeframe",
+			Message: "Timeframe must be one of day, week, month, year or all",
+		})
+		return
+	}
 
 	metrics, err := h.orderService.GetExecutionMetrics(userID, timeframe)
 	if err != nil {
@@ -700,6 +1219,35 @@ type OCOOrderResponse struct {
 	Message     string       `json:"message"`
 }
 
+type BracketOrderResponse struct {
+	Bracket domain.BracketOrder `json:"bracket"`
+	Message string              `json:"message"`
+}
+
+type AlgoOrderResponse struct {
+	Algo    domain.AlgoOrder `json:"algo_order"`
+	Message string           `json:"message,omitempty"`
+}
+
+type OrderExecutionsResponse struct {
+	Executions []domain.OrderExecution `json:"executions"`
+	Total      int                     `json:"total"`
+}
+
+type OrderEventsResponse struct {
+	Events []domain.OrderEvent `json:"events"`
+	Total  int                 `json:"total"`
+}
+
+type OrderVersionsResponse struct {
+	Versions []domain.OrderVersion `json:"versions"`
+	Total    int                   `json:"total"`
+}
+
+type OCOGroupResponse struct {
+	Group domain.OCOGroup `json:"group"`
+}
+
 type OrderListResponse struct {
 	Orders     []domain.Order `json:"orders"`
 	Total      int           `json:"total"`
@@ -713,6 +1261,21 @@ type CancelAllOrdersResponse struct {
 	Message        string `json:"message"`
 }
 
+type BulkCancelRequest struct {
+	OrderIDs []int `json:"order_ids" binding:"required,min=1,max=100"`
+}
+
+type BulkCancelResponse struct {
+	Results   []services.BulkCancelResult `json:"results"`
+	Cancelled int                         `json:"cancelled"`
+	Failed    int                         `json:"failed"` // Orders not cancelled, whatever the reason
+}
+
+type RebalanceResponse struct {
+	Rebalance domain.Rebalance `json:"rebalance"`
+	Message   string           `json:"message"`
+}
+
 type OrderStatsResponse struct {
 	Statistics domain.OrderStats `json:"statistics"`
 }
@@ -750,9 +1313,33 @@ type MessageResponse struct {
 	Message string `json:"message"`
 }
 
+type RiskRejectionResponse struct {
+	Error   string                `json:"error"`
+	Code    domain.RiskReasonCode `json:"code"`
+	Message string                `json:"message"`
+	Limit   float64               `json:"limit"`
+}
+
 // Helper functions
+
+// writeRiskRejection answers 422 with the reason code when err is a
+// pre-trade risk rejection, and reports whether it did
+func writeRiskRejection(c *gin.Context, err error) bool {
+	var rejection *domain.RiskRejection
+	if !errors.As(err, &rejection) {
+		return false
+	}
+	c.JSON(http.StatusUnprocessableEntity, RiskRejectionResponse{
+		Error:   "Order rejected by risk checks",
+		Code:    rejection.Code,
+		Message: rejection.Message,
+		Limit:   rejection.Limit,
+	})
+	return true
+}
+
 func getUserIDFromContext(c *gin.Context) int {
-	if userID, exists := c.Get("user_id"); exists {
+	if userID, exists := c.Get("userID"); exists {
 		if id, ok := userID.(int); ok {
 			return id
 		}
@@ -760,15 +1347,6 @@ func getUserIDFromContext(c *gin.Context) int {
 	return 0
 }
 
-func getIntQuery(c *gin.Context, key string, defaultValue int) int {
-	if value := c.Query(key); value != "" {
-		if intValue, err := strconv.Atoi(value); err == nil {
-			return intValue
-		}
-	}
-	return defaultValue
-}
-
 func parseValidationErrors(err error) map[string]string {
 	// This would parse validation errors from the binding
 	// Implementation depends on your validation library
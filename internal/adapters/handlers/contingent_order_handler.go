@@ -0,0 +1,160 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"stock-simulation-backend/internal/core/domain"
+	"stock-simulation-backend/internal/core/ports/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ContingentOrderHandler struct {
+	contingentOrderService services.ContingentOrderService
+}
+
+type ContingentOrderResponse struct {
+	Contingent domain.ContingentOrder `json:"contingent_order"`
+	Message    string                 `json:"message,omitempty"`
+}
+
+func NewContingentOrderHandler(contingentOrderService services.ContingentOrderService) *ContingentOrderHandler {
+	return &ContingentOrderHandler{
+		contingentOrderService: contingentOrderService,
+	}
+}
+
+func (h *ContingentOrderHandler) CreateContingentOrder(c *gin.Context) {
+	userID := getUserIDFromContext(c)
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{
+			Error: "Unauthorized",
+		})
+		return
+	}
+
+	var request domain.ContingentOrderRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, ValidationErrorResponse{
+			Error:   "Invalid request",
+			Message: "Please check your contingent order parameters",
+			Details: parseValidationErrors(err),
+		})
+		return
+	}
+
+	contingent, err := h.contingentOrderService.CreateContingentOrder(userID, &request)
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, ErrorResponse{
+			Error:   "Failed to create contingent order",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, ContingentOrderResponse{
+		Contingent: *contingent,
+		Message:    "Contingent order created successfully",
+	})
+}
+
+func (h *ContingentOrderHandler) GetContingentOrders(c *gin.Context) {
+	userID := getUserIDFromContext(c)
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{
+			Error: "Unauthorized",
+		})
+		return
+	}
+
+	contingents, err := h.contingentOrderService.GetUserContingentOrders(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error:   "Failed to get contingent orders",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"contingent_orders": contingents})
+}
+
+func (h *ContingentOrderHandler) GetContingentOrder(c *gin.Context) {
+	userID := getUserIDFromContext(c)
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error: "Invalid contingent order ID",
+		})
+		return
+	}
+
+	contingent, err := h.contingentOrderService.GetContingentOrder(userID, id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{
+			Error:   "Contingent order not found",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, ContingentOrderResponse{Contingent: *contingent})
+}
+
+func (h *ContingentOrderHandler) UpdateContingentOrder(c *gin.Context) {
+	userID := getUserIDFromContext(c)
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error: "Invalid contingent order ID",
+		})
+		return
+	}
+
+	var request domain.ContingentOrderRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, ValidationErrorResponse{
+			Error:   "Invalid request",
+			Message: "Please check your contingent order parameters",
+			Details: parseValidationErrors(err),
+		})
+		return
+	}
+
+	contingent, err := h.contingentOrderService.UpdateContingentOrder(userID, id, &request)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "Failed to update contingent order",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, ContingentOrderResponse{
+		Contingent: *contingent,
+		Message:    "Contingent order updated successfully",
+	})
+}
+
+func (h *ContingentOrderHandler) CancelContingentOrder(c *gin.Context) {
+	userID := getUserIDFromContext(c)
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error: "Invalid contingent order ID",
+		})
+		return
+	}
+
+	if err := h.contingentOrderService.CancelContingentOrder(userID, id); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "Failed to cancel contingent order",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, MessageResponse{
+		Message: "Contingent order cancelled successfully",
+	})
+}
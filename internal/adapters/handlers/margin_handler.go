@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"stock-simulation-backend/internal/core/domain"
+	"stock-simulation-backend/internal/core/ports/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type MarginHandler struct {
+	marginService services.MarginService
+}
+
+func NewMarginHandler(marginService services.MarginService) *MarginHandler {
+	return &MarginHandler{
+		marginService: marginService,
+	}
+}
+
+func (h *MarginHandler) GetMarginAccount(c *gin.Context) {
+	userID := getUserIDFromContext(c)
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	status, err := h.marginService.GetMarginStatus(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"margin": status})
+}
+
+func (h *MarginHandler) UpdateMarginAccount(c *gin.Context) {
+	userID := getUserIDFromContext(c)
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req struct {
+		AccountType string `json:"account_type" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	accountType := domain.AccountType(strings.ToUpper(req.AccountType))
+	status, err := h.marginService.SetAccountType(userID, accountType)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"margin": status})
+}
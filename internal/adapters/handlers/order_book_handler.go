@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"stock-simulation-backend/internal/core/ports/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+const maxOrderBookDepth = 50
+
+type OrderBookHandler struct {
+	orderBookService services.OrderBookService
+}
+
+func NewOrderBookHandler(orderBookService services.OrderBookService) *OrderBookHandler {
+	return &OrderBookHandler{
+		orderBookService: orderBookService,
+	}
+}
+
+func (h *OrderBookHandler) GetOrderBook(c *gin.Context) {
+	symbol := strings.ToUpper(c.Param("symbol"))
+	if symbol == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Symbol is required"})
+		return
+	}
+
+	depth, err := strconv.Atoi(c.DefaultQuery("depth", "10"))
+	if err != nil || depth < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid depth parameter"})
+		return
+	}
+	if depth > maxOrderBookDepth {
+		depth = maxOrderBookDepth
+	}
+
+	book, err := h.orderBookService.GetOrderBook(symbol, depth)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"order_book": book})
+}
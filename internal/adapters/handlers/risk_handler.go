@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"net/http"
+	"stock-simulation-backend/internal/core/domain"
+	"stock-simulation-backend/internal/core/ports/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type RiskHandler struct {
+	riskManager services.RiskManager
+}
+
+func NewRiskHandler(riskManager services.RiskManager) *RiskHandler {
+	return &RiskHandler{
+		riskManager: riskManager,
+	}
+}
+
+// GetRiskReport returns the account's risk metrics and its positions' risk
+func (h *RiskHandler) GetRiskReport(c *gin.Context) {
+	userID := getUserIDFromContext(c)
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	metrics, err := h.riskManager.CalculateRiskMetrics(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	positions, err := h.riskManager.MonitorPositions(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"metrics": metrics, "positions": positions})
+}
+
+func (h *RiskHandler) GetRiskLimits(c *gin.Context) {
+	userID := getUserIDFromContext(c)
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	limits, err := h.riskManager.GetRiskLimits(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"limits": limits})
+}
+
+// UpdateRiskLimits sets the user's own limits, which may only be tighter
+// than the global ones. Omitted fields go back to the global limit.
+func (h *RiskHandler) UpdateRiskLimits(c *gin.Context) {
+	userID := getUserIDFromContext(c)
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var overrides domain.RiskLimitOverrides
+	if err := c.ShouldBindJSON(&overrides); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	limits, err := h.riskManager.UpdateRiskLimits(userID, &overrides)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"limits": limits})
+}
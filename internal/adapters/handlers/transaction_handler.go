@@ -34,6 +34,9 @@ func (h *TransactionHandler) BuyStock(c *gin.Context) {
 
 	response, err := h.transactionService.BuyStock(userID.(int), &req)
 	if err != nil {
+		if writeRiskRejection(c, err) {
+			return
+		}
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
@@ -56,6 +59,9 @@ func (h *TransactionHandler) SellStock(c *gin.Context) {
 
 	response, err := h.transactionService.SellStock(userID.(int), &req)
 	if err != nil {
+		if writeRiskRejection(c, err) {
+			return
+		}
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
@@ -0,0 +1,105 @@
+package middleware
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"stock-simulation-backend/internal/core/domain"
+	"stock-simulation-backend/internal/core/ports/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// IdempotencyKeyHeader carries the client's key for a retryable request
+	IdempotencyKeyHeader = "Idempotency-Key"
+
+	// IdempotentReplayedHeader marks a response replayed from an earlier request
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+
+	maxIdempotencyKeyLength = 255
+)
+
+// responseRecorder keeps a copy of the response body as it is written
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *responseRecorder) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *responseRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// Idempotency middleware makes trading requests safe to retry. Requests
+// without an Idempotency-Key header pass straight through; a retry with a known key
+// gets the original response, and a key reused for a different request gets
+// 409. Server errors free the key so the retry runs again. Must run after Auth.
+func Idempotency(idempotencyService services.IdempotencyService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := strings.TrimSpace(c.GetHeader(IdempotencyKeyHeader))
+		if key == "" || idempotencyService == nil {
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Idempotency key is too long"})
+			c.Abort()
+			return
+		}
+
+		userID := c.GetInt("userID")
+		if userID == 0 {
+			c.Next()
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read request body"})
+			c.Abort()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		requestHash := domain.IdempotencyRequestHash(c.Request.Method, c.Request.URL.Path, body)
+
+		record, err := idempotencyService.Begin(userID, key, requestHash)
+		switch {
+		case errors.Is(err, domain.ErrIdempotencyKeyReused), errors.Is(err, domain.ErrIdempotencyKeyInFlight):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			c.Abort()
+			return
+		case err != nil:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check idempotency key"})
+			c.Abort()
+			return
+		case record != nil:
+			c.Header(IdempotentReplayedHeader, "true")
+			c.Data(record.StatusCode, "application/json; charset=utf-8", record.ResponseBody)
+			c.Abort()
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		c.Next()
+
+		status := recorder.Status()
+		if status >= http.StatusInternalServerError {
+			err = idempotencyService.Release(userID, key)
+		} else {
+			err = idempotencyService.Complete(userID, key, requestHash, status, recorder.body.Bytes())
+		}
+		if err != nil {
+			c.Error(err)
+		}
+	}
+}
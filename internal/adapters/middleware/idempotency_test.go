@@ -0,0 +1,108 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"stock-simulation-backend/internal/core/domain"
+
+	"github.com/gin-gonic/gin"
+)
+
+// fakeIdempotencyService keeps keys in memory with the semantics of the
+// IdempotencyService port
+type fakeIdempotencyService struct {
+	records map[string]*domain.IdempotencyRecord
+}
+
+func (s *fakeIdempotencyService) Begin(userID int, key, requestHash string) (*domain.IdempotencyRecord, error) {
+	record, ok := s.records[key]
+	switch {
+	case !ok:
+		s.records[key] = &domain.IdempotencyRecord{UserID: userID, Key: key, RequestHash: requestHash}
+		return nil, nil
+	case record.RequestHash != requestHash:
+		return nil, domain.ErrIdempotencyKeyReused
+	case !record.IsCompleted():
+		return nil, domain.ErrIdempotencyKeyInFlight
+	}
+	return record, nil
+}
+
+func (s *fakeIdempotencyService) Complete(userID int, key, requestHash string, statusCode int, body []byte) error {
+	record := s.records[key]
+	record.StatusCode, record.ResponseBody = statusCode, body
+	record.CompletedAt = &record.CreatedAt
+	return nil
+}
+
+func (s *fakeIdempotencyService) Release(userID int, key string) error {
+	delete(s.records, key)
+	return nil
+}
+
+func TestIdempotency(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	service := &fakeIdempotencyService{records: map[string]*domain.IdempotencyRecord{
+		"busy": {UserID: 1, Key: "busy", RequestHash: domain.IdempotencyRequestHash(http.MethodPost, "/orders", []byte(`{"quantity":1}`))},
+	}}
+
+	// The handler fails while failing is set, and numbers its responses
+	calls, failing := 0, false
+	router := gin.New()
+	router.Use(func(c *gin.Context) { c.Set("userID", 1) }, Idempotency(service))
+	router.POST("/orders", func(c *gin.Context) {
+		calls++
+		if failing {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "database unavailable"})
+			return
+		}
+		c.JSON(http.StatusCreated, gin.H{"id": calls})
+	})
+
+	tests := []struct {
+		name         string
+		key          string
+		body         string
+		failing      bool
+		wantStatus   int
+		wantBody     string
+		wantReplayed bool
+		wantCalls    int
+	}{
+		{"first request runs", "a", `{"quantity":1}`, false, http.StatusCreated, `{"id":1}`, false, 1},
+		{"retry replays the response", "a", `{"quantity":1}`, false, http.StatusCreated, `{"id":1}`, true, 1},
+		{"key reused for another request", "a", `{"quantity":2}`, false, http.StatusConflict, "", false, 1},
+		{"request still in flight", "busy", `{"quantity":1}`, false, http.StatusConflict, "", false, 1},
+		{"requests without a key always run", "", `{"quantity":1}`, false, http.StatusCreated, `{"id":2}`, false, 2},
+		{"server error frees the key", "b", `{"quantity":1}`, true, http.StatusInternalServerError, "", false, 3},
+		{"retry after a server error runs again", "b", `{"quantity":1}`, false, http.StatusCreated, `{"id":4}`, false, 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			failing = tt.failing
+			request := httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader(tt.body))
+			if tt.key != "" {
+				request.Header.Set(IdempotencyKeyHeader, tt.key)
+			}
+			response := httptest.NewRecorder()
+			router.ServeHTTP(response, request)
+
+			if response.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", response.Code, tt.wantStatus)
+			}
+			if tt.wantBody != "" && response.Body.String() != tt.wantBody {
+				t.Errorf("body = %s, want %s", response.Body.String(), tt.wantBody)
+			}
+			if replayed := response.Header().Get(IdempotentReplayedHeader) == "true"; replayed != tt.wantReplayed {
+				t.Errorf("replayed = %v, want %v", replayed, tt.wantReplayed)
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("handler ran %d times, want %d", calls, tt.wantCalls)
+			}
+		})
+	}
+}
@@ -3,6 +3,7 @@ package mysql
 import (
 	"database/sql"
 	"fmt"
+	"math"
 	"strings"
 	"time"
 
@@ -11,27 +12,122 @@ import (
 )
 
 type AdvancedOrderRepository struct {
-	db *sql.DB
+	db dbExecutor
 }
 
 func NewAdvancedOrderRepository(db *sql.DB) repositories.AdvancedOrderRepositoryWithSearch {
 	return &AdvancedOrderRepository{db: db}
 }
 
+// orderColumns is the column list every order query selects, in the order
+// expected by scanOrder.
+const orderColumns = `id, user_id, stock_symbol, order_type, side, quantity, price, stop_price,
+		       trailing_amount, trailing_percent, high_water_mark, triggered_at, time_in_force, status,
+		       version, executed_price, executed_quantity, remaining_quantity, display_quantity,
+		       visible_remaining, priority_at, market_price, bid_price,
+		       ask_price, commission, fees, spread, executed_at, expires_at, parent_order_id,
+		       linked_order_id, oco_group_id, cancel_reason, created_at, updated_at`
+
+// activeStatuses is the SQL list of statuses an order can still trade in
+const activeStatuses = `('PENDING', 'PARTIALLY_FILLED')`
+
+// cancellableStatuses adds the paused algo orders, which cannot trade but can
+// still be cancelled
+const cancellableStatuses = `('PENDING', 'PARTIALLY_FILLED', 'PAUSED')`
+
+// algoParentTypes is the SQL list of algo parent order types. A parent's
+// fills are its slices' fills, so execution metrics count the slices and
+// leave the parents out.
+const algoParentTypes = `('TWAP', 'VWAP')`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanOrder(row rowScanner) (*domain.Order, error) {
+	var order domain.Order
+	err := row.Scan(
+		&order.ID, &order.UserID, &order.StockSymbol, &order.OrderType, &order.Side,
+		&order.Quantity, &order.Price, &order.StopPrice, &order.TrailingAmount,
+		&order.TrailingPercent, &order.HighWaterMark, &order.TriggeredAt, &order.TimeInForce, &order.Status, &order.Version, &order.ExecutedPrice,
+		&order.ExecutedQuantity, &order.RemainingQuantity, &order.DisplayQuantity,
+		&order.VisibleRemaining, &order.PriorityAt, &order.MarketPrice,
+		&order.BidPrice, &order.AskPrice, &order.Commission, &order.Fees, &order.Spread,
+		&order.ExecutedAt, &order.ExpiresAt, &order.ParentOrderID, &order.LinkedOrderID,
+		&order.OCOGroupID, &order.CancelReason, &order.CreatedAt, &order.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+// queryOrders runs a SELECT built on orderColumns and scans every row.
+func (r *AdvancedOrderRepository) queryOrders(query string, args ...interface{}) ([]domain.Order, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	orders := []domain.Order{}
+	for rows.Next() {
+		order, err := scanOrder(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan order: %w", err)
+		}
+		orders = append(orders, *order)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate orders: %w", err)
+	}
+
+	return orders, nil
+}
+
+// Create stores a new order and opens its event log. Orders placed by an
+// algo parent are logged as the system's; everything else as the user's.
 func (r *AdvancedOrderRepository) Create(order *domain.Order) error {
+	if !domain.CanTransition("", order.Status) {
+		return fmt.Errorf("orders cannot be created with status %s", order.Status)
+	}
+	if order.PriorityAt.IsZero() {
+		order.PriorityAt = time.Now()
+	}
+
+	return r.inTx(func(tx *AdvancedOrderRepository) error {
+		if err := tx.insert(order); err != nil {
+			return err
+		}
+
+		reason, actor := "Order placed", domain.OrderActorUser
+		if order.ParentOrderID != nil && order.Status != domain.OrderStatusDormant {
+			reason = fmt.Sprintf("Slice of parent order #%d", *order.ParentOrderID)
+			actor = domain.OrderActorSystem
+		}
+		return tx.recordEvent(order.ID, nil, order.Status, reason, actor)
+	})
+}
+
+func (r *AdvancedOrderRepository) insert(order *domain.Order) error {
 	query := `
 		INSERT INTO advanced_orders 
 		(user_id, stock_symbol, order_type, side, quantity, price, stop_price, trailing_amount, 
-		 trailing_percent, time_in_force, status, market_price, bid_price, ask_price, 
-		 commission, fees, expires_at, parent_order_id, linked_order_id)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		 trailing_percent, high_water_mark, time_in_force, status, executed_quantity, remaining_quantity,
+		 display_quantity, visible_remaining, priority_at,
+		 market_price, bid_price, ask_price, commission, fees, spread, expires_at,
+		 parent_order_id, linked_order_id, oco_group_id)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
 	result, err := r.db.Exec(query,
 		order.UserID, order.StockSymbol, order.OrderType, order.Side, order.Quantity,
 		order.Price, order.StopPrice, order.TrailingAmount, order.TrailingPercent,
-		order.TimeInForce, order.Status, order.MarketPrice, order.BidPrice, order.AskPrice,
-		order.Commission, order.Fees, order.ExpiresAt, order.ParentOrderID, order.LinkedOrderID,
+		order.HighWaterMark, order.TimeInForce, order.Status, order.ExecutedQuantity, order.RemainingQuantity,
+		order.DisplayQuantity, order.VisibleRemaining, order.PriorityAt,
+		order.MarketPrice, order.BidPrice, order.AskPrice, order.Commission, order.Fees,
+		order.Spread, order.ExpiresAt, order.ParentOrderID, order.LinkedOrderID, order.OCOGroupID,
 	)
 
 	if err != nil {
@@ -44,6 +140,7 @@ func (r *AdvancedOrderRepository) Create(order *domain.Order) error {
 	}
 
 	order.ID = int(id)
+	order.Version = 1
 	order.CreatedAt = time.Now()
 	order.UpdatedAt = time.Now()
 
@@ -52,26 +149,12 @@ func (r *AdvancedOrderRepository) Create(order *domain.Order) error {
 
 func (r *AdvancedOrderRepository) GetByID(orderID int) (*domain.Order, error) {
 	query := `
-		SELECT id, user_id, stock_symbol, order_type, side, quantity, price, stop_price,
-		       trailing_amount, trailing_percent, time_in_force, status, executed_price,
-		       executed_quantity, remaining_quantity, market_price, bid_price, ask_price,
-		       commission, fees, spread, executed_at, expires_at, parent_order_id,
-		       linked_order_id, created_at, updated_at
+		SELECT ` + orderColumns + `
 		FROM advanced_orders 
 		WHERE id = ?
 	`
 
-	var order domain.Order
-	err := r.db.QueryRow(query, orderID).Scan(
-		&order.ID, &order.UserID, &order.StockSymbol, &order.OrderType, &order.Side,
-		&order.Quantity, &order.Price, &order.StopPrice, &order.TrailingAmount,
-		&order.TrailingPercent, &order.TimeInForce, &order.Status, &order.ExecutedPrice,
-		&order.ExecutedQuantity, &order.RemainingQuantity, &order.MarketPrice,
-		&order.BidPrice, &order.AskPrice, &order.Commission, &order.Fees, &order.Spread,
-		&order.ExecutedAt, &order.ExpiresAt, &order.ParentOrderID, &order.LinkedOrderID,
-		&order.CreatedAt, &order.UpdatedAt,
-	)
-
+	order, err := scanOrder(r.db.QueryRow(query, orderID))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, fmt.Errorf("order not found")
@@ -79,21 +162,46 @@ func (r *AdvancedOrderRepository) GetByID(orderID int) (*domain.Order, error) {
 		return nil, fmt.Errorf("failed to get order: %w", err)
 	}
 
-	return &order, nil
+	return order, nil
+}
+
+// GetByIDForUpdate reads the order with a row lock held until the surrounding
+// transaction ends, so a fill and a cancel cannot both act on it.
+func (r *AdvancedOrderRepository) GetByIDForUpdate(orderID int) (*domain.Order, error) {
+	query := `
+		SELECT ` + orderColumns + `
+		FROM advanced_orders 
+		WHERE id = ?
+		FOR UPDATE
+	`
+
+	order, err := scanOrder(r.db.QueryRow(query, orderID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("order not found")
+		}
+		return nil, fmt.Errorf("failed to lock order: %w", err)
+	}
+
+	return order, nil
 }
 
+// Update saves an order's terms and fill state. Status is left alone; it only
+// changes through the transition methods, which keep the event log.
 func (r *AdvancedOrderRepository) Update(order *domain.Order) error {
 	query := `
 		UPDATE advanced_orders 
-		SET price = ?, stop_price = ?, quantity = ?, time_in_force = ?, status = ?,
+		SET price = ?, stop_price = ?, quantity = ?, time_in_force = ?,
 		    executed_price = ?, executed_quantity = ?, remaining_quantity = ?,
+		    visible_remaining = ?, priority_at = ?,
 		    trailing_amount = ?, trailing_percent = ?, expires_at = ?, updated_at = NOW()
 		WHERE id = ? AND user_id = ?
 	`
 
 	_, err := r.db.Exec(query,
-		order.Price, order.StopPrice, order.Quantity, order.TimeInForce, order.Status,
+		order.Price, order.StopPrice, order.Quantity, order.TimeInForce,
 		order.ExecutedPrice, order.ExecutedQuantity, order.RemainingQuantity,
+		order.VisibleRemaining, order.PriorityAt,
 		order.TrailingAmount, order.TrailingPercent, order.ExpiresAt,
 		order.ID, order.UserID,
 	)
@@ -107,218 +215,225 @@ func (r *AdvancedOrderRepository) Update(order *domain.Order) error {
 }
 
 func (r *AdvancedOrderRepository) Delete(orderID int) error {
-	query := `UPDATE advanced_orders SET status = 'CANCELLED', updated_at = NOW() WHERE id = ?`
-	
-	result, err := r.db.Exec(query, orderID)
-	if err != nil {
-		return fmt.Errorf("failed to cancel order: %w", err)
-	}
+	return r.transitionOne(orderTransition{
+		filter:  `id = ? AND status IN ` + cancellableStatuses,
+		args:    []interface{}{orderID},
+		to:      domain.OrderStatusCancelled,
+		set:     `cancel_reason = ?, `,
+		setArgs: []interface{}{"Cancelled by user"},
+		reason:  "Cancelled by user",
+		actor:   domain.OrderActorUser,
+	}, "order not found or already cancelled")
+}
 
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
-		return fmt.Errorf("failed to get affected rows: %w", err)
+// SearchOrders finds the orders matching every criterion set, across all
+// users unless criteria.UserID is set, a page at a time. Total counts every
+// match, not just the page. Price bounds only match orders with a limit price.
+func (r *AdvancedOrderRepository) SearchOrders(criteria *repositories.OrderSearchCriteria) (*repositories.OrderSearchResult, error) {
+	conditions := []string{"1 = 1"}
+	args := []interface{}{}
+	where := func(condition string, arg interface{}) {
+		conditions = append(conditions, condition)
+		args = append(args, arg)
 	}
 
-	if rowsAffected == 0 {
-		return fmt.Errorf("order not found or already cancelled")
+	if criteria.UserID != nil {
+		where("user_id = ?", *criteria.UserID)
 	}
-
-	return nil
-}
-
-func (r *AdvancedOrderRepository) Search(userID int, criteria *repositories.OrderSearchCriteria) (*repositories.OrderSearchResult, error) {
-	baseQuery := `
-		SELECT id, user_id, stock_symbol, order_type, side, quantity, price, stop_price,
-		       trailing_amount, trailing_percent, time_in_force, status, executed_price,
-		       executed_quantity, remaining_quantity, market_price, bid_price, ask_price,
-		       commission, fees, spread, executed_at, expires_at, parent_order_id,
-		       linked_order_id, created_at, updated_at
-		FROM advanced_orders 
-		WHERE user_id = ?
-	`
-
-	countQuery := `SELECT COUNT(*) FROM advanced_orders WHERE user_id = ?`
-	
-	var args []interface{}
-	var whereConditions []string
-	args = append(args, userID)
-
 	if criteria.Symbol != nil {
-		whereConditions = append(whereConditions, "stock_symbol = ?")
-		args = append(args, *criteria.Symbol)
+		where("stock_symbol = ?", *criteria.Symbol)
 	}
-
 	if criteria.OrderType != nil {
-		whereConditions = append(whereConditions, "order_type = ?")
-		args = append(args, *criteria.OrderType)
+		where("order_type = ?", *criteria.OrderType)
 	}
-
 	if criteria.Status != nil {
-		whereConditions = append(whereConditions, "status = ?")
-		args = append(args, *criteria.Status)
+		where("status = ?", *criteria.Status)
+	}
+	if criteria.Side != nil {
+		where("side = ?", *criteria.Side)
 	}
-
 	if criteria.StartDate != nil {
-		whereConditions = append(whereConditions, "created_at >= ?")
-		args = append(args, *criteria.StartDate)
+		where("created_at >= ?", *criteria.StartDate)
 	}
-
 	if criteria.EndDate != nil {
-		whereConditions = append(whereConditions, "created_at <= ?")
-		args = append(args, *criteria.EndDate)
+		where("created_at <= ?", *criteria.EndDate)
 	}
-
-	if len(whereConditions) > 0 {
-		whereClause := " AND " + strings.Join(whereConditions, " AND ")
-		baseQuery += whereClause
-		countQuery += whereClause
+	if criteria.MinPrice != nil {
+		where("price >= ?", *criteria.MinPrice)
 	}
-
-	// Get total count
-	var total int
-	err := r.db.QueryRow(countQuery, args...).Scan(&total)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get total count: %w", err)
+	if criteria.MaxPrice != nil {
+		where("price <= ?", *criteria.MaxPrice)
 	}
-
-	// Add ordering and pagination
-	baseQuery += " ORDER BY created_at DESC"
-	if criteria.Limit > 0 {
-		baseQuery += fmt.Sprintf(" LIMIT %d", criteria.Limit)
+	if criteria.MinQuantity != nil {
+		where("quantity >= ?", *criteria.MinQuantity)
 	}
-	if criteria.Offset > 0 {
-		baseQuery += fmt.Sprintf(" OFFSET %d", criteria.Offset)
+	if criteria.MaxQuantity != nil {
+		where("quantity <= ?", *criteria.MaxQuantity)
 	}
+	whereClause := strings.Join(conditions, " AND ")
 
-	rows, err := r.db.Query(baseQuery, args...)
+	var total int
+	err := r.db.QueryRow(`SELECT COUNT(*) FROM advanced_orders WHERE `+whereClause, args...).Scan(&total)
 	if err != nil {
-		return nil, fmt.Errorf("failed to search orders: %w", err)
+		return nil, fmt.Errorf("failed to get total count: %w", err)
 	}
-	defer rows.Close()
 
-	var orders []domain.Order
-	for rows.Next() {
-		var order domain.Order
-		err := rows.Scan(
-			&order.ID, &order.UserID, &order.StockSymbol, &order.OrderType, &order.Side,
-			&order.Quantity, &order.Price, &order.StopPrice, &order.TrailingAmount,
-			&order.TrailingPercent, &order.TimeInForce, &order.Status, &order.ExecutedPrice,
-			&order.ExecutedQuantity, &order.RemainingQuantity, &order.MarketPrice,
-			&order.BidPrice, &order.AskPrice, &order.Commission, &order.Fees, &order.Spread,
-			&order.ExecutedAt, &order.ExpiresAt, &order.ParentOrderID, &order.LinkedOrderID,
-			&order.CreatedAt, &order.UpdatedAt,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan order: %w", err)
-		}
-		orders = append(orders, order)
+	sortBy := criteria.SortBy
+	if !repositories.OrderSortFields[sortBy] {
+		sortBy = "created_at"
 	}
-
-	if err = rows.Err(); err != nil {
-		return nil, fmt.Errorf("failed to iterate orders: %w", err)
+	sortOrder := "DESC"
+	if strings.EqualFold(criteria.SortOrder, "ASC") {
+		sortOrder = "ASC"
 	}
-
 	pageSize := criteria.Limit
-	if pageSize == 0 {
-		pageSize = 20
+	if pageSize <= 0 {
+		pageSize = repositories.DefaultOrderSearchLimit
 	}
+	pageSize = min(pageSize, repositories.MaxOrderSearchLimit)
+	offset := max(criteria.Offset, 0)
 
-	totalPages := (total + pageSize - 1) / pageSize
-	page := (criteria.Offset / pageSize) + 1
+	// The ID breaks ties so pages never overlap
+	query := `
+		SELECT ` + orderColumns + `
+		FROM advanced_orders
+		WHERE ` + whereClause + `
+		ORDER BY ` + sortBy + ` ` + sortOrder + `, id ` + sortOrder + `
+		LIMIT ? OFFSET ?
+	`
+	orders, err := r.queryOrders(query, append(args, pageSize, offset)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search orders: %w", err)
+	}
 
 	return &repositories.OrderSearchResult{
 		Orders:     orders,
 		Total:      total,
-		Page:       page,
+		Page:       offset/pageSize + 1,
 		PageSize:   pageSize,
-		TotalPages: totalPages,
+		TotalPages: (total + pageSize - 1) / pageSize,
 	}, nil
 }
 
+// Search is SearchOrdersByUser
+func (r *AdvancedOrderRepository) Search(userID int, criteria *repositories.OrderSearchCriteria) (*repositories.OrderSearchResult, error) {
+	return r.SearchOrdersByUser(userID, criteria)
+}
+
+// SearchOrdersByUser searches one user's orders, whatever criteria.UserID says
+func (r *AdvancedOrderRepository) SearchOrdersByUser(userID int, criteria *repositories.OrderSearchCriteria) (*repositories.OrderSearchResult, error) {
+	scoped := *criteria
+	scoped.UserID = &userID
+	return r.SearchOrders(&scoped)
+}
+
+// SearchOrdersBySymbol searches every user's orders in one symbol
+func (r *AdvancedOrderRepository) SearchOrdersBySymbol(symbol string, criteria *repositories.OrderSearchCriteria) (*repositories.OrderSearchResult, error) {
+	scoped := *criteria
+	scoped.Symbol = &symbol
+	return r.SearchOrders(&scoped)
+}
+
 func (r *AdvancedOrderRepository) GetActiveOrdersByUser(userID int) ([]domain.Order, error) {
 	query := `
-		SELECT id, user_id, stock_symbol, order_type, side, quantity, price, stop_price,
-		       trailing_amount, trailing_percent, time_in_force, status, executed_price,
-		       executed_quantity, remaining_quantity, market_price, bid_price, ask_price,
-		       commission, fees, spread, executed_at, expires_at, parent_order_id,
-		       linked_order_id, created_at, updated_at
+		SELECT ` + orderColumns + `
 		FROM advanced_orders 
-		WHERE user_id = ? AND status IN ('PENDING', 'PARTIALLY_FILLED')
+		WHERE user_id = ? AND status IN ` + activeStatuses + `
 		ORDER BY created_at DESC
 	`
 
-	rows, err := r.db.Query(query, userID)
+	orders, err := r.queryOrders(query, userID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get active orders: %w", err)
 	}
-	defer rows.Close()
 
-	var orders []domain.Order
-	for rows.Next() {
-		var order domain.Order
-		err := rows.Scan(
-			&order.ID, &order.UserID, &order.StockSymbol, &order.OrderType, &order.Side,
-			&order.Quantity, &order.Price, &order.StopPrice, &order.TrailingAmount,
-			&order.TrailingPercent, &order.TimeInForce, &order.Status, &order.ExecutedPrice,
-			&order.ExecutedQuantity, &order.RemainingQuantity, &order.MarketPrice,
-			&order.BidPrice, &order.AskPrice, &order.Commission, &order.Fees, &order.Spread,
-			&order.ExecutedAt, &order.ExpiresAt, &order.ParentOrderID, &order.LinkedOrderID,
-			&order.CreatedAt, &order.UpdatedAt,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan order: %w", err)
-		}
-		orders = append(orders, order)
+	return orders, nil
+}
+
+// GetWorkingOrdersByUser returns the user's orders that may still trade,
+// including paused algo orders; these are the orders that hold cash or shares
+func (r *AdvancedOrderRepository) GetWorkingOrdersByUser(userID int) ([]domain.Order, error) {
+	query := `
+		SELECT ` + orderColumns + `
+		FROM advanced_orders
+		WHERE user_id = ? AND status IN ` + cancellableStatuses + `
+	`
+
+	orders, err := r.queryOrders(query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get working orders: %w", err)
 	}
 
 	return orders, nil
 }
 
+// CancelAllOrdersByUser cancels the user's working orders, paused algo
+// parents included, and everything that depends on them as BulkCancelOrders
+// does
 func (r *AdvancedOrderRepository) CancelAllOrdersByUser(userID int, symbol *string) (int, error) {
-	query := `UPDATE advanced_orders SET status = 'CANCELLED', updated_at = NOW() 
-	          WHERE user_id = ? AND status IN ('PENDING', 'PARTIALLY_FILLED')`
+	// Dormant bracket exits go too; their entries are being cancelled with them
+	filter := `user_id = ? AND status IN ('PENDING', 'PARTIALLY_FILLED', 'PAUSED', 'DORMANT')`
 	args := []interface{}{userID}
 
 	if symbol != nil {
-		query += " AND stock_symbol = ?"
+		filter += " AND stock_symbol = ?"
 		args = append(args, *symbol)
 	}
 
-	result, err := r.db.Exec(query, args...)
+	var cancelled []int
+	err := r.inTx(func(tx *AdvancedOrderRepository) error {
+		var err error
+		cancelled, err = tx.transition(orderTransition{
+			filter:  filter,
+			args:    args,
+			to:      domain.OrderStatusCancelled,
+			set:     `cancel_reason = ?, `,
+			setArgs: []interface{}{"Cancelled by user"},
+			reason:  "Cancelled by user",
+			actor:   domain.OrderActorUser,
+		})
+		if err != nil || len(cancelled) == 0 {
+			return err
+		}
+		return tx.cancelDependents(cancelled)
+	})
 	if err != nil {
 		return 0, fmt.Errorf("failed to cancel orders: %w", err)
 	}
 
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
-		return 0, fmt.Errorf("failed to get affected rows: %w", err)
-	}
-
-	return int(rowsAffected), nil
+	return len(cancelled), nil
 }
 
 func (r *AdvancedOrderRepository) GetOrderStatistics(userID int) (*domain.OrderStats, error) {
+	return r.orderStats("user_id = ?", userID)
+}
+
+// orderStats summarises the orders matching filter
+func (r *AdvancedOrderRepository) orderStats(filter string, args ...interface{}) (*domain.OrderStats, error) {
 	query := `
 		SELECT 
 			COUNT(*) as total_orders,
 			COUNT(CASE WHEN status = 'PENDING' THEN 1 END) as pending_orders,
 			COUNT(CASE WHEN status = 'EXECUTED' THEN 1 END) as executed_orders,
 			COUNT(CASE WHEN status = 'CANCELLED' THEN 1 END) as cancelled_orders,
+			COUNT(CASE WHEN status = 'EXPIRED' THEN 1 END) as expired_orders,
+			COUNT(CASE WHEN status = 'REJECTED' THEN 1 END) as rejected_orders,
 			COUNT(CASE WHEN status = 'PARTIALLY_FILLED' THEN 1 END) as partially_filled,
 			COALESCE(SUM(commission), 0) as total_commission,
 			COALESCE(SUM(fees), 0) as total_fees
 		FROM advanced_orders 
-		WHERE user_id = ?
-	`
+		WHERE ` + filter
 
 	var stats domain.OrderStats
 	var totalOrders, executedOrders int
 
-	err := r.db.QueryRow(query, userID).Scan(
+	err := r.db.QueryRow(query, args...).Scan(
 		&totalOrders,
 		&stats.PendingOrders,
 		&executedOrders,
 		&stats.CancelledOrders,
+		&stats.ExpiredOrders,
+		&stats.RejectedOrders,
 		&stats.PartiallyFilled,
 		&stats.TotalCommission,
 		&stats.TotalFees,
@@ -335,132 +450,288 @@ func (r *AdvancedOrderRepository) GetOrderStatistics(userID int) (*domain.OrderS
 		stats.SuccessRate = float64(executedOrders) / float64(totalOrders) * 100
 	}
 
-	// Calculate average execution time (handle NULL with COALESCE)
-	timeQuery := `
+	stats.AverageExecutionTime, err = r.averageExecutionTime(filter, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &stats, nil
+}
+
+// averageExecutionTime is the mean seconds from creation to execution of the
+// executed orders matching filter
+func (r *AdvancedOrderRepository) averageExecutionTime(filter string, args ...interface{}) (float64, error) {
+	query := `
 		SELECT COALESCE(AVG(TIMESTAMPDIFF(MICROSECOND, created_at, executed_at)) / 1000000, 0)
 		FROM advanced_orders 
-		WHERE user_id = ? AND status = 'EXECUTED' AND executed_at IS NOT NULL
+		WHERE status = 'EXECUTED' AND executed_at IS NOT NULL AND ` + filter
+
+	var seconds float64
+	if err := r.db.QueryRow(query, args...).Scan(&seconds); err != nil {
+		return 0, fmt.Errorf("failed to calculate execution time: %w", err)
+	}
+	return seconds, nil
+}
+
+// priceImprovement is an order's average fill against the market price at
+// submission, in basis points. Buyers improve by paying less and sellers by
+// receiving more; unfilled orders have none.
+const priceImprovement = `
+	CASE WHEN executed_quantity > 0 AND executed_price IS NOT NULL AND market_price > 0 THEN
+		CASE WHEN side IN ('BUY', 'COVER')
+			THEN (market_price - executed_price) / market_price * 10000
+			ELSE (executed_price - market_price) / market_price * 10000
+		END
+	END`
+
+func (r *AdvancedOrderRepository) GetExecutionMetrics(userID int, timeframe string) (*repositories.OrderExecutionMetrics, error) {
+	since, ok := domain.TimeframeStart(timeframe, time.Now())
+	if !ok {
+		return nil, fmt.Errorf("invalid timeframe: %s", timeframe)
+	}
+
+	filter := "user_id = ? AND status <> 'DORMANT' AND order_type NOT IN " + algoParentTypes
+	args := []interface{}{userID}
+	if !since.IsZero() {
+		filter += " AND created_at >= ?"
+		args = append(args, since)
+	}
+
+	// Sums rather than averages come back per type, so the totals below
+	// weight each type by its size
+	query := `
+		SELECT order_type,
+			COUNT(*),
+			COUNT(CASE WHEN status = 'EXECUTED' THEN 1 END),
+			COUNT(CASE WHEN status = 'CANCELLED' THEN 1 END),
+			COUNT(CASE WHEN executed_quantity > 0 AND executed_quantity < quantity THEN 1 END),
+			COALESCE(SUM(quantity), 0),
+			COALESCE(SUM(executed_quantity), 0),
+			COALESCE(SUM(CASE WHEN status = 'EXECUTED' AND executed_at IS NOT NULL
+				THEN TIMESTAMPDIFF(MICROSECOND, created_at, executed_at) END) / 1000000, 0),
+			COUNT(CASE WHEN status = 'EXECUTED' AND executed_at IS NOT NULL THEN 1 END),
+			COALESCE(SUM(improvement), 0),
+			COUNT(improvement),
+			MIN(improvement),
+			MAX(improvement)
+		FROM (
+			SELECT order_type, status, quantity, executed_quantity, created_at, executed_at,
+				` + priceImprovement + ` AS improvement
+			FROM advanced_orders
+			WHERE ` + filter + `
+		) o
+		GROUP BY order_type
+		ORDER BY order_type
 	`
-	
-	err = r.db.QueryRow(timeQuery, userID).Scan(&stats.AverageExecutionTime)
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get execution metrics: %w", err)
+	}
+	defer rows.Close()
+
+	metrics := &repositories.OrderExecutionMetrics{
+		UserID:      userID,
+		Timeframe:   timeframe,
+		ByOrderType: []repositories.OrderTypeMetrics{},
+	}
+
+	var quantity, executedQuantity, timedOrders, improvedOrders int
+	var executionSeconds, improvementSum float64
+	var worst, best sql.NullFloat64
+	for rows.Next() {
+		var byType repositories.OrderTypeMetrics
+		var cancelled, partial, typeQuantity, typeExecuted, typeTimed, typeImproved int
+		var typeSeconds, typeImprovement float64
+		var typeWorst, typeBest sql.NullFloat64
+		err := rows.Scan(
+			&byType.OrderType, &byType.TotalOrders, &byType.ExecutedOrders, &cancelled, &partial,
+			&typeQuantity, &typeExecuted, &typeSeconds, &typeTimed,
+			&typeImprovement, &typeImproved, &typeWorst, &typeBest,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan execution metrics: %w", err)
+		}
+
+		byType.FillRate = percentOf(typeExecuted, typeQuantity)
+		byType.AverageExecutionTime = averageOf(typeSeconds, typeTimed)
+		byType.AveragePriceImprovement = averageOf(typeImprovement, typeImproved)
+		metrics.ByOrderType = append(metrics.ByOrderType, byType)
+
+		metrics.TotalOrders += byType.TotalOrders
+		metrics.ExecutedOrders += byType.ExecutedOrders
+		metrics.CancelledOrders += cancelled
+		metrics.PartiallyFilled += partial
+		quantity += typeQuantity
+		executedQuantity += typeExecuted
+		executionSeconds += typeSeconds
+		timedOrders += typeTimed
+		improvementSum += typeImprovement
+		improvedOrders += typeImproved
+		if typeWorst.Valid && (!worst.Valid || typeWorst.Float64 < worst.Float64) {
+			worst = typeWorst
+		}
+		if typeBest.Valid && (!best.Valid || typeBest.Float64 > best.Float64) {
+			best = typeBest
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read execution metrics: %w", err)
+	}
+
+	metrics.FillRate = percentOf(executedQuantity, quantity)
+	metrics.AverageExecutionTime = averageOf(executionSeconds, timedOrders)
+	metrics.AveragePriceImprovement = averageOf(improvementSum, improvedOrders)
+	if best.Valid {
+		metrics.BestExecution = best.Float64
+	}
+	// Worst execution is reported as slippage, the negated least improvement
+	if worst.Valid && worst.Float64 < 0 {
+		metrics.WorstExecution = -worst.Float64
+	}
+
+	// Slippage and charges come from the fills made within the timeframe
+	executionFilter := "o.user_id = ? AND o.order_type NOT IN " + algoParentTypes
+	executionArgs := []interface{}{userID}
+	if !since.IsZero() {
+		executionFilter += " AND e.executed_at >= ?"
+		executionArgs = append(executionArgs, since)
+	}
+	executionQuery := `
+		SELECT COALESCE(AVG(e.slippage), 0), COALESCE(SUM(e.commission), 0), COALESCE(SUM(e.fees), 0)
+		FROM order_executions e
+		JOIN advanced_orders o ON o.id = e.order_id
+		WHERE ` + executionFilter
+
+	err = r.db.QueryRow(executionQuery, executionArgs...).Scan(
+		&metrics.AverageSlippage, &metrics.TotalCommission, &metrics.TotalFees,
+	)
 	if err != nil {
-		return nil, fmt.Errorf("failed to calculate execution time: %w", err)
+		return nil, fmt.Errorf("failed to get execution charges: %w", err)
 	}
 
-	return &stats, nil
+	return metrics, nil
 }
 
-func (r *AdvancedOrderRepository) GetExecutionMetrics(userID int, timeframe string) (*repositories.OrderExecutionMetrics, error) {
-	// For now, return mock data - this can be implemented with complex queries
-	return &repositories.OrderExecutionMetrics{
-		UserID:               userID,
-		Timeframe:           timeframe,
-		TotalOrders:         0,
-		ExecutedOrders:      0,
-		CancelledOrders:     0,
-		PartiallyFilled:     0,
-		AverageExecutionTime: 0.0,
-		FillRate:            0.0,
-		AverageSlippage:     0.0,
-		BestExecution:       0.0,
-		WorstExecution:      0.0,
-		TotalCommission:     0.0,
-		TotalFees:          0.0,
-	}, nil
+func percentOf(part, whole int) float64 {
+	if whole == 0 {
+		return 0
+	}
+	return float64(part) / float64(whole) * 100
+}
+
+func averageOf(sum float64, count int) float64 {
+	if count == 0 {
+		return 0
+	}
+	return sum / float64(count)
 }
 
 func (r *AdvancedOrderRepository) GetSlippageAnalysis(userID int, symbol string) (*repositories.SlippageAnalysis, error) {
-	// For now, return mock data - this can be implemented with complex queries
-	return &repositories.SlippageAnalysis{
-		Symbol:               symbol,
-		UserID:              userID,
-		TotalTrades:         0,
-		AverageSlippage:     0.0,
-		MedianSlippage:      0.0,
-		SlippageStdDev:      0.0,
-		BestExecution:       0.0,
-		WorstSlippage:       0.0,
-		MarketOrderSlippage: 0.0,
-		LimitOrderSlippage:  0.0,
-	}, nil
+	query := `
+		SELECT e.slippage, o.order_type
+		FROM order_executions e
+		JOIN advanced_orders o ON o.id = e.order_id
+		WHERE o.user_id = ? AND o.stock_symbol = ? AND o.order_type NOT IN ` + algoParentTypes + `
+		ORDER BY e.slippage
+	`
+
+	rows, err := r.db.Query(query, userID, symbol)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get slippage history: %w", err)
+	}
+	defer rows.Close()
+
+	analysis := &repositories.SlippageAnalysis{
+		Symbol: symbol,
+		UserID: userID,
+	}
+
+	// Rows arrive sorted, so the ends are the best and worst fills and the
+	// middle is the median
+	slippages := []float64{}
+	byType := map[domain.OrderType][]float64{}
+	for rows.Next() {
+		var slippage float64
+		var orderType domain.OrderType
+		if err := rows.Scan(&slippage, &orderType); err != nil {
+			return nil, fmt.Errorf("failed to scan slippage: %w", err)
+		}
+		slippages = append(slippages, slippage)
+		byType[orderType] = append(byType[orderType], slippage)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read slippage history: %w", err)
+	}
+
+	count := len(slippages)
+	if count == 0 {
+		return analysis, nil
+	}
+
+	analysis.TotalTrades = count
+	analysis.AverageSlippage = mean(slippages)
+	analysis.BestExecution = slippages[0]
+	analysis.WorstSlippage = slippages[count-1]
+	if count%2 == 1 {
+		analysis.MedianSlippage = slippages[count/2]
+	} else {
+		analysis.MedianSlippage = (slippages[count/2-1] + slippages[count/2]) / 2
+	}
+
+	variance := 0.0
+	for _, slippage := range slippages {
+		variance += (slippage - analysis.AverageSlippage) * (slippage - analysis.AverageSlippage)
+	}
+	analysis.SlippageStdDev = math.Sqrt(variance / float64(count))
+
+	analysis.MarketOrderSlippage = mean(byType[domain.OrderTypeMarket])
+	analysis.LimitOrderSlippage = mean(byType[domain.OrderTypeLimit])
+
+	return analysis, nil
+}
+
+func mean(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sum := 0.0
+	for _, value := range values {
+		sum += value
+	}
+	return sum / float64(len(values))
 }
 
 // Additional methods required by interface
 func (r *AdvancedOrderRepository) GetByUserID(userID int, limit, offset int) ([]domain.Order, error) {
 	query := `
-		SELECT id, user_id, stock_symbol, order_type, side, quantity, price, stop_price,
-		       trailing_amount, trailing_percent, time_in_force, status, executed_price,
-		       executed_quantity, remaining_quantity, market_price, bid_price, ask_price,
-		       commission, fees, spread, executed_at, expires_at, parent_order_id,
-		       linked_order_id, created_at, updated_at
+		SELECT ` + orderColumns + `
 		FROM advanced_orders 
 		WHERE user_id = ?
 		ORDER BY created_at DESC
 		LIMIT ? OFFSET ?
 	`
 
-	rows, err := r.db.Query(query, userID, limit, offset)
+	orders, err := r.queryOrders(query, userID, limit, offset)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get orders by user: %w", err)
 	}
-	defer rows.Close()
-
-	var orders []domain.Order
-	for rows.Next() {
-		var order domain.Order
-		err := rows.Scan(
-			&order.ID, &order.UserID, &order.StockSymbol, &order.OrderType, &order.Side,
-			&order.Quantity, &order.Price, &order.StopPrice, &order.TrailingAmount,
-			&order.TrailingPercent, &order.TimeInForce, &order.Status, &order.ExecutedPrice,
-			&order.ExecutedQuantity, &order.RemainingQuantity, &order.MarketPrice,
-			&order.BidPrice, &order.AskPrice, &order.Commission, &order.Fees, &order.Spread,
-			&order.ExecutedAt, &order.ExpiresAt, &order.ParentOrderID, &order.LinkedOrderID,
-			&order.CreatedAt, &order.UpdatedAt,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan order: %w", err)
-		}
-		orders = append(orders, order)
-	}
 
 	return orders, nil
 }
 
 func (r *AdvancedOrderRepository) GetByUserIDAndStatus(userID int, status domain.OrderStatus) ([]domain.Order, error) {
 	query := `
-		SELECT id, user_id, stock_symbol, order_type, side, quantity, price, stop_price,
-		       trailing_amount, trailing_percent, time_in_force, status, executed_price,
-		       executed_quantity, remaining_quantity, market_price, bid_price, ask_price,
-		       commission, fees, spread, executed_at, expires_at, parent_order_id,
-		       linked_order_id, created_at, updated_at
+		SELECT ` + orderColumns + `
 		FROM advanced_orders 
 		WHERE user_id = ? AND status = ?
 		ORDER BY created_at DESC
 	`
 
-	rows, err := r.db.Query(query, userID, status)
+	orders, err := r.queryOrders(query, userID, status)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get orders by user and status: %w", err)
 	}
-	defer rows.Close()
-
-	var orders []domain.Order
-	for rows.Next() {
-		var order domain.Order
-		err := rows.Scan(
-			&order.ID, &order.UserID, &order.StockSymbol, &order.OrderType, &order.Side,
-			&order.Quantity, &order.Price, &order.StopPrice, &order.TrailingAmount,
-			&order.TrailingPercent, &order.TimeInForce, &order.Status, &order.ExecutedPrice,
-			&order.ExecutedQuantity, &order.RemainingQuantity, &order.MarketPrice,
-			&order.BidPrice, &order.AskPrice, &order.Commission, &order.Fees, &order.Spread,
-			&order.ExecutedAt, &order.ExpiresAt, &order.ParentOrderID, &order.LinkedOrderID,
-			&order.CreatedAt, &order.UpdatedAt,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan order: %w", err)
-		}
-		orders = append(orders, order)
-	}
 
 	return orders, nil
 }
@@ -476,7 +747,19 @@ func (r *AdvancedOrderRepository) CalculateOrderCommission(userID int, order *do
 
 // Stub implementations for remaining interface methods
 func (r *AdvancedOrderRepository) GetBySymbol(symbol string) ([]domain.Order, error) {
-	return []domain.Order{}, nil
+	query := `
+		SELECT ` + orderColumns + `
+		FROM advanced_orders 
+		WHERE stock_symbol = ?
+		ORDER BY created_at DESC
+	`
+
+	orders, err := r.queryOrders(query, symbol)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get orders by symbol: %w", err)
+	}
+
+	return orders, nil
 }
 
 func (r *AdvancedOrderRepository) GetByMarketAndStatus(marketCode string, status domain.OrderStatus) ([]domain.Order, error) {
@@ -484,122 +767,770 @@ func (r *AdvancedOrderRepository) GetByMarketAndStatus(marketCode string, status
 }
 
 func (r *AdvancedOrderRepository) GetActiveOrders() ([]domain.Order, error) {
-	return []domain.Order{}, nil
-}
+	query := `
+		SELECT ` + orderColumns + `
+		FROM advanced_orders 
+		WHERE status IN ` + activeStatuses + `
+		ORDER BY created_at ASC
+	`
 
-func (r *AdvancedOrderRepository) GetPendingOrders() ([]domain.Order, error) {
-	return []domain.Order{}, nil
-}
+	orders, err := r.queryOrders(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get active orders: %w", err)
+	}
 
-func (r *AdvancedOrderRepository) GetExpiredOrders() ([]domain.Order, error) {
-	return []domain.Order{}, nil
+	return orders, nil
 }
 
+func (r *AdvancedOrderRepository) GetPendingOrders() ([]domain.Order, error) {
+	query := `
+		SELECT ` + orderColumns + `
+		FROM advanced_orders 
+		WHERE status = 'PENDING'
+		ORDER BY created_at ASC
+	`
+
+	orders, err := r.queryOrders(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pending orders: %w", err)
+	}
+
+	return orders, nil
+}
+
+// GetExpiredOrders returns the active orders whose expires_at has passed
+func (r *AdvancedOrderRepository) GetExpiredOrders() ([]domain.Order, error) {
+	query := `
+		SELECT ` + orderColumns + `
+		FROM advanced_orders 
+		WHERE expires_at IS NOT NULL AND expires_at <= NOW()
+		  AND status IN ` + activeStatuses + `
+		ORDER BY expires_at ASC
+	`
+
+	orders, err := r.queryOrders(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get expired orders: %w", err)
+	}
+
+	return orders, nil
+}
+
+// GetOrdersForExecution returns the active orders on a symbol whose trigger
+// condition is met at currentPrice in time priority, so earlier orders fill
+// first and a refilled iceberg slice waits behind them.
 func (r *AdvancedOrderRepository) GetOrdersForExecution(symbol string, currentPrice float64) ([]domain.Order, error) {
-	return []domain.Order{}, nil
+	query := `
+		SELECT ` + orderColumns + `
+		FROM advanced_orders 
+		WHERE stock_symbol = ? AND status IN ` + activeStatuses + `
+		  AND (
+		    order_type = 'MARKET'
+		    OR ((order_type = 'LIMIT' OR (order_type = 'STOP_LIMIT' AND triggered_at IS NOT NULL)) AND (
+		        (side IN ('BUY', 'COVER') AND price >= ?) OR
+		        (side IN ('SELL', 'SHORT') AND price <= ?)))
+		    OR (order_type = 'STOP_LOSS' AND (
+		        (side IN ('BUY', 'COVER') AND stop_price <= ?) OR
+		        (side IN ('SELL', 'SHORT') AND stop_price >= ?)))
+		    OR (order_type = 'TAKE_PROFIT' AND (
+		        (side IN ('BUY', 'COVER') AND stop_price >= ?) OR
+		        (side IN ('SELL', 'SHORT') AND stop_price <= ?)))
+		  )
+		ORDER BY priority_at ASC
+	`
+
+	orders, err := r.queryOrders(query, symbol,
+		currentPrice, currentPrice,
+		currentPrice, currentPrice,
+		currentPrice, currentPrice,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get orders for execution: %w", err)
+	}
+
+	return orders, nil
 }
 
+// GetLimitOrders returns resting limit orders for one side of a symbol in
+// price-time priority: best price first, then oldest. Triggered stop-limits
+// rest alongside plain limits.
 func (r *AdvancedOrderRepository) GetLimitOrders(symbol string, side domain.OrderSide) ([]domain.Order, error) {
-	return []domain.Order{}, nil
+	priceOrder := "ASC"
+	if side == domain.OrderSideBuy || side == domain.OrderSideCover {
+		priceOrder = "DESC"
+	}
+
+	query := `
+		SELECT ` + orderColumns + `
+		FROM advanced_orders 
+		WHERE stock_symbol = ? AND side = ?
+		  AND (order_type = 'LIMIT' OR (order_type = 'STOP_LIMIT' AND triggered_at IS NOT NULL))
+		  AND status IN ` + activeStatuses + `
+		ORDER BY price ` + priceOrder + `, priority_at ASC
+	`
+
+	orders, err := r.queryOrders(query, symbol, side)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get limit orders: %w", err)
+	}
+
+	return orders, nil
 }
 
+// GetStopOrders returns the active orders on a symbol still waiting on a stop
+// price: stop-loss and take-profit orders and untriggered stop-limits.
 func (r *AdvancedOrderRepository) GetStopOrders(symbol string) ([]domain.Order, error) {
-	return []domain.Order{}, nil
+	query := `
+		SELECT ` + orderColumns + `
+		FROM advanced_orders 
+		WHERE stock_symbol = ?
+		  AND (order_type IN ('STOP_LOSS', 'TAKE_PROFIT') OR (order_type = 'STOP_LIMIT' AND triggered_at IS NULL))
+		  AND status IN ` + activeStatuses + `
+		ORDER BY created_at ASC
+	`
+
+	orders, err := r.queryOrders(query, symbol)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stop orders: %w", err)
+	}
+
+	return orders, nil
 }
 
 func (r *AdvancedOrderRepository) GetTrailingStopOrders() ([]domain.Order, error) {
-	return []domain.Order{}, nil
+	query := `
+		SELECT ` + orderColumns + `
+		FROM advanced_orders 
+		WHERE order_type = 'TRAILING_STOP' AND status IN ` + activeStatuses + `
+		ORDER BY created_at ASC
+	`
+
+	orders, err := r.queryOrders(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get trailing stop orders: %w", err)
+	}
+
+	return orders, nil
 }
 
 func (r *AdvancedOrderRepository) GetOCOOrders(userID int) ([]domain.Order, error) {
-	return []domain.Order{}, nil
+	query := `
+		SELECT ` + orderColumns + `
+		FROM advanced_orders 
+		WHERE user_id = ? AND oco_group_id IS NOT NULL
+		ORDER BY oco_group_id DESC, id ASC
+	`
+
+	orders, err := r.queryOrders(query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get OCO orders: %w", err)
+	}
+
+	return orders, nil
+}
+
+func (r *AdvancedOrderRepository) GetOCOGroup(groupID int) ([]domain.Order, error) {
+	query := `
+		SELECT ` + orderColumns + `
+		FROM advanced_orders 
+		WHERE oco_group_id = ?
+		ORDER BY id ASC
+	`
+
+	orders, err := r.queryOrders(query, groupID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get OCO group: %w", err)
+	}
+
+	return orders, nil
 }
 
 func (r *AdvancedOrderRepository) ExecuteOrder(orderID int, executedPrice float64, executedQuantity int) error {
-	return nil
+	query := `
+		UPDATE advanced_orders 
+		SET status = 'EXECUTED', executed_price = ?, executed_quantity = ?,
+		    remaining_quantity = GREATEST(quantity - ?, 0), executed_at = NOW(), updated_at = NOW()
+		WHERE id = ? AND status IN ` + activeStatuses + `
+	`
+
+	reason := fmt.Sprintf("Executed %d @ %.2f", executedQuantity, executedPrice)
+	return r.fill(orderID, reason, func(tx *AdvancedOrderRepository) error {
+		result, err := tx.db.Exec(query, executedPrice, executedQuantity, executedQuantity, orderID)
+		if err != nil {
+			return fmt.Errorf("failed to execute order: %w", err)
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("failed to get affected rows: %w", err)
+		}
+
+		if rowsAffected == 0 {
+			return fmt.Errorf("order not found or no longer active")
+		}
+
+		return nil
+	})
 }
 
+// PartialFillOrder applies one fill to an active order: executed_price becomes
+// the volume-weighted average of all fills, and the order is EXECUTED once
+// nothing remains. MySQL evaluates single-table SET clauses left to right, so
+// executed_price must be computed before executed_quantity is bumped and the
+// status must follow the remaining_quantity update. An iceberg fills at most
+// its visible slice; once the slice is used up, priority_at is reset from the
+// old visible_remaining before the slice refills from what remains.
 func (r *AdvancedOrderRepository) PartialFillOrder(orderID int, filledQuantity int, filledPrice float64) error {
-	return nil
+	query := `
+		UPDATE advanced_orders 
+		SET executed_price = (COALESCE(executed_price, 0) * executed_quantity + ? * ?) / (executed_quantity + ?),
+		    executed_quantity = executed_quantity + ?,
+		    remaining_quantity = remaining_quantity - ?,
+		    priority_at = IF(display_quantity IS NOT NULL AND visible_remaining <= ? AND remaining_quantity > 0,
+		        NOW(), priority_at),
+		    visible_remaining = IF(display_quantity IS NULL, visible_remaining,
+		        IF(visible_remaining > ?, visible_remaining - ?, LEAST(display_quantity, remaining_quantity))),
+		    status = IF(remaining_quantity = 0, 'EXECUTED', 'PARTIALLY_FILLED'),
+		    executed_at = IF(remaining_quantity = 0, NOW(), executed_at),
+		    updated_at = NOW()
+		WHERE id = ? AND remaining_quantity >= ? AND status IN ` + activeStatuses + `
+		  AND (display_quantity IS NULL OR visible_remaining >= ?)
+	`
+
+	reason := fmt.Sprintf("Filled %d @ %.2f", filledQuantity, filledPrice)
+	return r.fill(orderID, reason, func(tx *AdvancedOrderRepository) error {
+		result, err := tx.db.Exec(query,
+			filledPrice, filledQuantity, filledQuantity,
+			filledQuantity, filledQuantity,
+			filledQuantity, filledQuantity, filledQuantity,
+			orderID, filledQuantity, filledQuantity,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to fill order: %w", err)
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("failed to get affected rows: %w", err)
+		}
+
+		if rowsAffected == 0 {
+			return fmt.Errorf("order not found, no longer active or overfilled")
+		}
+
+		return nil
+	})
 }
 
-func (r *AdvancedOrderRepository) CancelOrder(orderID int, reason string) error {
+func (r *AdvancedOrderRepository) RecordExecution(execution *domain.OrderExecution) error {
+	query := `
+		INSERT INTO order_executions (
+			order_id, price, quantity, commission, fees, slippage, total_amount, executed_at
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	result, err := r.db.Exec(query,
+		execution.OrderID, execution.ExecutedPrice, execution.ExecutedQuantity,
+		execution.Commission, execution.Fees, execution.Slippage,
+		execution.TotalAmount, execution.ExecutedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record execution: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get execution ID: %w", err)
+	}
+
+	execution.ID = int(id)
 	return nil
 }
 
-func (r *AdvancedOrderRepository) ExpireOrder(orderID int) error {
-	return nil
+func (r *AdvancedOrderRepository) GetExecutions(orderID int) ([]domain.OrderExecution, error) {
+	query := `
+		SELECT id, order_id, price, quantity, commission, fees, slippage, total_amount, executed_at
+		FROM order_executions 
+		WHERE order_id = ?
+		ORDER BY executed_at ASC, id ASC
+	`
+
+	rows, err := r.db.Query(query, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get executions: %w", err)
+	}
+	defer rows.Close()
+
+	executions := []domain.OrderExecution{}
+	for rows.Next() {
+		var execution domain.OrderExecution
+		err := rows.Scan(
+			&execution.ID, &execution.OrderID, &execution.ExecutedPrice, &execution.ExecutedQuantity,
+			&execution.Commission, &execution.Fees, &execution.Slippage,
+			&execution.TotalAmount, &execution.ExecutedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan execution: %w", err)
+		}
+		executions = append(executions, execution)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate executions: %w", err)
+	}
+
+	return executions, nil
+}
+
+func (r *AdvancedOrderRepository) CancelOrder(orderID int, reason string, actor domain.OrderActor) error {
+	return r.transitionOne(orderTransition{
+		filter:  `id = ? AND status IN ` + cancellableStatuses,
+		args:    []interface{}{orderID},
+		to:      domain.OrderStatusCancelled,
+		set:     `cancel_reason = ?, `,
+		setArgs: []interface{}{reason},
+		reason:  reason,
+		actor:   actor,
+	}, "order not found or no longer active")
+}
+
+func (r *AdvancedOrderRepository) ExpireOrder(orderID int, reason string) error {
+	return r.transitionOne(orderTransition{
+		filter:  `id = ? AND status IN ` + activeStatuses,
+		args:    []interface{}{orderID},
+		to:      domain.OrderStatusExpired,
+		set:     `cancel_reason = ?, `,
+		setArgs: []interface{}{reason},
+		reason:  reason,
+		actor:   domain.OrderActorSystem,
+	}, "order not found or no longer active")
+}
+
+func (r *AdvancedOrderRepository) RejectOrder(orderID int, reason string) error {
+	return r.transitionOne(orderTransition{
+		filter:  `id = ? AND status IN ` + activeStatuses,
+		args:    []interface{}{orderID},
+		to:      domain.OrderStatusRejected,
+		set:     `cancel_reason = ?, `,
+		setArgs: []interface{}{reason},
+		reason:  reason,
+		actor:   domain.OrderActorSystem,
+	}, "order not found or no longer active")
 }
 
 func (r *AdvancedOrderRepository) UpdateStatus(orderID int, status domain.OrderStatus) error {
-	return nil
+	return r.transitionOne(orderTransition{
+		filter: `id = ?`,
+		args:   []interface{}{orderID},
+		to:     status,
+		reason: fmt.Sprintf("Status set to %s", status),
+		actor:  domain.OrderActorSystem,
+	}, "order not found")
+}
+
+// ActivateOrder wakes a dormant order, such as a bracket exit whose entry has
+// started to fill
+func (r *AdvancedOrderRepository) ActivateOrder(orderID int, reason string) error {
+	return r.transitionOne(orderTransition{
+		filter: `id = ? AND status = 'DORMANT'`,
+		args:   []interface{}{orderID},
+		to:     domain.OrderStatusPending,
+		reason: reason,
+		actor:  domain.OrderActorSystem,
+	}, "order not found or not dormant")
 }
 
+// UpdateExecutionDetails records an execution against the order and applies
+// it to the order's filled and remaining quantities, in one transaction. The
+// cash and position side of the fill is the caller's to settle.
 func (r *AdvancedOrderRepository) UpdateExecutionDetails(orderID int, execution *domain.OrderExecution) error {
-	return nil
+	return r.inTx(func(tx *AdvancedOrderRepository) error {
+		execution.OrderID = orderID
+		if err := tx.RecordExecution(execution); err != nil {
+			return err
+		}
+		return tx.PartialFillOrder(orderID, execution.ExecutedQuantity, execution.ExecutedPrice)
+	})
 }
 
+// CreateOCOOrders inserts both legs and links them into one group keyed by the
+// first leg's ID. Call it inside a unit of work so the legs only become
+// visible to the matching engine once they are linked.
 func (r *AdvancedOrderRepository) CreateOCOOrders(parentOrder *domain.Order, linkedOrder *domain.Order) error {
+	if err := r.Create(parentOrder); err != nil {
+		return fmt.Errorf("failed to create first OCO leg: %w", err)
+	}
+	if err := r.Create(linkedOrder); err != nil {
+		return fmt.Errorf("failed to create second OCO leg: %w", err)
+	}
+
+	groupID := parentOrder.ID
+	query := `UPDATE advanced_orders SET linked_order_id = ?, oco_group_id = ? WHERE id = ?`
+
+	if _, err := r.db.Exec(query, linkedOrder.ID, groupID, parentOrder.ID); err != nil {
+		return fmt.Errorf("failed to link OCO orders: %w", err)
+	}
+	if _, err := r.db.Exec(query, parentOrder.ID, groupID, linkedOrder.ID); err != nil {
+		return fmt.Errorf("failed to link OCO orders: %w", err)
+	}
+
+	parentOrder.LinkedOrderID = &linkedOrder.ID
+	parentOrder.OCOGroupID = &groupID
+	linkedOrder.LinkedOrderID = &parentOrder.ID
+	linkedOrder.OCOGroupID = &groupID
+
 	return nil
 }
 
-func (r *AdvancedOrderRepository) CancelLinkedOrders(orderID int) error {
-	return nil
+// CancelLinkedOrders cancels the still-active orders that share an OCO group
+// with orderID, returning how many were cancelled.
+func (r *AdvancedOrderRepository) CancelLinkedOrders(orderID int, reason string) (int, error) {
+	cancelled, err := r.transition(orderTransition{
+		filter: `oco_group_id = (SELECT oco_group_id FROM advanced_orders WHERE id = ?)
+		         AND id <> ? AND status IN ` + activeStatuses,
+		args:    []interface{}{orderID, orderID},
+		to:      domain.OrderStatusCancelled,
+		set:     `cancel_reason = ?, `,
+		setArgs: []interface{}{reason},
+		reason:  reason,
+		actor:   domain.OrderActorSystem,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to cancel linked orders: %w", err)
+	}
+
+	return len(cancelled), nil
 }
 
+// GetLinkedOrders returns the other legs of orderID's OCO group
 func (r *AdvancedOrderRepository) GetLinkedOrders(orderID int) ([]domain.Order, error) {
-	return []domain.Order{}, nil
+	query := `
+		SELECT ` + orderColumns + `
+		FROM advanced_orders 
+		WHERE oco_group_id = (SELECT oco_group_id FROM advanced_orders WHERE id = ?)
+		  AND id <> ?
+		ORDER BY id ASC
+	`
+
+	orders, err := r.queryOrders(query, orderID, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get linked orders: %w", err)
+	}
+
+	return orders, nil
+}
+
+// CreateBracketOrders inserts an entry order and its two exits. The exits
+// reference the entry through parent_order_id and are linked to each other as
+// an OCO group; they are stored with whatever status the caller set (normally
+// DORMANT). Call it inside a UnitOfWork so the three rows commit together.
+func (r *AdvancedOrderRepository) CreateBracketOrders(entryOrder *domain.Order, takeProfit *domain.Order, stopLoss *domain.Order) error {
+	if err := r.Create(entryOrder); err != nil {
+		return fmt.Errorf("failed to create bracket entry order: %w", err)
+	}
+
+	takeProfit.ParentOrderID = &entryOrder.ID
+	stopLoss.ParentOrderID = &entryOrder.ID
+
+	if err := r.CreateOCOOrders(takeProfit, stopLoss); err != nil {
+		return fmt.Errorf("failed to create bracket exit orders: %w", err)
+	}
+
+	return nil
+}
+
+func (r *AdvancedOrderRepository) GetChildOrders(parentOrderID int) ([]domain.Order, error) {
+	query := `
+		SELECT ` + orderColumns + `
+		FROM advanced_orders 
+		WHERE parent_order_id = ?
+		ORDER BY id ASC
+	`
+
+	orders, err := r.queryOrders(query, parentOrderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get child orders: %w", err)
+	}
+
+	return orders, nil
+}
+
+// CancelChildOrders cancels the dormant children of a parent order, returning
+// how many were cancelled. Children that were already activated are left alone.
+func (r *AdvancedOrderRepository) CancelChildOrders(parentOrderID int, reason string) (int, error) {
+	cancelled, err := r.transition(orderTransition{
+		filter:  `parent_order_id = ? AND status = 'DORMANT'`,
+		args:    []interface{}{parentOrderID},
+		to:      domain.OrderStatusCancelled,
+		set:     `cancel_reason = ?, `,
+		setArgs: []interface{}{reason},
+		reason:  reason,
+		actor:   domain.OrderActorSystem,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to cancel child orders: %w", err)
+	}
+
+	return len(cancelled), nil
+}
+
+// PauseOrder holds an active order out of the market
+func (r *AdvancedOrderRepository) PauseOrder(orderID int) error {
+	return r.transitionOne(orderTransition{
+		filter: `id = ? AND status IN ` + activeStatuses,
+		args:   []interface{}{orderID},
+		to:     domain.OrderStatusPaused,
+		reason: "Paused by user",
+		actor:  domain.OrderActorUser,
+	}, "order not found or no longer active")
+}
+
+// ResumeOrder returns a paused order to PENDING, or PARTIALLY_FILLED if it
+// has fills
+func (r *AdvancedOrderRepository) ResumeOrder(orderID int) error {
+	return r.transitionOne(orderTransition{
+		filter: `id = ? AND status = 'PAUSED'`,
+		args:   []interface{}{orderID},
+		to:     domain.OrderStatusPending,
+		resume: true,
+		reason: "Resumed by user",
+		actor:  domain.OrderActorUser,
+	}, "order not found or not paused")
+}
+
+func (r *AdvancedOrderRepository) PauseChildOrders(parentOrderID int) (int, error) {
+	paused, err := r.transition(orderTransition{
+		filter: `parent_order_id = ? AND status IN ` + activeStatuses,
+		args:   []interface{}{parentOrderID},
+		to:     domain.OrderStatusPaused,
+		reason: fmt.Sprintf("Parent order #%d paused", parentOrderID),
+		actor:  domain.OrderActorUser,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to pause child orders: %w", err)
+	}
+
+	return len(paused), nil
+}
+
+func (r *AdvancedOrderRepository) ResumeChildOrders(parentOrderID int) (int, error) {
+	resumed, err := r.transition(orderTransition{
+		filter: `parent_order_id = ? AND status = 'PAUSED'`,
+		args:   []interface{}{parentOrderID},
+		to:     domain.OrderStatusPending,
+		resume: true,
+		reason: fmt.Sprintf("Parent order #%d resumed", parentOrderID),
+		actor:  domain.OrderActorUser,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to resume child orders: %w", err)
+	}
+
+	return len(resumed), nil
 }
 
 func (r *AdvancedOrderRepository) GetUserOrderStats(userID int) (*domain.OrderStats, error) {
-	return &domain.OrderStats{}, nil
+	return r.orderStats("user_id = ?", userID)
 }
 
 func (r *AdvancedOrderRepository) GetOrderStatsByDateRange(userID int, startDate, endDate time.Time) (*domain.OrderStats, error) {
-	return &domain.OrderStats{}, nil
+	return r.orderStats("user_id = ? AND created_at >= ? AND created_at < ?", userID, startDate, endDate)
 }
 
 func (r *AdvancedOrderRepository) GetOrderCountByType(userID int) (map[domain.OrderType]int, error) {
-	return make(map[domain.OrderType]int), nil
+	query := `
+		SELECT order_type, COUNT(*)
+		FROM advanced_orders
+		WHERE user_id = ?
+		GROUP BY order_type
+	`
+
+	rows, err := r.db.Query(query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count orders by type: %w", err)
+	}
+	defer rows.Close()
+
+	counts := make(map[domain.OrderType]int)
+	for rows.Next() {
+		var orderType domain.OrderType
+		var count int
+		if err := rows.Scan(&orderType, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan order count: %w", err)
+		}
+		counts[orderType] = count
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read order counts: %w", err)
+	}
+	return counts, nil
 }
 
 func (r *AdvancedOrderRepository) GetAverageExecutionTime(userID int) (float64, error) {
-	return 0.0, nil
+	return r.averageExecutionTime("user_id = ?", userID)
 }
 
 func (r *AdvancedOrderRepository) GetOrderHistory(userID int, limit, offset int) ([]domain.Order, error) {
-	return []domain.Order{}, nil
+	query := `
+		SELECT ` + orderColumns + `
+		FROM advanced_orders
+		WHERE user_id = ?
+		ORDER BY created_at DESC, id DESC
+		LIMIT ? OFFSET ?
+	`
+
+	orders, err := r.queryOrders(query, userID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get order history: %w", err)
+	}
+	return orders, nil
 }
 
+// GetOrdersByDateRange returns the user's orders created from startDate up
+// to, but not including, endDate, newest first
 func (r *AdvancedOrderRepository) GetOrdersByDateRange(userID int, startDate, endDate time.Time) ([]domain.Order, error) {
-	return []domain.Order{}, nil
+	query := `
+		SELECT ` + orderColumns + `
+		FROM advanced_orders
+		WHERE user_id = ? AND created_at >= ? AND created_at < ?
+		ORDER BY created_at DESC, id DESC
+	`
+
+	orders, err := r.queryOrders(query, userID, startDate, endDate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get orders by date range: %w", err)
+	}
+	return orders, nil
 }
 
 func (r *AdvancedOrderRepository) GetOrdersForSymbolAndDateRange(userID int, symbol string, startDate, endDate time.Time) ([]domain.Order, error) {
-	return []domain.Order{}, nil
+	query := `
+		SELECT ` + orderColumns + `
+		FROM advanced_orders
+		WHERE user_id = ? AND stock_symbol = ? AND created_at >= ? AND created_at < ?
+		ORDER BY created_at DESC, id DESC
+	`
+
+	orders, err := r.queryOrders(query, userID, symbol, startDate, endDate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get orders by symbol and date range: %w", err)
+	}
+	return orders, nil
 }
 
 func (r *AdvancedOrderRepository) ValidateOrderConstraints(order *domain.Order) error {
 	return nil
 }
 
-func (r *AdvancedOrderRepository) CheckDailyOrderLimit(userID int) (bool, error) {
-	return true, nil
+// CheckDailyOrderLimit counts the orders the user placed since the given
+// time. Bracket exits and algo slices are generated from an order the user
+// placed and do not count.
+func (r *AdvancedOrderRepository) CheckDailyOrderLimit(userID int, since time.Time, maxOrders int) (bool, error) {
+	query := `
+		SELECT COUNT(*) FROM advanced_orders
+		WHERE user_id = ? AND parent_order_id IS NULL AND created_at >= ?
+	`
+	var count int
+	if err := r.db.QueryRow(query, userID, since).Scan(&count); err != nil {
+		return false, fmt.Errorf("failed to count daily orders: %w", err)
+	}
+	return count < maxOrders, nil
 }
 
-func (r *AdvancedOrderRepository) CheckOrderSizeLimit(userID int, symbol string, quantity int) (bool, error) {
-	return true, nil
+// CheckOrderSizeLimit reports whether quantity more shares fit under
+// maxQuantity once the user's resting orders on the same symbol and side are
+// counted. Algo parents count for their whole remainder, so their slices are
+// skipped.
+func (r *AdvancedOrderRepository) CheckOrderSizeLimit(userID int, symbol string, side domain.OrderSide, quantity, maxQuantity int) (bool, error) {
+	query := `
+		SELECT COALESCE(SUM(remaining_quantity), 0) FROM advanced_orders
+		WHERE user_id = ? AND stock_symbol = ? AND side = ? AND parent_order_id IS NULL
+		  AND status IN ` + cancellableStatuses + `
+	`
+	var resting int
+	if err := r.db.QueryRow(query, userID, symbol, side).Scan(&resting); err != nil {
+		return false, fmt.Errorf("failed to sum resting orders: %w", err)
+	}
+	return resting+quantity <= maxQuantity, nil
 }
 
-func (r *AdvancedOrderRepository) UpdateTrailingStopPrice(orderID int, newStopPrice float64) error {
+func (r *AdvancedOrderRepository) UpdateTrailingStopPrice(orderID int, highWaterMark, newStopPrice float64) error {
+	query := `
+		UPDATE advanced_orders 
+		SET high_water_mark = ?, stop_price = ?, updated_at = NOW()
+		WHERE id = ? AND order_type = 'TRAILING_STOP' AND status IN ` + activeStatuses + `
+	`
+
+	_, err := r.db.Exec(query, highWaterMark, newStopPrice, orderID)
+	if err != nil {
+		return fmt.Errorf("failed to update trailing stop: %w", err)
+	}
+
 	return nil
 }
 
+// TriggerStopLimit records that a stop-limit's stop was hit, turning it into a
+// resting limit order. It reports false if the order had already triggered or
+// is no longer active.
+func (r *AdvancedOrderRepository) TriggerStopLimit(orderID int, triggeredAt time.Time) (bool, error) {
+	query := `
+		UPDATE advanced_orders 
+		SET triggered_at = ?, updated_at = NOW()
+		WHERE id = ? AND order_type = 'STOP_LIMIT' AND triggered_at IS NULL
+		  AND status IN ` + activeStatuses + `
+	`
+
+	result, err := r.db.Exec(query, triggeredAt, orderID)
+	if err != nil {
+		return false, fmt.Errorf("failed to trigger stop limit order: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get affected rows: %w", err)
+	}
+
+	return rowsAffected > 0, nil
+}
+
+// GetTrailingStopsToUpdate returns the active trailing stops on the symbols
+// present in priceUpdates.
 func (r *AdvancedOrderRepository) GetTrailingStopsToUpdate(priceUpdates map[string]float64) ([]domain.Order, error) {
-	return []domain.Order{}, nil
+	if len(priceUpdates) == 0 {
+		return []domain.Order{}, nil
+	}
+
+	placeholders := make([]string, 0, len(priceUpdates))
+	args := make([]interface{}, 0, len(priceUpdates))
+	for symbol := range priceUpdates {
+		placeholders = append(placeholders, "?")
+		args = append(args, symbol)
+	}
+
+	query := `
+		SELECT ` + orderColumns + `
+		FROM advanced_orders 
+		WHERE order_type = 'TRAILING_STOP' AND status IN ` + activeStatuses + `
+		  AND stock_symbol IN (` + strings.Join(placeholders, ", ") + `)
+		ORDER BY created_at ASC
+	`
+
+	orders, err := r.queryOrders(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get trailing stops to update: %w", err)
+	}
+
+	return orders, nil
 }
 
 func (r *AdvancedOrderRepository) UpdateOrderCommission(orderID int, commission, fees float64) error {
+	query := `UPDATE advanced_orders SET commission = ?, fees = ?, updated_at = NOW() WHERE id = ?`
+
+	_, err := r.db.Exec(query, commission, fees, orderID)
+	if err != nil {
+		return fmt.Errorf("failed to update order commission: %w", err)
+	}
+
 	return nil
 }
 
@@ -607,33 +1538,144 @@ func (r *AdvancedOrderRepository) GetOrdersAwaitingMarketOpen(marketCode string)
 	return []domain.Order{}, nil
 }
 
-func (r *AdvancedOrderRepository) GetOrdersToExpireAtMarketClose(marketCode string) ([]domain.Order, error) {
-	return []domain.Order{}, nil
-}
+// GetOrdersToExpireAtMarketClose returns the active DAY orders placed before
+// sessionClose. DAY orders placed after a close live until the next one.
+func (r *AdvancedOrderRepository) GetOrdersToExpireAtMarketClose(sessionClose time.Time) ([]domain.Order, error) {
+	query := `
+		SELECT ` + orderColumns + `
+		FROM advanced_orders 
+		WHERE time_in_force = 'DAY' AND created_at < ?
+		  AND status IN ` + activeStatuses + `
+		ORDER BY created_at ASC
+	`
 
-// Methods from AdvancedOrderRepositoryWithSearch interface
-func (r *AdvancedOrderRepository) SearchOrders(criteria *repositories.OrderSearchCriteria) (*repositories.OrderSearchResult, error) {
-	// Implementation already exists as Search method
-	return r.Search(0, criteria)
-}
+	orders, err := r.queryOrders(query, sessionClose)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get day orders: %w", err)
+	}
 
-func (r *AdvancedOrderRepository) SearchOrdersByUser(userID int, criteria *repositories.OrderSearchCriteria) (*repositories.OrderSearchResult, error) {
-	return r.Search(userID, criteria)
+	return orders, nil
 }
 
-func (r *AdvancedOrderRepository) SearchOrdersBySymbol(symbol string, criteria *repositories.OrderSearchCriteria) (*repositories.OrderSearchResult, error) {
-	criteria.Symbol = &symbol
-	return r.Search(0, criteria)
+// GetByIDs returns the orders with the given IDs that exist, in ID order
+func (r *AdvancedOrderRepository) GetByIDs(ids []int) ([]domain.Order, error) {
+	orders := []domain.Order{}
+	for start := 0; start < len(ids); start += batchSize {
+		batch := ids[start:min(start+batchSize, len(ids))]
+		query := `
+			SELECT ` + orderColumns + `
+			FROM advanced_orders
+			WHERE id IN (` + placeholders(len(batch)) + `)
+			ORDER BY id ASC
+		`
+		found, err := r.queryOrders(query, inArgs(batch)...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get orders: %w", err)
+		}
+		orders = append(orders, found...)
+	}
+	return orders, nil
 }
 
+// BulkUpdateStatus moves every listed order to status in one transaction.
+// A move the state machine forbids fails the whole batch.
 func (r *AdvancedOrderRepository) BulkUpdateStatus(orderIDs []int, status domain.OrderStatus) error {
+	if len(orderIDs) == 0 {
+		return nil
+	}
+	_, err := r.transition(orderTransition{
+		filter: `id IN (` + placeholders(len(orderIDs)) + `)`,
+		args:   inArgs(orderIDs),
+		to:     status,
+		reason: "Status updated in bulk",
+		actor:  domain.OrderActorSystem,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update order statuses: %w", err)
+	}
 	return nil
 }
 
-func (r *AdvancedOrderRepository) BulkCancelOrders(orderIDs []int, reason string) error {
-	return nil
+// BulkCancelOrders cancels the listed orders that are still working, in one
+// transaction, and everything that goes with them as a single cancel would
+// take it: the other legs of their OCO groups, the dormant exits of bracket
+// entries and the slices of algo parents. It returns the IDs of the listed
+// orders that were cancelled; the rest had already finished.
+func (r *AdvancedOrderRepository) BulkCancelOrders(orderIDs []int, reason string, actor domain.OrderActor) ([]int, error) {
+	if len(orderIDs) == 0 {
+		return []int{}, nil
+	}
+
+	var cancelled []int
+	err := r.inTx(func(tx *AdvancedOrderRepository) error {
+		var err error
+		cancelled, err = tx.transition(orderTransition{
+			filter:  `id IN (` + placeholders(len(orderIDs)) + `) AND status IN ` + cancellableStatuses,
+			args:    inArgs(orderIDs),
+			to:      domain.OrderStatusCancelled,
+			set:     `cancel_reason = ?, `,
+			setArgs: []interface{}{reason},
+			reason:  reason,
+			actor:   actor,
+		})
+		if err != nil || len(cancelled) == 0 {
+			return err
+		}
+		return tx.cancelDependents(cancelled)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to cancel orders: %w", err)
+	}
+
+	return cancelled, nil
 }
 
+// cancelDependents cancels what goes with the cancelled orders: the working
+// legs of their OCO groups, their dormant bracket exits and the slices of
+// algo parents among them
+func (r *AdvancedOrderRepository) cancelDependents(cancelled []int) error {
+	in := placeholders(len(cancelled))
+	linkedReason := "OCO: linked order cancelled"
+	_, err := r.transition(orderTransition{
+		filter: `oco_group_id IN (SELECT oco_group_id FROM advanced_orders WHERE id IN (` + in + `))
+		         AND status IN ` + activeStatuses,
+		args:    inArgs(cancelled),
+		to:      domain.OrderStatusCancelled,
+		set:     `cancel_reason = ?, `,
+		setArgs: []interface{}{linkedReason},
+		reason:  linkedReason,
+		actor:   domain.OrderActorSystem,
+	})
+	if err != nil {
+		return err
+	}
+
+	childReason := "Parent order cancelled"
+	_, err = r.transition(orderTransition{
+		filter: `parent_order_id IN (` + in + `)
+		         AND (status = 'DORMANT' OR (status IN ` + cancellableStatuses + ` AND parent_order_id IN
+		              (SELECT id FROM advanced_orders WHERE id IN (` + in + `) AND order_type IN ` + algoParentTypes + `)))`,
+		args:    append(inArgs(cancelled), inArgs(cancelled)...),
+		to:      domain.OrderStatusCancelled,
+		set:     `cancel_reason = ?, `,
+		setArgs: []interface{}{childReason},
+		reason:  childReason,
+		actor:   domain.OrderActorSystem,
+	})
+	return err
+}
+
+// BulkExecuteOrders applies each execution to its order as
+// UpdateExecutionDetails does, all in one transaction: if any order cannot
+// take its fill, none of them is filled
 func (r *AdvancedOrderRepository) BulkExecuteOrders(executions []domain.OrderExecution) error {
-	return nil
+	return r.inTx(func(tx *AdvancedOrderRepository) error {
+		for i := range executions {
+			execution := &executions[i]
+			if err := tx.UpdateExecutionDetails(execution.OrderID, execution); err != nil {
+				return fmt.Errorf("failed to execute order %d: %w", execution.OrderID, err)
+			}
+		}
+		return nil
+	})
 } 
\ No newline at end of file
@@ -0,0 +1,127 @@
+package mysql
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"stock-simulation-backend/internal/core/domain"
+	"stock-simulation-backend/internal/core/ports/repositories"
+)
+
+type algoOrderRepository struct {
+	db dbExecutor
+}
+
+func NewAlgoOrderRepository(db *sql.DB) repositories.AlgoOrderRepository {
+	return &algoOrderRepository{db: db}
+}
+
+const algoParamsColumns = `p.order_id, p.slice_type, p.start_at, p.end_at, p.arrival_price,
+		       p.volume_profile, p.paused_at, p.created_at, p.updated_at`
+
+func scanAlgoParams(row rowScanner) (*domain.AlgoParams, error) {
+	var params domain.AlgoParams
+	var profile sql.NullString
+	var pausedAt sql.NullTime
+	err := row.Scan(
+		&params.OrderID, &params.SliceType, &params.StartAt, &params.EndAt, &params.ArrivalPrice,
+		&profile, &pausedAt, &params.CreatedAt, &params.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if profile.Valid && profile.String != "" {
+		if err := json.Unmarshal([]byte(profile.String), &params.VolumeProfile); err != nil {
+			return nil, fmt.Errorf("invalid volume profile for order %d: %w", params.OrderID, err)
+		}
+	}
+	if pausedAt.Valid {
+		params.PausedAt = &pausedAt.Time
+	}
+	return &params, nil
+}
+
+// volumeProfileValue stores a profile as JSON, or NULL when there is none
+func volumeProfileValue(profile []float64) (interface{}, error) {
+	if len(profile) == 0 {
+		return nil, nil
+	}
+	data, err := json.Marshal(profile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode volume profile: %w", err)
+	}
+	return string(data), nil
+}
+
+func (r *algoOrderRepository) Create(params *domain.AlgoParams) error {
+	profile, err := volumeProfileValue(params.VolumeProfile)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO algo_order_params (order_id, slice_type, start_at, end_at, arrival_price,
+		                               volume_profile, paused_at, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, NOW(), NOW())
+	`
+	_, err = r.db.Exec(query, params.OrderID, params.SliceType, params.StartAt, params.EndAt,
+		params.ArrivalPrice, profile, params.PausedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create algo order params: %w", err)
+	}
+	return nil
+}
+
+func (r *algoOrderRepository) GetByOrderID(orderID int) (*domain.AlgoParams, error) {
+	query := `SELECT ` + algoParamsColumns + ` FROM algo_order_params p WHERE p.order_id = ?`
+	params, err := scanAlgoParams(r.db.QueryRow(query, orderID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("algo order not found")
+		}
+		return nil, fmt.Errorf("failed to get algo order params: %w", err)
+	}
+	return params, nil
+}
+
+func (r *algoOrderRepository) GetRunning() ([]domain.AlgoParams, error) {
+	query := `
+		SELECT ` + algoParamsColumns + `
+		FROM algo_order_params p
+		JOIN advanced_orders o ON o.id = p.order_id
+		WHERE o.status IN ` + activeStatuses + ` AND p.paused_at IS NULL
+		ORDER BY p.start_at ASC
+	`
+	rows, err := r.db.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get running algo orders: %w", err)
+	}
+	defer rows.Close()
+
+	running := []domain.AlgoParams{}
+	for rows.Next() {
+		params, err := scanAlgoParams(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan algo order params: %w", err)
+		}
+		running = append(running, *params)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read algo order params: %w", err)
+	}
+	return running, nil
+}
+
+func (r *algoOrderRepository) Update(params *domain.AlgoParams) error {
+	query := `
+		UPDATE algo_order_params
+		SET start_at = ?, end_at = ?, paused_at = ?, updated_at = NOW()
+		WHERE order_id = ?
+	`
+	_, err := r.db.Exec(query, params.StartAt, params.EndAt, params.PausedAt, params.OrderID)
+	if err != nil {
+		return fmt.Errorf("failed to update algo order params: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,221 @@
+package mysql
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"stock-simulation-backend/internal/core/domain"
+	"stock-simulation-backend/internal/core/ports/repositories"
+)
+
+type contingentOrderRepository struct {
+	db dbExecutor
+}
+
+func NewContingentOrderRepository(db *sql.DB) repositories.ContingentOrderRepository {
+	return &contingentOrderRepository{db: db}
+}
+
+const contingentOrderColumns = `id, user_id, conditions, logic, order_request, status, order_id,
+		       failure_reason, triggered_at, created_at, updated_at`
+
+func scanContingentOrder(row rowScanner) (*domain.ContingentOrder, error) {
+	var contingent domain.ContingentOrder
+	var conditions, orderRequest string
+	var orderID sql.NullInt64
+	var failureReason sql.NullString
+	var triggeredAt sql.NullTime
+	err := row.Scan(
+		&contingent.ID, &contingent.UserID, &conditions, &contingent.Logic, &orderRequest,
+		&contingent.Status, &orderID, &failureReason, &triggeredAt,
+		&contingent.CreatedAt, &contingent.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(conditions), &contingent.Conditions); err != nil {
+		return nil, fmt.Errorf("invalid conditions for contingent order %d: %w", contingent.ID, err)
+	}
+	if err := json.Unmarshal([]byte(orderRequest), &contingent.Order); err != nil {
+		return nil, fmt.Errorf("invalid order request for contingent order %d: %w", contingent.ID, err)
+	}
+	if orderID.Valid {
+		id := int(orderID.Int64)
+		contingent.OrderID = &id
+	}
+	if failureReason.Valid {
+		contingent.FailureReason = &failureReason.String
+	}
+	if triggeredAt.Valid {
+		contingent.TriggeredAt = &triggeredAt.Time
+	}
+	return &contingent, nil
+}
+
+// encodeContingentOrder stores the conditions and order request as JSON
+func encodeContingentOrder(contingent *domain.ContingentOrder) (string, string, error) {
+	conditions, err := json.Marshal(contingent.Conditions)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to encode conditions: %w", err)
+	}
+	orderRequest, err := json.Marshal(contingent.Order)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to encode order request: %w", err)
+	}
+	return string(conditions), string(orderRequest), nil
+}
+
+func (r *contingentOrderRepository) queryContingentOrders(query string, args ...interface{}) ([]domain.ContingentOrder, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get contingent orders: %w", err)
+	}
+	defer rows.Close()
+
+	contingents := []domain.ContingentOrder{}
+	for rows.Next() {
+		contingent, err := scanContingentOrder(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan contingent order: %w", err)
+		}
+		contingents = append(contingents, *contingent)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read contingent orders: %w", err)
+	}
+	return contingents, nil
+}
+
+func (r *contingentOrderRepository) Create(contingent *domain.ContingentOrder) error {
+	conditions, orderRequest, err := encodeContingentOrder(contingent)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO contingent_orders (user_id, conditions, logic, order_request, status, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, NOW(), NOW())
+	`
+	result, err := r.db.Exec(query, contingent.UserID, conditions, contingent.Logic, orderRequest, contingent.Status)
+	if err != nil {
+		return fmt.Errorf("failed to create contingent order: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get contingent order ID: %w", err)
+	}
+	contingent.ID = int(id)
+	contingent.CreatedAt = time.Now()
+	contingent.UpdatedAt = contingent.CreatedAt
+	return nil
+}
+
+func (r *contingentOrderRepository) GetByID(id int) (*domain.ContingentOrder, error) {
+	query := `SELECT ` + contingentOrderColumns + ` FROM contingent_orders WHERE id = ?`
+	contingent, err := scanContingentOrder(r.db.QueryRow(query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("contingent order not found")
+		}
+		return nil, fmt.Errorf("failed to get contingent order: %w", err)
+	}
+	return contingent, nil
+}
+
+func (r *contingentOrderRepository) GetByUserID(userID int) ([]domain.ContingentOrder, error) {
+	query := `
+		SELECT ` + contingentOrderColumns + `
+		FROM contingent_orders
+		WHERE user_id = ?
+		ORDER BY created_at DESC
+	`
+	return r.queryContingentOrders(query, userID)
+}
+
+func (r *contingentOrderRepository) GetActive() ([]domain.ContingentOrder, error) {
+	query := `
+		SELECT ` + contingentOrderColumns + `
+		FROM contingent_orders
+		WHERE status = 'ACTIVE'
+		ORDER BY created_at ASC
+	`
+	return r.queryContingentOrders(query)
+}
+
+func (r *contingentOrderRepository) Update(contingent *domain.ContingentOrder) (bool, error) {
+	conditions, orderRequest, err := encodeContingentOrder(contingent)
+	if err != nil {
+		return false, err
+	}
+
+	query := `
+		UPDATE contingent_orders
+		SET conditions = ?, logic = ?, order_request = ?, updated_at = NOW()
+		WHERE id = ? AND status = 'ACTIVE'
+	`
+	result, err := r.db.Exec(query, conditions, contingent.Logic, orderRequest, contingent.ID)
+	if err != nil {
+		return false, fmt.Errorf("failed to update contingent order: %w", err)
+	}
+	return contingentRowsAffected(result)
+}
+
+func (r *contingentOrderRepository) Cancel(id int) (bool, error) {
+	query := `
+		UPDATE contingent_orders
+		SET status = 'CANCELLED', updated_at = NOW()
+		WHERE id = ? AND status = 'ACTIVE'
+	`
+	result, err := r.db.Exec(query, id)
+	if err != nil {
+		return false, fmt.Errorf("failed to cancel contingent order: %w", err)
+	}
+	return contingentRowsAffected(result)
+}
+
+func (r *contingentOrderRepository) MarkTriggered(id int, conditions []domain.TriggerCondition, triggeredAt time.Time) (bool, error) {
+	encoded, err := json.Marshal(conditions)
+	if err != nil {
+		return false, fmt.Errorf("failed to encode conditions: %w", err)
+	}
+
+	query := `
+		UPDATE contingent_orders
+		SET status = 'TRIGGERED', conditions = ?, triggered_at = ?, updated_at = NOW()
+		WHERE id = ? AND status = 'ACTIVE'
+	`
+	result, err := r.db.Exec(query, string(encoded), triggeredAt, id)
+	if err != nil {
+		return false, fmt.Errorf("failed to mark contingent order triggered: %w", err)
+	}
+	return contingentRowsAffected(result)
+}
+
+func (r *contingentOrderRepository) RecordResult(id int, orderID *int, failureReason *string) error {
+	status := domain.ContingentStatusTriggered
+	if failureReason != nil {
+		status = domain.ContingentStatusFailed
+	}
+
+	query := `
+		UPDATE contingent_orders
+		SET order_id = ?, failure_reason = ?, status = ?, updated_at = NOW()
+		WHERE id = ?
+	`
+	_, err := r.db.Exec(query, orderID, failureReason, status, id)
+	if err != nil {
+		return fmt.Errorf("failed to record contingent order result: %w", err)
+	}
+	return nil
+}
+
+func contingentRowsAffected(result sql.Result) (bool, error) {
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	return rowsAffected > 0, nil
+}
@@ -131,97 +131,7 @@ func (r *historicalPriceRepository) GetChartData(symbol string, period string) (
 }
 
 func (r *historicalPriceRepository) calculateIndicators(prices []domain.HistoricalPrice) domain.ChartIndicators {
-	indicators := domain.ChartIndicators{
-		MA20:   []float64{},
-		MA50:   []float64{},
-		RSI:    []float64{},
-		Volume: []int64{},
-	}
-	
-	if len(prices) == 0 {
-		return indicators
-	}
-	
-	// Extract closing prices and volumes
-	closePrices := make([]float64, len(prices))
-	volumes := make([]int64, len(prices))
-	
-	for i, price := range prices {
-		closePrices[i] = price.Close
-		volumes[i] = price.Volume
-	}
-	
-	indicators.Volume = volumes
-	
-	// Calculate Moving Averages
-	indicators.MA20 = r.calculateMovingAverage(closePrices, 20)
-	indicators.MA50 = r.calculateMovingAverage(closePrices, 50)
-	
-	// Calculate RSI
-	indicators.RSI = r.calculateRSI(closePrices, 14)
-	
-	return indicators
-}
-
-func (r *historicalPriceRepository) calculateMovingAverage(prices []float64, period int) []float64 {
-	if len(prices) < period {
-		return make([]float64, len(prices))
-	}
-	
-	ma := make([]float64, len(prices))
-	
-	for i := period - 1; i < len(prices); i++ {
-		sum := 0.0
-		for j := i - period + 1; j <= i; j++ {
-			sum += prices[j]
-		}
-		ma[i] = sum / float64(period)
-	}
-	
-	return ma
-}
-
-func (r *historicalPriceRepository) calculateRSI(prices []float64, period int) []float64 {
-	if len(prices) < period+1 {
-		return make([]float64, len(prices))
-	}
-	
-	rsi := make([]float64, len(prices))
-	gains := make([]float64, len(prices)-1)
-	losses := make([]float64, len(prices)-1)
-	
-	// Calculate gains and losses
-	for i := 1; i < len(prices); i++ {
-		change := prices[i] - prices[i-1]
-		if change > 0 {
-			gains[i-1] = change
-		} else {
-			losses[i-1] = -change
-		}
-	}
-	
-	// Calculate RSI
-	for i := period; i < len(prices); i++ {
-		avgGain := 0.0
-		avgLoss := 0.0
-		
-		for j := i - period; j < i; j++ {
-			avgGain += gains[j]
-			avgLoss += losses[j]
-		}
-		
-		avgGain /= float64(period)
-		avgLoss /= float64(period)
-		
-		if avgLoss == 0 {
-			rsi[i] = 100
-		} else {
-			rs := avgGain / avgLoss
-			rsi[i] = 100 - (100 / (1 + rs))
-		}
-	}
-	
-	return rsi
+	return domain.NewChartIndicators(prices)
 }
 
 func (r *historicalPriceRepository) Update(price *domain.HistoricalPrice) error {
@@ -310,4 +220,30 @@ func (r *historicalPriceRepository) GetAvailableSymbols() ([]string, error) {
 	}
 	
 	return symbols, nil
-} 
\ No newline at end of file
+} 
+func (r *historicalPriceRepository) GetHourlyVolume(symbol string, since time.Time) (map[int]int64, error) {
+	query := `
+		SELECT HOUR(date), SUM(volume) 
+		FROM historical_prices 
+		WHERE symbol = ? AND date >= ? 
+		GROUP BY HOUR(date)
+	`
+	
+	rows, err := r.db.Query(query, symbol, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	
+	volumeByHour := make(map[int]int64)
+	for rows.Next() {
+		var hour int
+		var volume int64
+		if err := rows.Scan(&hour, &volume); err != nil {
+			return nil, err
+		}
+		volumeByHour[hour] = volume
+	}
+	
+	return volumeByHour, rows.Err()
+}
@@ -0,0 +1,94 @@
+package mysql
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"stock-simulation-backend/internal/core/domain"
+	"stock-simulation-backend/internal/core/ports/repositories"
+)
+
+type idempotencyRepository struct {
+	db dbExecutor
+}
+
+func NewIdempotencyRepository(db *sql.DB) repositories.IdempotencyRepository {
+	return &idempotencyRepository{db: db}
+}
+
+// Reserve inserts the record unless the key exists. The no-op update on a
+// duplicate leaves the row untouched, so no affected rows means taken.
+func (r *idempotencyRepository) Reserve(record *domain.IdempotencyRecord) (bool, error) {
+	query := `
+		INSERT INTO idempotency_keys (user_id, idempotency_key, request_hash, created_at, expires_at)
+		VALUES (?, ?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE user_id = user_id
+	`
+	result, err := r.db.Exec(query, record.UserID, record.Key, record.RequestHash, record.CreatedAt, record.ExpiresAt)
+	if err != nil {
+		return false, fmt.Errorf("failed to reserve idempotency key: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	return rowsAffected > 0, nil
+}
+
+func (r *idempotencyRepository) Get(userID int, key string) (*domain.IdempotencyRecord, error) {
+	query := `
+		SELECT user_id, idempotency_key, request_hash, status_code, response_body,
+		       created_at, completed_at, expires_at
+		FROM idempotency_keys
+		WHERE user_id = ? AND idempotency_key = ?
+	`
+
+	var record domain.IdempotencyRecord
+	var statusCode sql.NullInt64
+	var completedAt sql.NullTime
+	err := r.db.QueryRow(query, userID, key).Scan(
+		&record.UserID, &record.Key, &record.RequestHash, &statusCode, &record.ResponseBody,
+		&record.CreatedAt, &completedAt, &record.ExpiresAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get idempotency key: %w", err)
+	}
+
+	if statusCode.Valid {
+		record.StatusCode = int(statusCode.Int64)
+	}
+	if completedAt.Valid {
+		record.CompletedAt = &completedAt.Time
+	}
+	return &record, nil
+}
+
+func (r *idempotencyRepository) Complete(record *domain.IdempotencyRecord) error {
+	completedAt := time.Now()
+	query := `
+		UPDATE idempotency_keys
+		SET status_code = ?, response_body = ?, completed_at = ?
+		WHERE user_id = ? AND idempotency_key = ? AND request_hash = ?
+	`
+	_, err := r.db.Exec(query, record.StatusCode, record.ResponseBody, completedAt,
+		record.UserID, record.Key, record.RequestHash)
+	if err != nil {
+		return fmt.Errorf("failed to complete idempotency key: %w", err)
+	}
+
+	record.CompletedAt = &completedAt
+	return nil
+}
+
+func (r *idempotencyRepository) Delete(userID int, key string) error {
+	query := `DELETE FROM idempotency_keys WHERE user_id = ? AND idempotency_key = ?`
+	if _, err := r.db.Exec(query, userID, key); err != nil {
+		return fmt.Errorf("failed to delete idempotency key: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,157 @@
+package mysql
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"stock-simulation-backend/internal/core/domain"
+	"stock-simulation-backend/internal/core/ports/repositories"
+)
+
+type marginAccountRepository struct {
+	db dbExecutor
+}
+
+func NewMarginAccountRepository(db *sql.DB) repositories.MarginAccountRepository {
+	return &marginAccountRepository{db: db}
+}
+
+const marginAccountColumns = `id, user_id, account_type, borrowed_funds, accrued_interest, margin_call_amount,
+		       margin_call_at, last_interest_at, created_at, updated_at`
+
+func scanMarginAccount(row rowScanner) (*domain.MarginAccount, error) {
+	var account domain.MarginAccount
+	var marginCallAt sql.NullTime
+	err := row.Scan(
+		&account.ID, &account.UserID, &account.AccountType, &account.BorrowedFunds,
+		&account.AccruedInterest, &account.MarginCallAmount, &marginCallAt,
+		&account.LastInterestAt, &account.CreatedAt, &account.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if marginCallAt.Valid {
+		account.MarginCallAt = &marginCallAt.Time
+	}
+	return &account, nil
+}
+
+func (r *marginAccountRepository) Create(account *domain.MarginAccount) error {
+	query := `
+		INSERT INTO margin_accounts (user_id, account_type, borrowed_funds, accrued_interest,
+		                             last_interest_at, created_at, updated_at)
+		VALUES (?, ?, ?, ?, NOW(), NOW(), NOW())
+	`
+	result, err := r.db.Exec(query, account.UserID, account.AccountType,
+		account.BorrowedFunds, account.AccruedInterest)
+	if err != nil {
+		return fmt.Errorf("failed to create margin account: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get margin account ID: %w", err)
+	}
+
+	account.ID = int(id)
+	return nil
+}
+
+func (r *marginAccountRepository) GetByUserID(userID int) (*domain.MarginAccount, error) {
+	query := `SELECT ` + marginAccountColumns + ` FROM margin_accounts WHERE user_id = ?`
+	account, err := scanMarginAccount(r.db.QueryRow(query, userID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get margin account: %w", err)
+	}
+	return account, nil
+}
+
+// GetByUserIDForUpdate is GetByUserID with a row lock held until the
+// surrounding transaction ends.
+func (r *marginAccountRepository) GetByUserIDForUpdate(userID int) (*domain.MarginAccount, error) {
+	query := `SELECT ` + marginAccountColumns + ` FROM margin_accounts WHERE user_id = ? FOR UPDATE`
+	account, err := scanMarginAccount(r.db.QueryRow(query, userID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to lock margin account: %w", err)
+	}
+	return account, nil
+}
+
+func (r *marginAccountRepository) GetMarginAccounts() ([]domain.MarginAccount, error) {
+	query := `SELECT ` + marginAccountColumns + ` FROM margin_accounts WHERE account_type = 'MARGIN' ORDER BY user_id`
+	rows, err := r.db.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get margin accounts: %w", err)
+	}
+	defer rows.Close()
+
+	accounts := []domain.MarginAccount{}
+	for rows.Next() {
+		account, err := scanMarginAccount(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan margin account: %w", err)
+		}
+		accounts = append(accounts, *account)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read margin accounts: %w", err)
+	}
+	return accounts, nil
+}
+
+func (r *marginAccountRepository) Update(account *domain.MarginAccount) error {
+	query := `
+		UPDATE margin_accounts
+		SET account_type = ?, borrowed_funds = ?, accrued_interest = ?, updated_at = NOW()
+		WHERE id = ?
+	`
+	_, err := r.db.Exec(query, account.AccountType, account.BorrowedFunds,
+		account.AccruedInterest, account.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update margin account: %w", err)
+	}
+	return nil
+}
+
+func (r *marginAccountRepository) SetMarginCall(userID int, amount float64, calledAt *time.Time) error {
+	query := `
+		UPDATE margin_accounts
+		SET margin_call_amount = ?, margin_call_at = ?, updated_at = NOW()
+		WHERE user_id = ?
+	`
+	_, err := r.db.Exec(query, amount, calledAt, userID)
+	if err != nil {
+		return fmt.Errorf("failed to set margin call: %w", err)
+	}
+	return nil
+}
+
+func (r *marginAccountRepository) AccrueInterest(asOf time.Time) (int, error) {
+	// Assignments run left to right, so interest is charged on the days
+	// counted from the old last_interest_at before it moves forward
+	query := `
+		UPDATE margin_accounts
+		SET accrued_interest = accrued_interest + ROUND(
+		        borrowed_funds * ? / 360 * FLOOR(TIMESTAMPDIFF(HOUR, last_interest_at, ?) / 24), 2),
+		    last_interest_at = DATE_ADD(last_interest_at, INTERVAL FLOOR(TIMESTAMPDIFF(HOUR, last_interest_at, ?) / 24) DAY),
+		    updated_at = NOW()
+		WHERE account_type = 'MARGIN' AND last_interest_at <= DATE_SUB(?, INTERVAL 1 DAY)
+	`
+	result, err := r.db.Exec(query, domain.MarginInterestRate, asOf, asOf, asOf)
+	if err != nil {
+		return 0, fmt.Errorf("failed to accrue margin interest: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	return int(rowsAffected), nil
+}
@@ -0,0 +1,250 @@
+package mysql
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"stock-simulation-backend/internal/core/domain"
+)
+
+// batchSize caps the rows a single statement updates or inserts, keeping bulk
+// statements well inside MySQL's placeholder limit
+const batchSize = 500
+
+// orderTransition is a status change applied to every order matching filter
+type orderTransition struct {
+	filter  string // WHERE clause choosing the orders, normally including their current statuses
+	args    []interface{}
+	to      domain.OrderStatus
+	resume  bool   // Move to PARTIALLY_FILLED instead of PENDING when the order has fills
+	set     string // Further assignments, each followed by a comma
+	setArgs []interface{}
+	reason  string
+	actor   domain.OrderActor
+}
+
+// orderMove is one order's status change within a transition
+type orderMove struct {
+	id   int
+	from domain.OrderStatus
+	to   domain.OrderStatus
+}
+
+// placeholders returns n comma-separated bind parameters for an IN list
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
+
+// inArgs turns IDs into the arguments of an IN list
+func inArgs(ids []int) []interface{} {
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	return args
+}
+
+// inTx runs fn in a transaction of its own, or in the caller's when the
+// repository already belongs to a unit of work
+func (r *AdvancedOrderRepository) inTx(fn func(tx *AdvancedOrderRepository) error) error {
+	db, ok := r.db.(*sql.DB)
+	if !ok {
+		return fn(r)
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	if err := fn(&AdvancedOrderRepository{db: tx}); err != nil {
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			return fmt.Errorf("%w (rollback failed: %v)", err, rollbackErr)
+		}
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// transition locks the orders matching t.filter and moves them, checking
+// each move against the domain state machine and appending it to
+// order_events in the same transaction. Orders going to the same status are
+// updated together, in batches. It returns the IDs of the orders that moved.
+func (r *AdvancedOrderRepository) transition(t orderTransition) ([]int, error) {
+	type lockedOrder struct {
+		id               int
+		status           domain.OrderStatus
+		executedQuantity int
+	}
+
+	moved := []int{}
+	err := r.inTx(func(tx *AdvancedOrderRepository) error {
+		query := `SELECT id, status, executed_quantity FROM advanced_orders WHERE ` + t.filter + ` ORDER BY id FOR UPDATE`
+		rows, err := tx.db.Query(query, t.args...)
+		if err != nil {
+			return fmt.Errorf("failed to lock orders: %w", err)
+		}
+
+		orders := []lockedOrder{}
+		for rows.Next() {
+			var order lockedOrder
+			if err := rows.Scan(&order.id, &order.status, &order.executedQuantity); err != nil {
+				rows.Close()
+				return fmt.Errorf("failed to scan order status: %w", err)
+			}
+			orders = append(orders, order)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("failed to iterate order statuses: %w", err)
+		}
+
+		moves := make([]orderMove, 0, len(orders))
+		byStatus := make(map[domain.OrderStatus][]int)
+		for _, order := range orders {
+			to := t.to
+			if t.resume && order.executedQuantity > 0 {
+				to = domain.OrderStatusPartiallyFilled
+			}
+			if !domain.CanTransition(order.status, to) {
+				return fmt.Errorf("order %d cannot move from %s to %s", order.id, order.status, to)
+			}
+			moves = append(moves, orderMove{id: order.id, from: order.status, to: to})
+			byStatus[to] = append(byStatus[to], order.id)
+			moved = append(moved, order.id)
+		}
+
+		for to, ids := range byStatus {
+			for start := 0; start < len(ids); start += batchSize {
+				batch := ids[start:min(start+batchSize, len(ids))]
+				update := `UPDATE advanced_orders SET status = ?, ` + t.set + `updated_at = NOW() WHERE id IN (` + placeholders(len(batch)) + `)`
+				args := append([]interface{}{to}, t.setArgs...)
+				args = append(args, inArgs(batch)...)
+				if _, err := tx.db.Exec(update, args...); err != nil {
+					return fmt.Errorf("failed to update order statuses: %w", err)
+				}
+			}
+		}
+
+		return tx.recordEvents(moves, t.reason, t.actor)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return moved, nil
+}
+
+// transitionOne is transition for a single order, failing with notFound when
+// the order is missing or not in a status the filter accepts
+func (r *AdvancedOrderRepository) transitionOne(t orderTransition, notFound string) error {
+	moved, err := r.transition(t)
+	if err != nil {
+		return err
+	}
+	if len(moved) == 0 {
+		return fmt.Errorf("%s", notFound)
+	}
+	return nil
+}
+
+// fill runs a fill statement against a locked order and logs the status
+// change it causes, if any. The statement decides the new status itself, so
+// it is read back and checked afterwards; a move the state machine forbids
+// rolls the fill back.
+func (r *AdvancedOrderRepository) fill(orderID int, reason string, apply func(tx *AdvancedOrderRepository) error) error {
+	return r.inTx(func(tx *AdvancedOrderRepository) error {
+		var before domain.OrderStatus
+		err := tx.db.QueryRow(`SELECT status FROM advanced_orders WHERE id = ? FOR UPDATE`, orderID).Scan(&before)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return fmt.Errorf("order not found")
+			}
+			return fmt.Errorf("failed to lock order: %w", err)
+		}
+
+		if err := apply(tx); err != nil {
+			return err
+		}
+
+		var after domain.OrderStatus
+		if err := tx.db.QueryRow(`SELECT status FROM advanced_orders WHERE id = ?`, orderID).Scan(&after); err != nil {
+			return fmt.Errorf("failed to read order status: %w", err)
+		}
+		if after == before {
+			return nil
+		}
+		if !domain.CanTransition(before, after) {
+			return fmt.Errorf("order %d cannot move from %s to %s", orderID, before, after)
+		}
+		return tx.recordEvent(orderID, &before, after, reason, domain.OrderActorSystem)
+	})
+}
+
+func (r *AdvancedOrderRepository) recordEvent(orderID int, from *domain.OrderStatus, to domain.OrderStatus, reason string, actor domain.OrderActor) error {
+	query := `
+		INSERT INTO order_events (order_id, from_status, to_status, reason, actor, created_at)
+		VALUES (?, ?, ?, ?, ?, NOW(6))
+	`
+	if _, err := r.db.Exec(query, orderID, from, to, reason, actor); err != nil {
+		return fmt.Errorf("failed to record order event: %w", err)
+	}
+	return nil
+}
+
+// recordEvents logs status changes that share a reason and actor, a batch of
+// rows per statement
+func (r *AdvancedOrderRepository) recordEvents(moves []orderMove, reason string, actor domain.OrderActor) error {
+	for start := 0; start < len(moves); start += batchSize {
+		batch := moves[start:min(start+batchSize, len(moves))]
+		args := make([]interface{}, 0, 5*len(batch))
+		for _, move := range batch {
+			args = append(args, move.id, move.from, move.to, reason, actor)
+		}
+		values := strings.TrimSuffix(strings.Repeat("(?, ?, ?, ?, ?, NOW(6)), ", len(batch)), ", ")
+		query := `INSERT INTO order_events (order_id, from_status, to_status, reason, actor, created_at) VALUES ` + values
+		if _, err := r.db.Exec(query, args...); err != nil {
+			return fmt.Errorf("failed to record order events: %w", err)
+		}
+	}
+	return nil
+}
+
+// GetEvents returns an order's status history, oldest first
+func (r *AdvancedOrderRepository) GetEvents(orderID int) ([]domain.OrderEvent, error) {
+	query := `
+		SELECT id, order_id, from_status, to_status, reason, actor, created_at
+		FROM order_events
+		WHERE order_id = ?
+		ORDER BY created_at ASC, id ASC
+	`
+
+	rows, err := r.db.Query(query, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get order events: %w", err)
+	}
+	defer rows.Close()
+
+	events := []domain.OrderEvent{}
+	for rows.Next() {
+		var event domain.OrderEvent
+		var from sql.NullString
+		err := rows.Scan(&event.ID, &event.OrderID, &from, &event.ToStatus, &event.Reason, &event.Actor, &event.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan order event: %w", err)
+		}
+		if from.Valid {
+			status := domain.OrderStatus(from.String)
+			event.FromStatus = &status
+		}
+		events = append(events, event)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate order events: %w", err)
+	}
+
+	return events, nil
+}
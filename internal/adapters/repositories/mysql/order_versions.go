@@ -0,0 +1,113 @@
+package mysql
+
+import (
+	"fmt"
+	"time"
+
+	"stock-simulation-backend/internal/core/domain"
+)
+
+// Amend replaces current, a working order, with its amended terms and moves
+// it to the next version. The terms of both versions go to order_versions;
+// current's row already exists unless this is the order's first amendment.
+// The update only matches the version that was read, so an amendment that
+// lost a race fails with domain.ErrOrderVersionConflict instead of
+// overwriting the winner.
+func (r *AdvancedOrderRepository) Amend(current, amended *domain.Order) error {
+	return r.inTx(func(tx *AdvancedOrderRepository) error {
+		query := `
+			UPDATE advanced_orders
+			SET quantity = ?, remaining_quantity = ?, visible_remaining = ?, priority_at = ?,
+			    price = ?, stop_price = ?, trailing_amount = ?, trailing_percent = ?, high_water_mark = ?,
+			    time_in_force = ?, expires_at = ?, market_price = ?, bid_price = ?, ask_price = ?, spread = ?,
+			    commission = ?, fees = ?, version = version + 1, updated_at = NOW()
+			WHERE id = ? AND version = ? AND status IN ` + activeStatuses + `
+		`
+		result, err := tx.db.Exec(query,
+			amended.Quantity, amended.RemainingQuantity, amended.VisibleRemaining, amended.PriorityAt,
+			amended.Price, amended.StopPrice, amended.TrailingAmount, amended.TrailingPercent, amended.HighWaterMark,
+			amended.TimeInForce, amended.ExpiresAt, amended.MarketPrice, amended.BidPrice, amended.AskPrice, amended.Spread,
+			amended.Commission, amended.Fees,
+			current.ID, current.Version,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to amend order: %w", err)
+		}
+		rows, err := result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("failed to get affected rows: %w", err)
+		}
+		if rows == 0 {
+			return domain.ErrOrderVersionConflict
+		}
+
+		amended.Version = current.Version + 1
+		amended.UpdatedAt = time.Now()
+		if err := tx.recordVersion(domain.NewOrderVersion(current, domain.OrderActorUser), true); err != nil {
+			return err
+		}
+		return tx.recordVersion(domain.NewOrderVersion(amended, domain.OrderActorUser), false)
+	})
+}
+
+// recordVersion stores a version's terms. With existing set, a version
+// already on file is left as it is.
+func (r *AdvancedOrderRepository) recordVersion(version domain.OrderVersion, existing bool) error {
+	insert := `INSERT INTO`
+	if existing {
+		insert = `INSERT IGNORE INTO`
+	}
+	query := insert + ` order_versions
+		(order_id, version, quantity, executed_quantity, remaining_quantity, price, stop_price,
+		 trailing_amount, trailing_percent, time_in_force, expires_at, priority_at, actor, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, NOW(6))
+	`
+	_, err := r.db.Exec(query,
+		version.OrderID, version.Version, version.Quantity, version.ExecutedQuantity, version.RemainingQuantity,
+		version.Price, version.StopPrice, version.TrailingAmount, version.TrailingPercent,
+		version.TimeInForce, version.ExpiresAt, version.PriorityAt, version.Actor,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record order version: %w", err)
+	}
+	return nil
+}
+
+// GetVersions returns the recorded versions of an order, oldest first. An
+// order that was never amended has none.
+func (r *AdvancedOrderRepository) GetVersions(orderID int) ([]domain.OrderVersion, error) {
+	query := `
+		SELECT order_id, version, quantity, executed_quantity, remaining_quantity, price, stop_price,
+		       trailing_amount, trailing_percent, time_in_force, expires_at, priority_at, actor, created_at
+		FROM order_versions
+		WHERE order_id = ?
+		ORDER BY version ASC
+	`
+
+	rows, err := r.db.Query(query, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get order versions: %w", err)
+	}
+	defer rows.Close()
+
+	versions := []domain.OrderVersion{}
+	for rows.Next() {
+		var version domain.OrderVersion
+		err := rows.Scan(
+			&version.OrderID, &version.Version, &version.Quantity, &version.ExecutedQuantity,
+			&version.RemainingQuantity, &version.Price, &version.StopPrice, &version.TrailingAmount,
+			&version.TrailingPercent, &version.TimeInForce, &version.ExpiresAt, &version.PriorityAt,
+			&version.Actor, &version.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan order version: %w", err)
+		}
+		versions = append(versions, version)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate order versions: %w", err)
+	}
+
+	return versions, nil
+}
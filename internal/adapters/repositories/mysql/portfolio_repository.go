@@ -8,7 +8,7 @@ import (
 )
 
 type portfolioRepository struct {
-	db *sql.DB
+	db dbExecutor
 }
 
 func NewPortfolioRepository(db *sql.DB) repositories.PortfolioRepository {
@@ -84,6 +84,30 @@ func (r *portfolioRepository) GetByUserIDAndSymbol(userID int, stockSymbol strin
 	return &portfolio, nil
 }
 
+// GetByUserIDAndSymbolForUpdate is GetByUserIDAndSymbol with a row lock held
+// until the surrounding transaction ends.
+func (r *portfolioRepository) GetByUserIDAndSymbolForUpdate(userID int, stockSymbol string) (*domain.Portfolio, error) {
+	query := `
+		SELECT id, user_id, stock_symbol, quantity, average_price, total_cost, updated_at
+		FROM portfolio 
+		WHERE user_id = ? AND stock_symbol = ?
+		FOR UPDATE
+	`
+	var portfolio domain.Portfolio
+	err := r.db.QueryRow(query, userID, stockSymbol).Scan(
+		&portfolio.ID, &portfolio.UserID, &portfolio.StockSymbol,
+		&portfolio.Quantity, &portfolio.AveragePrice, &portfolio.TotalCost,
+		&portfolio.UpdatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to lock portfolio: %w", err)
+	}
+	return &portfolio, nil
+}
+
 func (r *portfolioRepository) Update(portfolio *domain.Portfolio) error {
 	query := `
 		UPDATE portfolio 
@@ -127,6 +151,7 @@ func (r *portfolioRepository) GetPortfolioSummary(userID int) (*domain.Portfolio
 		SELECT 
 			p.stock_symbol,
 			s.name as stock_name,
+			'LONG' as side,
 			p.quantity,
 			p.average_price,
 			s.current_price,
@@ -150,7 +175,7 @@ func (r *portfolioRepository) GetPortfolioSummary(userID int) (*domain.Portfolio
 
 	for rows.Next() {
 		var item domain.PortfolioItem
-		err := rows.Scan(&item.StockSymbol, &item.StockName, &item.Quantity,
+		err := rows.Scan(&item.StockSymbol, &item.StockName, &item.Side, &item.Quantity,
 			&item.AveragePrice, &item.CurrentPrice, &item.TotalCost,
 			&item.CurrentValue, &item.ProfitLoss, &item.ProfitLossPct)
 		if err != nil {
@@ -0,0 +1,77 @@
+package mysql
+
+import (
+	"database/sql"
+	"fmt"
+
+	"stock-simulation-backend/internal/core/domain"
+	"stock-simulation-backend/internal/core/ports/repositories"
+)
+
+type riskLimitRepository struct {
+	db dbExecutor
+}
+
+func NewRiskLimitRepository(db *sql.DB) repositories.RiskLimitRepository {
+	return &riskLimitRepository{db: db}
+}
+
+func (r *riskLimitRepository) GetByUserID(userID int) (*domain.RiskLimitOverrides, error) {
+	query := `
+		SELECT user_id, max_order_notional, max_position_concentration, max_orders_per_day,
+		       daily_loss_limit, price_band_percent, updated_at
+		FROM user_risk_limits WHERE user_id = ?
+	`
+	var overrides domain.RiskLimitOverrides
+	var maxNotional, maxConcentration, dailyLoss, priceBand sql.NullFloat64
+	var maxOrders sql.NullInt64
+	err := r.db.QueryRow(query, userID).Scan(
+		&overrides.UserID, &maxNotional, &maxConcentration, &maxOrders,
+		&dailyLoss, &priceBand, &overrides.UpdatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get risk limits: %w", err)
+	}
+
+	overrides.MaxOrderNotional = nullFloat(maxNotional)
+	overrides.MaxPositionConcentration = nullFloat(maxConcentration)
+	overrides.DailyLossLimit = nullFloat(dailyLoss)
+	overrides.PriceBandPercent = nullFloat(priceBand)
+	if maxOrders.Valid {
+		orders := int(maxOrders.Int64)
+		overrides.MaxOrdersPerDay = &orders
+	}
+	return &overrides, nil
+}
+
+func (r *riskLimitRepository) Upsert(overrides *domain.RiskLimitOverrides) error {
+	query := `
+		INSERT INTO user_risk_limits (user_id, max_order_notional, max_position_concentration,
+		                              max_orders_per_day, daily_loss_limit, price_band_percent, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, NOW())
+		ON DUPLICATE KEY UPDATE
+			max_order_notional = VALUES(max_order_notional),
+			max_position_concentration = VALUES(max_position_concentration),
+			max_orders_per_day = VALUES(max_orders_per_day),
+			daily_loss_limit = VALUES(daily_loss_limit),
+			price_band_percent = VALUES(price_band_percent),
+			updated_at = NOW()
+	`
+	_, err := r.db.Exec(query, overrides.UserID, overrides.MaxOrderNotional,
+		overrides.MaxPositionConcentration, overrides.MaxOrdersPerDay,
+		overrides.DailyLossLimit, overrides.PriceBandPercent)
+	if err != nil {
+		return fmt.Errorf("failed to save risk limits: %w", err)
+	}
+	return nil
+}
+
+func nullFloat(value sql.NullFloat64) *float64 {
+	if !value.Valid {
+		return nil
+	}
+	return &value.Float64
+}
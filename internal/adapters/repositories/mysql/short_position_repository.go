@@ -0,0 +1,159 @@
+package mysql
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"stock-simulation-backend/internal/core/domain"
+	"stock-simulation-backend/internal/core/ports/repositories"
+)
+
+type shortPositionRepository struct {
+	db dbExecutor
+}
+
+func NewShortPositionRepository(db *sql.DB) repositories.ShortPositionRepository {
+	return &shortPositionRepository{db: db}
+}
+
+const shortPositionColumns = `id, user_id, stock_symbol, quantity, average_price, proceeds, collateral,
+		       accrued_borrow_fees, last_accrual_at, created_at, updated_at`
+
+func scanShortPosition(row rowScanner) (*domain.ShortPosition, error) {
+	var position domain.ShortPosition
+	err := row.Scan(
+		&position.ID, &position.UserID, &position.StockSymbol, &position.Quantity,
+		&position.AveragePrice, &position.Proceeds, &position.Collateral,
+		&position.AccruedBorrowFees, &position.LastAccrualAt, &position.CreatedAt, &position.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &position, nil
+}
+
+func (r *shortPositionRepository) Create(position *domain.ShortPosition) error {
+	query := `
+		INSERT INTO short_positions (user_id, stock_symbol, quantity, average_price, proceeds,
+		                             collateral, accrued_borrow_fees, last_accrual_at, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, NOW(), NOW(), NOW())
+	`
+	result, err := r.db.Exec(query, position.UserID, position.StockSymbol, position.Quantity,
+		position.AveragePrice, position.Proceeds, position.Collateral, position.AccruedBorrowFees)
+	if err != nil {
+		return fmt.Errorf("failed to create short position: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get short position ID: %w", err)
+	}
+
+	position.ID = int(id)
+	return nil
+}
+
+func (r *shortPositionRepository) GetByUserID(userID int) ([]domain.ShortPosition, error) {
+	query := `SELECT ` + shortPositionColumns + ` FROM short_positions WHERE user_id = ? ORDER BY stock_symbol`
+	return r.queryShortPositions(query, userID)
+}
+
+func (r *shortPositionRepository) GetByUserIDAndSymbol(userID int, stockSymbol string) (*domain.ShortPosition, error) {
+	query := `SELECT ` + shortPositionColumns + ` FROM short_positions WHERE user_id = ? AND stock_symbol = ?`
+	position, err := scanShortPosition(r.db.QueryRow(query, userID, stockSymbol))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get short position: %w", err)
+	}
+	return position, nil
+}
+
+// GetByUserIDAndSymbolForUpdate is GetByUserIDAndSymbol with a row lock held
+// until the surrounding transaction ends.
+func (r *shortPositionRepository) GetByUserIDAndSymbolForUpdate(userID int, stockSymbol string) (*domain.ShortPosition, error) {
+	query := `SELECT ` + shortPositionColumns + ` FROM short_positions WHERE user_id = ? AND stock_symbol = ? FOR UPDATE`
+	position, err := scanShortPosition(r.db.QueryRow(query, userID, stockSymbol))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to lock short position: %w", err)
+	}
+	return position, nil
+}
+
+func (r *shortPositionRepository) GetAll() ([]domain.ShortPosition, error) {
+	query := `SELECT ` + shortPositionColumns + ` FROM short_positions ORDER BY user_id, stock_symbol`
+	return r.queryShortPositions(query)
+}
+
+func (r *shortPositionRepository) Update(position *domain.ShortPosition) error {
+	query := `
+		UPDATE short_positions
+		SET quantity = ?, average_price = ?, proceeds = ?, collateral = ?, accrued_borrow_fees = ?, updated_at = NOW()
+		WHERE id = ?
+	`
+	_, err := r.db.Exec(query, position.Quantity, position.AveragePrice, position.Proceeds,
+		position.Collateral, position.AccruedBorrowFees, position.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update short position: %w", err)
+	}
+	return nil
+}
+
+func (r *shortPositionRepository) Delete(userID int, stockSymbol string) error {
+	query := `DELETE FROM short_positions WHERE user_id = ? AND stock_symbol = ?`
+	_, err := r.db.Exec(query, userID, stockSymbol)
+	if err != nil {
+		return fmt.Errorf("failed to delete short position: %w", err)
+	}
+	return nil
+}
+
+func (r *shortPositionRepository) AccrueBorrowFees(asOf time.Time) (int, error) {
+	// Assignments run left to right, so the fee is charged on the days counted
+	// from the old last_accrual_at before it moves forward
+	query := `
+		UPDATE short_positions
+		SET accrued_borrow_fees = accrued_borrow_fees + ROUND(
+		        quantity * (SELECT current_price FROM stocks WHERE symbol = short_positions.stock_symbol)
+		        * ? / 360 * FLOOR(TIMESTAMPDIFF(HOUR, last_accrual_at, ?) / 24), 2),
+		    last_accrual_at = DATE_ADD(last_accrual_at, INTERVAL FLOOR(TIMESTAMPDIFF(HOUR, last_accrual_at, ?) / 24) DAY),
+		    updated_at = NOW()
+		WHERE last_accrual_at <= DATE_SUB(?, INTERVAL 1 DAY)
+	`
+	result, err := r.db.Exec(query, domain.ShortBorrowRate, asOf, asOf, asOf)
+	if err != nil {
+		return 0, fmt.Errorf("failed to accrue borrow fees: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	return int(rowsAffected), nil
+}
+
+func (r *shortPositionRepository) queryShortPositions(query string, args ...interface{}) ([]domain.ShortPosition, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get short positions: %w", err)
+	}
+	defer rows.Close()
+
+	positions := []domain.ShortPosition{}
+	for rows.Next() {
+		position, err := scanShortPosition(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan short position: %w", err)
+		}
+		positions = append(positions, *position)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read short positions: %w", err)
+	}
+	return positions, nil
+}
@@ -5,23 +5,30 @@ import (
 	"fmt"
 	"stock-simulation-backend/internal/core/domain"
 	"stock-simulation-backend/internal/core/ports/repositories"
+	"time"
 )
 
 type transactionRepository struct {
-	db *sql.DB
+	db dbExecutor
 }
 
 func NewTransactionRepository(db *sql.DB) repositories.TransactionRepository {
 	return &transactionRepository{db: db}
 }
 
+const transactionColumns = `id, user_id, stock_symbol, transaction_type, quantity, price, bid_price, ask_price, total_amount,
+		       commission, fees, order_id, realized_pnl, created_at`
+
 func (r *transactionRepository) Create(transaction *domain.Transaction) error {
 	query := `
-		INSERT INTO transactions (user_id, stock_symbol, transaction_type, quantity, price, total_amount, created_at)
-		VALUES (?, ?, ?, ?, ?, ?, NOW())
+		INSERT INTO transactions (user_id, stock_symbol, transaction_type, quantity, price, bid_price, ask_price,
+		                          total_amount, commission, fees, order_id, realized_pnl, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, NOW())
 	`
 	result, err := r.db.Exec(query, transaction.UserID, transaction.StockSymbol,
-		transaction.Type, transaction.Quantity, transaction.Price, transaction.TotalAmount)
+		transaction.Type, transaction.Quantity, transaction.Price, transaction.BidPrice, transaction.AskPrice,
+		transaction.TotalAmount,
+		transaction.Commission, transaction.Fees, transaction.OrderID, transaction.RealizedPnL)
 	if err != nil {
 		return fmt.Errorf("failed to create transaction: %w", err)
 	}
@@ -37,14 +44,15 @@ func (r *transactionRepository) Create(transaction *domain.Transaction) error {
 
 func (r *transactionRepository) GetByID(id int) (*domain.Transaction, error) {
 	query := `
-		SELECT id, user_id, stock_symbol, transaction_type, quantity, price, total_amount, created_at
+		SELECT ` + transactionColumns + `
 		FROM transactions WHERE id = ?
 	`
 	var transaction domain.Transaction
 	err := r.db.QueryRow(query, id).Scan(
 		&transaction.ID, &transaction.UserID, &transaction.StockSymbol,
 		&transaction.Type, &transaction.Quantity, &transaction.Price,
-		&transaction.TotalAmount, &transaction.CreatedAt,
+		&transaction.BidPrice, &transaction.AskPrice, &transaction.TotalAmount, &transaction.Commission, &transaction.Fees,
+		&transaction.OrderID, &transaction.RealizedPnL, &transaction.CreatedAt,
 	)
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -57,7 +65,7 @@ func (r *transactionRepository) GetByID(id int) (*domain.Transaction, error) {
 
 func (r *transactionRepository) GetByUserID(userID int, limit, offset int) ([]domain.Transaction, error) {
 	query := `
-		SELECT id, user_id, stock_symbol, transaction_type, quantity, price, total_amount, created_at
+		SELECT ` + transactionColumns + `
 		FROM transactions 
 		WHERE user_id = ?
 		ORDER BY created_at DESC
@@ -74,7 +82,8 @@ func (r *transactionRepository) GetByUserID(userID int, limit, offset int) ([]do
 		var transaction domain.Transaction
 		err := rows.Scan(&transaction.ID, &transaction.UserID, &transaction.StockSymbol,
 			&transaction.Type, &transaction.Quantity, &transaction.Price,
-			&transaction.TotalAmount, &transaction.CreatedAt)
+			&transaction.BidPrice, &transaction.AskPrice, &transaction.TotalAmount, &transaction.Commission, &transaction.Fees,
+			&transaction.OrderID, &transaction.RealizedPnL, &transaction.CreatedAt)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan transaction: %w", err)
 		}
@@ -86,7 +95,7 @@ func (r *transactionRepository) GetByUserID(userID int, limit, offset int) ([]do
 
 func (r *transactionRepository) GetByUserIDAndSymbol(userID int, stockSymbol string, limit int) ([]domain.Transaction, error) {
 	query := `
-		SELECT id, user_id, stock_symbol, transaction_type, quantity, price, total_amount, created_at
+		SELECT ` + transactionColumns + `
 		FROM transactions 
 		WHERE user_id = ? AND stock_symbol = ?
 		ORDER BY created_at DESC
@@ -103,7 +112,8 @@ func (r *transactionRepository) GetByUserIDAndSymbol(userID int, stockSymbol str
 		var transaction domain.Transaction
 		err := rows.Scan(&transaction.ID, &transaction.UserID, &transaction.StockSymbol,
 			&transaction.Type, &transaction.Quantity, &transaction.Price,
-			&transaction.TotalAmount, &transaction.CreatedAt)
+			&transaction.BidPrice, &transaction.AskPrice, &transaction.TotalAmount, &transaction.Commission, &transaction.Fees,
+			&transaction.OrderID, &transaction.RealizedPnL, &transaction.CreatedAt)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan transaction: %w", err)
 		}
@@ -115,7 +125,7 @@ func (r *transactionRepository) GetByUserIDAndSymbol(userID int, stockSymbol str
 
 func (r *transactionRepository) GetByUserIDAndType(userID int, transactionType string, limit int) ([]domain.Transaction, error) {
 	query := `
-		SELECT id, user_id, stock_symbol, transaction_type, quantity, price, total_amount, created_at
+		SELECT ` + transactionColumns + `
 		FROM transactions 
 		WHERE user_id = ? AND transaction_type = ?
 		ORDER BY created_at DESC
@@ -132,7 +142,8 @@ func (r *transactionRepository) GetByUserIDAndType(userID int, transactionType s
 		var transaction domain.Transaction
 		err := rows.Scan(&transaction.ID, &transaction.UserID, &transaction.StockSymbol,
 			&transaction.Type, &transaction.Quantity, &transaction.Price,
-			&transaction.TotalAmount, &transaction.CreatedAt)
+			&transaction.BidPrice, &transaction.AskPrice, &transaction.TotalAmount, &transaction.Commission, &transaction.Fees,
+			&transaction.OrderID, &transaction.RealizedPnL, &transaction.CreatedAt)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan transaction: %w", err)
 		}
@@ -144,7 +155,7 @@ func (r *transactionRepository) GetByUserIDAndType(userID int, transactionType s
 
 func (r *transactionRepository) GetUserTransactionHistory(userID int, stockSymbol, transactionType string, limit int) ([]domain.Transaction, error) {
 	query := `
-		SELECT id, user_id, stock_symbol, transaction_type, quantity, price, total_amount, created_at
+		SELECT ` + transactionColumns + `
 		FROM transactions 
 		WHERE user_id = ?
 	`
@@ -174,7 +185,8 @@ func (r *transactionRepository) GetUserTransactionHistory(userID int, stockSymbo
 		var transaction domain.Transaction
 		err := rows.Scan(&transaction.ID, &transaction.UserID, &transaction.StockSymbol,
 			&transaction.Type, &transaction.Quantity, &transaction.Price,
-			&transaction.TotalAmount, &transaction.CreatedAt)
+			&transaction.BidPrice, &transaction.AskPrice, &transaction.TotalAmount, &transaction.Commission, &transaction.Fees,
+			&transaction.OrderID, &transaction.RealizedPnL, &transaction.CreatedAt)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan transaction: %w", err)
 		}
@@ -192,4 +204,16 @@ func (r *transactionRepository) GetTotalTransactionsByUser(userID int) (int, err
 		return 0, fmt.Errorf("failed to get total transactions: %w", err)
 	}
 	return count, nil
-}
\ No newline at end of file
+}
+
+// GetRealizedPnLSince sums the P&L realized by the user's sells and covers
+// since the given time; a loss is negative
+func (r *transactionRepository) GetRealizedPnLSince(userID int, since time.Time) (float64, error) {
+	query := `SELECT COALESCE(SUM(realized_pnl), 0) FROM transactions WHERE user_id = ? AND created_at >= ?`
+	var pnl float64
+	err := r.db.QueryRow(query, userID, since).Scan(&pnl)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get realized P&L: %w", err)
+	}
+	return pnl, nil
+}
@@ -0,0 +1,83 @@
+package mysql
+
+import (
+	"database/sql"
+	"fmt"
+
+	"stock-simulation-backend/internal/core/ports/repositories"
+)
+
+// dbExecutor is the part of *sql.DB and *sql.Tx the repositories use, so the
+// same repository code runs either standalone or inside a unit of work.
+type dbExecutor interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+type unitOfWork struct {
+	db *sql.DB
+}
+
+func NewUnitOfWork(db *sql.DB) repositories.UnitOfWork {
+	return &unitOfWork{db: db}
+}
+
+func (u *unitOfWork) Do(fn func(tx repositories.TxRepositories) error) error {
+	tx, err := u.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p)
+		}
+	}()
+
+	if err := fn(&txRepositories{tx: tx}); err != nil {
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			return fmt.Errorf("%w (rollback failed: %v)", err, rollbackErr)
+		}
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+type txRepositories struct {
+	tx *sql.Tx
+}
+
+func (t *txRepositories) Users() repositories.UserRepository {
+	return &userRepository{db: t.tx}
+}
+
+func (t *txRepositories) Portfolios() repositories.PortfolioRepository {
+	return &portfolioRepository{db: t.tx}
+}
+
+func (t *txRepositories) Transactions() repositories.TransactionRepository {
+	return &transactionRepository{db: t.tx}
+}
+
+func (t *txRepositories) Orders() repositories.AdvancedOrderRepositoryWithSearch {
+	return &AdvancedOrderRepository{db: t.tx}
+}
+
+func (t *txRepositories) ShortPositions() repositories.ShortPositionRepository {
+	return &shortPositionRepository{db: t.tx}
+}
+
+func (t *txRepositories) MarginAccounts() repositories.MarginAccountRepository {
+	return &marginAccountRepository{db: t.tx}
+}
+
+func (t *txRepositories) AlgoOrders() repositories.AlgoOrderRepository {
+	return &algoOrderRepository{db: t.tx}
+}
@@ -8,7 +8,7 @@ import (
 )
 
 type userRepository struct {
-	db *sql.DB
+	db dbExecutor
 }
 
 func NewUserRepository(db *sql.DB) repositories.UserRepository {
@@ -53,6 +53,28 @@ func (r *userRepository) GetByID(id int) (*domain.User, error) {
 	return &user, nil
 }
 
+// GetByIDForUpdate reads the user with a row lock held until the surrounding
+// transaction ends, so concurrent trades cannot spend the same balance.
+func (r *userRepository) GetByIDForUpdate(id int) (*domain.User, error) {
+	query := `
+		SELECT id, username, email, password_hash, balance, total_profit, created_at, updated_at
+		FROM users WHERE id = ?
+		FOR UPDATE
+	`
+	var user domain.User
+	err := r.db.QueryRow(query, id).Scan(
+		&user.ID, &user.Username, &user.Email, &user.PasswordHash,
+		&user.Balance, &user.TotalProfit, &user.CreatedAt, &user.UpdatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("user not found")
+		}
+		return nil, fmt.Errorf("failed to lock user: %w", err)
+	}
+	return &user, nil
+}
+
 func (r *userRepository) GetByEmail(email string) (*domain.User, error) {
 	query := `
 		SELECT id, username, email, password_hash, balance, total_profit, created_at, updated_at
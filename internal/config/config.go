@@ -17,6 +17,7 @@ type Config struct {
 	JWT      JWTConfig
 	CORS     CORSConfig
 	Redis    RedisConfig
+	Risk     RiskConfig
 }
 
 type DatabaseConfig struct {
@@ -51,6 +52,15 @@ type RedisConfig struct {
 	Client *redis.Client
 }
 
+// RiskConfig holds the global pre-trade risk limits; zero disables a limit
+type RiskConfig struct {
+	MaxOrderNotional         float64
+	MaxPositionConcentration float64
+	MaxOrdersPerDay          int
+	DailyLossLimit           float64
+	PriceBandPercent         float64
+}
+
 func LoadConfig() *Config {
 	// Load .env file if it exists (for local development)
 	_ = godotenv.Load()
@@ -125,6 +135,13 @@ func LoadConfig() *Config {
 			Port:   getEnv("REDIS_PORT", "6379"),
 			Client: redisClient,
 		},
+		Risk: RiskConfig{
+			MaxOrderNotional:         getEnvFloat("RISK_MAX_ORDER_NOTIONAL", 250000),
+			MaxPositionConcentration: getEnvFloat("RISK_MAX_POSITION_CONCENTRATION", 1.0),
+			MaxOrdersPerDay:          int(getEnvFloat("RISK_MAX_ORDERS_PER_DAY", 500)),
+			DailyLossLimit:           getEnvFloat("RISK_DAILY_LOSS_LIMIT", 50000),
+			PriceBandPercent:         getEnvFloat("RISK_PRICE_BAND_PERCENT", 20),
+		},
 	}
 }
 
@@ -135,6 +152,16 @@ func getEnv(key, defaultValue string) string {
 	return defaultValue
 }
 
+// getEnvFloat reads a numeric setting, keeping the default when it is unset
+// or malformed
+func getEnvFloat(key string, defaultValue float64) float64 {
+	value, err := strconv.ParseFloat(getEnv(key, ""), 64)
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
 // GetDSN returns the database connection string
 func (c *Config) GetDSN() string {
 	// For Railway production, try internal connection first
@@ -0,0 +1,158 @@
+package domain
+
+import (
+    "math"
+    "time"
+)
+
+// MaxAlgoDuration bounds how long a TWAP or VWAP parent may be scheduled over
+const MaxAlgoDuration = 24 * time.Hour
+
+// AlgoParams is the schedule of a TWAP or VWAP parent order. The parent's
+// quantity is released as child orders of SliceType between StartAt and
+// EndAt; a LIMIT slice uses the parent's Price.
+type AlgoParams struct {
+    OrderID       int        `json:"order_id" db:"order_id"`
+    SliceType     OrderType  `json:"slice_type" db:"slice_type"`
+    StartAt       time.Time  `json:"start_at" db:"start_at"`
+    EndAt         time.Time  `json:"end_at" db:"end_at"`
+    ArrivalPrice  float64    `json:"arrival_price" db:"arrival_price"`
+    VolumeProfile []float64  `json:"volume_profile,omitempty" db:"volume_profile"` // VWAP weight per hour of day
+    PausedAt      *time.Time `json:"paused_at,omitempty" db:"paused_at"`
+    CreatedAt     time.Time  `json:"created_at" db:"created_at"`
+    UpdatedAt     time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// NewVolumeProfile turns traded volume by hour of day into 24 weights. Hours
+// with no history get no weight; an empty history gives a nil profile, which
+// schedules like TWAP.
+func NewVolumeProfile(volumeByHour map[int]int64) []float64 {
+    total := int64(0)
+    for _, volume := range volumeByHour {
+        total += volume
+    }
+    if total <= 0 {
+        return nil
+    }
+
+    profile := make([]float64, 24)
+    for hour, volume := range volumeByHour {
+        if hour >= 0 && hour < 24 && volume > 0 {
+            profile[hour] = float64(volume) / float64(total)
+        }
+    }
+    return profile
+}
+
+// TargetQuantity is how many of total shares the schedule calls for by now.
+// TWAP releases them evenly; VWAP in proportion to the volume expected in
+// each hour. A paused schedule stands still at the moment it was paused.
+func (p *AlgoParams) TargetQuantity(strategy OrderType, total int, now time.Time) int {
+    if p.PausedAt != nil {
+        now = *p.PausedAt
+    }
+    if !now.Before(p.EndAt) {
+        return total
+    }
+    if !now.After(p.StartAt) {
+        return 0
+    }
+
+    fraction := float64(now.Sub(p.StartAt)) / float64(p.EndAt.Sub(p.StartAt))
+    if strategy == OrderTypeVWAP {
+        if volumeFraction, ok := p.volumeFraction(now); ok {
+            fraction = volumeFraction
+        }
+    }
+    return int(math.Floor(fraction * float64(total)))
+}
+
+// volumeFraction is the share of the window's expected volume that trades
+// before now, integrated minute by minute over the hourly profile
+func (p *AlgoParams) volumeFraction(now time.Time) (float64, bool) {
+    if len(p.VolumeProfile) != 24 {
+        return 0, false
+    }
+
+    done, total := 0.0, 0.0
+    for t := p.StartAt; t.Before(p.EndAt); t = t.Add(time.Minute) {
+        step := time.Minute
+        if remaining := p.EndAt.Sub(t); remaining < step {
+            step = remaining
+        }
+        weight := p.VolumeProfile[t.Hour()] * step.Minutes()
+        total += weight
+
+        if elapsed := now.Sub(t); elapsed >= step {
+            done += weight
+        } else if elapsed > 0 {
+            done += weight * float64(elapsed) / float64(step)
+        }
+    }
+
+    if total == 0 {
+        return 0, false
+    }
+    return done / total, true
+}
+
+// Pause stops the schedule's clock
+func (p *AlgoParams) Pause(at time.Time) {
+    if p.PausedAt == nil {
+        p.PausedAt = &at
+    }
+}
+
+// Resume restarts the clock, pushing the rest of the schedule back by the
+// time spent paused so the skipped slices are not all sent at once
+func (p *AlgoParams) Resume(at time.Time) {
+    if p.PausedAt == nil {
+        return
+    }
+    paused := at.Sub(*p.PausedAt)
+    p.StartAt = p.StartAt.Add(paused)
+    p.EndAt = p.EndAt.Add(paused)
+    p.PausedAt = nil
+}
+
+// AlgoOrder is a TWAP or VWAP parent with its slices and progress. Arrival
+// slippage is in basis points against the price when the parent was placed,
+// positive when the fills were worse.
+type AlgoOrder struct {
+    Order             Order      `json:"order"`
+    Params            AlgoParams `json:"params"`
+    Children          []Order    `json:"children"`
+    PercentComplete   float64    `json:"percent_complete"`
+    ScheduledQuantity int        `json:"scheduled_quantity"`
+    WorkingQuantity   int        `json:"working_quantity"`
+    AveragePrice      *float64   `json:"average_price,omitempty"`
+    ArrivalSlippage   float64    `json:"arrival_slippage"`
+}
+
+// NewAlgoOrder derives an algo parent's progress from its fills and slices
+func NewAlgoOrder(parent Order, params AlgoParams, children []Order, now time.Time) *AlgoOrder {
+    algo := &AlgoOrder{
+        Order:             parent,
+        Params:            params,
+        Children:          children,
+        ScheduledQuantity: params.TargetQuantity(parent.OrderType, parent.Quantity, now),
+        AveragePrice:      parent.ExecutedPrice,
+    }
+    if parent.Quantity > 0 {
+        algo.PercentComplete = math.Round(float64(parent.ExecutedQuantity)/float64(parent.Quantity)*10000) / 100
+    }
+    for _, child := range children {
+        if child.IsActive() || child.Status == OrderStatusPaused {
+            algo.WorkingQuantity += child.RemainingQuantity
+        }
+    }
+
+    if parent.ExecutedPrice != nil && parent.ExecutedQuantity > 0 && params.ArrivalPrice > 0 {
+        slippage := (*parent.ExecutedPrice - params.ArrivalPrice) / params.ArrivalPrice * 10000
+        if !parent.IsBuySide() {
+            slippage = -slippage
+        }
+        algo.ArrivalSlippage = math.Round(slippage*100) / 100
+    }
+    return algo
+}
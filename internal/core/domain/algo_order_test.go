@@ -0,0 +1,46 @@
+package domain
+
+import (
+    "testing"
+    "time"
+)
+
+func TestAlgoParamsTargetQuantity(t *testing.T) {
+    start := time.Date(2024, 3, 4, 10, 0, 0, 0, time.UTC)
+    at := func(minutes int) time.Time { return start.Add(time.Duration(minutes) * time.Minute) }
+
+    // All of the expected volume trades between 10:00 and 11:00
+    profile := make([]float64, 24)
+    profile[10] = 1
+
+    tests := []struct {
+        name     string
+        strategy OrderType
+        params   AlgoParams
+        now      time.Time
+        want     int
+    }{
+        {"TWAP before the start", OrderTypeTWAP, AlgoParams{StartAt: start, EndAt: at(60)}, at(-5), 0},
+        {"TWAP at the start", OrderTypeTWAP, AlgoParams{StartAt: start, EndAt: at(60)}, start, 0},
+        {"TWAP halfway", OrderTypeTWAP, AlgoParams{StartAt: start, EndAt: at(60)}, at(30), 50},
+        {"TWAP rounds down", OrderTypeTWAP, AlgoParams{StartAt: start, EndAt: at(60)}, at(1), 1},
+        {"TWAP at the end", OrderTypeTWAP, AlgoParams{StartAt: start, EndAt: at(60)}, at(60), 100},
+        {"TWAP after the end", OrderTypeTWAP, AlgoParams{StartAt: start, EndAt: at(60)}, at(90), 100},
+        {"paused stands still", OrderTypeTWAP, AlgoParams{StartAt: start, EndAt: at(60), PausedAt: timePtr(at(15))}, at(45), 25},
+        {"VWAP follows the volume profile", OrderTypeVWAP, AlgoParams{StartAt: start, EndAt: at(120), VolumeProfile: profile}, at(60), 100},
+        {"VWAP within an hour", OrderTypeVWAP, AlgoParams{StartAt: start, EndAt: at(120), VolumeProfile: profile}, at(30), 50},
+        {"VWAP without a profile schedules like TWAP", OrderTypeVWAP, AlgoParams{StartAt: start, EndAt: at(120)}, at(60), 50},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            if got := tt.params.TargetQuantity(tt.strategy, 100, tt.now); got != tt.want {
+                t.Errorf("TargetQuantity() = %d, want %d", got, tt.want)
+            }
+        })
+    }
+}
+
+func timePtr(t time.Time) *time.Time {
+    return &t
+}
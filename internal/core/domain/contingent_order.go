@@ -0,0 +1,199 @@
+package domain
+
+import (
+    "fmt"
+    "strings"
+    "time"
+)
+
+// MaxContingentConditions bounds how many conditions one contingent order may watch
+const MaxContingentConditions = 5
+
+type ConditionType string
+
+const (
+    ConditionTypePrice         ConditionType = "PRICE"
+    ConditionTypePercentChange ConditionType = "PERCENT_CHANGE" // Change from the previous close, in percent
+    ConditionTypeIndicator     ConditionType = "INDICATOR"
+)
+
+type ConditionOperator string
+
+const (
+    ConditionAbove        ConditionOperator = "ABOVE"
+    ConditionBelow        ConditionOperator = "BELOW"
+    ConditionCrossesAbove ConditionOperator = "CROSSES_ABOVE"
+    ConditionCrossesBelow ConditionOperator = "CROSSES_BELOW"
+)
+
+// IndicatorType names a series of ChartIndicators
+type IndicatorType string
+
+const (
+    IndicatorMA20 IndicatorType = "MA20"
+    IndicatorMA50 IndicatorType = "MA50"
+    IndicatorRSI  IndicatorType = "RSI"
+)
+
+type ConditionLogic string
+
+const (
+    ConditionLogicAll ConditionLogic = "ALL"
+    ConditionLogicAny ConditionLogic = "ANY"
+)
+
+type ContingentOrderStatus string
+
+const (
+    ContingentStatusActive    ContingentOrderStatus = "ACTIVE"
+    ContingentStatusTriggered ContingentOrderStatus = "TRIGGERED"
+    ContingentStatusCancelled ContingentOrderStatus = "CANCELLED"
+    ContingentStatusFailed    ContingentOrderStatus = "FAILED" // Triggered, but the order was rejected
+)
+
+// TriggerCondition watches one value of a symbol, which need not be the
+// symbol the order trades. A crossing needs two observations: the value seen
+// on the previous tick and the current one.
+type TriggerCondition struct {
+    Symbol        string            `json:"symbol" binding:"required"`
+    Type          ConditionType     `json:"type" binding:"required"`
+    Indicator     IndicatorType     `json:"indicator,omitempty"` // For INDICATOR conditions
+    Operator      ConditionOperator `json:"operator" binding:"required"`
+    Value         float64           `json:"value"`
+    ObservedValue *float64          `json:"observed_value,omitempty"` // Value seen when the order fired
+}
+
+// ContingentOrder is a dormant order request that is submitted once its
+// conditions are met
+type ContingentOrder struct {
+    ID            int                   `json:"id" db:"id"`
+    UserID        int                   `json:"user_id" db:"user_id"`
+    Conditions    []TriggerCondition    `json:"conditions" db:"conditions"`
+    Logic         ConditionLogic        `json:"logic" db:"logic"`
+    Order         OrderRequest          `json:"order" db:"order_request"`
+    Status        ContingentOrderStatus `json:"status" db:"status"`
+    OrderID       *int                  `json:"order_id,omitempty" db:"order_id"` // Order placed when triggered
+    FailureReason *string               `json:"failure_reason,omitempty" db:"failure_reason"`
+    TriggeredAt   *time.Time            `json:"triggered_at,omitempty" db:"triggered_at"`
+    CreatedAt     time.Time             `json:"created_at" db:"created_at"`
+    UpdatedAt     time.Time             `json:"updated_at" db:"updated_at"`
+}
+
+type ContingentOrderRequest struct {
+    Conditions []TriggerCondition `json:"conditions" binding:"required,min=1,dive"`
+    Logic      ConditionLogic     `json:"logic"` // ALL (default) or ANY
+    Order      OrderRequest       `json:"order" binding:"required"`
+}
+
+// Key identifies the value a condition watches, so conditions on
+// the same value share one observation per tick
+func (c *TriggerCondition) Key() string {
+    if c.Type == ConditionTypeIndicator {
+        return c.Symbol + ":" + string(c.Type) + ":" + string(c.Indicator)
+    }
+    return c.Symbol + ":" + string(c.Type)
+}
+
+// Validate checks the condition and normalises its symbol
+func (c *TriggerCondition) Validate() error {
+    c.Symbol = strings.ToUpper(strings.TrimSpace(c.Symbol))
+    if c.Symbol == "" {
+        return fmt.Errorf("condition symbol is required")
+    }
+
+    switch c.Type {
+    case ConditionTypePrice:
+        if c.Value <= 0 {
+            return fmt.Errorf("price conditions require a positive value")
+        }
+    case ConditionTypePercentChange:
+    case ConditionTypeIndicator:
+        switch c.Indicator {
+        case IndicatorMA20, IndicatorMA50:
+            if c.Value <= 0 {
+                return fmt.Errorf("moving average conditions require a positive value")
+            }
+        case IndicatorRSI:
+            if c.Value <= 0 || c.Value >= 100 {
+                return fmt.Errorf("RSI conditions require a value between 0 and 100")
+            }
+        default:
+            return fmt.Errorf("invalid indicator: %s", c.Indicator)
+        }
+    default:
+        return fmt.Errorf("invalid condition type: %s", c.Type)
+    }
+    if c.Type != ConditionTypeIndicator && c.Indicator != "" {
+        return fmt.Errorf("indicator is only supported on indicator conditions")
+    }
+
+    switch c.Operator {
+    case ConditionAbove, ConditionBelow, ConditionCrossesAbove, ConditionCrossesBelow:
+    default:
+        return fmt.Errorf("invalid condition operator: %s", c.Operator)
+    }
+    return nil
+}
+
+// Met reports whether current satisfies the condition. Crossings also need
+// the previous observation; without one they are not met.
+func (c *TriggerCondition) Met(current float64, previous *float64) bool {
+    switch c.Operator {
+    case ConditionAbove:
+        return current > c.Value
+    case ConditionBelow:
+        return current < c.Value
+    case ConditionCrossesAbove:
+        return previous != nil && *previous <= c.Value && current > c.Value
+    case ConditionCrossesBelow:
+        return previous != nil && *previous >= c.Value && current < c.Value
+    }
+    return false
+}
+
+// IndicatorValue is the latest point of the named series, or false while
+// there is not enough history to compute it
+func (ci ChartIndicators) IndicatorValue(indicator IndicatorType) (float64, bool) {
+    var series []float64
+    switch indicator {
+    case IndicatorMA20:
+        series = ci.MA20
+    case IndicatorMA50:
+        series = ci.MA50
+    case IndicatorRSI:
+        series = ci.RSI
+    }
+    if len(series) == 0 || series[len(series)-1] == 0 {
+        return 0, false
+    }
+    return series[len(series)-1], true
+}
+
+// Validate checks the conditions and fills in the default logic
+func (r *ContingentOrderRequest) Validate() error {
+    if len(r.Conditions) == 0 {
+        return fmt.Errorf("at least one condition is required")
+    }
+    if len(r.Conditions) > MaxContingentConditions {
+        return fmt.Errorf("at most %d conditions are allowed", MaxContingentConditions)
+    }
+    for i := range r.Conditions {
+        r.Conditions[i].ObservedValue = nil
+        if err := r.Conditions[i].Validate(); err != nil {
+            return fmt.Errorf("condition %d: %w", i+1, err)
+        }
+    }
+
+    switch r.Logic {
+    case "":
+        r.Logic = ConditionLogicAll
+    case ConditionLogicAll, ConditionLogicAny:
+    default:
+        return fmt.Errorf("invalid condition logic: %s", r.Logic)
+    }
+    return nil
+}
+
+func (c *ContingentOrder) IsActive() bool {
+    return c.Status == ContingentStatusActive
+}
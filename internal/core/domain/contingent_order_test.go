@@ -0,0 +1,37 @@
+package domain
+
+import "testing"
+
+func TestTriggerConditionMet(t *testing.T) {
+    value := func(v float64) *float64 { return &v }
+
+    tests := []struct {
+        name     string
+        operator ConditionOperator
+        current  float64
+        previous *float64
+        want     bool
+    }{
+        {"above", ConditionAbove, 101, nil, true},
+        {"at is not above", ConditionAbove, 100, nil, false},
+        {"below", ConditionBelow, 99, nil, true},
+        {"at is not below", ConditionBelow, 100, nil, false},
+        {"crosses above", ConditionCrossesAbove, 101, value(99), true},
+        {"crosses above from the level", ConditionCrossesAbove, 101, value(100), true},
+        {"already above has not crossed", ConditionCrossesAbove, 102, value(101), false},
+        {"crossing needs a previous value", ConditionCrossesAbove, 101, nil, false},
+        {"crosses below", ConditionCrossesBelow, 99, value(101), true},
+        {"already below has not crossed", ConditionCrossesBelow, 98, value(99), false},
+        {"crossing below needs a previous value", ConditionCrossesBelow, 99, nil, false},
+        {"unknown operator", "EQUALS", 100, value(100), false},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            condition := &TriggerCondition{Symbol: "AAPL", Type: ConditionTypePrice, Operator: tt.operator, Value: 100}
+            if got := condition.Met(tt.current, tt.previous); got != tt.want {
+                t.Errorf("Met(%v, %v) = %v, want %v", tt.current, tt.previous, got, tt.want)
+            }
+        })
+    }
+}
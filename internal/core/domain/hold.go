@@ -0,0 +1,108 @@
+package domain
+
+import "math"
+
+// Holds are the cash and shares set aside by a user's working orders. They
+// are worked out from the orders themselves, so an order's hold is released
+// as soon as it is cancelled, expires or fills.
+type Holds struct {
+    Cash   float64        `json:"cash"`   // Buys at their price with costs, plus short sale margin
+    Shares map[string]int `json:"shares"` // Long shares that resting sells would deliver
+    Covers map[string]int `json:"covers"` // Short shares that resting covers would buy back
+}
+
+func newHolds() *Holds {
+    return &Holds{Shares: make(map[string]int), Covers: make(map[string]int)}
+}
+
+// NewHolds totals the holds of working orders. An algo parent holds for its
+// whole remainder, so its slices hold nothing more, and only one leg of an
+// OCO group can fill, so a group holds the most any one leg would.
+func NewHolds(orders []Order) *Holds {
+    algoParents := make(map[int]bool)
+    for i := range orders {
+        if orders[i].IsAlgo() {
+            algoParents[orders[i].ID] = true
+        }
+    }
+
+    holds := newHolds()
+    groups := make(map[int]*Holds)
+    for i := range orders {
+        order := &orders[i]
+        if !order.IsActive() && order.Status != OrderStatusPaused {
+            continue
+        }
+        if order.ParentOrderID != nil && algoParents[*order.ParentOrderID] {
+            continue
+        }
+
+        hold := order.Hold()
+        if order.OCOGroupID == nil {
+            holds.add(hold)
+            continue
+        }
+        group, ok := groups[*order.OCOGroupID]
+        if !ok {
+            group = newHolds()
+            groups[*order.OCOGroupID] = group
+        }
+        group.widen(hold)
+    }
+
+    for _, group := range groups {
+        holds.add(group)
+    }
+    return holds
+}
+
+// Hold is what the order sets aside for its unfilled quantity: a buy its
+// value at its reference price plus the costs still to be charged, a short
+// its initial margin, and a sell or cover the shares it will close
+func (o *Order) Hold() *Holds {
+    hold := newHolds()
+    remaining := o.RemainingQuantity
+    if remaining <= 0 {
+        return hold
+    }
+
+    value := float64(remaining) * o.ReferencePrice()
+    switch o.Side {
+    case OrderSideBuy:
+        commission, fees := o.CostsForFill(remaining)
+        hold.Cash = math.Round((value+commission+fees)*100) / 100
+    case OrderSideShort:
+        hold.Cash = ShortInitialMargin(value)
+    case OrderSideSell:
+        hold.Shares[o.StockSymbol] = remaining
+    case OrderSideCover:
+        hold.Covers[o.StockSymbol] = remaining
+    }
+    return hold
+}
+
+// add adds other's holds to h
+func (h *Holds) add(other *Holds) {
+    h.Cash = math.Round((h.Cash+other.Cash)*100) / 100
+    for symbol, shares := range other.Shares {
+        h.Shares[symbol] += shares
+    }
+    for symbol, shares := range other.Covers {
+        h.Covers[symbol] += shares
+    }
+}
+
+// widen raises each of h's holds to other's where other's is larger
+func (h *Holds) widen(other *Holds) {
+    h.Cash = math.Max(h.Cash, other.Cash)
+    for symbol, shares := range other.Shares {
+        if shares > h.Shares[symbol] {
+            h.Shares[symbol] = shares
+        }
+    }
+    for symbol, shares := range other.Covers {
+        if shares > h.Covers[symbol] {
+            h.Covers[symbol] = shares
+        }
+    }
+}
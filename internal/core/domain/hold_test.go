@@ -0,0 +1,135 @@
+package domain
+
+import (
+    "reflect"
+    "testing"
+)
+
+func TestNewHolds(t *testing.T) {
+    price := func(p float64) *float64 { return &p }
+    id := func(i int) *int { return &i }
+    order := func(o Order) Order {
+        if o.Status == "" {
+            o.Status = OrderStatusPending
+        }
+        if o.RemainingQuantity == 0 {
+            o.RemainingQuantity = o.Quantity - o.ExecutedQuantity
+        }
+        return o
+    }
+
+    tests := []struct {
+        name       string
+        orders     []Order
+        wantCash   float64
+        wantShares map[string]int
+        wantCovers map[string]int
+    }{
+        {
+            name:   "no orders",
+            orders: nil,
+        },
+        {
+            name: "buy holds its value and costs",
+            orders: []Order{
+                order(Order{ID: 1, StockSymbol: "AAPL", Side: OrderSideBuy, Quantity: 10, Price: price(50), Commission: 1, Fees: 0.5}),
+            },
+            wantCash: 501.5,
+        },
+        {
+            name: "partly filled buy holds its remainder and costs still due",
+            orders: []Order{
+                order(Order{ID: 1, StockSymbol: "AAPL", Side: OrderSideBuy, Quantity: 10, ExecutedQuantity: 5,
+                    Status: OrderStatusPartiallyFilled, Price: price(50), Commission: 2}),
+            },
+            wantCash: 251,
+        },
+        {
+            name: "short holds initial margin",
+            orders: []Order{
+                order(Order{ID: 1, StockSymbol: "TSLA", Side: OrderSideShort, Quantity: 10, MarketPrice: 20}),
+            },
+            wantCash: 100,
+        },
+        {
+            name: "sells and covers hold shares",
+            orders: []Order{
+                order(Order{ID: 1, StockSymbol: "AAPL", Side: OrderSideSell, Quantity: 5}),
+                order(Order{ID: 2, StockSymbol: "AAPL", Side: OrderSideSell, Quantity: 3}),
+                order(Order{ID: 3, StockSymbol: "TSLA", Side: OrderSideCover, Quantity: 4}),
+            },
+            wantShares: map[string]int{"AAPL": 8},
+            wantCovers: map[string]int{"TSLA": 4},
+        },
+        {
+            name: "finished orders hold nothing",
+            orders: []Order{
+                order(Order{ID: 1, StockSymbol: "AAPL", Side: OrderSideSell, Quantity: 5, Status: OrderStatusCancelled}),
+                order(Order{ID: 2, StockSymbol: "AAPL", Side: OrderSideBuy, Quantity: 5, Price: price(10), Status: OrderStatusExecuted}),
+                order(Order{ID: 3, StockSymbol: "AAPL", Side: OrderSideSell, Quantity: 5, Status: OrderStatusDormant}),
+            },
+        },
+        {
+            name: "OCO group holds its largest leg",
+            orders: []Order{
+                order(Order{ID: 1, StockSymbol: "AAPL", Side: OrderSideSell, Quantity: 10, OCOGroupID: id(1)}),
+                order(Order{ID: 2, StockSymbol: "AAPL", Side: OrderSideSell, Quantity: 6, OCOGroupID: id(1)}),
+                order(Order{ID: 3, StockSymbol: "AAPL", Side: OrderSideSell, Quantity: 2}),
+            },
+            wantShares: map[string]int{"AAPL": 12},
+        },
+        {
+            name: "OCO group widens each hold separately",
+            orders: []Order{
+                order(Order{ID: 1, StockSymbol: "AAPL", Side: OrderSideBuy, Quantity: 10, Price: price(20), OCOGroupID: id(7)}),
+                order(Order{ID: 2, StockSymbol: "AAPL", Side: OrderSideBuy, Quantity: 10, Price: price(30), OCOGroupID: id(7)}),
+                order(Order{ID: 3, StockSymbol: "MSFT", Side: OrderSideSell, Quantity: 4, OCOGroupID: id(7)}),
+                order(Order{ID: 4, StockSymbol: "AAPL", Side: OrderSideBuy, Quantity: 1, Price: price(25), OCOGroupID: id(8)}),
+            },
+            wantCash:   325,
+            wantShares: map[string]int{"MSFT": 4},
+        },
+        {
+            name: "algo parent holds for its slices",
+            orders: []Order{
+                order(Order{ID: 1, StockSymbol: "AAPL", OrderType: OrderTypeTWAP, Side: OrderSideSell, Quantity: 100, ExecutedQuantity: 40}),
+                order(Order{ID: 2, StockSymbol: "AAPL", OrderType: OrderTypeMarket, Side: OrderSideSell, Quantity: 10, ParentOrderID: id(1)}),
+                order(Order{ID: 3, StockSymbol: "AAPL", OrderType: OrderTypeLimit, Side: OrderSideSell, Quantity: 5, ParentOrderID: id(99)}),
+            },
+            wantShares: map[string]int{"AAPL": 65},
+        },
+        {
+            name: "paused algo parent still holds",
+            orders: []Order{
+                order(Order{ID: 1, StockSymbol: "AAPL", OrderType: OrderTypeVWAP, Side: OrderSideBuy, Quantity: 10,
+                    Price: price(10), Status: OrderStatusPaused}),
+                order(Order{ID: 2, StockSymbol: "AAPL", OrderType: OrderTypeLimit, Side: OrderSideBuy, Quantity: 5,
+                    Price: price(10), ParentOrderID: id(1), Status: OrderStatusPaused}),
+            },
+            wantCash: 100,
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            holds := NewHolds(tt.orders)
+            if holds.Cash != tt.wantCash {
+                t.Errorf("Cash = %v, want %v", holds.Cash, tt.wantCash)
+            }
+            if !sameCounts(holds.Shares, tt.wantShares) {
+                t.Errorf("Shares = %v, want %v", holds.Shares, tt.wantShares)
+            }
+            if !sameCounts(holds.Covers, tt.wantCovers) {
+                t.Errorf("Covers = %v, want %v", holds.Covers, tt.wantCovers)
+            }
+        })
+    }
+}
+
+// sameCounts compares share counts, treating a nil map as empty
+func sameCounts(got, want map[string]int) bool {
+    if len(got) == 0 && len(want) == 0 {
+        return true
+    }
+    return reflect.DeepEqual(got, want)
+}
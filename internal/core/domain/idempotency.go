@@ -0,0 +1,74 @@
+package domain
+
+import (
+    "crypto/sha256"
+    "encoding/hex"
+    "errors"
+    "time"
+)
+
+const (
+    // IdempotencyWindow is how long a key replays its original response
+    IdempotencyWindow = 24 * time.Hour
+
+    // IdempotencyLockTimeout is how long a request may hold its key before
+    // the key is considered abandoned and can be taken again
+    IdempotencyLockTimeout = 5 * time.Minute
+)
+
+var (
+    // ErrIdempotencyKeyReused is returned when a key comes back with a different request
+    ErrIdempotencyKeyReused = errors.New("idempotency key was already used for a different request")
+
+    // ErrIdempotencyKeyInFlight is returned when the first request with a key has not finished
+    ErrIdempotencyKeyInFlight = errors.New("a request with this idempotency key is still being processed")
+)
+
+// IdempotencyRecord is a client-chosen key and, once the request finished,
+// the response to replay for it. Keys are scoped to the user.
+type IdempotencyRecord struct {
+    UserID       int        `json:"user_id" db:"user_id"`
+    Key          string     `json:"key" db:"idempotency_key"`
+    RequestHash  string     `json:"request_hash" db:"request_hash"`
+    StatusCode   int        `json:"status_code" db:"status_code"` // Zero while the request is in flight
+    ResponseBody []byte     `json:"response_body" db:"response_body"`
+    CreatedAt    time.Time  `json:"created_at" db:"created_at"`
+    CompletedAt  *time.Time `json:"completed_at,omitempty" db:"completed_at"`
+    ExpiresAt    time.Time  `json:"expires_at" db:"expires_at"`
+}
+
+// NewIdempotencyRecord reserves key for a request that is about to run
+func NewIdempotencyRecord(userID int, key, requestHash string, now time.Time) *IdempotencyRecord {
+    return &IdempotencyRecord{
+        UserID:      userID,
+        Key:         key,
+        RequestHash: requestHash,
+        CreatedAt:   now,
+        ExpiresAt:   now.Add(IdempotencyWindow),
+    }
+}
+
+// IdempotencyRequestHash fingerprints a request so a reused key can be told
+// apart from a genuine retry
+func IdempotencyRequestHash(method, path string, body []byte) string {
+    hash := sha256.New()
+    hash.Write([]byte(method))
+    hash.Write([]byte{0})
+    hash.Write([]byte(path))
+    hash.Write([]byte{0})
+    hash.Write(body)
+    return hex.EncodeToString(hash.Sum(nil))
+}
+
+func (r *IdempotencyRecord) IsCompleted() bool {
+    return r.CompletedAt != nil
+}
+
+// IsExpired reports whether the key can be used afresh: its window has
+// passed, or it was never completed and its lock has timed out
+func (r *IdempotencyRecord) IsExpired(now time.Time) bool {
+    if !now.Before(r.ExpiresAt) {
+        return true
+    }
+    return !r.IsCompleted() && now.Sub(r.CreatedAt) >= IdempotencyLockTimeout
+}
@@ -0,0 +1,147 @@
+package domain
+
+import "math"
+
+// Indicator periods used by ChartIndicators
+const (
+    RSIPeriod = 14
+)
+
+// NewChartIndicators computes the chart indicators over prices in the order
+// given
+func NewChartIndicators(prices []HistoricalPrice) ChartIndicators {
+    indicators := ChartIndicators{
+        MA20:   []float64{},
+        MA50:   []float64{},
+        RSI:    []float64{},
+        Volume: []int64{},
+    }
+
+    if len(prices) == 0 {
+        return indicators
+    }
+
+    // Extract closing prices and volumes
+    closePrices := make([]float64, len(prices))
+    volumes := make([]int64, len(prices))
+
+    for i, price := range prices {
+        closePrices[i] = price.Close
+        volumes[i] = price.Volume
+    }
+
+    indicators.Volume = volumes
+    indicators.MA20 = MovingAverage(closePrices, 20)
+    indicators.MA50 = MovingAverage(closePrices, 50)
+    indicators.RSI = RSI(closePrices, RSIPeriod)
+
+    return indicators
+}
+
+// MovingAverage returns the simple moving average of prices over period.
+// Points without a full period of history are zero.
+func MovingAverage(prices []float64, period int) []float64 {
+    if len(prices) < period {
+        return make([]float64, len(prices))
+    }
+
+    ma := make([]float64, len(prices))
+
+    for i := period - 1; i < len(prices); i++ {
+        sum := 0.0
+        for j := i - period + 1; j <= i; j++ {
+            sum += prices[j]
+        }
+        ma[i] = sum / float64(period)
+    }
+
+    return ma
+}
+
+// RSI returns the relative strength index of prices over period. Points
+// without a full period of history are zero.
+func RSI(prices []float64, period int) []float64 {
+    if len(prices) < period+1 {
+        return make([]float64, len(prices))
+    }
+
+    rsi := make([]float64, len(prices))
+    gains := make([]float64, len(prices)-1)
+    losses := make([]float64, len(prices)-1)
+
+    // Calculate gains and losses
+    for i := 1; i < len(prices); i++ {
+        change := prices[i] - prices[i-1]
+        if change > 0 {
+            gains[i-1] = change
+        } else {
+            losses[i-1] = -change
+        }
+    }
+
+    for i := period; i < len(prices); i++ {
+        avgGain := 0.0
+        avgLoss := 0.0
+
+        for j := i - period; j < i; j++ {
+            avgGain += gains[j]
+            avgLoss += losses[j]
+        }
+
+        avgGain /= float64(period)
+        avgLoss /= float64(period)
+
+        if avgLoss == 0 {
+            rsi[i] = 100
+        } else {
+            rs := avgGain / avgLoss
+            rsi[i] = 100 - (100 / (1 + rs))
+        }
+    }
+
+    return rsi
+}
+
+// ReturnVolatility is the standard deviation of the returns between
+// consecutive closes, in percent, with prices most recent first. Fewer than
+// three prices give zero.
+func ReturnVolatility(prices []HistoricalPrice) float64 {
+    return Volatility(PriceReturns(prices))
+}
+
+// PriceReturns are the returns between consecutive closes, in percent, oldest
+// first, with prices most recent first
+func PriceReturns(prices []HistoricalPrice) []float64 {
+    if len(prices) < 2 {
+        return nil
+    }
+
+    returns := make([]float64, 0, len(prices)-1)
+    for i := len(prices) - 1; i > 0; i-- {
+        // Most recent first, so prices[i] is the earlier close
+        if prices[i].Close > 0 {
+            returns = append(returns, (prices[i-1].Close-prices[i].Close)/prices[i].Close*100)
+        }
+    }
+    return returns
+}
+
+// Volatility is the sample standard deviation of returns. Fewer than two
+// returns give zero.
+func Volatility(returns []float64) float64 {
+    if len(returns) < 2 {
+        return 0
+    }
+
+    mean := 0.0
+    for _, r := range returns {
+        mean += r
+    }
+    mean /= float64(len(returns))
+
+    variance := 0.0
+    for _, r := range returns {
+        variance += (r - mean) * (r - mean)
+    }
+    return math.Sqrt(variance / float64(len(returns)-1))
+}
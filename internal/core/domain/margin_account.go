@@ -0,0 +1,146 @@
+package domain
+
+import (
+    "math"
+    "time"
+)
+
+// AccountType is how a user's purchases are funded
+type AccountType string
+
+const (
+    AccountTypeCash   AccountType = "CASH"
+    AccountTypeMargin AccountType = "MARGIN"
+)
+
+const (
+    // MarginInitialRate is the share of a margin purchase the user must fund
+    // from equity; the rest may be borrowed
+    MarginInitialRate = 0.50
+
+    // MarginMaintenanceRate is the equity a margin account must keep as a
+    // share of its long market value
+    MarginMaintenanceRate = 0.25
+
+    // MarginInterestRate is the annual rate on borrowed funds, charged daily
+    // over a 360 day year
+    MarginInterestRate = 0.08
+
+    // MarginCallGracePeriod is how long a margin call may stay unmet before
+    // positions are liquidated
+    MarginCallGracePeriod = 24 * time.Hour
+)
+
+// MarginAccount is a user's borrowing against their long positions. Shorts
+// carry their own collateral and are not part of it. Users without one trade
+// on cash only.
+type MarginAccount struct {
+    ID               int         `json:"id" db:"id"`
+    UserID           int         `json:"user_id" db:"user_id"`
+    AccountType      AccountType `json:"account_type" db:"account_type"`
+    BorrowedFunds    float64     `json:"borrowed_funds" db:"borrowed_funds"`
+    AccruedInterest  float64     `json:"accrued_interest" db:"accrued_interest"`
+    MarginCallAmount float64     `json:"margin_call_amount" db:"margin_call_amount"`
+    MarginCallAt     *time.Time  `json:"margin_call_at,omitempty" db:"margin_call_at"`
+    LastInterestAt   time.Time   `json:"last_interest_at" db:"last_interest_at"`
+    CreatedAt        time.Time   `json:"created_at" db:"created_at"`
+    UpdatedAt        time.Time   `json:"updated_at" db:"updated_at"`
+}
+
+// IsMargin reports whether the account may borrow
+func (a *MarginAccount) IsMargin() bool {
+    return a != nil && a.AccountType == AccountTypeMargin
+}
+
+// Debt is what the user owes: the loan plus unpaid interest
+func (a *MarginAccount) Debt() float64 {
+    if a == nil {
+        return 0
+    }
+    return a.BorrowedFunds + a.AccruedInterest
+}
+
+// Borrow adds amount to the loan
+func (a *MarginAccount) Borrow(amount float64) {
+    a.BorrowedFunds = math.Round((a.BorrowedFunds+amount)*100) / 100
+}
+
+// Repay pays down interest and then the loan from up to amount, and returns
+// how much of amount it used
+func (a *MarginAccount) Repay(amount float64) float64 {
+    paid := math.Min(amount, a.Debt())
+    interest := math.Min(paid, a.AccruedInterest)
+    a.AccruedInterest = math.Round((a.AccruedInterest-interest)*100) / 100
+    a.BorrowedFunds = math.Round((a.BorrowedFunds-(paid-interest))*100) / 100
+    return paid
+}
+
+// MarginStatus is a margin account valued at current prices
+type MarginStatus struct {
+    UserID                 int         `json:"user_id"`
+    AccountType            AccountType `json:"account_type"`
+    CashBalance            float64     `json:"cash_balance"`
+    LongMarketValue        float64     `json:"long_market_value"`
+    BorrowedFunds          float64     `json:"borrowed_funds"`
+    AccruedInterest        float64     `json:"accrued_interest"`
+    Equity                 float64     `json:"equity"`
+    InitialRequirement     float64     `json:"initial_requirement"`
+    MaintenanceRequirement float64     `json:"maintenance_requirement"`
+    ExcessEquity           float64     `json:"excess_equity"`
+    BuyingPower            float64     `json:"buying_power"`
+    MarginCallAmount       float64     `json:"margin_call_amount"`
+    MarginCallAt           *time.Time  `json:"margin_call_at,omitempty"`
+    LiquidateAfter         *time.Time  `json:"liquidate_after,omitempty"`
+}
+
+// NewMarginStatus values an account holding cash and longs worth
+// longMarketValue. A nil account is a cash account.
+func NewMarginStatus(userID int, account *MarginAccount, cash, longMarketValue float64) *MarginStatus {
+    status := &MarginStatus{
+        UserID:          userID,
+        AccountType:     AccountTypeCash,
+        CashBalance:     cash,
+        LongMarketValue: longMarketValue,
+        BuyingPower:     math.Max(0, cash),
+    }
+    if account == nil {
+        status.Equity = cash + longMarketValue
+        return status
+    }
+
+    status.AccountType = account.AccountType
+    status.BorrowedFunds = account.BorrowedFunds
+    status.AccruedInterest = account.AccruedInterest
+    status.Equity = cash + longMarketValue - account.Debt()
+    status.MarginCallAmount = account.MarginCallAmount
+    status.MarginCallAt = account.MarginCallAt
+    if account.MarginCallAt != nil {
+        deadline := account.MarginCallAt.Add(MarginCallGracePeriod)
+        status.LiquidateAfter = &deadline
+    }
+
+    if account.IsMargin() {
+        status.InitialRequirement = longMarketValue * MarginInitialRate
+        status.MaintenanceRequirement = longMarketValue * MarginMaintenanceRate
+        status.ExcessEquity = status.Equity - status.InitialRequirement
+        status.BuyingPower = math.Max(0, status.ExcessEquity) / MarginInitialRate
+    }
+    return status
+}
+
+// MaintenanceDeficit is how far equity is below the maintenance requirement,
+// zero when the account is in good standing
+func (s *MarginStatus) MaintenanceDeficit() float64 {
+    return math.Max(0, s.MaintenanceRequirement-s.Equity)
+}
+
+// BalanceUpdate is the status as a WebSocket balance message
+func (s *MarginStatus) BalanceUpdate() BalanceUpdateMessage {
+    return BalanceUpdateMessage{
+        CashBalance:       s.CashBalance,
+        MarginBalance:     s.BorrowedFunds + s.AccruedInterest,
+        BuyingPower:       s.BuyingPower,
+        PortfolioValue:    s.LongMarketValue,
+        MaintenanceMargin: s.MaintenanceRequirement,
+    }
+}
@@ -298,6 +298,29 @@ type MarketService interface {
     CanTrade(marketCode string, symbol string, orderType OrderType) (bool, string, error)
 }
 
+// regularSessionCloseHour is when the regular session ends in the market's
+// local time; DAY orders expire at this close
+const regularSessionCloseHour = 16
+
+// LastSessionClose returns the most recent regular-session close at or before
+// now in the market's time zone, skipping weekends and US holidays
+func LastSessionClose(marketCode string, now time.Time) time.Time {
+    loc, err := time.LoadLocation(GetMarketTimeZone(marketCode))
+    if err != nil {
+        loc = time.UTC
+    }
+
+    local := now.In(loc)
+    sessionClose := time.Date(local.Year(), local.Month(), local.Day(), regularSessionCloseHour, 0, 0, 0, loc)
+    if sessionClose.After(local) {
+        sessionClose = sessionClose.AddDate(0, 0, -1)
+    }
+    for IsWeekend(sessionClose) || IsUSHoliday(sessionClose) {
+        sessionClose = sessionClose.AddDate(0, 0, -1)
+    }
+    return sessionClose
+}
+
 // Helper functions for common market operations
 func IsWeekend(t time.Time) bool {
     weekday := t.Weekday()
@@ -3,6 +3,7 @@ package domain
 import (
     "time"
     "fmt"
+    "math"
 )
 
 // OrderType represents different types of orders
@@ -14,7 +15,11 @@ const (
     OrderTypeStopLoss   OrderType = "STOP_LOSS"
     OrderTypeTakeProfit OrderType = "TAKE_PROFIT"
     OrderTypeTrailingStop OrderType = "TRAILING_STOP"
+    OrderTypeStopLimit  OrderType = "STOP_LIMIT" // Rests as a limit order once its stop triggers
     OrderTypeOCO        OrderType = "OCO" // One-Cancels-Other
+    OrderTypeBracket    OrderType = "BRACKET" // Entry with take-profit and stop-loss exits
+    OrderTypeTWAP       OrderType = "TWAP" // Parent sliced evenly over time
+    OrderTypeVWAP       OrderType = "VWAP" // Parent sliced along the intraday volume profile
 )
 
 // OrderStatus represents the current status of an order
@@ -26,6 +31,9 @@ const (
     OrderStatusCancelled OrderStatus = "CANCELLED"
     OrderStatusExpired   OrderStatus = "EXPIRED"
     OrderStatusPartiallyFilled OrderStatus = "PARTIALLY_FILLED"
+    OrderStatusDormant   OrderStatus = "DORMANT" // Bracket exit waiting for its entry to fill
+    OrderStatusRejected  OrderStatus = "REJECTED" // Refused on arrival, e.g. an unfillable FOK
+    OrderStatusPaused    OrderStatus = "PAUSED" // Algo parent (and its slices) held by the user
 )
 
 // OrderSide represents buy or sell
@@ -46,6 +54,7 @@ const (
     TimeInForceIOC TimeInForce = "IOC" // Immediate or Cancel
     TimeInForceFOK TimeInForce = "FOK" // Fill or Kill
     TimeInForceDAY TimeInForce = "DAY" // Day order
+    TimeInForceGTD TimeInForce = "GTD" // Good Till Date (uses ExpiresAt)
 )
 
 // Order represents a trading order with advanced features
@@ -60,11 +69,17 @@ type Order struct {
     StopPrice          *float64    `json:"stop_price,omitempty" db:"stop_price"` // For stop orders
     TrailingAmount     *float64    `json:"trailing_amount,omitempty" db:"trailing_amount"` // For trailing stops
     TrailingPercent    *float64    `json:"trailing_percent,omitempty" db:"trailing_percent"` // For trailing stops
+    HighWaterMark      *float64    `json:"high_water_mark,omitempty" db:"high_water_mark"` // Best price seen by a trailing stop
+    TriggeredAt        *time.Time  `json:"triggered_at,omitempty" db:"triggered_at"` // When a stop-limit's stop was hit
     TimeInForce        TimeInForce `json:"time_in_force" db:"time_in_force"`
     Status             OrderStatus `json:"status" db:"status"`
+    Version            int         `json:"version" db:"version"` // Starts at 1; each amendment adds one
     ExecutedPrice      *float64    `json:"executed_price,omitempty" db:"executed_price"`
     ExecutedQuantity   int         `json:"executed_quantity" db:"executed_quantity"`
     RemainingQuantity  int         `json:"remaining_quantity" db:"remaining_quantity"`
+    DisplayQuantity    *int        `json:"display_quantity,omitempty" db:"display_quantity"` // Iceberg slice size
+    VisibleRemaining   int         `json:"visible_remaining,omitempty" db:"visible_remaining"` // Unfilled part of the current iceberg slice
+    PriorityAt         time.Time   `json:"priority_at" db:"priority_at"` // Time priority in the book; reset by each iceberg refill
     ExecutedAt         *time.Time  `json:"executed_at,omitempty" db:"executed_at"`
     ExpiresAt          *time.Time  `json:"expires_at,omitempty" db:"expires_at"`
     CreatedAt          time.Time   `json:"created_at" db:"created_at"`
@@ -73,6 +88,8 @@ type Order struct {
     // OCO Related
     ParentOrderID      *int        `json:"parent_order_id,omitempty" db:"parent_order_id"`
     LinkedOrderID      *int        `json:"linked_order_id,omitempty" db:"linked_order_id"`
+    OCOGroupID         *int        `json:"oco_group_id,omitempty" db:"oco_group_id"`
+    CancelReason       *string     `json:"cancel_reason,omitempty" db:"cancel_reason"`
     
     // Commission and fees
     Commission         float64     `json:"commission" db:"commission"`
@@ -91,6 +108,7 @@ type OrderRequest struct {
     OrderType          OrderType   `json:"order_type" binding:"required"`
     Side               OrderSide   `json:"side" binding:"required"`
     Quantity           int         `json:"quantity" binding:"required,min=1"`
+    DisplayQuantity    *int        `json:"display_quantity,omitempty"` // Shows only this many shares at a time (limit orders)
     Price              *float64    `json:"price,omitempty"`
     StopPrice          *float64    `json:"stop_price,omitempty"`
     TrailingAmount     *float64    `json:"trailing_amount,omitempty"`
@@ -98,16 +116,52 @@ type OrderRequest struct {
     TimeInForce        TimeInForce `json:"time_in_force"`
     ExpiresAt          *time.Time  `json:"expires_at,omitempty"`
     LinkedOrderRequest *OrderRequest `json:"linked_order,omitempty"` // For OCO orders
+    TakeProfitPrice    *float64    `json:"take_profit_price,omitempty"` // For bracket orders
+    StopLossPrice      *float64    `json:"stop_loss_price,omitempty"` // For bracket orders
+    DurationMinutes    *int        `json:"duration_minutes,omitempty"` // For TWAP and VWAP orders
+    SliceOrderType     OrderType   `json:"slice_order_type,omitempty"` // MARKET (default) or LIMIT at Price, for TWAP and VWAP
+}
+
+// BracketOrder is an entry order with its take-profit and stop-loss exits.
+// The exits form an OCO group and stay DORMANT until the entry fills.
+type BracketOrder struct {
+    Entry      Order `json:"entry"`
+    TakeProfit Order `json:"take_profit"`
+    StopLoss   Order `json:"stop_loss"`
+}
+
+// OCOGroup is a one-cancels-other pair returned as a single unit. The group ID
+// is the ID of the group's first leg.
+type OCOGroup struct {
+    GroupID int     `json:"group_id"`
+    Status  string  `json:"status"` // ACTIVE, EXECUTED or CANCELLED
+    Orders  []Order `json:"orders"`
+}
+
+// NewOCOGroup derives the group status from its legs
+func NewOCOGroup(groupID int, orders []Order) *OCOGroup {
+    status := "CANCELLED"
+    for _, order := range orders {
+        if order.IsActive() {
+            status = "ACTIVE"
+            break
+        }
+        if order.ExecutedQuantity > 0 {
+            status = "EXECUTED"
+        }
+    }
+    return &OCOGroup{GroupID: groupID, Status: status, Orders: orders}
 }
 
 // OrderExecution represents the result of order execution
 type OrderExecution struct {
+    ID                int       `json:"id,omitempty"`
     OrderID           int       `json:"order_id"`
     ExecutedPrice     float64   `json:"executed_price"`
     ExecutedQuantity  int       `json:"executed_quantity"`
     Commission        float64   `json:"commission"`
     Fees              float64   `json:"fees"`
-    Slippage          float64   `json:"slippage"`
+    Slippage          float64   `json:"slippage"`          // in basis points
     ExecutedAt        time.Time `json:"executed_at"`
     TotalAmount       float64   `json:"total_amount"`
 }
@@ -118,6 +172,8 @@ type OrderStats struct {
     PendingOrders      int     `json:"pending_orders"`
     ExecutedOrders     int     `json:"executed_orders"`
     CancelledOrders    int     `json:"cancelled_orders"`
+    ExpiredOrders      int     `json:"expired_orders"`
+    RejectedOrders     int     `json:"rejected_orders"`
     PartiallyFilled    int     `json:"partially_filled"`
     SuccessRate        float64 `json:"success_rate"`
     AverageExecutionTime float64 `json:"average_execution_time"`
@@ -125,6 +181,24 @@ type OrderStats struct {
     TotalFees          float64 `json:"total_fees"`
 }
 
+// TimeframeStart returns where a metrics timeframe (day, week, month, year or
+// all) begins. All time starts at the zero time.
+func TimeframeStart(timeframe string, now time.Time) (time.Time, bool) {
+    switch timeframe {
+    case "day":
+        return now.AddDate(0, 0, -1), true
+    case "week":
+        return now.AddDate(0, 0, -7), true
+    case "month":
+        return now.AddDate(0, -1, 0), true
+    case "year":
+        return now.AddDate(-1, 0, 0), true
+    case "all":
+        return time.Time{}, true
+    }
+    return time.Time{}, false
+}
+
 // Validation methods
 func (o *Order) Validate() error {
     if o.UserID <= 0 {
@@ -149,6 +223,13 @@ func (o *Order) Validate() error {
         if o.StopPrice == nil || *o.StopPrice <= 0 {
             return fmt.Errorf("stop orders require a valid stop price")
         }
+    case OrderTypeStopLimit:
+        if o.StopPrice == nil || *o.StopPrice <= 0 {
+            return fmt.Errorf("stop limit orders require a valid stop price")
+        }
+        if o.Price == nil || *o.Price <= 0 {
+            return fmt.Errorf("stop limit orders require a valid limit price")
+        }
     case OrderTypeTrailingStop:
         if (o.TrailingAmount == nil && o.TrailingPercent == nil) ||
            (o.TrailingAmount != nil && *o.TrailingAmount <= 0) ||
@@ -156,6 +237,15 @@ func (o *Order) Validate() error {
             return fmt.Errorf("trailing stop orders require valid trailing amount or percent")
         }
     }
+
+    if o.DisplayQuantity != nil {
+        if o.OrderType != OrderTypeLimit && o.OrderType != OrderTypeStopLimit {
+            return fmt.Errorf("display quantity is only supported on limit orders")
+        }
+        if *o.DisplayQuantity <= 0 || *o.DisplayQuantity >= o.Quantity {
+            return fmt.Errorf("display quantity must be positive and less than the order quantity")
+        }
+    }
     
     return nil
 }
@@ -165,8 +255,33 @@ func (o *Order) IsActive() bool {
     return o.Status == OrderStatusPending || o.Status == OrderStatusPartiallyFilled
 }
 
+// IsAlgo reports whether the order is a TWAP or VWAP parent, worked through
+// child slices rather than traded itself
+func (o *Order) IsAlgo() bool {
+    return o.OrderType == OrderTypeTWAP || o.OrderType == OrderTypeVWAP
+}
+
+// IsBuySide reports whether the order buys shares (opening long or covering short)
+func (o *Order) IsBuySide() bool {
+    return o.Side == OrderSideBuy || o.Side == OrderSideCover
+}
+
+// ExitSide returns the side that closes the position this order opens
+func (o *Order) ExitSide() OrderSide {
+    if o.Side == OrderSideShort {
+        return OrderSideCover
+    }
+    return OrderSideSell
+}
+
 func (o *Order) IsCompleted() bool {
-    return o.Status == OrderStatusExecuted || o.Status == OrderStatusCancelled || o.Status == OrderStatusExpired
+    return o.Status == OrderStatusExecuted || o.Status == OrderStatusCancelled ||
+        o.Status == OrderStatusExpired || o.Status == OrderStatusRejected
+}
+
+// IsImmediate reports whether the order must trade on arrival and never rest
+func (o *Order) IsImmediate() bool {
+    return o.TimeInForce == TimeInForceIOC || o.TimeInForce == TimeInForceFOK
 }
 
 func (o *Order) CalculateTotalCost() float64 {
@@ -185,18 +300,19 @@ func (o *Order) CanBeExecuted(currentPrice float64) bool {
     case OrderTypeMarket:
         return true
     case OrderTypeLimit:
-        if o.Price == nil {
-            return false
-        }
-        if o.Side == OrderSideBuy {
-            return currentPrice <= *o.Price
-        }
-        return currentPrice >= *o.Price
-    case OrderTypeStopLoss:
+        return o.limitReached(currentPrice)
+    case OrderTypeStopLimit:
+        // Until its stop triggers a stop-limit is not in the market at all
+        return o.TriggeredAt != nil && o.limitReached(currentPrice)
+    case OrderTypeStopLoss, OrderTypeTrailingStop:
         if o.StopPrice == nil {
             return false
         }
-        if o.Side == OrderSideBuy {
+        // A stop that has started filling has already triggered
+        if o.ExecutedQuantity > 0 {
+            return true
+        }
+        if o.IsBuySide() {
             return currentPrice >= *o.StopPrice
         }
         return currentPrice <= *o.StopPrice
@@ -204,11 +320,156 @@ func (o *Order) CanBeExecuted(currentPrice float64) bool {
         if o.StopPrice == nil {
             return false
         }
-        if o.Side == OrderSideBuy {
+        if o.ExecutedQuantity > 0 {
+            return true
+        }
+        if o.IsBuySide() {
             return currentPrice <= *o.StopPrice
         }
         return currentPrice >= *o.StopPrice
     }
     
     return false
-} 
\ No newline at end of file
+}
+
+func (o *Order) limitReached(currentPrice float64) bool {
+    if o.Price == nil {
+        return false
+    }
+    if o.IsBuySide() {
+        return currentPrice <= *o.Price
+    }
+    return currentPrice >= *o.Price
+}
+
+// IsIceberg reports whether the order shows only a slice of its quantity
+func (o *Order) IsIceberg() bool {
+    return o.DisplayQuantity != nil
+}
+
+// VisibleQuantity returns the shares the order shows to the market and may
+// fill before its next refill: the current slice for an iceberg, otherwise
+// everything that remains
+func (o *Order) VisibleQuantity() int {
+    if !o.IsIceberg() || o.VisibleRemaining > o.RemainingQuantity {
+        return o.RemainingQuantity
+    }
+    return o.VisibleRemaining
+}
+
+// ResetSlice shows a fresh iceberg slice from the hidden remainder. The order
+// goes to the back of the queue at its price, as a new order would.
+func (o *Order) ResetSlice(at time.Time) {
+    if !o.IsIceberg() {
+        return
+    }
+    o.VisibleRemaining = *o.DisplayQuantity
+    if o.VisibleRemaining > o.RemainingQuantity {
+        o.VisibleRemaining = o.RemainingQuantity
+    }
+    o.PriorityAt = at
+}
+
+// IsRestingLimit reports whether the order is working at its limit price: a
+// limit order, or a stop-limit whose stop has triggered
+func (o *Order) IsRestingLimit() bool {
+    return o.OrderType == OrderTypeLimit || (o.OrderType == OrderTypeStopLimit && o.TriggeredAt != nil)
+}
+
+// StopTriggered reports whether currentPrice hits an untriggered stop-limit's
+// stop: at or above it for a buy, at or below it for a sell
+func (o *Order) StopTriggered(currentPrice float64) bool {
+    if o.OrderType != OrderTypeStopLimit || o.TriggeredAt != nil || o.StopPrice == nil || !o.IsActive() {
+        return false
+    }
+    if o.IsBuySide() {
+        return currentPrice >= *o.StopPrice
+    }
+    return currentPrice <= *o.StopPrice
+}
+
+// CostsForFill returns the share of the order's commission and fees owed by a
+// fill of quantity shares. Each fill is charged the difference between the
+// rounded pro-rata totals before and after it, so the fills of a completed
+// order add up to exactly Commission and Fees.
+func (o *Order) CostsForFill(quantity int) (commission, fees float64) {
+    return proRata(o.Commission, o.ExecutedQuantity, quantity, o.Quantity),
+        proRata(o.Fees, o.ExecutedQuantity, quantity, o.Quantity)
+}
+
+func proRata(total float64, done, quantity, of int) float64 {
+    if of <= 0 {
+        return 0
+    }
+    before := math.Round(total*float64(done)/float64(of)*100) / 100
+    after := math.Round(total*float64(done+quantity)/float64(of)*100) / 100
+    return after - before
+}
+
+// ApplyFill records a fill of quantity shares at price. ExecutedPrice is kept
+// as the volume-weighted average of all fills, and the order moves to
+// PARTIALLY_FILLED or, once nothing remains, EXECUTED. An iceberg whose slice
+// is used up refills from the hidden remainder.
+func (o *Order) ApplyFill(quantity int, price float64, at time.Time) {
+    average := price
+    if o.ExecutedPrice != nil && o.ExecutedQuantity > 0 {
+        average = (*o.ExecutedPrice*float64(o.ExecutedQuantity) + price*float64(quantity)) /
+            float64(o.ExecutedQuantity+quantity)
+    }
+    o.ExecutedPrice = &average
+    o.ExecutedQuantity += quantity
+    o.RemainingQuantity -= quantity
+
+    if o.IsIceberg() {
+        o.VisibleRemaining -= quantity
+        if o.VisibleRemaining <= 0 && o.RemainingQuantity > 0 {
+            o.ResetSlice(at)
+        }
+    }
+
+    if o.RemainingQuantity <= 0 {
+        o.RemainingQuantity = 0
+        o.Status = OrderStatusExecuted
+        o.ExecutedAt = &at
+    } else {
+        o.Status = OrderStatusPartiallyFilled
+    }
+}
+
+// UpdateTrailingStop moves a trailing stop's high-water mark and StopPrice with
+// the market. A sell stop trails the highest price seen and a buy stop (used
+// to protect a short) trails the lowest; the stop never moves back. Returns
+// true if either value changed.
+func (o *Order) UpdateTrailingStop(currentPrice float64) bool {
+    if o.OrderType != OrderTypeTrailingStop {
+        return false
+    }
+
+    best := currentPrice
+    if o.HighWaterMark != nil {
+        if o.IsBuySide() {
+            best = math.Min(*o.HighWaterMark, currentPrice)
+        } else {
+            best = math.Max(*o.HighWaterMark, currentPrice)
+        }
+    }
+
+    offset := 0.0
+    if o.TrailingAmount != nil {
+        offset = *o.TrailingAmount
+    } else if o.TrailingPercent != nil {
+        offset = best * *o.TrailingPercent / 100
+    }
+
+    stop := best - offset
+    if o.IsBuySide() {
+        stop = best + offset
+    }
+    stop = math.Round(stop*100) / 100
+
+    changed := o.HighWaterMark == nil || *o.HighWaterMark != best ||
+        o.StopPrice == nil || *o.StopPrice != stop
+    o.HighWaterMark = &best
+    o.StopPrice = &stop
+    return changed
+}
@@ -0,0 +1,118 @@
+package domain
+
+import (
+    "math"
+    "sort"
+    "time"
+)
+
+// OrderBookEntry is one resting quantity in an order book. Synthetic
+// liquidity has no OrderID.
+type OrderBookEntry struct {
+    OrderID  int       `json:"order_id,omitempty"`
+    Price    float64   `json:"price"`
+    Quantity int       `json:"quantity"`
+    PlacedAt time.Time `json:"placed_at"`
+}
+
+// OrderBook is a price-time priority book for one symbol. Bids are kept best
+// (highest) first and asks best (lowest) first; entries at the same price keep
+// arrival order.
+type OrderBook struct {
+    Symbol    string
+    Bids      []OrderBookEntry
+    Asks      []OrderBookEntry
+    Sequence  int64
+    UpdatedAt time.Time
+}
+
+// NewOrderBook sorts the given entries into price-time priority
+func NewOrderBook(symbol string, bids, asks []OrderBookEntry) *OrderBook {
+    sort.SliceStable(bids, func(i, j int) bool {
+        if bids[i].Price != bids[j].Price {
+            return bids[i].Price > bids[j].Price
+        }
+        return bids[i].PlacedAt.Before(bids[j].PlacedAt)
+    })
+    sort.SliceStable(asks, func(i, j int) bool {
+        if asks[i].Price != asks[j].Price {
+            return asks[i].Price < asks[j].Price
+        }
+        return asks[i].PlacedAt.Before(asks[j].PlacedAt)
+    })
+
+    return &OrderBook{
+        Symbol:    symbol,
+        Bids:      bids,
+        Asks:      asks,
+        UpdatedAt: time.Now(),
+    }
+}
+
+// Snapshot aggregates the best depth price levels on each side
+func (b *OrderBook) Snapshot(depth int) *OrderBookUpdate {
+    return &OrderBookUpdate{
+        Symbol:    b.Symbol,
+        Bids:      aggregateLevels(b.Bids, depth),
+        Asks:      aggregateLevels(b.Asks, depth),
+        Timestamp: b.UpdatedAt,
+        Sequence:  b.Sequence,
+    }
+}
+
+func aggregateLevels(entries []OrderBookEntry, depth int) []OrderBookLevel {
+    levels := []OrderBookLevel{}
+    for _, entry := range entries {
+        if entry.Quantity <= 0 {
+            continue
+        }
+        last := len(levels) - 1
+        if last >= 0 && levels[last].Price == entry.Price {
+            levels[last].Quantity += entry.Quantity
+            levels[last].Orders++
+            continue
+        }
+        if len(levels) == depth {
+            break
+        }
+        levels = append(levels, OrderBookLevel{Price: entry.Price, Quantity: entry.Quantity, Orders: 1})
+    }
+    return levels
+}
+
+// Walk takes up to quantity shares from the side a taker trades against (the
+// asks for a buyer, the bids for a seller), best price first. It returns the
+// volume-weighted price of what was taken and how many shares that was.
+// Only synthetic liquidity is taken: users' resting orders are in the book
+// for depth, but they trade when the matching engine fills them, never
+// against a taker here that would leave them unsettled.
+func (b *OrderBook) Walk(buy bool, quantity int) (float64, int) {
+    side := &b.Bids
+    if buy {
+        side = &b.Asks
+    }
+
+    taken := 0
+    notional := 0.0
+    kept := (*side)[:0]
+    for _, entry := range *side {
+        if entry.OrderID == 0 && taken < quantity {
+            take := min(entry.Quantity, quantity-taken)
+            entry.Quantity -= take
+            taken += take
+            notional += float64(take) * entry.Price
+            if entry.Quantity == 0 {
+                continue
+            }
+        }
+        kept = append(kept, entry)
+    }
+    *side = kept
+
+    if taken == 0 {
+        return 0, 0
+    }
+    b.Sequence++
+    b.UpdatedAt = time.Now()
+    return math.Round(notional/float64(taken)*10000) / 10000, taken
+}
@@ -0,0 +1,111 @@
+package domain
+
+import (
+    "reflect"
+    "testing"
+)
+
+func TestOrderBookWalk(t *testing.T) {
+    book := func() *OrderBook {
+        return NewOrderBook("AAPL",
+            []OrderBookEntry{
+                {Price: 99.99, Quantity: 100},
+                {OrderID: 7, Price: 100.00, Quantity: 50},
+                {Price: 99.98, Quantity: 200},
+            },
+            []OrderBookEntry{
+                {Price: 100.01, Quantity: 100},
+                {OrderID: 8, Price: 100.01, Quantity: 40},
+                {Price: 100.03, Quantity: 100},
+            })
+    }
+
+    tests := []struct {
+        name      string
+        buy       bool
+        quantity  int
+        wantPrice float64
+        wantTaken int
+        wantSide  []OrderBookEntry
+    }{
+        {
+            name:      "buy within the best level",
+            buy:       true,
+            quantity:  60,
+            wantPrice: 100.01,
+            wantTaken: 60,
+            wantSide: []OrderBookEntry{
+                {Price: 100.01, Quantity: 40},
+                {OrderID: 8, Price: 100.01, Quantity: 40},
+                {Price: 100.03, Quantity: 100},
+            },
+        },
+        {
+            name:      "buy across levels at the volume-weighted price, skipping user orders",
+            buy:       true,
+            quantity:  150,
+            wantPrice: 100.0167,
+            wantTaken: 150,
+            wantSide: []OrderBookEntry{
+                {OrderID: 8, Price: 100.01, Quantity: 40},
+                {Price: 100.03, Quantity: 50},
+            },
+        },
+        {
+            name:      "buy more than the book holds",
+            buy:       true,
+            quantity:  500,
+            wantPrice: 100.02,
+            wantTaken: 200,
+            wantSide: []OrderBookEntry{
+                {OrderID: 8, Price: 100.01, Quantity: 40},
+            },
+        },
+        {
+            name:      "sell walks the bids, skipping the better user bid",
+            buy:       false,
+            quantity:  200,
+            wantPrice: 99.985,
+            wantTaken: 200,
+            wantSide: []OrderBookEntry{
+                {OrderID: 7, Price: 100.00, Quantity: 50},
+                {Price: 99.98, Quantity: 100},
+            },
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            b := book()
+            price, taken := b.Walk(tt.buy, tt.quantity)
+            if price != tt.wantPrice || taken != tt.wantTaken {
+                t.Errorf("Walk() = %v, %d, want %v, %d", price, taken, tt.wantPrice, tt.wantTaken)
+            }
+            side := b.Bids
+            if tt.buy {
+                side = b.Asks
+            }
+            if !reflect.DeepEqual(side, tt.wantSide) {
+                t.Errorf("side left = %+v, want %+v", side, tt.wantSide)
+            }
+            if b.Sequence != 1 {
+                t.Errorf("Sequence = %d, want 1", b.Sequence)
+            }
+        })
+    }
+}
+
+func TestOrderBookWalkNothingToTake(t *testing.T) {
+    b := NewOrderBook("AAPL", nil, []OrderBookEntry{{OrderID: 3, Price: 100.01, Quantity: 40}})
+
+    price, taken := b.Walk(true, 10)
+    if price != 0 || taken != 0 {
+        t.Errorf("Walk() = %v, %d, want 0, 0", price, taken)
+    }
+    if len(b.Asks) != 1 || b.Asks[0].Quantity != 40 {
+        t.Errorf("Asks = %+v, want the user's order untouched", b.Asks)
+    }
+    if b.Sequence != 0 {
+        t.Errorf("Sequence = %d, want 0", b.Sequence)
+    }
+}
@@ -0,0 +1,61 @@
+package domain
+
+import (
+    "fmt"
+    "time"
+)
+
+// OrderActor is who caused an order's status to change
+type OrderActor string
+
+const (
+    OrderActorUser   OrderActor = "USER"   // The order's owner, through the API
+    OrderActorSystem OrderActor = "SYSTEM" // Matching, expiry and the other background jobs
+)
+
+// orderTransitions lists the statuses each status may move to. The empty
+// status is an order not yet stored; EXECUTED, CANCELLED, EXPIRED and
+// REJECTED are final.
+var orderTransitions = map[OrderStatus][]OrderStatus{
+    "":                         {OrderStatusPending, OrderStatusDormant},
+    OrderStatusDormant:         {OrderStatusPending, OrderStatusCancelled},
+    OrderStatusPending:         {OrderStatusPartiallyFilled, OrderStatusExecuted, OrderStatusCancelled, OrderStatusExpired, OrderStatusRejected, OrderStatusPaused},
+    OrderStatusPartiallyFilled: {OrderStatusExecuted, OrderStatusCancelled, OrderStatusExpired, OrderStatusPaused},
+    OrderStatusPaused:          {OrderStatusPending, OrderStatusPartiallyFilled, OrderStatusCancelled},
+}
+
+// CanTransition reports whether an order may move from one status to another
+func CanTransition(from, to OrderStatus) bool {
+    for _, allowed := range orderTransitions[from] {
+        if allowed == to {
+            return true
+        }
+    }
+    return false
+}
+
+// TransitionTo moves the order to status, refusing moves the state machine
+// does not allow. Staying in the same status is not a transition and is
+// always allowed.
+func (o *Order) TransitionTo(status OrderStatus) error {
+    if o.Status == status {
+        return nil
+    }
+    if !CanTransition(o.Status, status) {
+        return fmt.Errorf("order %d cannot move from %s to %s", o.ID, o.Status, status)
+    }
+    o.Status = status
+    return nil
+}
+
+// OrderEvent is one entry in an order's append-only status history. The
+// first event of every order has no FromStatus.
+type OrderEvent struct {
+    ID         int          `json:"id" db:"id"`
+    OrderID    int          `json:"order_id" db:"order_id"`
+    FromStatus *OrderStatus `json:"from_status,omitempty" db:"from_status"`
+    ToStatus   OrderStatus  `json:"to_status" db:"to_status"`
+    Reason     string       `json:"reason" db:"reason"`
+    Actor      OrderActor   `json:"actor" db:"actor"`
+    CreatedAt  time.Time    `json:"created_at" db:"created_at"`
+}
@@ -0,0 +1,72 @@
+package domain
+
+import "testing"
+
+func TestCanTransition(t *testing.T) {
+    tests := []struct {
+        from, to OrderStatus
+        want     bool
+    }{
+        {"", OrderStatusPending, true},
+        {"", OrderStatusDormant, true},
+        {"", OrderStatusExecuted, false},
+        {OrderStatusDormant, OrderStatusPending, true},
+        {OrderStatusDormant, OrderStatusCancelled, true},
+        {OrderStatusDormant, OrderStatusExecuted, false},
+        {OrderStatusPending, OrderStatusPartiallyFilled, true},
+        {OrderStatusPending, OrderStatusExecuted, true},
+        {OrderStatusPending, OrderStatusCancelled, true},
+        {OrderStatusPending, OrderStatusExpired, true},
+        {OrderStatusPending, OrderStatusRejected, true},
+        {OrderStatusPending, OrderStatusPaused, true},
+        {OrderStatusPending, OrderStatusDormant, false},
+        {OrderStatusPartiallyFilled, OrderStatusExecuted, true},
+        {OrderStatusPartiallyFilled, OrderStatusPaused, true},
+        {OrderStatusPartiallyFilled, OrderStatusPending, false},
+        {OrderStatusPartiallyFilled, OrderStatusRejected, false},
+        {OrderStatusPaused, OrderStatusPending, true},
+        {OrderStatusPaused, OrderStatusPartiallyFilled, true},
+        {OrderStatusPaused, OrderStatusCancelled, true},
+        {OrderStatusPaused, OrderStatusExecuted, false},
+        {OrderStatusExecuted, OrderStatusCancelled, false},
+        {OrderStatusCancelled, OrderStatusPending, false},
+        {OrderStatusExpired, OrderStatusPending, false},
+        {OrderStatusRejected, OrderStatusPending, false},
+    }
+
+    for _, tt := range tests {
+        if got := CanTransition(tt.from, tt.to); got != tt.want {
+            t.Errorf("CanTransition(%q, %q) = %v, want %v", tt.from, tt.to, got, tt.want)
+        }
+    }
+}
+
+func TestOrderTransitionTo(t *testing.T) {
+    tests := []struct {
+        name    string
+        from    OrderStatus
+        to      OrderStatus
+        wantErr bool
+    }{
+        {"allowed move", OrderStatusPending, OrderStatusExecuted, false},
+        {"same status", OrderStatusExecuted, OrderStatusExecuted, false},
+        {"out of a final status", OrderStatusCancelled, OrderStatusPending, true},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            order := &Order{Status: tt.from}
+            err := order.TransitionTo(tt.to)
+            if (err != nil) != tt.wantErr {
+                t.Fatalf("TransitionTo(%q) error = %v, want error %v", tt.to, err, tt.wantErr)
+            }
+            want := tt.to
+            if tt.wantErr {
+                want = tt.from
+            }
+            if order.Status != want {
+                t.Errorf("status = %q, want %q", order.Status, want)
+            }
+        })
+    }
+}
@@ -0,0 +1,66 @@
+package domain
+
+import (
+    "errors"
+    "time"
+)
+
+// ErrOrderVersionConflict is returned when an amendment was made against a
+// version of the order that is no longer current
+var ErrOrderVersionConflict = errors.New("order has changed since the version being amended")
+
+// OrderVersion is the terms of an order as of one version. An order starts at
+// version 1 and every amendment stores the terms it replaced and the terms it
+// set, so the history shows what the order looked like at each step.
+type OrderVersion struct {
+    OrderID           int         `json:"order_id" db:"order_id"`
+    Version           int         `json:"version" db:"version"`
+    Quantity          int         `json:"quantity" db:"quantity"`
+    ExecutedQuantity  int         `json:"executed_quantity" db:"executed_quantity"` // Filled when the version was recorded
+    RemainingQuantity int         `json:"remaining_quantity" db:"remaining_quantity"`
+    Price             *float64    `json:"price,omitempty" db:"price"`
+    StopPrice         *float64    `json:"stop_price,omitempty" db:"stop_price"`
+    TrailingAmount    *float64    `json:"trailing_amount,omitempty" db:"trailing_amount"`
+    TrailingPercent   *float64    `json:"trailing_percent,omitempty" db:"trailing_percent"`
+    TimeInForce       TimeInForce `json:"time_in_force" db:"time_in_force"`
+    ExpiresAt         *time.Time  `json:"expires_at,omitempty" db:"expires_at"`
+    PriorityAt        time.Time   `json:"priority_at" db:"priority_at"`
+    Actor             OrderActor  `json:"actor" db:"actor"`
+    CreatedAt         time.Time   `json:"created_at" db:"created_at"`
+}
+
+// NewOrderVersion snapshots the order's current terms
+func NewOrderVersion(o *Order, actor OrderActor) OrderVersion {
+    return OrderVersion{
+        OrderID:           o.ID,
+        Version:           o.Version,
+        Quantity:          o.Quantity,
+        ExecutedQuantity:  o.ExecutedQuantity,
+        RemainingQuantity: o.RemainingQuantity,
+        Price:             o.Price,
+        StopPrice:         o.StopPrice,
+        TrailingAmount:    o.TrailingAmount,
+        TrailingPercent:   o.TrailingPercent,
+        TimeInForce:       o.TimeInForce,
+        ExpiresAt:         o.ExpiresAt,
+        PriorityAt:        o.PriorityAt,
+        Actor:             actor,
+    }
+}
+
+// KeepsPriority reports whether amended, a new version of o, keeps o's place
+// in the queue at its price. Only a higher price or a larger quantity sends
+// the order to the back; lowering either, or changing anything else, does not.
+func (o *Order) KeepsPriority(amended *Order) bool {
+    return !raised(o.Price, amended.Price) &&
+        !raised(o.StopPrice, amended.StopPrice) &&
+        amended.Quantity <= o.Quantity
+}
+
+// raised reports whether a price went up, or was set where there was none
+func raised(before, after *float64) bool {
+    if after == nil {
+        return false
+    }
+    return before == nil || *after > *before
+}
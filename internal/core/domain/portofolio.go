@@ -14,23 +14,41 @@ type Portfolio struct {
     UpdatedAt    time.Time `json:"updated_at" db:"updated_at"`
 }
 
+// PortfolioItem is one holding. Shorts carry a negative quantity; their cost
+// is the net proceeds and their value what buying back would cost. Held and
+// available quantities are share counts, positive on either side.
 type PortfolioItem struct {
-    StockSymbol   string  `json:"stock_symbol"`
-    StockName     string  `json:"stock_name"`
-    Quantity      int     `json:"quantity"`
-    AveragePrice  float64 `json:"average_price"`
-    CurrentPrice  float64 `json:"current_price"`
-    TotalCost     float64 `json:"total_cost"`
-    CurrentValue  float64 `json:"current_value"`
-    ProfitLoss    float64 `json:"profit_loss"`
-    ProfitLossPct float64 `json:"profit_loss_pct"`
+    StockSymbol       string  `json:"stock_symbol"`
+    StockName         string  `json:"stock_name"`
+    Side              string  `json:"side"`
+    Quantity          int     `json:"quantity"`
+    HeldQuantity      int     `json:"held_quantity"`      // Promised to working sells or covers
+    AvailableQuantity int     `json:"available_quantity"` // Free to sell or cover
+    AveragePrice      float64 `json:"average_price"`
+    CurrentPrice      float64 `json:"current_price"`
+    TotalCost         float64 `json:"total_cost"`
+    CurrentValue      float64 `json:"current_value"`
+    ProfitLoss        float64 `json:"profit_loss"`
+    ProfitLossPct     float64 `json:"profit_loss_pct"`
+    BorrowFees        float64 `json:"borrow_fees,omitempty"`
+    Collateral        float64 `json:"collateral,omitempty"`
 }
 
+const (
+    PositionSideLong  = "LONG"
+    PositionSideShort = "SHORT"
+)
+
+// PortfolioSummary totals the holdings. TotalValue is cost plus P&L, which for
+// longs is their market value.
 type PortfolioSummary struct {
     TotalValue     float64         `json:"total_value"`
     TotalCost      float64         `json:"total_cost"`
     TotalProfit    float64         `json:"total_profit"`
     TotalProfitPct float64         `json:"total_profit_pct"`
+    CashBalance    float64         `json:"cash_balance"`
+    HeldCash       float64         `json:"held_cash"`      // Set aside by working orders
+    AvailableCash  float64         `json:"available_cash"` // Cash less holds, never below zero
     Holdings       []PortfolioItem `json:"holdings"`
 }
 
@@ -20,6 +20,7 @@ const (
     MessageTypeTradeExecution  WebSocketMessageType = "TRADE_EXECUTION"
     MessageTypePositionUpdate  WebSocketMessageType = "POSITION_UPDATE"
     MessageTypeBalanceUpdate   WebSocketMessageType = "BALANCE_UPDATE"
+    MessageTypeMarginCall      WebSocketMessageType = "MARGIN_CALL"
     
     // Portfolio messages
     MessageTypePortfolioUpdate WebSocketMessageType = "PORTFOLIO_UPDATE"
@@ -0,0 +1,263 @@
+package domain
+
+import (
+    "fmt"
+    "math"
+    "sort"
+    "strings"
+)
+
+// RebalanceCashKey is the target that weights cash
+const RebalanceCashKey = "CASH"
+
+// RebalanceMode is how a rebalance basket copes with an order that fails
+type RebalanceMode string
+
+const (
+    RebalanceModeAllOrNone  RebalanceMode = "ALL_OR_NONE" // Settled as one unit: every trade fills in full, or none does
+    RebalanceModeBestEffort RebalanceMode = "BEST_EFFORT" // Sends every order and reports how each one did
+)
+
+// RebalanceStatus is where a rebalance ended up
+type RebalanceStatus string
+
+const (
+    RebalanceStatusPreview   RebalanceStatus = "PREVIEW"   // Dry run; nothing was sent
+    RebalanceStatusCompleted RebalanceStatus = "COMPLETED" // Every trade filled
+    RebalanceStatusPartial   RebalanceStatus = "PARTIAL"   // Best effort; some trades did not fill
+    RebalanceStatusAborted   RebalanceStatus = "ABORTED"   // All or none; a trade could not fill in full, so nothing traded
+)
+
+// RebalanceTradeStatus is what became of one trade in a rebalance
+type RebalanceTradeStatus string
+
+const (
+    RebalanceTradePlanned    RebalanceTradeStatus = "PLANNED"     // Would be sent
+    RebalanceTradeSkipped    RebalanceTradeStatus = "SKIPPED"     // Inside the tolerance band, or too small to trade
+    RebalanceTradeFilled     RebalanceTradeStatus = "FILLED"
+    RebalanceTradeWorking    RebalanceTradeStatus = "WORKING"     // Best effort; sent but not completely filled
+    RebalanceTradeRejected   RebalanceTradeStatus = "REJECTED"
+    RebalanceTradeRolledBack RebalanceTradeStatus = "ROLLED_BACK" // All or none; undone with the rest of the basket
+)
+
+// RebalanceRequest asks for the portfolio to be moved to target weights, in
+// percent of its total value, e.g. {"AAPL": 40, "MSFT": 30, "CASH": 30}.
+// Holdings missing from the targets are sold. Drifts of no more than
+// TolerancePercent points are left alone.
+type RebalanceRequest struct {
+    Targets          map[string]float64 `json:"targets" binding:"required"`
+    Mode             RebalanceMode      `json:"mode,omitempty"` // ALL_OR_NONE (default) or BEST_EFFORT
+    TolerancePercent float64            `json:"tolerance_percent,omitempty"`
+    DryRun           bool               `json:"dry_run,omitempty"`
+}
+
+// Weights checks the targets and splits them into stock weights, keyed by
+// upper-cased symbol, and the cash weight. Cash left out of the targets gets
+// whatever the stocks leave over.
+func (r *RebalanceRequest) Weights() (map[string]float64, float64, error) {
+    switch r.Mode {
+    case "":
+        r.Mode = RebalanceModeAllOrNone
+    case RebalanceModeAllOrNone, RebalanceModeBestEffort:
+    default:
+        return nil, 0, fmt.Errorf("invalid rebalance mode: %s", r.Mode)
+    }
+    if r.TolerancePercent < 0 || r.TolerancePercent >= 100 {
+        return nil, 0, fmt.Errorf("tolerance must be between 0 and 100 percent")
+    }
+    if len(r.Targets) == 0 {
+        return nil, 0, fmt.Errorf("at least one target weight is required")
+    }
+
+    weights := make(map[string]float64)
+    cashWeight, cashSet, total := 0.0, false, 0.0
+    for key, weight := range r.Targets {
+        if weight < 0 || weight > 100 {
+            return nil, 0, fmt.Errorf("weight of %s must be between 0 and 100 percent", key)
+        }
+        symbol := strings.ToUpper(strings.TrimSpace(key))
+        if symbol == "" {
+            return nil, 0, fmt.Errorf("target symbols cannot be empty")
+        }
+        if _, ok := weights[symbol]; ok || (cashSet && symbol == RebalanceCashKey) {
+            return nil, 0, fmt.Errorf("%s is targeted more than once", symbol)
+        }
+        if symbol == RebalanceCashKey {
+            cashWeight, cashSet = weight, true
+        } else {
+            weights[symbol] = weight
+        }
+        total += weight
+    }
+
+    if !cashSet {
+        cashWeight = 100 - total
+        total = 100
+    }
+    if math.Abs(total-100) > 0.01 || cashWeight < -0.01 {
+        return nil, 0, fmt.Errorf("target weights add up to %.2f%%, not 100%%", total)
+    }
+    return weights, math.Max(0, cashWeight), nil
+}
+
+// RebalanceTrade is the trade one symbol needs to reach its target weight
+type RebalanceTrade struct {
+    Symbol          string               `json:"symbol"`
+    Side            OrderSide            `json:"side,omitempty"`
+    Quantity        int                  `json:"quantity"`
+    Price           float64              `json:"price"`           // Current price the trade was sized at
+    EstimatedValue  float64              `json:"estimated_value"` // Quantity at Price, before costs
+    CurrentQuantity int                  `json:"current_quantity"`
+    CurrentWeight   float64              `json:"current_weight"`
+    TargetWeight    float64              `json:"target_weight"`
+    Status          RebalanceTradeStatus `json:"status"`
+    OrderID         *int                 `json:"order_id,omitempty"`
+    Reason          string               `json:"reason,omitempty"` // Why the trade was skipped or did not fill
+}
+
+// Rebalance is a rebalancing plan and, unless it was a dry run, how its
+// basket of orders went
+type Rebalance struct {
+    Mode             RebalanceMode    `json:"mode"`
+    Status           RebalanceStatus  `json:"status"`
+    DryRun           bool             `json:"dry_run"`
+    TotalValue       float64          `json:"total_value"`    // Cash plus holdings at current prices
+    Cash             float64          `json:"cash"`
+    CashWeight       float64          `json:"cash_weight"`
+    TargetCashWeight float64          `json:"target_cash_weight"`
+    ProjectedCash    float64          `json:"projected_cash"` // Cash once every planned trade fills at current prices, after costs
+    Trades           []RebalanceTrade `json:"trades"`         // Sells, then buys, then the trades skipped
+    Error            string           `json:"error,omitempty"` // Why the basket would be or was stopped
+}
+
+// RebalancePosition is a holding as the planner sees it: its size, the part
+// not held by working sells, and its current price
+type RebalancePosition struct {
+    Quantity  int
+    Available int
+    Price     float64
+}
+
+// PlanRebalance sizes the trades that move positions to the target weights.
+// positions must include every targeted symbol, held or not, for its price.
+// Everything is valued at current prices. Sells are capped at the shares
+// not held by working orders, and buys are paid for from cash not held by
+// working orders plus the sells' proceeds, trimmed if that falls short.
+// costs returns the commission and fees of a trade.
+func PlanRebalance(request *RebalanceRequest, weights map[string]float64, cashWeight float64,
+    positions map[string]RebalancePosition, cash, availableCash float64,
+    costs func(quantity int, price float64) float64) *Rebalance {
+
+    total := cash
+    symbols := make([]string, 0, len(positions))
+    for symbol, position := range positions {
+        total += float64(position.Quantity) * position.Price
+        symbols = append(symbols, symbol)
+    }
+    sort.Strings(symbols)
+
+    rebalance := &Rebalance{
+        Mode:             request.Mode,
+        Status:           RebalanceStatusPreview,
+        DryRun:           request.DryRun,
+        TotalValue:       roundCents(total),
+        Cash:             roundCents(cash),
+        TargetCashWeight: cashWeight,
+    }
+    if total > 0 {
+        rebalance.CashWeight = roundWeight(cash / total * 100)
+    }
+
+    var sells, buys, skipped []RebalanceTrade
+    for _, symbol := range symbols {
+        position := positions[symbol]
+        value := float64(position.Quantity) * position.Price
+        trade := RebalanceTrade{
+            Symbol:          symbol,
+            Price:           position.Price,
+            CurrentQuantity: position.Quantity,
+            TargetWeight:    weights[symbol],
+            Status:          RebalanceTradePlanned,
+        }
+        if total > 0 {
+            trade.CurrentWeight = roundWeight(value / total * 100)
+        }
+
+        if math.Abs(trade.TargetWeight-trade.CurrentWeight) <= request.TolerancePercent {
+            trade.Status, trade.Reason = RebalanceTradeSkipped, "within tolerance"
+            skipped = append(skipped, trade)
+            continue
+        }
+
+        diff := total*trade.TargetWeight/100 - value
+        if diff < 0 {
+            trade.Side = OrderSideSell
+            trade.Quantity = int(math.Floor(-diff / position.Price))
+            if trade.TargetWeight == 0 {
+                trade.Quantity = position.Quantity
+            }
+            if trade.Quantity > position.Available {
+                trade.Quantity = position.Available
+                trade.Reason = fmt.Sprintf("%d shares held by working orders", position.Quantity-position.Available)
+            }
+        } else {
+            trade.Side = OrderSideBuy
+            trade.Quantity = int(math.Floor(diff / position.Price))
+        }
+
+        if trade.Quantity <= 0 {
+            trade.Side, trade.Quantity, trade.Status = "", 0, RebalanceTradeSkipped
+            if trade.Reason == "" {
+                trade.Reason = "less than one share to trade"
+            }
+            skipped = append(skipped, trade)
+            continue
+        }
+        if trade.Side == OrderSideSell {
+            sells = append(sells, trade)
+        } else {
+            buys = append(buys, trade)
+        }
+    }
+
+    budget := math.Max(0, availableCash)
+    projected := cash
+    for i := range sells {
+        sell := &sells[i]
+        sell.EstimatedValue = roundCents(float64(sell.Quantity) * sell.Price)
+        proceeds := sell.EstimatedValue - costs(sell.Quantity, sell.Price)
+        budget += proceeds
+        projected += proceeds
+    }
+
+    planned := make([]RebalanceTrade, 0, len(buys))
+    for _, buy := range buys {
+        cost := func(quantity int) float64 { return float64(quantity)*buy.Price + costs(quantity, buy.Price) }
+        buy.Quantity = min(buy.Quantity, int(math.Floor(budget/buy.Price)))
+        for buy.Quantity > 0 && cost(buy.Quantity) > budget {
+            buy.Quantity--
+        }
+        if buy.Quantity <= 0 {
+            buy.Quantity, buy.Side, buy.Status, buy.Reason = 0, "", RebalanceTradeSkipped, "not enough cash"
+            skipped = append(skipped, buy)
+            continue
+        }
+
+        buy.EstimatedValue = roundCents(float64(buy.Quantity) * buy.Price)
+        budget -= cost(buy.Quantity)
+        projected -= cost(buy.Quantity)
+        planned = append(planned, buy)
+    }
+
+    rebalance.ProjectedCash = roundCents(projected)
+    rebalance.Trades = append(append(sells, planned...), skipped...)
+    return rebalance
+}
+
+func roundCents(value float64) float64 {
+    return math.Round(value*100) / 100
+}
+
+func roundWeight(percent float64) float64 {
+    return math.Round(percent*100) / 100
+}
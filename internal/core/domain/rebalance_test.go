@@ -0,0 +1,207 @@
+package domain
+
+import (
+    "reflect"
+    "testing"
+)
+
+func TestRebalanceRequestWeights(t *testing.T) {
+    tests := []struct {
+        name        string
+        request     RebalanceRequest
+        wantWeights map[string]float64
+        wantCash    float64
+        wantErr     bool
+    }{
+        {
+            name:        "cash takes what is left over",
+            request:     RebalanceRequest{Targets: map[string]float64{"aapl": 40, " msft ": 30}},
+            wantWeights: map[string]float64{"AAPL": 40, "MSFT": 30},
+            wantCash:    30,
+        },
+        {
+            name:        "explicit cash",
+            request:     RebalanceRequest{Targets: map[string]float64{"AAPL": 75, "cash": 25}},
+            wantWeights: map[string]float64{"AAPL": 75},
+            wantCash:    25,
+        },
+        {name: "no targets", request: RebalanceRequest{}, wantErr: true},
+        {name: "over 100 percent", request: RebalanceRequest{Targets: map[string]float64{"AAPL": 60, "MSFT": 50}}, wantErr: true},
+        {name: "explicit weights short of 100", request: RebalanceRequest{Targets: map[string]float64{"AAPL": 60, "CASH": 30}}, wantErr: true},
+        {name: "negative weight", request: RebalanceRequest{Targets: map[string]float64{"AAPL": -10}}, wantErr: true},
+        {name: "symbol twice", request: RebalanceRequest{Targets: map[string]float64{"AAPL": 10, "aapl": 10}}, wantErr: true},
+        {name: "empty symbol", request: RebalanceRequest{Targets: map[string]float64{" ": 10}}, wantErr: true},
+        {name: "unknown mode", request: RebalanceRequest{Targets: map[string]float64{"AAPL": 10}, Mode: "SOME"}, wantErr: true},
+        {name: "tolerance out of range", request: RebalanceRequest{Targets: map[string]float64{"AAPL": 10}, TolerancePercent: 100}, wantErr: true},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            weights, cash, err := tt.request.Weights()
+            if (err != nil) != tt.wantErr {
+                t.Fatalf("Weights() error = %v, want error %v", err, tt.wantErr)
+            }
+            if tt.wantErr {
+                return
+            }
+            if !reflect.DeepEqual(weights, tt.wantWeights) || cash != tt.wantCash {
+                t.Errorf("Weights() = %v, %v, want %v, %v", weights, cash, tt.wantWeights, tt.wantCash)
+            }
+            if tt.request.Mode != RebalanceModeAllOrNone {
+                t.Errorf("Mode = %q, want the %q default", tt.request.Mode, RebalanceModeAllOrNone)
+            }
+        })
+    }
+}
+
+func TestPlanRebalance(t *testing.T) {
+    noCosts := func(int, float64) float64 { return 0 }
+    flatCosts := func(int, float64) float64 { return 1 }
+
+    // want is what the test checks of each trade, in the order planned
+    type want struct {
+        Symbol   string
+        Side     OrderSide
+        Quantity int
+        Status   RebalanceTradeStatus
+        Reason   string
+    }
+
+    tests := []struct {
+        name          string
+        tolerance     float64
+        weights       map[string]float64
+        cashWeight    float64
+        positions     map[string]RebalancePosition
+        cash          float64
+        availableCash float64
+        costs         func(int, float64) float64
+        wantTrades    []want
+        wantProjected float64
+    }{
+        {
+            name:       "sells first, then buys, then skipped",
+            weights:    map[string]float64{"AAPL": 50, "MSFT": 50},
+            positions: map[string]RebalancePosition{
+                "AAPL": {Quantity: 10, Available: 10, Price: 100},
+                "GOOG": {Quantity: 0, Available: 0, Price: 10},
+                "MSFT": {Quantity: 0, Available: 0, Price: 50},
+            },
+            costs: noCosts,
+            wantTrades: []want{
+                {"AAPL", OrderSideSell, 5, RebalanceTradePlanned, ""},
+                {"MSFT", OrderSideBuy, 10, RebalanceTradePlanned, ""},
+                {"GOOG", "", 0, RebalanceTradeSkipped, "within tolerance"},
+            },
+        },
+        {
+            name:       "drift within tolerance is left alone",
+            tolerance:  5,
+            weights:    map[string]float64{"AAPL": 54},
+            cashWeight: 46,
+            positions: map[string]RebalancePosition{
+                "AAPL": {Quantity: 10, Available: 10, Price: 100},
+            },
+            cash:          1000,
+            availableCash: 1000,
+            costs:         noCosts,
+            wantTrades: []want{
+                {"AAPL", "", 0, RebalanceTradeSkipped, "within tolerance"},
+            },
+            wantProjected: 1000,
+        },
+        {
+            name:       "drift beyond tolerance trades",
+            tolerance:  5,
+            weights:    map[string]float64{"AAPL": 60},
+            cashWeight: 40,
+            positions: map[string]RebalancePosition{
+                "AAPL": {Quantity: 10, Available: 10, Price: 100},
+            },
+            cash:          1000,
+            availableCash: 1000,
+            costs:         noCosts,
+            wantTrades: []want{
+                {"AAPL", OrderSideBuy, 2, RebalanceTradePlanned, ""},
+            },
+            wantProjected: 800,
+        },
+        {
+            name:       "buys trimmed to the sells' proceeds after costs",
+            weights:    map[string]float64{"AAPL": 50, "MSFT": 50},
+            positions: map[string]RebalancePosition{
+                "AAPL": {Quantity: 10, Available: 10, Price: 100},
+                "MSFT": {Quantity: 0, Available: 0, Price: 50},
+            },
+            costs: flatCosts,
+            wantTrades: []want{
+                {"AAPL", OrderSideSell, 5, RebalanceTradePlanned, ""},
+                {"MSFT", OrderSideBuy, 9, RebalanceTradePlanned, ""},
+            },
+            wantProjected: 48,
+        },
+        {
+            name:       "cash held by working orders cannot pay for buys",
+            weights:    map[string]float64{"AAPL": 100},
+            positions: map[string]RebalancePosition{
+                "AAPL": {Quantity: 0, Available: 0, Price: 100},
+            },
+            cash:          1000,
+            availableCash: 0,
+            costs:         noCosts,
+            wantTrades: []want{
+                {"AAPL", "", 0, RebalanceTradeSkipped, "not enough cash"},
+            },
+            wantProjected: 1000,
+        },
+        {
+            name:       "sells capped at the shares not held",
+            cashWeight: 100,
+            positions: map[string]RebalancePosition{
+                "AAPL": {Quantity: 10, Available: 4, Price: 100},
+            },
+            costs: noCosts,
+            wantTrades: []want{
+                {"AAPL", OrderSideSell, 4, RebalanceTradePlanned, "6 shares held by working orders"},
+            },
+            wantProjected: 400,
+        },
+        {
+            name:       "less than one share",
+            weights:    map[string]float64{"AAPL": 10},
+            cashWeight: 90,
+            positions: map[string]RebalancePosition{
+                "AAPL": {Quantity: 0, Available: 0, Price: 600},
+            },
+            cash:          1000,
+            availableCash: 1000,
+            costs:         noCosts,
+            wantTrades: []want{
+                {"AAPL", "", 0, RebalanceTradeSkipped, "less than one share to trade"},
+            },
+            wantProjected: 1000,
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            request := &RebalanceRequest{Mode: RebalanceModeAllOrNone, TolerancePercent: tt.tolerance}
+            rebalance := PlanRebalance(request, tt.weights, tt.cashWeight, tt.positions,
+                tt.cash, tt.availableCash, tt.costs)
+
+            var got []want
+            for _, trade := range rebalance.Trades {
+                got = append(got, want{trade.Symbol, trade.Side, trade.Quantity, trade.Status, trade.Reason})
+            }
+            if !reflect.DeepEqual(got, tt.wantTrades) {
+                t.Errorf("trades = %+v, want %+v", got, tt.wantTrades)
+            }
+            if rebalance.ProjectedCash != tt.wantProjected {
+                t.Errorf("ProjectedCash = %v, want %v", rebalance.ProjectedCash, tt.wantProjected)
+            }
+            if rebalance.Status != RebalanceStatusPreview {
+                t.Errorf("Status = %q, want %q", rebalance.Status, RebalanceStatusPreview)
+            }
+        })
+    }
+}
@@ -0,0 +1,141 @@
+package domain
+
+import (
+    "fmt"
+    "math"
+    "time"
+)
+
+// RiskReasonCode identifies the pre-trade check that rejected an order
+type RiskReasonCode string
+
+const (
+    RiskReasonOrderNotional      RiskReasonCode = "MAX_ORDER_NOTIONAL"
+    RiskReasonConcentration      RiskReasonCode = "MAX_POSITION_CONCENTRATION"
+    RiskReasonDailyOrderCount    RiskReasonCode = "MAX_ORDERS_PER_DAY"
+    RiskReasonDailyLoss          RiskReasonCode = "DAILY_LOSS_LIMIT"
+    RiskReasonPriceBand          RiskReasonCode = "PRICE_OUTSIDE_BAND"
+    RiskReasonInsufficientFunds  RiskReasonCode = "INSUFFICIENT_BUYING_POWER"
+    RiskReasonInsufficientShares RiskReasonCode = "INSUFFICIENT_SHARES"
+)
+
+// RiskLimits are the pre-trade limits orders are checked against. A zero
+// limit is not enforced.
+type RiskLimits struct {
+    MaxOrderNotional         float64 `json:"max_order_notional"`
+    MaxPositionConcentration float64 `json:"max_position_concentration"` // Share of equity one symbol may reach, 0-1
+    MaxOrdersPerDay          int     `json:"max_orders_per_day"`
+    DailyLossLimit           float64 `json:"daily_loss_limit"`           // Realized loss since the last session close
+    PriceBandPercent         float64 `json:"price_band_percent"`         // How far a limit price may be from the current price
+}
+
+// RiskLimitOverrides are the limits set for one user. Unset fields fall back
+// to the global limits.
+type RiskLimitOverrides struct {
+    UserID                   int       `json:"user_id" db:"user_id"`
+    MaxOrderNotional         *float64  `json:"max_order_notional,omitempty" db:"max_order_notional"`
+    MaxPositionConcentration *float64  `json:"max_position_concentration,omitempty" db:"max_position_concentration"`
+    MaxOrdersPerDay          *int      `json:"max_orders_per_day,omitempty" db:"max_orders_per_day"`
+    DailyLossLimit           *float64  `json:"daily_loss_limit,omitempty" db:"daily_loss_limit"`
+    PriceBandPercent         *float64  `json:"price_band_percent,omitempty" db:"price_band_percent"`
+    UpdatedAt                time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// WithOverrides returns the limits with a user's overrides applied
+func (l RiskLimits) WithOverrides(overrides *RiskLimitOverrides) RiskLimits {
+    if overrides == nil {
+        return l
+    }
+    if overrides.MaxOrderNotional != nil {
+        l.MaxOrderNotional = *overrides.MaxOrderNotional
+    }
+    if overrides.MaxPositionConcentration != nil {
+        l.MaxPositionConcentration = *overrides.MaxPositionConcentration
+    }
+    if overrides.MaxOrdersPerDay != nil {
+        l.MaxOrdersPerDay = *overrides.MaxOrdersPerDay
+    }
+    if overrides.DailyLossLimit != nil {
+        l.DailyLossLimit = *overrides.DailyLossLimit
+    }
+    if overrides.PriceBandPercent != nil {
+        l.PriceBandPercent = *overrides.PriceBandPercent
+    }
+    return l
+}
+
+// ValidateAgainst checks overrides a user sets for themselves only tighten
+// the global limits: each must be positive and no looser than its global
+// counterpart
+func (o *RiskLimitOverrides) ValidateAgainst(global RiskLimits) error {
+    if err := tighterThan("max_order_notional", o.MaxOrderNotional, global.MaxOrderNotional); err != nil {
+        return err
+    }
+    if err := tighterThan("max_position_concentration", o.MaxPositionConcentration, global.MaxPositionConcentration); err != nil {
+        return err
+    }
+    if o.MaxPositionConcentration != nil && *o.MaxPositionConcentration > 1 {
+        return fmt.Errorf("max_position_concentration is a share of equity and cannot exceed 1")
+    }
+    if o.MaxOrdersPerDay != nil {
+        maxOrders := float64(*o.MaxOrdersPerDay)
+        if err := tighterThan("max_orders_per_day", &maxOrders, float64(global.MaxOrdersPerDay)); err != nil {
+            return err
+        }
+    }
+    if err := tighterThan("daily_loss_limit", o.DailyLossLimit, global.DailyLossLimit); err != nil {
+        return err
+    }
+    return tighterThan("price_band_percent", o.PriceBandPercent, global.PriceBandPercent)
+}
+
+func tighterThan(field string, value *float64, global float64) error {
+    if value == nil {
+        return nil
+    }
+    if *value <= 0 {
+        return fmt.Errorf("%s must be positive", field)
+    }
+    if global > 0 && *value > global {
+        return fmt.Errorf("%s cannot exceed the global limit of %g", field, global)
+    }
+    return nil
+}
+
+// RiskRejection is an order refused by a pre-trade risk check
+type RiskRejection struct {
+    Code    RiskReasonCode `json:"code"`
+    Message string         `json:"message"`
+    Limit   float64        `json:"limit"`
+}
+
+func NewRiskRejection(code RiskReasonCode, limit float64, format string, args ...interface{}) *RiskRejection {
+    return &RiskRejection{Code: code, Message: fmt.Sprintf(format, args...), Limit: limit}
+}
+
+func (r *RiskRejection) Error() string {
+    return fmt.Sprintf("%s: %s", r.Code, r.Message)
+}
+
+// IsOpening reports whether the order adds to a position (BUY or SHORT)
+// rather than closing one
+func (o *Order) IsOpening() bool {
+    return o.Side == OrderSideBuy || o.Side == OrderSideShort
+}
+
+// ReferencePrice is the price an order is expected to trade at: its limit,
+// else its stop, else the market price at placement
+func (o *Order) ReferencePrice() float64 {
+    if o.Price != nil {
+        return *o.Price
+    }
+    if o.StopPrice != nil {
+        return *o.StopPrice
+    }
+    return o.MarketPrice
+}
+
+// Notional is the order's value at its reference price
+func (o *Order) Notional() float64 {
+    return math.Round(float64(o.Quantity)*o.ReferencePrice()*100) / 100
+}
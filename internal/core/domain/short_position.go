@@ -0,0 +1,93 @@
+package domain
+
+import (
+    "math"
+    "time"
+)
+
+const (
+    // ShortInitialMarginRate is the cash a new short must post on top of its
+    // proceeds, as a share of the value sold (Reg T's 150%)
+    ShortInitialMarginRate = 0.50
+
+    // ShortMaintenanceMarginRate is the equity a short must keep as a share
+    // of what it would cost to buy back
+    ShortMaintenanceMarginRate = 0.30
+
+    // ShortBorrowRate is the annual fee for borrowing shares, charged daily on
+    // the position's market value over a 360 day year
+    ShortBorrowRate = 0.03
+)
+
+// ShortPosition is a user's open short in one symbol. The sale proceeds are
+// never spendable; together with the initial margin they are held as
+// collateral until the shares are bought back.
+type ShortPosition struct {
+    ID                int       `json:"id" db:"id"`
+    UserID            int       `json:"user_id" db:"user_id"`
+    StockSymbol       string    `json:"stock_symbol" db:"stock_symbol"`
+    Quantity          int       `json:"quantity" db:"quantity"`
+    AveragePrice      float64   `json:"average_price" db:"average_price"`             // Net proceeds per share
+    Proceeds          float64   `json:"proceeds" db:"proceeds"`                       // Net of commission and fees
+    Collateral        float64   `json:"collateral" db:"collateral"`                   // Proceeds plus initial margin
+    AccruedBorrowFees float64   `json:"accrued_borrow_fees" db:"accrued_borrow_fees"` // Owed, settled on cover
+    LastAccrualAt     time.Time `json:"last_accrual_at" db:"last_accrual_at"`
+    CreatedAt         time.Time `json:"created_at" db:"created_at"`
+    UpdatedAt         time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// ShortInitialMargin is the cash a short sale of value locks up besides its
+// proceeds
+func ShortInitialMargin(value float64) float64 {
+    return math.Round(value*ShortInitialMarginRate*100) / 100
+}
+
+// Add books a further short sale of quantity shares with the given net
+// proceeds and initial margin
+func (p *ShortPosition) Add(quantity int, proceeds, margin float64) {
+    p.Quantity += quantity
+    p.Proceeds += proceeds
+    p.Collateral += proceeds + margin
+    p.AveragePrice = p.Proceeds / float64(p.Quantity)
+}
+
+// Release takes quantity shares off the position and returns their share of
+// the proceeds, collateral and accrued fees
+func (p *ShortPosition) Release(quantity int) (proceeds, collateral, fees float64) {
+    ratio := float64(quantity) / float64(p.Quantity)
+    proceeds = math.Round(p.Proceeds*ratio*100) / 100
+    collateral = math.Round(p.Collateral*ratio*100) / 100
+    fees = math.Round(p.AccruedBorrowFees*ratio*100) / 100
+
+    p.Quantity -= quantity
+    p.Proceeds -= proceeds
+    p.Collateral -= collateral
+    p.AccruedBorrowFees -= fees
+    if p.Quantity > 0 {
+        p.AveragePrice = p.Proceeds / float64(p.Quantity)
+    }
+    return proceeds, collateral, fees
+}
+
+// MarketValue is what buying the shares back would cost at price
+func (p *ShortPosition) MarketValue(price float64) float64 {
+    return float64(p.Quantity) * price
+}
+
+// UnrealizedPnL is the gain if the short were covered at price, after the
+// borrow fees owed so far
+func (p *ShortPosition) UnrealizedPnL(price float64) float64 {
+    return p.Proceeds - p.MarketValue(price) - p.AccruedBorrowFees
+}
+
+// Equity is the collateral left once the shares are bought back and the
+// borrow fees paid
+func (p *ShortPosition) Equity(price float64) float64 {
+    return p.Collateral - p.MarketValue(price) - p.AccruedBorrowFees
+}
+
+// IsBelowMaintenance reports a margin call: equity under the maintenance
+// share of the market value
+func (p *ShortPosition) IsBelowMaintenance(price float64) bool {
+    return p.Equity(price) < p.MarketValue(price)*ShortMaintenanceMarginRate
+}
@@ -1,6 +1,7 @@
 package domain
 
 import (
+    "math"
     "time"
 )
 
@@ -23,6 +24,71 @@ type StockPrice struct {
     UpdatedAt time.Time `json:"updated_at"`
 }
 
+// Quote is the simulated top of book for a symbol
+type Quote struct {
+    Symbol    string    `json:"symbol"`
+    Last      float64   `json:"last"`
+    Bid       float64   `json:"bid"`
+    Ask       float64   `json:"ask"`
+    BidSize   int       `json:"bid_size"`
+    AskSize   int       `json:"ask_size"`
+    UpdatedAt time.Time `json:"updated_at"`
+}
+
+// NewQuote places a bid and ask around last. The spread widens for cheap
+// stocks, where a one cent tick is a larger share of the price, and with the
+// symbol's recent volatility (the standard deviation of its returns, in
+// percent).
+func NewQuote(symbol string, last, volatility float64, size int) Quote {
+    spread := math.Round(last*quoteSpreadRate(last)*(1+math.Max(0, volatility))*100) / 100
+    if spread < 0.01 {
+        spread = 0.01
+    }
+
+    bid := math.Round((last-spread/2)*100) / 100
+    if bid < 0.01 {
+        bid = 0.01
+    }
+
+    return Quote{
+        Symbol:    symbol,
+        Last:      last,
+        Bid:       bid,
+        Ask:       math.Round((bid+spread)*100) / 100,
+        BidSize:   size,
+        AskSize:   size,
+        UpdatedAt: time.Now(),
+    }
+}
+
+// quoteSpreadRate is the calm-market spread as a fraction of the price
+func quoteSpreadRate(price float64) float64 {
+    switch {
+    case price < 10:
+        return 0.0020
+    case price < 50:
+        return 0.0010
+    case price < 1000:
+        return 0.0005
+    default:
+        return 0.0003
+    }
+}
+
+// Spread is the distance between the ask and the bid
+func (q Quote) Spread() float64 {
+    return math.Round((q.Ask-q.Bid)*100) / 100
+}
+
+// PriceFor returns the side of the quote a taker trades against: buyers pay
+// the ask and sellers receive the bid
+func (q Quote) PriceFor(buy bool) float64 {
+    if buy {
+        return q.Ask
+    }
+    return q.Bid
+}
+
 // Historical price data for charting
 type HistoricalPrice struct {
     ID        int       `json:"id" db:"id"`
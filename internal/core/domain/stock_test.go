@@ -0,0 +1,71 @@
+package domain
+
+import "testing"
+
+func TestNewQuote(t *testing.T) {
+    tests := []struct {
+        name       string
+        last       float64
+        volatility float64
+        wantBid    float64
+        wantAsk    float64
+    }{
+        {"calm large cap", 200, 0, 199.95, 200.05},
+        {"volatile large cap quotes wider", 200, 1, 199.90, 200.10},
+        {"cheap stock quotes a wider share of its price", 5, 0, 5.00, 5.01},
+        {"spread is at least a cent", 1, 0, 1.00, 1.01},
+        {"negative volatility counts as calm", 200, -1, 199.95, 200.05},
+        {"bid never below a cent", 0.01, 0, 0.01, 0.02},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            quote := NewQuote("AAPL", tt.last, tt.volatility, 100)
+            if quote.Bid != tt.wantBid || quote.Ask != tt.wantAsk {
+                t.Errorf("NewQuote() = %v/%v, want %v/%v", quote.Bid, quote.Ask, tt.wantBid, tt.wantAsk)
+            }
+            if quote.BidSize != 100 || quote.AskSize != 100 {
+                t.Errorf("sizes = %d/%d, want 100/100", quote.BidSize, quote.AskSize)
+            }
+        })
+    }
+}
+
+func TestVolatility(t *testing.T) {
+    prices := func(closes ...float64) []HistoricalPrice {
+        out := make([]HistoricalPrice, len(closes))
+        for i, c := range closes {
+            out[i] = HistoricalPrice{Close: c}
+        }
+        return out
+    }
+
+    tests := []struct {
+        name        string
+        prices      []HistoricalPrice
+        wantReturns []float64
+        want        float64
+    }{
+        {"no history", nil, nil, 0},
+        {"one return", prices(101, 100), []float64{1}, 0},
+        {"steady returns", prices(102, 101, 100), []float64{1, 0.9900990099009901}, 0.007001057239470774},
+        {"swinging returns", prices(100, 110, 100), []float64{10, -9.090909090909092}, 13.499311277197727},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            returns := PriceReturns(tt.prices)
+            if len(returns) != len(tt.wantReturns) {
+                t.Fatalf("PriceReturns() = %v, want %v", returns, tt.wantReturns)
+            }
+            for i := range returns {
+                if diff := returns[i] - tt.wantReturns[i]; diff > 1e-9 || diff < -1e-9 {
+                    t.Fatalf("PriceReturns() = %v, want %v", returns, tt.wantReturns)
+                }
+            }
+            if got := Volatility(returns); got-tt.want > 1e-9 || tt.want-got > 1e-9 {
+                t.Errorf("Volatility() = %v, want %v", got, tt.want)
+            }
+        })
+    }
+}
@@ -1,14 +1,17 @@
 package domain
 
 import (
+    "fmt"
     "time"
 )
 
 type TransactionType string
 
 const (
-    TransactionTypeBuy  TransactionType = "BUY"
-    TransactionTypeSell TransactionType = "SELL"
+    TransactionTypeBuy   TransactionType = "BUY"
+    TransactionTypeSell  TransactionType = "SELL"
+    TransactionTypeShort TransactionType = "SHORT"
+    TransactionTypeCover TransactionType = "COVER"
 )
 
 type Transaction struct {
@@ -18,10 +21,56 @@ type Transaction struct {
     Type        TransactionType `json:"type" db:"transaction_type"`
     Quantity    int             `json:"quantity" db:"quantity"`
     Price       float64         `json:"price" db:"price"`
-    TotalAmount float64         `json:"total_amount" db:"total_amount"`
+    BidPrice    *float64        `json:"bid_price,omitempty" db:"bid_price"` // Quote a direct trade was priced against
+    AskPrice    *float64        `json:"ask_price,omitempty" db:"ask_price"`
+    TotalAmount float64         `json:"total_amount" db:"total_amount"` // Cash actually debited or credited, costs included
+    Commission  float64         `json:"commission" db:"commission"`
+    Fees        float64         `json:"fees" db:"fees"`
+    OrderID     *int            `json:"order_id,omitempty" db:"order_id"`
+    RealizedPnL float64         `json:"realized_pnl" db:"realized_pnl"` // Set on sells and covers, net of costs
     CreatedAt   time.Time       `json:"created_at" db:"created_at"`
 }
 
+// TradeFill is a single execution to be settled against a user's cash and
+// position at an explicit price, with its commission and fees
+type TradeFill struct {
+    UserID      int
+    StockSymbol string
+    Type        TransactionType
+    Quantity    int
+    Price       float64
+    BidPrice    *float64 // Quote the price was taken from, for direct trades
+    AskPrice    *float64
+    Commission  float64
+    Fees        float64
+    OrderID     *int
+}
+
+// GrossAmount is the fill's value before commission and fees
+func (f *TradeFill) GrossAmount() float64 {
+    return float64(f.Quantity) * f.Price
+}
+
+// Costs is the commission and fees charged on the fill
+func (f *TradeFill) Costs() float64 {
+    return f.Commission + f.Fees
+}
+
+// SettlementRefusal is a fill the user's cash or position cannot settle.
+// Retrying it will not help, unlike a fill that failed on a lock timeout or a
+// lost connection.
+type SettlementRefusal struct {
+    Message string
+}
+
+func RefuseSettlement(format string, args ...interface{}) *SettlementRefusal {
+    return &SettlementRefusal{Message: fmt.Sprintf(format, args...)}
+}
+
+func (r *SettlementRefusal) Error() string {
+    return r.Message
+}
+
 type TransactionRequest struct {
     StockSymbol string `json:"stock_symbol" binding:"required"`
     Quantity    int    `json:"quantity" binding:"required,min=1"`
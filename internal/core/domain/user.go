@@ -27,10 +27,12 @@ type UserLogin struct {
 }
 
 type UserProfile struct {
-    ID          int     `json:"id"`
-    Username    string  `json:"username"`
-    Email       string  `json:"email"`
-    Balance     float64 `json:"balance"`
-    TotalProfit float64 `json:"total_profit"`
-    Rank        int     `json:"rank,omitempty"`
+    ID               int      `json:"id"`
+    Username         string   `json:"username"`
+    Email            string   `json:"email"`
+    Balance          float64  `json:"balance"`
+    HeldBalance      *float64 `json:"held_balance,omitempty"`      // Set aside by working orders
+    AvailableBalance *float64 `json:"available_balance,omitempty"` // Balance less holds, never below zero
+    TotalProfit      float64  `json:"total_profit"`
+    Rank             int      `json:"rank,omitempty"`
 }
\ No newline at end of file
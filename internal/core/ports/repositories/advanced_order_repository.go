@@ -9,6 +9,8 @@ type AdvancedOrderRepository interface {
 	// Basic CRUD operations
 	Create(order *domain.Order) error
 	GetByID(id int) (*domain.Order, error)
+	GetByIDForUpdate(id int) (*domain.Order, error) // Locks the row; use inside a UnitOfWork
+	GetByIDs(ids []int) ([]domain.Order, error) // Missing IDs are left out
 	Update(order *domain.Order) error
 	Delete(id int) error
 	
@@ -20,6 +22,7 @@ type AdvancedOrderRepository interface {
 	
 	// Active orders management
 	GetActiveOrders() ([]domain.Order, error)
+	GetWorkingOrdersByUser(userID int) ([]domain.Order, error) // Active or paused; the orders holding cash and shares
 	GetPendingOrders() ([]domain.Order, error)
 	GetExpiredOrders() ([]domain.Order, error)
 	GetOrdersForExecution(symbol string, currentPrice float64) ([]domain.Order, error)
@@ -29,22 +32,45 @@ type AdvancedOrderRepository interface {
 	GetStopOrders(symbol string) ([]domain.Order, error)
 	GetTrailingStopOrders() ([]domain.Order, error)
 	GetOCOOrders(userID int) ([]domain.Order, error)
+	GetOCOGroup(groupID int) ([]domain.Order, error)
 	
 	// Order execution
 	ExecuteOrder(orderID int, executedPrice float64, executedQuantity int) error
 	PartialFillOrder(orderID int, filledQuantity int, filledPrice float64) error
-	CancelOrder(orderID int, reason string) error
-	ExpireOrder(orderID int) error
-	
-	// Order status updates
+	RecordExecution(execution *domain.OrderExecution) error
+	GetExecutions(orderID int) ([]domain.OrderExecution, error)
+	CancelOrder(orderID int, reason string, actor domain.OrderActor) error
+	ExpireOrder(orderID int, reason string) error
+	RejectOrder(orderID int, reason string) error
+	
+	// Order status updates; every status change is checked against the
+	// domain state machine and appended to the order's event log
 	UpdateStatus(orderID int, status domain.OrderStatus) error
+	ActivateOrder(orderID int, reason string) error // Wake a DORMANT order
+	GetEvents(orderID int) ([]domain.OrderEvent, error)
+	
+	// Amendments: a working order is amended in place, moving to its next
+	// version; the terms of every version are kept
+	Amend(current, amended *domain.Order) error
+	GetVersions(orderID int) ([]domain.OrderVersion, error)
 	UpdateExecutionDetails(orderID int, execution *domain.OrderExecution) error
 	
 	// OCO order management
 	CreateOCOOrders(parentOrder *domain.Order, linkedOrder *domain.Order) error
-	CancelLinkedOrders(orderID int) error
+	CancelLinkedOrders(orderID int, reason string) (int, error)
 	GetLinkedOrders(orderID int) ([]domain.Order, error)
 	
+	// Bracket order management
+	CreateBracketOrders(entryOrder *domain.Order, takeProfit *domain.Order, stopLoss *domain.Order) error
+	GetChildOrders(parentOrderID int) ([]domain.Order, error)
+	CancelChildOrders(parentOrderID int, reason string) (int, error)
+	
+	// Algo (TWAP/VWAP) order management: pausing a parent pauses its slices
+	PauseOrder(orderID int) error
+	ResumeOrder(orderID int) error
+	PauseChildOrders(parentOrderID int) (int, error)
+	ResumeChildOrders(parentOrderID int) (int, error)
+	
 	// Statistics and analytics
 	GetUserOrderStats(userID int) (*domain.OrderStats, error)
 	GetOrderStatsByDateRange(userID int, startDate, endDate time.Time) (*domain.OrderStats, error)
@@ -58,20 +84,23 @@ type AdvancedOrderRepository interface {
 	
 	// Validation and constraints
 	ValidateOrderConstraints(order *domain.Order) error
-	CheckDailyOrderLimit(userID int) (bool, error)
-	CheckOrderSizeLimit(userID int, symbol string, quantity int) (bool, error)
+	CheckDailyOrderLimit(userID int, since time.Time, maxOrders int) (bool, error) // False once the user placed maxOrders since then
+	CheckOrderSizeLimit(userID int, symbol string, side domain.OrderSide, quantity, maxQuantity int) (bool, error) // Counts the user's resting orders on that side
 	
 	// Trailing stop specific
-	UpdateTrailingStopPrice(orderID int, newStopPrice float64) error
+	UpdateTrailingStopPrice(orderID int, highWaterMark, newStopPrice float64) error
 	GetTrailingStopsToUpdate(priceUpdates map[string]float64) ([]domain.Order, error)
 	
+	// Stop-limit specific
+	TriggerStopLimit(orderID int, triggeredAt time.Time) (bool, error)
+	
 	// Commission and fees calculation
 	CalculateOrderCommission(userID int, order *domain.Order) (float64, error)
 	UpdateOrderCommission(orderID int, commission, fees float64) error
 	
 	// Market hours integration
 	GetOrdersAwaitingMarketOpen(marketCode string) ([]domain.Order, error)
-	GetOrdersToExpireAtMarketClose(marketCode string) ([]domain.Order, error)
+	GetOrdersToExpireAtMarketClose(sessionClose time.Time) ([]domain.Order, error)
 }
 
 // OrderSearchCriteria represents search criteria for orders
@@ -87,12 +116,26 @@ type OrderSearchCriteria struct {
 	MaxPrice     *float64               `json:"max_price,omitempty"`
 	MinQuantity  *int                   `json:"min_quantity,omitempty"`
 	MaxQuantity  *int                   `json:"max_quantity,omitempty"`
-	SortBy       string                 `json:"sort_by,omitempty"`       // created_at, price, quantity
-	SortOrder    string                 `json:"sort_order,omitempty"`    // ASC, DESC
+	SortBy       string                 `json:"sort_by,omitempty"`       // One of OrderSortFields; created_at by default
+	SortOrder    string                 `json:"sort_order,omitempty"`    // ASC, DESC (default)
 	Limit        int                    `json:"limit,omitempty"`
 	Offset       int                    `json:"offset,omitempty"`
 }
 
+// OrderSortFields are the fields an order search can sort by
+var OrderSortFields = map[string]bool{
+	"created_at":  true,
+	"updated_at":  true,
+	"executed_at": true,
+	"price":       true,
+	"quantity":    true,
+}
+
+const (
+	DefaultOrderSearchLimit = 20  // Page size when the criteria set none
+	MaxOrderSearchLimit     = 100 // Largest page a search returns
+)
+
 // OrderSearchResult represents the result of an order search
 type OrderSearchResult struct {
 	Orders     []domain.Order `json:"orders"`
@@ -112,9 +155,9 @@ type AdvancedOrderRepositoryWithSearch interface {
 	SearchOrdersBySymbol(symbol string, criteria *OrderSearchCriteria) (*OrderSearchResult, error)
 	Search(userID int, criteria *OrderSearchCriteria) (*OrderSearchResult, error)
 	
-	// Bulk operations
+	// Bulk operations, each in a single transaction
 	BulkUpdateStatus(orderIDs []int, status domain.OrderStatus) error
-	BulkCancelOrders(orderIDs []int, reason string) error
+	BulkCancelOrders(orderIDs []int, reason string, actor domain.OrderActor) ([]int, error) // Returns the IDs cancelled
 	BulkExecuteOrders(executions []domain.OrderExecution) error
 	
 	// Performance analytics
@@ -138,10 +181,22 @@ type OrderExecutionMetrics struct {
 	AverageExecutionTime float64 `json:"average_execution_time"` // in seconds
 	FillRate            float64 `json:"fill_rate"`              // percentage
 	AverageSlippage     float64 `json:"average_slippage"`       // in basis points
+	AveragePriceImprovement float64 `json:"average_price_improvement"` // in basis points versus the market price at submission
 	BestExecution       float64 `json:"best_execution"`         // best price improvement
 	WorstExecution      float64 `json:"worst_execution"`        // worst slippage
 	TotalCommission     float64 `json:"total_commission"`
 	TotalFees           float64 `json:"total_fees"`
+	ByOrderType         []OrderTypeMetrics `json:"by_order_type"`
+}
+
+// OrderTypeMetrics breaks execution metrics down for one order type
+type OrderTypeMetrics struct {
+	OrderType               domain.OrderType `json:"order_type"`
+	TotalOrders             int              `json:"total_orders"`
+	ExecutedOrders          int              `json:"executed_orders"`
+	FillRate                float64          `json:"fill_rate"`                 // percentage
+	AverageExecutionTime    float64          `json:"average_execution_time"`    // in seconds
+	AveragePriceImprovement float64          `json:"average_price_improvement"` // in basis points
 }
 
 // SlippageAnalysis represents slippage analysis for a symbol
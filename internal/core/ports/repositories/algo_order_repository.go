@@ -0,0 +1,14 @@
+package repositories
+
+import "stock-simulation-backend/internal/core/domain"
+
+type AlgoOrderRepository interface {
+	Create(params *domain.AlgoParams) error
+	GetByOrderID(orderID int) (*domain.AlgoParams, error)
+
+	// GetRunning returns the schedules of active, unpaused algo parents
+	GetRunning() ([]domain.AlgoParams, error)
+
+	// Update saves the schedule window and pause state
+	Update(params *domain.AlgoParams) error
+}
@@ -0,0 +1,31 @@
+package repositories
+
+import (
+	"time"
+
+	"stock-simulation-backend/internal/core/domain"
+)
+
+type ContingentOrderRepository interface {
+	Create(contingent *domain.ContingentOrder) error
+	GetByID(id int) (*domain.ContingentOrder, error)
+	GetByUserID(userID int) ([]domain.ContingentOrder, error)
+
+	// GetActive returns every contingent order still waiting on its conditions
+	GetActive() ([]domain.ContingentOrder, error)
+
+	// Update saves the conditions and order of an active contingent order;
+	// false means it is no longer active
+	Update(contingent *domain.ContingentOrder) (bool, error)
+
+	// Cancel retires an active contingent order; false means it is no longer active
+	Cancel(id int) (bool, error)
+
+	// MarkTriggered claims an active contingent order for submission and
+	// records the values that fired it; false means another tick claimed it
+	MarkTriggered(id int, conditions []domain.TriggerCondition, triggeredAt time.Time) (bool, error)
+
+	// RecordResult stores the order placed for a triggered contingent order,
+	// or the reason it could not be placed
+	RecordResult(id int, orderID *int, failureReason *string) error
+}
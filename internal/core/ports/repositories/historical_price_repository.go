@@ -32,4 +32,7 @@ type HistoricalPriceRepository interface {
 	
 	// Get available symbols with historical data
 	GetAvailableSymbols() ([]string, error)
+	
+	// Get traded volume by hour of day since the given time
+	GetHourlyVolume(symbol string, since time.Time) (map[int]int64, error)
 } 
\ No newline at end of file
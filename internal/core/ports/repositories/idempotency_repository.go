@@ -0,0 +1,17 @@
+package repositories
+
+import "stock-simulation-backend/internal/core/domain"
+
+type IdempotencyRepository interface {
+	// Reserve stores an in-flight record; false means the key is already taken
+	Reserve(record *domain.IdempotencyRecord) (bool, error)
+
+	// Get returns the user's record for key, or nil if there is none
+	Get(userID int, key string) (*domain.IdempotencyRecord, error)
+
+	// Complete stores the response of the request holding the key
+	Complete(record *domain.IdempotencyRecord) error
+
+	// Delete frees the key, e.g. after the request failed or the record expired
+	Delete(userID int, key string) error
+}
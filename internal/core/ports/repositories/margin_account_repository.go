@@ -0,0 +1,22 @@
+package repositories
+
+import (
+	"stock-simulation-backend/internal/core/domain"
+	"time"
+)
+
+type MarginAccountRepository interface {
+	Create(account *domain.MarginAccount) error
+	GetByUserID(userID int) (*domain.MarginAccount, error)
+	GetByUserIDForUpdate(userID int) (*domain.MarginAccount, error) // Locks the row; use inside a UnitOfWork
+	GetMarginAccounts() ([]domain.MarginAccount, error)
+
+	// Update saves the account type and the loan; margin calls are set apart
+	// so the watcher never overwrites a loan changed by a concurrent trade
+	Update(account *domain.MarginAccount) error
+	SetMarginCall(userID int, amount float64, calledAt *time.Time) error
+
+	// AccrueInterest charges every whole day elapsed since each account's last
+	// charge up to asOf, and returns how many accounts were charged
+	AccrueInterest(asOf time.Time) (int, error)
+}
@@ -6,6 +6,7 @@ type PortfolioRepository interface {
 	Create(portfolio *domain.Portfolio) error
 	GetByUserID(userID int) ([]domain.Portfolio, error)
 	GetByUserIDAndSymbol(userID int, stockSymbol string) (*domain.Portfolio, error)
+	GetByUserIDAndSymbolForUpdate(userID int, stockSymbol string) (*domain.Portfolio, error) // Locks the row; use inside a UnitOfWork
 	Update(portfolio *domain.Portfolio) error
 	Delete(userID int, stockSymbol string) error
 	GetPortfolioValue(userID int) (float64, error)
@@ -0,0 +1,11 @@
+package repositories
+
+import "stock-simulation-backend/internal/core/domain"
+
+type RiskLimitRepository interface {
+	// GetByUserID returns the user's overrides, or nil if they have none
+	GetByUserID(userID int) (*domain.RiskLimitOverrides, error)
+
+	// Upsert replaces the user's overrides; nil fields clear an override
+	Upsert(overrides *domain.RiskLimitOverrides) error
+}
@@ -0,0 +1,20 @@
+package repositories
+
+import (
+	"stock-simulation-backend/internal/core/domain"
+	"time"
+)
+
+type ShortPositionRepository interface {
+	Create(position *domain.ShortPosition) error
+	GetByUserID(userID int) ([]domain.ShortPosition, error)
+	GetByUserIDAndSymbol(userID int, stockSymbol string) (*domain.ShortPosition, error)
+	GetByUserIDAndSymbolForUpdate(userID int, stockSymbol string) (*domain.ShortPosition, error) // Locks the row; use inside a UnitOfWork
+	GetAll() ([]domain.ShortPosition, error)
+	Update(position *domain.ShortPosition) error
+	Delete(userID int, stockSymbol string) error
+
+	// AccrueBorrowFees charges every whole day elapsed since each position's
+	// last accrual up to asOf, and returns how many positions were charged
+	AccrueBorrowFees(asOf time.Time) (int, error)
+}
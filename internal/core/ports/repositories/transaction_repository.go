@@ -1,6 +1,9 @@
 package repositories
 
-import "stock-simulation-backend/internal/core/domain"
+import (
+	"stock-simulation-backend/internal/core/domain"
+	"time"
+)
 
 type TransactionRepository interface {
 	Create(transaction *domain.Transaction) error
@@ -10,4 +13,5 @@ type TransactionRepository interface {
 	GetByUserIDAndType(userID int, transactionType string, limit int) ([]domain.Transaction, error)
 	GetUserTransactionHistory(userID int, stockSymbol, transactionType string, limit int) ([]domain.Transaction, error)
 	GetTotalTransactionsByUser(userID int) (int, error)
+	GetRealizedPnLSince(userID int, since time.Time) (float64, error) // Net of costs; losses are negative
 }
\ No newline at end of file
@@ -0,0 +1,20 @@
+package repositories
+
+// TxRepositories exposes repositories bound to a single database transaction.
+// Reads made through them see the transaction's own writes, and the
+// ...ForUpdate methods hold row locks until the transaction ends.
+type TxRepositories interface {
+	Users() UserRepository
+	Portfolios() PortfolioRepository
+	Transactions() TransactionRepository
+	Orders() AdvancedOrderRepositoryWithSearch
+	ShortPositions() ShortPositionRepository
+	MarginAccounts() MarginAccountRepository
+	AlgoOrders() AlgoOrderRepository
+}
+
+// UnitOfWork runs fn inside one database transaction. The transaction is
+// committed if fn returns nil and rolled back otherwise.
+type UnitOfWork interface {
+	Do(fn func(tx TxRepositories) error) error
+}
@@ -5,6 +5,7 @@ import "stock-simulation-backend/internal/core/domain"
 type UserRepository interface {
 	Create(user *domain.User) error
 	GetByID(id int) (*domain.User, error)
+	GetByIDForUpdate(id int) (*domain.User, error) // Locks the row; use inside a UnitOfWork
 	GetByEmail(email string) (*domain.User, error)
 	GetByUsername(username string) (*domain.User, error)
 	UpdateBalance(userID int, newBalance float64) error
@@ -9,16 +9,26 @@ import (
 type AdvancedOrderService interface {
 	// Order creation and management
 	CreateOrder(userID int, request *domain.OrderRequest) (*domain.Order, error)
+	CreateForcedOrder(userID int, request *domain.OrderRequest) (*domain.Order, error) // Margin call closing order; skips the pre-trade risk checks
 	CreateOCOOrder(userID int, parentRequest *domain.OrderRequest, linkedRequest *domain.OrderRequest) (*domain.Order, *domain.Order, error)
+	GetOCOGroup(userID int, groupID int) (*domain.OCOGroup, error)
+	CreateBracketOrder(userID int, request *domain.OrderRequest) (*domain.BracketOrder, error)
+	CreateAlgoOrder(userID int, request *domain.OrderRequest) (*domain.AlgoOrder, error)
+	GetAlgoOrder(userID int, orderID int) (*domain.AlgoOrder, error)
+	PauseAlgoOrder(userID int, orderID int) (*domain.AlgoOrder, error)
+	ResumeAlgoOrder(userID int, orderID int) (*domain.AlgoOrder, error)
 	ModifyOrder(userID int, orderID int, modifications *OrderModificationRequest) (*domain.Order, error)
 	CancelOrder(userID int, orderID int) error
 	CancelAllOrders(userID int, symbol *string) (int, error)
+	BulkCancelOrders(userID int, orderIDs []int) ([]BulkCancelResult, error) // One result per distinct ID, in request order
+	Rebalance(userID int, request *domain.RebalanceRequest) (*domain.Rebalance, error) // Moves the portfolio to target weights
 	
 	// Order execution
 	ExecuteOrder(orderID int, marketPrice float64) (*domain.OrderExecution, error)
 	ExecuteMarketOrders(symbol string, currentPrice float64) ([]domain.OrderExecution, error)
 	ExecuteLimitOrders(symbol string, currentPrice float64) ([]domain.OrderExecution, error)
 	ExecuteStopOrders(symbol string, currentPrice float64) ([]domain.OrderExecution, error)
+	TriggerStopLimits(symbol string, currentPrice float64) ([]domain.Order, error) // Convert hit stop-limits into resting limits
 	ExecuteTrailingStops(priceUpdates map[string]float64) ([]domain.OrderExecution, error)
 	
 	// Order validation
@@ -31,14 +41,21 @@ type AdvancedOrderService interface {
 	// Order queries
 	GetUserOrders(userID int, status *domain.OrderStatus, limit, offset int) ([]domain.Order, error)
 	GetOrderByID(userID int, orderID int) (*domain.Order, error)
+	GetOrderExecutions(userID int, orderID int) ([]domain.OrderExecution, error)
+	GetOrderEvents(userID int, orderID int) ([]domain.OrderEvent, error) // Every status change, oldest first
+	GetOrderVersions(userID int, orderID int) ([]domain.OrderVersion, error) // Terms of every version of an amended order
 	GetActiveOrders(userID int) ([]domain.Order, error)
+	GetWorkingOrders(userID int) ([]domain.Order, error) // Every active or paused order, unpaged
 	GetOrderHistory(userID int, startDate, endDate *time.Time, limit, offset int) ([]domain.Order, error)
 	SearchOrders(userID int, criteria *repositories.OrderSearchCriteria) (*repositories.OrderSearchResult, error)
 	
 	// Order monitoring and management
 	MonitorOrders() error // Background service to monitor and execute orders
+	ProcessPriceUpdates(priceUpdates map[string]float64) error // Match resting orders against a price tick
 	ExpireOrders() error  // Background service to expire day orders and time-based orders
 	UpdateTrailingStops(priceUpdates map[string]float64) error
+	ProcessAlgoOrders(priceUpdates map[string]float64) error // Send TWAP and VWAP slices due by now
+	EnforceShortMargins(priceUpdates map[string]float64) error // Accrue borrow fees and buy in shorts below maintenance
 	ProcessMarketClose(marketCode string) error
 	ProcessMarketOpen(marketCode string) error
 	
@@ -173,6 +190,25 @@ type OrderModificationRequest struct {
 	ExpiresAt       *time.Time `json:"expires_at,omitempty"`
 	TrailingAmount  *float64  `json:"trailing_amount,omitempty"`
 	TrailingPercent *float64  `json:"trailing_percent,omitempty"`
+	Version         *int      `json:"version,omitempty"` // The version being amended; refused if the order has moved on
+}
+
+// BulkCancelStatus is the outcome of one order in a bulk cancel
+type BulkCancelStatus string
+
+const (
+	BulkCancelStatusCancelled      BulkCancelStatus = "CANCELLED"
+	BulkCancelStatusNotFound       BulkCancelStatus = "NOT_FOUND"       // Missing or owned by someone else
+	BulkCancelStatusNotCancellable BulkCancelStatus = "NOT_CANCELLABLE" // Already finished
+)
+
+// MaxBulkCancelOrders caps how many orders one bulk cancel takes
+const MaxBulkCancelOrders = 100
+
+type BulkCancelResult struct {
+	OrderID int              `json:"order_id"`
+	Status  BulkCancelStatus `json:"status"`
+	Error   string           `json:"error,omitempty"`
 }
 
 type OrderUpdateType string
@@ -184,6 +220,10 @@ const (
 	OrderUpdateTypeExecuted        OrderUpdateType = "EXECUTED"
 	OrderUpdateTypePartiallyFilled OrderUpdateType = "PARTIALLY_FILLED"
 	OrderUpdateTypeExpired         OrderUpdateType = "EXPIRED"
+	OrderUpdateTypeRejected        OrderUpdateType = "REJECTED"
+	OrderUpdateTypeTriggered       OrderUpdateType = "TRIGGERED"
+	OrderUpdateTypePaused          OrderUpdateType = "PAUSED"
+	OrderUpdateTypeResumed         OrderUpdateType = "RESUMED"
 )
 
 type CommissionProfileUpdates struct {
@@ -234,14 +274,22 @@ type OrderProcessor interface {
 	NotifyExecution(execution *domain.OrderExecution) error
 }
 
-// Risk management interface
+// Risk management interface. Rejected orders come back as a
+// *domain.RiskRejection carrying the reason code.
 type RiskManager interface {
 	ValidateOrder(userID int, order *domain.Order) error
+	ValidateAmendment(userID int, current, amended *domain.Order) error // Checks amended in place of the working order current
+	ValidateBasket(userID int, orders []*domain.Order) error // Checks orders sent in sequence as a whole
 	CheckPositionLimits(userID int, order *domain.Order) error
 	CheckMarginRequirements(userID int, order *domain.Order) error
+	CheckAvailableShares(userID int, order *domain.Order) error
 	CheckDailyLimits(userID int) error
 	CalculateRiskMetrics(userID int) (*RiskMetrics, error)
 	MonitorPositions(userID int) (*PositionRisk, error)
+
+	// Limits in force for a user: the global limits with their overrides
+	GetRiskLimits(userID int) (*domain.RiskLimits, error)
+	UpdateRiskLimits(userID int, overrides *domain.RiskLimitOverrides) (*domain.RiskLimits, error)
 }
 
 type RiskMetrics struct {
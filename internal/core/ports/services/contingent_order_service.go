@@ -0,0 +1,16 @@
+package services
+
+import "stock-simulation-backend/internal/core/domain"
+
+type ContingentOrderService interface {
+	CreateContingentOrder(userID int, request *domain.ContingentOrderRequest) (*domain.ContingentOrder, error)
+	GetContingentOrder(userID int, id int) (*domain.ContingentOrder, error)
+	GetUserContingentOrders(userID int) ([]domain.ContingentOrder, error)
+
+	// Replace the conditions and order of a contingent order that has not fired
+	UpdateContingentOrder(userID int, id int, request *domain.ContingentOrderRequest) (*domain.ContingentOrder, error)
+	CancelContingentOrder(userID int, id int) error
+
+	// Evaluate every active contingent order and submit those whose conditions are met
+	ProcessPriceUpdates(prices map[string]float64) error
+}
@@ -0,0 +1,17 @@
+package services
+
+import "stock-simulation-backend/internal/core/domain"
+
+type IdempotencyService interface {
+	// Begin claims key for a request. It returns the stored record when the
+	// request was already completed and should be replayed, nil when the
+	// request should run, or domain.ErrIdempotencyKeyReused /
+	// domain.ErrIdempotencyKeyInFlight when it must be refused.
+	Begin(userID int, key, requestHash string) (*domain.IdempotencyRecord, error)
+
+	// Complete saves the response so retries can replay it
+	Complete(userID int, key, requestHash string, statusCode int, body []byte) error
+
+	// Release frees a key whose request failed, so a retry runs again
+	Release(userID int, key string) error
+}
@@ -0,0 +1,14 @@
+package services
+
+import "stock-simulation-backend/internal/core/domain"
+
+type MarginService interface {
+	// Value the user's account at current prices; users without a margin account trade on cash
+	GetMarginStatus(userID int) (*domain.MarginStatus, error)
+
+	// Switch between cash and margin; an account with debt cannot go back to cash
+	SetAccountType(userID int, accountType domain.AccountType) (*domain.MarginStatus, error)
+
+	// Accrue interest, issue or clear margin calls and liquidate unmet calls after a price tick
+	ProcessPriceUpdates(prices map[string]float64) error
+}
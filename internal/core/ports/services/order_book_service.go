@@ -0,0 +1,17 @@
+package services
+
+import "stock-simulation-backend/internal/core/domain"
+
+type OrderBookService interface {
+	// Get the best depth price levels on each side of a symbol's book
+	GetOrderBook(symbol string, depth int) (*domain.OrderBookUpdate, error)
+
+	// Rebuild the books for a price tick from resting limit orders and synthetic liquidity
+	RefreshBooks(prices map[string]float64) error
+
+	// Stream the current books to WebSocket clients
+	BroadcastBooks(symbols []string)
+
+	// Take liquidity for a market order, returning the average price and quantity taken
+	WalkBook(symbol string, buy bool, quantity int) (float64, int, error)
+}
@@ -1,6 +1,9 @@
 package services
 
-import "stock-simulation-backend/internal/core/domain"
+import (
+	"stock-simulation-backend/internal/core/domain"
+	"stock-simulation-backend/internal/core/ports/repositories"
+)
 
 type TransactionService interface {
 	BuyStock(userID int, req *domain.TransactionRequest) (*domain.TransactionResponse, error)
@@ -8,4 +11,9 @@ type TransactionService interface {
 	GetUserTransactions(userID int, limit, offset int) ([]domain.Transaction, error)
 	GetTransactionHistory(userID int, stockSymbol, transactionType string, limit int) ([]domain.Transaction, error)
 	GetTransactionByID(transactionID int) (*domain.Transaction, error)
-}
\ No newline at end of file
+
+	// ExecuteFill settles one execution at an explicit price, commission and
+	// fees. Pass the caller's unit of work so the fill commits with its other
+	// writes, or nil to run it in a unit of work of its own.
+	ExecuteFill(tx repositories.TxRepositories, fill *domain.TradeFill) (*domain.TransactionResponse, error)
+}
@@ -1,7 +1,10 @@
 package services
 
 import (
+	"errors"
 	"fmt"
+	"math"
+	"strings"
 	"time"
 
 	"stock-simulation-backend/internal/core/domain"
@@ -9,27 +12,63 @@ import (
 	"stock-simulation-backend/internal/core/ports/services"
 )
 
+// defaultMarketCode is the market whose session times apply to every
+// simulated symbol
+const defaultMarketCode = "NYSE"
+
 type AdvancedOrderService struct {
-	orderRepo          repositories.AdvancedOrderRepositoryWithSearch
-	stockRepo          repositories.StockRepository
-	portfolioRepo      repositories.PortfolioRepository
-	userRepo           repositories.UserRepository
-	transactionService services.TransactionService
+	uow                 repositories.UnitOfWork
+	orderRepo           repositories.AdvancedOrderRepositoryWithSearch
+	stockRepo           repositories.StockRepository
+	portfolioRepo       repositories.PortfolioRepository
+	userRepo            repositories.UserRepository
+	shortPositionRepo   repositories.ShortPositionRepository
+	marginAccountRepo   repositories.MarginAccountRepository
+	algoOrderRepo       repositories.AlgoOrderRepository
+	historicalPriceRepo repositories.HistoricalPriceRepository
+	transactionService  services.TransactionService
+	commissionService   services.CommissionService
+	riskManager         services.RiskManager
+	realTimeService     *RealTimeService
+	orderBookService    services.OrderBookService
+	quotes              *QuoteCache
+	liquidity           *tickLiquidity
 }
 
 func NewAdvancedOrderService(
+	uow repositories.UnitOfWork,
 	orderRepo repositories.AdvancedOrderRepositoryWithSearch,
 	stockRepo repositories.StockRepository,
 	portfolioRepo repositories.PortfolioRepository,
 	userRepo repositories.UserRepository,
+	shortPositionRepo repositories.ShortPositionRepository,
+	marginAccountRepo repositories.MarginAccountRepository,
+	algoOrderRepo repositories.AlgoOrderRepository,
+	historicalPriceRepo repositories.HistoricalPriceRepository,
 	transactionService services.TransactionService,
+	commissionService services.CommissionService,
+	riskManager services.RiskManager,
+	realTimeService *RealTimeService,
+	orderBookService services.OrderBookService,
+	quotes *QuoteCache,
 ) services.AdvancedOrderService {
 	return &AdvancedOrderService{
-		orderRepo:          orderRepo,
-		stockRepo:          stockRepo,
-		portfolioRepo:      portfolioRepo,
-		userRepo:           userRepo,
-		transactionService: transactionService,
+		uow:                 uow,
+		orderRepo:           orderRepo,
+		stockRepo:           stockRepo,
+		portfolioRepo:       portfolioRepo,
+		userRepo:            userRepo,
+		shortPositionRepo:   shortPositionRepo,
+		marginAccountRepo:   marginAccountRepo,
+		algoOrderRepo:       algoOrderRepo,
+		historicalPriceRepo: historicalPriceRepo,
+		transactionService:  transactionService,
+		commissionService:   commissionService,
+		riskManager:         riskManager,
+		realTimeService:     realTimeService,
+		orderBookService:    orderBookService,
+		quotes:              quotes,
+		liquidity:           newTickLiquidity(),
 	}
 }
 
@@ -62,6 +101,16 @@ func (s *AdvancedOrderService) ValidateOrder(userID int, request *domain.OrderRe
 		return fmt.Errorf("stop orders require a valid stop price")
 	}
 
+	// Validate stop limit orders, which need both prices
+	if request.OrderType == domain.OrderTypeStopLimit {
+		if request.StopPrice == nil || *request.StopPrice <= 0 {
+			return fmt.Errorf("stop limit orders require a valid stop price")
+		}
+		if request.Price == nil || *request.Price <= 0 {
+			return fmt.Errorf("stop limit orders require a valid limit price")
+		}
+	}
+
 	// Validate trailing stop parameters
 	if request.OrderType == "TRAILING_STOP" {
 		if request.TrailingAmount == nil && request.TrailingPercent == nil {
@@ -75,6 +124,78 @@ func (s *AdvancedOrderService) ValidateOrder(userID int, request *domain.OrderRe
 		}
 	}
 
+	// Validate iceberg orders: only a resting limit can hide part of itself
+	if request.DisplayQuantity != nil {
+		if request.OrderType != domain.OrderTypeLimit && request.OrderType != domain.OrderTypeStopLimit {
+			return fmt.Errorf("display quantity is only supported on limit orders")
+		}
+		if *request.DisplayQuantity <= 0 || *request.DisplayQuantity >= request.Quantity {
+			return fmt.Errorf("display quantity must be positive and less than the order quantity")
+		}
+		if request.TimeInForce == domain.TimeInForceIOC || request.TimeInForce == domain.TimeInForceFOK {
+			return fmt.Errorf("display quantity cannot be used with %s orders", request.TimeInForce)
+		}
+	}
+
+	// Validate time in force
+	switch request.TimeInForce {
+	case "", domain.TimeInForceGTC, domain.TimeInForceIOC, domain.TimeInForceFOK, domain.TimeInForceDAY:
+	case domain.TimeInForceGTD:
+		if request.ExpiresAt == nil {
+			return fmt.Errorf("GTD orders require an expiry time")
+		}
+	default:
+		return fmt.Errorf("invalid time in force: %s", request.TimeInForce)
+	}
+	if request.ExpiresAt != nil && !request.ExpiresAt.After(time.Now()) {
+		return fmt.Errorf("expiry time must be in the future")
+	}
+
+	// Validate bracket exits
+	if request.OrderType == domain.OrderTypeBracket {
+		if err := validateBracket(request); err != nil {
+			return err
+		}
+	}
+
+	// Validate TWAP and VWAP schedules
+	if request.OrderType == domain.OrderTypeTWAP || request.OrderType == domain.OrderTypeVWAP {
+		if err := validateAlgo(request); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// validateBracket checks a bracket's exits bracket its entry: above and below
+// it for a long entry, mirrored for a short one. A market entry has no price
+// yet, so only the exits are checked against each other.
+func validateBracket(request *domain.OrderRequest) error {
+	if request.Side != domain.OrderSideBuy && request.Side != domain.OrderSideShort {
+		return fmt.Errorf("bracket orders must open a position (BUY or SHORT)")
+	}
+	if request.TakeProfitPrice == nil || *request.TakeProfitPrice <= 0 {
+		return fmt.Errorf("bracket orders require a valid take profit price")
+	}
+	if request.StopLossPrice == nil || *request.StopLossPrice <= 0 {
+		return fmt.Errorf("bracket orders require a valid stop loss price")
+	}
+	if request.Price != nil && *request.Price <= 0 {
+		return fmt.Errorf("bracket entry price must be positive")
+	}
+
+	upper, lower := *request.TakeProfitPrice, *request.StopLossPrice
+	if request.Side == domain.OrderSideShort {
+		upper, lower = lower, upper
+	}
+	if upper <= lower {
+		return fmt.Errorf("take profit and stop loss are on the wrong side of each other for a %s bracket", request.Side)
+	}
+	if request.Price != nil && (*request.Price <= lower || *request.Price >= upper) {
+		return fmt.Errorf("bracket entry price must lie between the take profit and stop loss prices")
+	}
+
 	return nil
 }
 
@@ -84,13 +205,97 @@ func (s *AdvancedOrderService) CreateOrder(userID int, request *domain.OrderRequ
 		return nil, err
 	}
 
+	if request.OrderType == domain.OrderTypeBracket {
+		bracket, err := s.CreateBracketOrder(userID, request)
+		if err != nil {
+			return nil, err
+		}
+		return &bracket.Entry, nil
+	}
+
+	if request.OrderType == domain.OrderTypeTWAP || request.OrderType == domain.OrderTypeVWAP {
+		algo, err := s.CreateAlgoOrder(userID, request)
+		if err != nil {
+			return nil, err
+		}
+		return &algo.Order, nil
+	}
+
 	// Get current stock price
 	stock, err := s.stockRepo.GetBySymbol(request.StockSymbol)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get stock price: %w", err)
 	}
 
-	// Create order object
+	order := s.newOrder(userID, request, stock)
+	err = s.placeChecked(userID, func() error {
+		return s.riskManager.ValidateOrder(userID, order)
+	}, func(tx repositories.TxRepositories) error {
+		// Save to database first
+		if err := tx.Orders().Create(order); err != nil {
+			return fmt.Errorf("failed to create order: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.activateOrder(order, stock.CurrentPrice); err != nil {
+		return nil, err
+	}
+
+	return order, nil
+}
+
+// placeChecked runs a pre-trade check and the writes placing what it passed
+// in one unit of work that holds the user's row lock. A user's concurrent
+// orders are then checked one at a time, each against the holds and daily
+// count of those placed before it, and cannot together break a limit.
+func (s *AdvancedOrderService) placeChecked(userID int, check func() error, place func(tx repositories.TxRepositories) error) error {
+	return s.uow.Do(func(tx repositories.TxRepositories) error {
+		if _, err := tx.Users().GetByIDForUpdate(userID); err != nil {
+			return fmt.Errorf("failed to lock user: %w", err)
+		}
+		if err := check(); err != nil {
+			return err
+		}
+		return place(tx)
+	})
+}
+
+// CreateForcedOrder places a closing market order the system needs filled: a
+// margin call liquidation or a short buy-in. The pre-trade risk checks are
+// skipped, so neither the shares held by the user's own resting sells and
+// covers nor the user's limits can keep a margin call from being met.
+// Settlement still checks the position is there to close, and whichever of
+// the forced order and a resting one fills second is refused and cancelled.
+func (s *AdvancedOrderService) CreateForcedOrder(userID int, request *domain.OrderRequest) (*domain.Order, error) {
+	if request.OrderType != domain.OrderTypeMarket ||
+		(request.Side != domain.OrderSideSell && request.Side != domain.OrderSideCover) {
+		return nil, fmt.Errorf("forced orders are market sells or covers, not %s %s", request.OrderType, request.Side)
+	}
+	if err := s.ValidateOrder(userID, request); err != nil {
+		return nil, err
+	}
+
+	stock, err := s.stockRepo.GetBySymbol(request.StockSymbol)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stock price: %w", err)
+	}
+
+	order := s.newOrder(userID, request, stock)
+	if err := s.orderRepo.Create(order); err != nil {
+		return nil, fmt.Errorf("failed to create order: %w", err)
+	}
+	if err := s.activateOrder(order, stock.CurrentPrice); err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+// newOrder builds a PENDING order from a request at the stock's current price
+func (s *AdvancedOrderService) newOrder(userID int, request *domain.OrderRequest, stock *domain.Stock) *domain.Order {
 	order := &domain.Order{
 		UserID:           userID,
 		StockSymbol:      request.StockSymbol,
@@ -101,9 +306,10 @@ func (s *AdvancedOrderService) CreateOrder(userID int, request *domain.OrderRequ
 		StopPrice:        request.StopPrice,
 		TrailingAmount:   request.TrailingAmount,
 		TrailingPercent:  request.TrailingPercent,
-		TimeInForce:      domain.TimeInForce(request.TimeInForce),
+		TimeInForce:      request.TimeInForce,
 		Status:           "PENDING",
 		RemainingQuantity: request.Quantity,
+		DisplayQuantity:  request.DisplayQuantity,
 		MarketPrice:      stock.CurrentPrice,
 		Commission:       s.calculateCommission(request.Quantity, stock.CurrentPrice),
 		Fees:            s.calculateFees(request.Quantity, stock.CurrentPrice),
@@ -112,34 +318,78 @@ func (s *AdvancedOrderService) CreateOrder(userID int, request *domain.OrderRequ
 	if request.ExpiresAt != nil {
 		order.ExpiresAt = request.ExpiresAt
 	}
+	if order.TimeInForce == "" {
+		order.TimeInForce = domain.TimeInForceGTC
+	}
+	s.recordQuote(order, stock)
 
-	// Save to database first
-	err = s.orderRepo.Create(order)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create order: %w", err)
+	// Trailing stops start trailing from the price at placement
+	if order.OrderType == domain.OrderTypeTrailingStop {
+		order.UpdateTrailingStop(stock.CurrentPrice)
+	}
+
+	// Icebergs start out showing their first slice
+	order.ResetSlice(time.Now())
+
+	return order
+}
+
+// recordQuote stamps the quote at placement on the order, the benchmark for
+// its slippage and execution quality
+func (s *AdvancedOrderService) recordQuote(order *domain.Order, stock *domain.Stock) {
+	if s.quotes == nil {
+		return
+	}
+	quote := s.quotes.QuoteFor(stock)
+	spread := quote.Spread()
+	order.BidPrice = &quote.Bid
+	order.AskPrice = &quote.Ask
+	order.Spread = &spread
+}
+
+// activateOrder gives a newly stored order its first chance to execute:
+// market orders fill immediately and marketable limit orders fill at their
+// limit; everything else rests until the matching engine triggers it.
+func (s *AdvancedOrderService) activateOrder(order *domain.Order, currentPrice float64) error {
+	if order.IsImmediate() {
+		return s.activateImmediateOrder(order, currentPrice)
 	}
 
 	// Execute order based on type
-	switch request.OrderType {
+	switch order.OrderType {
 	case "MARKET":
 		// Market orders execute immediately at current price
-		err = s.executeOrderTransaction(order, stock.CurrentPrice)
+		_, err := s.fillOrder(order, currentPrice)
 		if err != nil {
-			// If execution fails, cancel the order
-			if deleteErr := s.orderRepo.Delete(order.ID); deleteErr != nil {
-				fmt.Printf("Warning: failed to delete failed order %d: %v\n", order.ID, deleteErr)
+			if !order.IsActive() {
+				// The matching engine picked the order up first
+				return nil
+			}
+			if errors.Is(err, errNoLiquidity) {
+				// Rest until the next tick brings more volume
+				fmt.Printf("⏳ Market order %d waiting for liquidity\n", order.ID)
+				return nil
 			}
-			return nil, fmt.Errorf("failed to execute market order: %w", err)
+			if !fillRefused(err) {
+				// The matching engine retries it on the next tick
+				fmt.Printf("⚠️ Market order %d failed to fill, retrying next tick: %v\n", order.ID, err)
+				return nil
+			}
+			// An order that can never settle is cancelled
+			if cancelErr := s.closeOrder(order, domain.OrderStatusCancelled, err.Error(), domain.OrderActorSystem); cancelErr != nil {
+				fmt.Printf("Warning: failed to cancel failed order %d: %v\n", order.ID, cancelErr)
+			}
+			return fmt.Errorf("failed to execute market order: %w", err)
 		}
 		
 	case "LIMIT":
 		// Check if limit order can be executed immediately
 		fmt.Printf("🔍 Checking LIMIT order execution: Symbol=%s, Side=%s, LimitPrice=%.2f, CurrentPrice=%.2f\n", 
-			order.StockSymbol, order.Side, *order.Price, stock.CurrentPrice)
+			order.StockSymbol, order.Side, *order.Price, currentPrice)
 			
-		if s.canExecuteLimitOrder(order, stock.CurrentPrice) {
+		if order.CanBeExecuted(currentPrice) {
 			fmt.Printf("✅ LIMIT order conditions met, executing immediately...\n")
-			err = s.executeOrderTransaction(order, *order.Price)
+			_, err := s.fillOrder(order, currentPrice)
 			if err != nil {
 				// If execution fails, keep order pending
 				fmt.Printf("⚠️ Limit order execution failed, keeping PENDING: %v\n", err)
@@ -149,10 +399,10 @@ func (s *AdvancedOrderService) CreateOrder(userID int, request *domain.OrderRequ
 		} else {
 			// Keep order pending for future execution
 			fmt.Printf("📝 Limit order created and kept PENDING: ID=%d, LimitPrice=%.2f, CurrentPrice=%.2f\n", 
-				order.ID, *order.Price, stock.CurrentPrice)
+				order.ID, *order.Price, currentPrice)
 		}
 		
-	case "STOP_LOSS", "TAKE_PROFIT":
+	case "STOP_LOSS", "TAKE_PROFIT", "STOP_LIMIT":
 		// Stop orders are kept pending until triggered
 		fmt.Printf("📝 Stop order created and kept PENDING: ID=%d, Type=%s, StopPrice=%.2f\n", 
 			order.ID, order.OrderType, *order.StopPrice)
@@ -163,33 +413,29 @@ func (s *AdvancedOrderService) CreateOrder(userID int, request *domain.OrderRequ
 			order.ID, order.OrderType)
 	}
 
-	return order, nil
+	return nil
 }
 
-// Helper function to check if limit order can be executed immediately
-func (s *AdvancedOrderService) canExecuteLimitOrder(order *domain.Order, currentPrice float64) bool {
-	if order.Price == nil {
-		fmt.Printf("❌ Limit order has no price set\n")
-		return false
+// activateImmediateOrder gives an IOC or FOK order its single chance to trade.
+// An IOC order keeps whatever fills and has the remainder cancelled; an FOK
+// order fills in full or is rejected without trading.
+func (s *AdvancedOrderService) activateImmediateOrder(order *domain.Order, currentPrice float64) error {
+	if order.CanBeExecuted(currentPrice) {
+		if _, err := s.fillOrder(order, currentPrice); err != nil &&
+			!errors.Is(err, errNoLiquidity) && !errors.Is(err, errFillOrKill) {
+			fmt.Printf("⚠️ %s order %d failed to fill: %v\n", order.TimeInForce, order.ID, err)
+		}
 	}
-	
-	limitPrice := *order.Price
-	
-	switch order.Side {
-	case "BUY":
-		// Buy limit order executes if current price <= limit price
-		canExecute := currentPrice <= limitPrice
-		fmt.Printf("🔍 BUY LIMIT check: current=%.2f <= limit=%.2f ? %v\n", currentPrice, limitPrice, canExecute)
-		return canExecute
-	case "SELL":
-		// Sell limit order executes if current price >= limit price
-		canExecute := currentPrice >= limitPrice
-		fmt.Printf("🔍 SELL LIMIT check: current=%.2f >= limit=%.2f ? %v\n", currentPrice, limitPrice, canExecute)
-		return canExecute
-	default:
-		fmt.Printf("❌ Unknown order side: %s\n", order.Side)
-		return false
+
+	if !order.IsActive() {
+		return nil
 	}
+
+	if order.TimeInForce == domain.TimeInForceFOK {
+		return s.closeOrder(order, domain.OrderStatusRejected, "FOK: full quantity not available", domain.OrderActorSystem)
+	}
+	return s.closeOrder(order, domain.OrderStatusCancelled,
+		fmt.Sprintf("IOC: %d unfilled shares cancelled", order.RemainingQuantity), domain.OrderActorSystem)
 }
 
 func (s *AdvancedOrderService) CreateOCOOrder(userID int, parentRequest, linkedRequest *domain.OrderRequest) (*domain.Order, *domain.Order, error) {
@@ -201,436 +447,1432 @@ func (s *AdvancedOrderService) CreateOCOOrder(userID int, parentRequest, linkedR
 		return nil, nil, fmt.Errorf("linked order validation failed: %w", err)
 	}
 
-	// Create parent order first
-	parentOrder, err := s.CreateOrder(userID, parentRequest)
-	if err != nil {
-		return nil, nil, fmt.Errorf("failed to create parent order: %w", err)
+	// A market leg would fill on arrival and leave nothing to cancel
+	for _, request := range []*domain.OrderRequest{parentRequest, linkedRequest} {
+		if request.OrderType == domain.OrderTypeMarket || request.OrderType == domain.OrderTypeOCO {
+			return nil, nil, fmt.Errorf("OCO legs must be resting orders, got %s", request.OrderType)
+		}
+		if request.TimeInForce == domain.TimeInForceIOC || request.TimeInForce == domain.TimeInForceFOK {
+			return nil, nil, fmt.Errorf("OCO legs must be resting orders, got time in force %s", request.TimeInForce)
+		}
 	}
 
-	// Create linked order
-	linkedOrder, err := s.CreateOrder(userID, linkedRequest)
+	parentStock, err := s.stockRepo.GetBySymbol(parentRequest.StockSymbol)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get stock price: %w", err)
+	}
+	linkedStock, err := s.stockRepo.GetBySymbol(linkedRequest.StockSymbol)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to create linked order: %w", err)
+		return nil, nil, fmt.Errorf("failed to get stock price: %w", err)
 	}
 
-	// Update both orders to reference each other
-	parentOrder.LinkedOrderID = &linkedOrder.ID
-	linkedOrder.ParentOrderID = &parentOrder.ID
+	parentOrder := s.newOrder(userID, parentRequest, parentStock)
+	linkedOrder := s.newOrder(userID, linkedRequest, linkedStock)
 
-	// Update in database
-	err = s.orderRepo.Update(parentOrder)
+	// Only one leg can fill, so each is checked on its own
+	err = s.placeChecked(userID, func() error {
+		if err := s.riskManager.ValidateOrder(userID, parentOrder); err != nil {
+			return fmt.Errorf("parent order rejected: %w", err)
+		}
+		if err := s.riskManager.ValidateOrder(userID, linkedOrder); err != nil {
+			return fmt.Errorf("linked order rejected: %w", err)
+		}
+		return nil
+	}, func(tx repositories.TxRepositories) error {
+		// Insert and link both legs in one transaction so the matching engine
+		// never sees an unlinked leg
+		if err := tx.Orders().CreateOCOOrders(parentOrder, linkedOrder); err != nil {
+			return fmt.Errorf("failed to create OCO orders: %w", err)
+		}
+		return nil
+	})
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to update parent order: %w", err)
+		return nil, nil, err
 	}
 
-	err = s.orderRepo.Update(linkedOrder)
-	if err != nil {
-		return nil, nil, fmt.Errorf("failed to update linked order: %w", err)
+	// Both legs are live; a leg that is already marketable fills now and
+	// cancels its sibling as part of the same fill
+	if err := s.activateOrder(parentOrder, parentStock.CurrentPrice); err != nil {
+		fmt.Printf("⚠️ OCO leg %d failed to activate: %v\n", parentOrder.ID, err)
+	}
+	if err := s.activateOrder(linkedOrder, linkedStock.CurrentPrice); err != nil {
+		fmt.Printf("⚠️ OCO leg %d failed to activate: %v\n", linkedOrder.ID, err)
+	}
+
+	// Return the stored state of both legs
+	if order, err := s.orderRepo.GetByID(parentOrder.ID); err == nil {
+		parentOrder = order
+	}
+	if order, err := s.orderRepo.GetByID(linkedOrder.ID); err == nil {
+		linkedOrder = order
 	}
 
 	return parentOrder, linkedOrder, nil
 }
 
-func (s *AdvancedOrderService) ModifyOrder(userID, orderID int, modifications *services.OrderModificationRequest) (*domain.Order, error) {
-	// Get existing order
-	order, err := s.orderRepo.GetByID(orderID)
-	if err != nil {
+// CreateBracketOrder places an entry order with take-profit and stop-loss
+// exits. The entry is a limit order when a price is given and a market order
+// otherwise. The exits are stored DORMANT and are activated, scaled to the
+// filled quantity, when the entry fills.
+func (s *AdvancedOrderService) CreateBracketOrder(userID int, request *domain.OrderRequest) (*domain.BracketOrder, error) {
+	if request.OrderType != domain.OrderTypeBracket {
+		return nil, fmt.Errorf("expected a %s order, got %s", domain.OrderTypeBracket, request.OrderType)
+	}
+	if err := s.ValidateOrder(userID, request); err != nil {
 		return nil, err
 	}
 
-	// Check if order can be modified
-	if order.Status != "PENDING" {
-		return nil, fmt.Errorf("cannot modify order with status: %s", order.Status)
+	stock, err := s.stockRepo.GetBySymbol(request.StockSymbol)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stock price: %w", err)
 	}
 
-	// Apply modifications
-	if modifications.Price != nil {
-		order.Price = modifications.Price
-	}
-	if modifications.StopPrice != nil {
-		order.StopPrice = modifications.StopPrice
-	}
-	if modifications.Quantity != nil {
-		order.Quantity = *modifications.Quantity
-		order.RemainingQuantity = *modifications.Quantity
+	entryRequest := *request
+	entryRequest.OrderType = domain.OrderTypeMarket
+	if request.Price != nil {
+		entryRequest.OrderType = domain.OrderTypeLimit
 	}
-	if modifications.TimeInForce != nil {
-		order.TimeInForce = domain.TimeInForce(*modifications.TimeInForce)
-	}
-	if modifications.TrailingAmount != nil {
-		order.TrailingAmount = modifications.TrailingAmount
+	entryOrder := s.newOrder(userID, &entryRequest, stock)
+	takeProfit := s.newBracketExit(entryOrder, domain.OrderTypeTakeProfit, *request.TakeProfitPrice, stock)
+	stopLoss := s.newBracketExit(entryOrder, domain.OrderTypeStopLoss, *request.StopLossPrice, stock)
+
+	err = s.placeChecked(userID, func() error {
+		return s.riskManager.ValidateOrder(userID, entryOrder)
+	}, func(tx repositories.TxRepositories) error {
+		if err := tx.Orders().CreateBracketOrders(entryOrder, takeProfit, stopLoss); err != nil {
+			return fmt.Errorf("failed to create bracket order: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
-	if modifications.TrailingPercent != nil {
-		order.TrailingPercent = modifications.TrailingPercent
+
+	// The exits only go live through the entry's fill
+	if err := s.activateOrder(entryOrder, stock.CurrentPrice); err != nil {
+		return nil, err
 	}
 
-	// Update in database
-	err = s.orderRepo.Update(order)
-	if err != nil {
-		return nil, fmt.Errorf("failed to update order: %w", err)
+	bracket := &domain.BracketOrder{Entry: *entryOrder, TakeProfit: *takeProfit, StopLoss: *stopLoss}
+	for _, order := range []*domain.Order{&bracket.Entry, &bracket.TakeProfit, &bracket.StopLoss} {
+		if current, err := s.orderRepo.GetByID(order.ID); err == nil {
+			*order = *current
+		}
 	}
 
-	return order, nil
+	return bracket, nil
 }
 
-func (s *AdvancedOrderService) CancelOrder(userID, orderID int) error {
-	// Get order to verify ownership and status
-	order, err := s.orderRepo.GetByID(orderID)
-	if err != nil {
-		return err
+// newBracketExit builds a dormant exit that closes the position opened by entryOrder
+func (s *AdvancedOrderService) newBracketExit(entryOrder *domain.Order, orderType domain.OrderType, stopPrice float64, stock *domain.Stock) *domain.Order {
+	exit := &domain.Order{
+		UserID:            entryOrder.UserID,
+		StockSymbol:       entryOrder.StockSymbol,
+		OrderType:         orderType,
+		Side:              entryOrder.ExitSide(),
+		Quantity:          entryOrder.Quantity,
+		StopPrice:         &stopPrice,
+		TimeInForce:       domain.TimeInForceGTC,
+		Status:            domain.OrderStatusDormant,
+		RemainingQuantity: entryOrder.Quantity,
+		MarketPrice:       stock.CurrentPrice,
+		Commission:        s.calculateCommission(entryOrder.Quantity, stopPrice),
+		Fees:              s.calculateFees(entryOrder.Quantity, stopPrice),
 	}
+	s.recordQuote(exit, stock)
+	return exit
+}
 
-	if order.Status != "PENDING" && order.Status != "PARTIALLY_FILLED" {
-		return fmt.Errorf("cannot cancel order with status: %s", order.Status)
+// activateChildOrders keeps a bracket entry's exits sized to what the entry
+// has filled so far: dormant exits wake up on the first fill and live exits
+// grow with each later fill. Exits that have closed (one OCO leg filled the
+// other) are left alone.
+func (s *AdvancedOrderService) activateChildOrders(tx repositories.TxRepositories, parent *domain.Order) error {
+	children, err := tx.Orders().GetChildOrders(parent.ID)
+	if err != nil {
+		return err
 	}
 
-	// Verify ownership
-	if order.UserID != userID {
-		return fmt.Errorf("order does not belong to user")
-	}
+	for i := range children {
+		child := &children[i]
+		if child.Status != domain.OrderStatusDormant && !child.IsActive() {
+			continue
+		}
 
-	// Cancel the order
-	err = s.orderRepo.Delete(orderID)
-	if err != nil {
-		return fmt.Errorf("failed to cancel order: %w", err)
-	}
+		if child.Status == domain.OrderStatusDormant {
+			reason := fmt.Sprintf("Bracket: entry order #%d filled", parent.ID)
+			if err := tx.Orders().ActivateOrder(child.ID, reason); err != nil {
+				return err
+			}
+			child.Status = domain.OrderStatusPending
+		}
+		child.Quantity = parent.ExecutedQuantity
+		child.RemainingQuantity = child.Quantity - child.ExecutedQuantity
+		if err := tx.Orders().Update(child); err != nil {
+			return err
+		}
 
-	// If this is part of an OCO order, cancel the linked order too
-	if order.LinkedOrderID != nil {
-		err = s.orderRepo.Delete(*order.LinkedOrderID)
-		if err != nil {
-			// Log error but don't fail the operation
-			fmt.Printf("Warning: failed to cancel linked order %d: %v\n", *order.LinkedOrderID, err)
+		price := child.MarketPrice
+		if child.StopPrice != nil {
+			price = *child.StopPrice
 		}
+		commission := s.calculateCommission(child.Quantity, price)
+		fees := s.calculateFees(child.Quantity, price)
+		if err := tx.Orders().UpdateOrderCommission(child.ID, commission, fees); err != nil {
+			return err
+		}
+
+		fmt.Printf("🪝 Bracket exit armed: ID=%d, Type=%s, Qty=%d, StopPrice=%.2f\n",
+			child.ID, child.OrderType, child.Quantity, price)
 	}
 
 	return nil
 }
 
-func (s *AdvancedOrderService) CancelAllOrders(userID int, symbol *string) (int, error) {
-	return s.orderRepo.CancelAllOrdersByUser(userID, symbol)
-}
-
-func (s *AdvancedOrderService) GetOrderByID(userID, orderID int) (*domain.Order, error) {
-	order, err := s.orderRepo.GetByID(orderID)
+// GetOCOGroup returns both legs of an OCO group owned by the user
+func (s *AdvancedOrderService) GetOCOGroup(userID, groupID int) (*domain.OCOGroup, error) {
+	orders, err := s.orderRepo.GetOCOGroup(groupID)
 	if err != nil {
 		return nil, err
 	}
-	
+
 	// Verify ownership
-	if order.UserID != userID {
-		return nil, fmt.Errorf("order not found")
+	if len(orders) == 0 || orders[0].UserID != userID {
+		return nil, fmt.Errorf("OCO group not found")
 	}
-	
-	return order, nil
-}
 
-func (s *AdvancedOrderService) SearchOrders(userID int, criteria *repositories.OrderSearchCriteria) (*repositories.OrderSearchResult, error) {
-	return s.orderRepo.Search(userID, criteria)
+	return domain.NewOCOGroup(groupID, orders), nil
 }
 
-func (s *AdvancedOrderService) GetActiveOrders(userID int) ([]domain.Order, error) {
-	return s.orderRepo.GetActiveOrdersByUser(userID)
-}
+// ModifyOrder amends a working order in place. The order keeps its ID and
+// its fills and moves to its next version, after the new terms pass the same
+// validation and risk checks as a new order would. Filled shares cannot be
+// amended away, and the order keeps its place in the queue unless its price
+// or quantity goes up.
+func (s *AdvancedOrderService) ModifyOrder(userID, orderID int, modifications *services.OrderModificationRequest) (*domain.Order, error) {
+	var amended *domain.Order
+	var stock *domain.Stock
+	err := s.uow.Do(func(tx repositories.TxRepositories) error {
+		// Lock the order so a fill cannot land between the checks and the write
+		current, err := tx.Orders().GetByIDForUpdate(orderID)
+		if err != nil {
+			return err
+		}
 
-func (s *AdvancedOrderService) GetOrderStatistics(userID int) (*domain.OrderStats, error) {
-	return s.orderRepo.GetOrderStatistics(userID)
-}
+		// Verify ownership
+		if current.UserID != userID {
+			return fmt.Errorf("order does not belong to user")
+		}
+		if modifications.Version != nil && *modifications.Version != current.Version {
+			return fmt.Errorf("%w: version %d was amended, but the order is at version %d",
+				domain.ErrOrderVersionConflict, *modifications.Version, current.Version)
+		}
+		if err := checkAmendable(tx, current, modifications); err != nil {
+			return err
+		}
 
-func (s *AdvancedOrderService) GetExecutionMetrics(userID int, timeframe string) (*repositories.OrderExecutionMetrics, error) {
-	return s.orderRepo.GetExecutionMetrics(userID, timeframe)
-}
+		stock, err = s.stockRepo.GetBySymbol(current.StockSymbol)
+		if err != nil {
+			return fmt.Errorf("failed to get stock price: %w", err)
+		}
+		amended = s.amendOrder(current, modifications, stock)
 
-func (s *AdvancedOrderService) GetSlippageAnalysis(userID int, symbol string) (*repositories.SlippageAnalysis, error) {
-	return s.orderRepo.GetSlippageAnalysis(userID, symbol)
-}
+		// Lock the user after the order, in the order a fill takes them, so
+		// the user's other orders are not checked alongside this one
+		if _, err := tx.Users().GetByIDForUpdate(userID); err != nil {
+			return fmt.Errorf("failed to lock user: %w", err)
+		}
 
-// Helper functions
-func (s *AdvancedOrderService) calculateCommission(quantity int, price float64) float64 {
-	// Basic commission calculation: $0.005 per share, minimum $1
-	commission := float64(quantity) * 0.005
-	if commission < 1.0 {
-		commission = 1.0
-	}
-	return commission
-}
+		request, err := amendmentRequest(tx, amended)
+		if err != nil {
+			return err
+		}
+		if err := s.ValidateOrder(userID, request); err != nil {
+			return err
+		}
+		if request.TakeProfitPrice != nil && request.StopLossPrice != nil {
+			if err := validateBracket(request); err != nil {
+				return err
+			}
+		}
+		if err := s.riskManager.ValidateAmendment(userID, current, amended); err != nil {
+			return err
+		}
 
-func (s *AdvancedOrderService) calculateFees(quantity int, price float64) float64 {
-	// Basic fee calculation: 0.1% of trade value, minimum $0.50
-	tradeValue := float64(quantity) * price
-	fees := tradeValue * 0.001
-	if fees < 0.50 {
-		fees = 0.50
+		return tx.Orders().Amend(current, amended)
+	})
+	if err != nil {
+		return nil, err
 	}
-	return fees
-}
 
-// Add missing interface method
-func (s *AdvancedOrderService) CalculateMarginRequirement(userID int, order *domain.Order) (float64, error) {
-	// Basic margin calculation: 50% of stock value for margin accounts
-	tradeValue := float64(order.Quantity) * order.MarketPrice
-	marginRequirement := tradeValue * 0.5 // 50% margin requirement
-	return marginRequirement, nil
-}
+	s.NotifyOrderUpdate(amended, services.OrderUpdateTypeModified)
 
-// Add missing interface methods with stub implementations
-func (s *AdvancedOrderService) ExecuteOrder(orderID int, marketPrice float64) (*domain.OrderExecution, error) {
-	return &domain.OrderExecution{}, nil
-}
-
-func (s *AdvancedOrderService) ExecuteMarketOrders(symbol string, currentPrice float64) ([]domain.OrderExecution, error) {
-	return []domain.OrderExecution{}, nil
-}
+	// A limit amended through the market trades now, as a new one would
+	if amended.OrderType == domain.OrderTypeLimit && amended.CanBeExecuted(stock.CurrentPrice) {
+		if err := s.activateOrder(amended, stock.CurrentPrice); err != nil {
+			return nil, err
+		}
+	}
 
-func (s *AdvancedOrderService) ExecuteLimitOrders(symbol string, currentPrice float64) ([]domain.OrderExecution, error) {
-	return []domain.OrderExecution{}, nil
+	return amended, nil
 }
 
-func (s *AdvancedOrderService) ExecuteStopOrders(symbol string, currentPrice float64) ([]domain.OrderExecution, error) {
-	return []domain.OrderExecution{}, nil
-}
+// checkAmendable refuses amendments the order cannot take: orders no longer
+// working, algo parents and their slices, and changes to terms the order
+// does not have or does not own
+func checkAmendable(tx repositories.TxRepositories, order *domain.Order, modifications *services.OrderModificationRequest) error {
+	if !order.IsActive() {
+		return fmt.Errorf("cannot modify order with status: %s", order.Status)
+	}
+	if order.IsAlgo() {
+		return fmt.Errorf("%s orders cannot be modified; cancel and resubmit", order.OrderType)
+	}
+	if order.ParentOrderID != nil {
+		parent, err := tx.Orders().GetByID(*order.ParentOrderID)
+		if err != nil {
+			return err
+		}
+		if parent.IsAlgo() {
+			return fmt.Errorf("slices of %s order #%d cannot be modified", parent.OrderType, parent.ID)
+		}
+		if modifications.Quantity != nil {
+			return fmt.Errorf("bracket exits are sized by their entry's fills and cannot change quantity")
+		}
+	}
 
-func (s *AdvancedOrderService) ExecuteTrailingStops(priceUpdates map[string]float64) ([]domain.OrderExecution, error) {
-	return []domain.OrderExecution{}, nil
-}
+	if modifications.Price == nil && modifications.StopPrice == nil && modifications.Quantity == nil &&
+		modifications.TimeInForce == nil && modifications.ExpiresAt == nil &&
+		modifications.TrailingAmount == nil && modifications.TrailingPercent == nil {
+		return fmt.Errorf("no modifications requested")
+	}
 
-func (s *AdvancedOrderService) ValidateBuyingPower(userID int, order *domain.Order) error {
-	return nil
-}
+	if modifications.Quantity != nil && *modifications.Quantity <= order.ExecutedQuantity {
+		return fmt.Errorf("quantity must be more than the %d shares already filled", order.ExecutedQuantity)
+	}
+	if modifications.TimeInForce != nil &&
+		(*modifications.TimeInForce == domain.TimeInForceIOC || *modifications.TimeInForce == domain.TimeInForceFOK) {
+		return fmt.Errorf("a working order cannot become %s", *modifications.TimeInForce)
+	}
+	if modifications.Price != nil && order.Price == nil {
+		return fmt.Errorf("%s orders have no limit price to modify", order.OrderType)
+	}
+
+	if order.OrderType == domain.OrderTypeTrailingStop {
+		if modifications.StopPrice != nil {
+			return fmt.Errorf("trailing stops set their own stop price; modify the trailing amount or percent")
+		}
+		if modifications.TrailingAmount != nil && modifications.TrailingPercent != nil {
+			return fmt.Errorf("set either a trailing amount or a trailing percent, not both")
+		}
+	} else if modifications.TrailingAmount != nil || modifications.TrailingPercent != nil {
+		return fmt.Errorf("only trailing stops have a trailing amount or percent")
+	}
+	if modifications.StopPrice != nil {
+		if order.StopPrice == nil {
+			return fmt.Errorf("%s orders have no stop price to modify", order.OrderType)
+		}
+		if order.TriggeredAt != nil {
+			return fmt.Errorf("the stop of a triggered stop limit has already been hit")
+		}
+	}
 
-func (s *AdvancedOrderService) ValidatePosition(userID int, order *domain.Order) error {
 	return nil
 }
 
-func (s *AdvancedOrderService) ValidateMarketHours(order *domain.Order) error {
-	return nil
+// amendOrder returns the next version of current with the modifications
+// applied, priced against the market now as a replacement order would be
+func (s *AdvancedOrderService) amendOrder(current *domain.Order, modifications *services.OrderModificationRequest, stock *domain.Stock) *domain.Order {
+	amended := *current
+	if modifications.Price != nil {
+		amended.Price = modifications.Price
+	}
+	if modifications.StopPrice != nil {
+		amended.StopPrice = modifications.StopPrice
+	}
+	if modifications.TimeInForce != nil {
+		amended.TimeInForce = *modifications.TimeInForce
+	}
+	if modifications.ExpiresAt != nil {
+		amended.ExpiresAt = modifications.ExpiresAt
+	}
+	if modifications.Quantity != nil {
+		amended.Quantity = *modifications.Quantity
+		amended.RemainingQuantity = amended.Quantity - amended.ExecutedQuantity
+		amended.Commission = s.calculateCommission(amended.Quantity, stock.CurrentPrice)
+		amended.Fees = s.calculateFees(amended.Quantity, stock.CurrentPrice)
+	}
+
+	// A new trail is measured from the best price the stop has seen so far
+	if modifications.TrailingAmount != nil || modifications.TrailingPercent != nil {
+		amended.TrailingAmount, amended.TrailingPercent = modifications.TrailingAmount, modifications.TrailingPercent
+		amended.StopPrice = nil
+		amended.UpdateTrailingStop(stock.CurrentPrice)
+	}
+
+	amended.MarketPrice = stock.CurrentPrice
+	s.recordQuote(&amended, stock)
+
+	if !current.KeepsPriority(&amended) {
+		now := time.Now()
+		amended.PriorityAt = now
+		amended.ResetSlice(now)
+	} else if amended.IsIceberg() && amended.VisibleRemaining > amended.RemainingQuantity {
+		amended.VisibleRemaining = amended.RemainingQuantity
+	}
+
+	return &amended
 }
 
-func (s *AdvancedOrderService) ValidateOrderLimits(userID int, order *domain.Order) error {
+// amendmentRequest restates an amended order as the request that would place
+// it, so it goes through ValidateOrder. A bracket entry brings its exit
+// prices along while they are still dormant, since it must stay between them.
+func amendmentRequest(tx repositories.TxRepositories, order *domain.Order) (*domain.OrderRequest, error) {
+	request := &domain.OrderRequest{
+		StockSymbol:     order.StockSymbol,
+		OrderType:       order.OrderType,
+		Side:            order.Side,
+		Quantity:        order.Quantity,
+		DisplayQuantity: order.DisplayQuantity,
+		Price:           order.Price,
+		StopPrice:       order.StopPrice,
+		TrailingAmount:  order.TrailingAmount,
+		TrailingPercent: order.TrailingPercent,
+		TimeInForce:     order.TimeInForce,
+		ExpiresAt:       order.ExpiresAt,
+	}
+
+	children, err := tx.Orders().GetChildOrders(order.ID)
+	if err != nil {
+		return nil, err
+	}
+	for _, child := range children {
+		if child.Status != domain.OrderStatusDormant {
+			continue
+		}
+		switch child.OrderType {
+		case domain.OrderTypeTakeProfit:
+			request.TakeProfitPrice = child.StopPrice
+		case domain.OrderTypeStopLoss:
+			request.StopLossPrice = child.StopPrice
+		}
+	}
+	return request, nil
+}
+
+func (s *AdvancedOrderService) CancelOrder(userID, orderID int) error {
+	// Algo parents cancel their slices one by one, outside the parent's lock
+	if order, err := s.orderRepo.GetByID(orderID); err == nil && order.IsAlgo() {
+		if order.UserID != userID {
+			return fmt.Errorf("order does not belong to user")
+		}
+		return s.cancelAlgoOrder(order, "Cancelled by user", domain.OrderActorUser)
+	}
+
+	return s.uow.Do(func(tx repositories.TxRepositories) error {
+		// Lock the order so a concurrent fill cannot race the cancel
+		order, err := tx.Orders().GetByIDForUpdate(orderID)
+		if err != nil {
+			return err
+		}
+
+		// Verify ownership
+		if order.UserID != userID {
+			return fmt.Errorf("order does not belong to user")
+		}
+
+		if !order.IsActive() {
+			return fmt.Errorf("cannot cancel order with status: %s", order.Status)
+		}
+
+		if err := tx.Orders().CancelOrder(orderID, "Cancelled by user", domain.OrderActorUser); err != nil {
+			return fmt.Errorf("failed to cancel order: %w", err)
+		}
+
+		// Cancelling one OCO leg cancels the rest of its group
+		if order.OCOGroupID != nil {
+			reason := fmt.Sprintf("OCO: linked order #%d cancelled", orderID)
+			if _, err := tx.Orders().CancelLinkedOrders(orderID, reason); err != nil {
+				return fmt.Errorf("failed to cancel linked orders: %w", err)
+			}
+		}
+
+		// A bracket entry takes its dormant exits with it
+		reason := fmt.Sprintf("Bracket: entry order #%d cancelled", orderID)
+		if _, err := tx.Orders().CancelChildOrders(orderID, reason); err != nil {
+			return fmt.Errorf("failed to cancel bracket exits: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// closeOrder ends an active order as CANCELLED, EXPIRED or REJECTED together
+// with any dormant bracket exits that were waiting on it, then notifies the
+// owner. Exits already armed by a partial fill keep protecting that fill.
+// actor is who the cancellation is logged against.
+func (s *AdvancedOrderService) closeOrder(order *domain.Order, status domain.OrderStatus, reason string, actor domain.OrderActor) error {
+	err := s.uow.Do(func(tx repositories.TxRepositories) error {
+		var err error
+		switch status {
+		case domain.OrderStatusExpired:
+			err = tx.Orders().ExpireOrder(order.ID, reason)
+		case domain.OrderStatusRejected:
+			err = tx.Orders().RejectOrder(order.ID, reason)
+		default:
+			err = tx.Orders().CancelOrder(order.ID, reason, actor)
+		}
+		if err != nil {
+			return err
+		}
+
+		childReason := fmt.Sprintf("Bracket: entry order #%d %s", order.ID, strings.ToLower(string(status)))
+		_, err = tx.Orders().CancelChildOrders(order.ID, childReason)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	order.Status = status
+	order.CancelReason = &reason
+
+	updateType := services.OrderUpdateTypeCancelled
+	switch status {
+	case domain.OrderStatusExpired:
+		updateType = services.OrderUpdateTypeExpired
+	case domain.OrderStatusRejected:
+		updateType = services.OrderUpdateTypeRejected
+	}
+	if err := s.NotifyOrderUpdate(order, updateType); err != nil {
+		fmt.Printf("⚠️ Failed to notify order %d update: %v\n", order.ID, err)
+	}
 	return nil
 }
 
-func (s *AdvancedOrderService) GetUserOrders(userID int, status *domain.OrderStatus, limit, offset int) ([]domain.Order, error) {
-	if status != nil {
-		return s.orderRepo.GetByUserIDAndStatus(userID, *status)
+func (s *AdvancedOrderService) CancelAllOrders(userID int, symbol *string) (int, error) {
+	return s.orderRepo.CancelAllOrdersByUser(userID, symbol)
+}
+
+// BulkCancelOrders cancels the user's listed orders together. Orders that
+// are missing, someone else's or already finished are reported rather than
+// failing the batch; the rest are cancelled in one transaction, along with
+// their OCO legs, dormant bracket exits and algo slices.
+func (s *AdvancedOrderService) BulkCancelOrders(userID int, orderIDs []int) ([]services.BulkCancelResult, error) {
+	ids := make([]int, 0, len(orderIDs))
+	seen := make(map[int]bool)
+	for _, id := range orderIDs {
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) > services.MaxBulkCancelOrders {
+		return nil, fmt.Errorf("at most %d orders can be cancelled at once", services.MaxBulkCancelOrders)
+	}
+
+	orders, err := s.orderRepo.GetByIDs(ids)
+	if err != nil {
+		return nil, err
 	}
-	return s.orderRepo.GetByUserID(userID, limit, offset)
+	byID := make(map[int]*domain.Order, len(orders))
+	for i := range orders {
+		byID[orders[i].ID] = &orders[i]
+	}
+
+	results := make(map[int]services.BulkCancelResult, len(ids))
+	cancellable := []int{}
+	for _, id := range ids {
+		order, ok := byID[id]
+		switch {
+		case !ok || order.UserID != userID:
+			results[id] = services.BulkCancelResult{OrderID: id, Status: services.BulkCancelStatusNotFound, Error: "order not found"}
+		case !order.IsActive() && !(order.IsAlgo() && order.Status == domain.OrderStatusPaused):
+			results[id] = services.BulkCancelResult{OrderID: id, Status: services.BulkCancelStatusNotCancellable,
+				Error: fmt.Sprintf("cannot cancel order with status: %s", order.Status)}
+		default:
+			cancellable = append(cancellable, id)
+		}
+	}
+
+	cancelled, err := s.orderRepo.BulkCancelOrders(cancellable, "Cancelled by user", domain.OrderActorUser)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range cancellable {
+		// Orders that finished since they were read are left alone
+		results[id] = services.BulkCancelResult{OrderID: id, Status: services.BulkCancelStatusNotCancellable,
+			Error: "order is no longer active"}
+	}
+	for _, id := range cancelled {
+		results[id] = services.BulkCancelResult{OrderID: id, Status: services.BulkCancelStatusCancelled}
+	}
+
+	ordered := make([]services.BulkCancelResult, 0, len(ids))
+	for _, id := range ids {
+		ordered = append(ordered, results[id])
+	}
+	return ordered, nil
 }
 
-func (s *AdvancedOrderService) GetOrderHistory(userID int, startDate, endDate *time.Time, limit, offset int) ([]domain.Order, error) {
-	return s.orderRepo.GetOrderHistory(userID, limit, offset)
+func (s *AdvancedOrderService) GetOrderByID(userID, orderID int) (*domain.Order, error) {
+	order, err := s.orderRepo.GetByID(orderID)
+	if err != nil {
+		return nil, err
+	}
+	
+	// Verify ownership
+	if order.UserID != userID {
+		return nil, fmt.Errorf("order not found")
+	}
+	
+	return order, nil
 }
 
-func (s *AdvancedOrderService) MonitorOrders() error {
-	return nil
+// GetOrderExecutions returns the individual fills of an order owned by the user
+func (s *AdvancedOrderService) GetOrderExecutions(userID, orderID int) ([]domain.OrderExecution, error) {
+	if _, err := s.GetOrderByID(userID, orderID); err != nil {
+		return nil, err
+	}
+	return s.orderRepo.GetExecutions(orderID)
 }
 
-func (s *AdvancedOrderService) ExpireOrders() error {
-	return nil
+// GetOrderEvents returns the status history of an order owned by the user
+func (s *AdvancedOrderService) GetOrderEvents(userID, orderID int) ([]domain.OrderEvent, error) {
+	if _, err := s.GetOrderByID(userID, orderID); err != nil {
+		return nil, err
+	}
+	return s.orderRepo.GetEvents(orderID)
 }
 
-func (s *AdvancedOrderService) UpdateTrailingStops(priceUpdates map[string]float64) error {
-	return nil
+// GetOrderVersions returns the terms of every version of an order owned by
+// the user. Orders never amended have no versions on record.
+func (s *AdvancedOrderService) GetOrderVersions(userID, orderID int) ([]domain.OrderVersion, error) {
+	if _, err := s.GetOrderByID(userID, orderID); err != nil {
+		return nil, err
+	}
+	return s.orderRepo.GetVersions(orderID)
 }
 
-func (s *AdvancedOrderService) ProcessMarketClose(marketCode string) error {
-	return nil
+// SearchOrders searches the user's own orders; criteria.UserID is ignored
+func (s *AdvancedOrderService) SearchOrders(userID int, criteria *repositories.OrderSearchCriteria) (*repositories.OrderSearchResult, error) {
+	return s.orderRepo.SearchOrdersByUser(userID, criteria)
 }
 
-func (s *AdvancedOrderService) ProcessMarketOpen(marketCode string) error {
-	return nil
+func (s *AdvancedOrderService) GetActiveOrders(userID int) ([]domain.Order, error) {
+	return s.orderRepo.GetActiveOrdersByUser(userID)
 }
 
-func (s *AdvancedOrderService) CheckPositionLimits(userID int, order *domain.Order) error {
-	return nil
+// GetWorkingOrders returns all of the user's active and paused orders in one
+// unpaged read, for background jobs that must see every one of them
+func (s *AdvancedOrderService) GetWorkingOrders(userID int) ([]domain.Order, error) {
+	return s.orderRepo.GetWorkingOrdersByUser(userID)
 }
 
-func (s *AdvancedOrderService) CheckDailyLimits(userID int) error {
-	return nil
+func (s *AdvancedOrderService) GetOrderStatistics(userID int) (*domain.OrderStats, error) {
+	return s.orderRepo.GetOrderStatistics(userID)
 }
 
-func (s *AdvancedOrderService) ValidateRiskParameters(userID int, order *domain.Order) error {
-	return nil
+func (s *AdvancedOrderService) GetExecutionMetrics(userID int, timeframe string) (*repositories.OrderExecutionMetrics, error) {
+	return s.orderRepo.GetExecutionMetrics(userID, timeframe)
 }
 
-func (s *AdvancedOrderService) NotifyOrderUpdate(order *domain.Order, updateType services.OrderUpdateType) error {
-	return nil
+func (s *AdvancedOrderService) GetSlippageAnalysis(userID int, symbol string) (*repositories.SlippageAnalysis, error) {
+	return s.orderRepo.GetSlippageAnalysis(userID, symbol)
 }
 
-func (s *AdvancedOrderService) UpdatePortfolioOnExecution(execution *domain.OrderExecution) error {
-	return nil
+// Helper functions
+func (s *AdvancedOrderService) calculateCommission(quantity int, price float64) float64 {
+	return tradeCommission(quantity, price)
 }
 
-// New method to execute order and update portfolio/balance
-func (s *AdvancedOrderService) executeOrderTransaction(order *domain.Order, executionPrice float64) error {
-	// Update order status to executed
-	order.Status = "EXECUTED"
-	order.ExecutedPrice = &executionPrice
-	order.ExecutedQuantity = order.Quantity
-	order.RemainingQuantity = 0
-	now := time.Now()
-	order.ExecutedAt = &now
+func (s *AdvancedOrderService) calculateFees(quantity int, price float64) float64 {
+	return tradeFees(quantity, price)
+}
 
-	// Use transaction service to create proper transaction records
-	// This ensures orders appear in transaction history
-	transactionRequest := &domain.TransactionRequest{
-		StockSymbol: order.StockSymbol,
-		Quantity:    order.Quantity,
+// tradeCommission is the commission on a trade, whether it is placed as an
+// order or directly
+func tradeCommission(quantity int, price float64) float64 {
+	// Basic commission calculation: $0.005 per share, minimum $1
+	commission := float64(quantity) * 0.005
+	if commission < 1.0 {
+		commission = 1.0
 	}
+	return commission
+}
+
+// tradeFees is the regulatory and exchange fees on a trade
+func tradeFees(quantity int, price float64) float64 {
+	// Basic fee calculation: 0.1% of trade value, minimum $0.50
+	tradeValue := float64(quantity) * price
+	fees := tradeValue * 0.001
+	if fees < 0.50 {
+		fees = 0.50
+	}
+	return fees
+}
+
+// Add missing interface method
+func (s *AdvancedOrderService) CalculateMarginRequirement(userID int, order *domain.Order) (float64, error) {
+	tradeValue := float64(order.Quantity) * order.MarketPrice
 
-	var err error
 	switch order.Side {
-	case "BUY":
-		fmt.Printf("🔄 Executing BUY order via transaction service: %s x%d @ $%.2f\n", 
-			order.StockSymbol, order.Quantity, executionPrice)
-		_, err = s.transactionService.BuyStock(order.UserID, transactionRequest)
+	case domain.OrderSideShort:
+		return domain.ShortInitialMargin(tradeValue), nil
+	case domain.OrderSideBuy:
+		account, err := s.marginAccountRepo.GetByUserID(userID)
 		if err != nil {
-			return fmt.Errorf("failed to execute buy transaction: %w", err)
+			return 0, err
 		}
+		if account.IsMargin() {
+			return tradeValue * domain.MarginInitialRate, nil
+		}
+	}
 
-	case "SELL":
-		fmt.Printf("🔄 Executing SELL order via transaction service: %s x%d @ $%.2f\n", 
-			order.StockSymbol, order.Quantity, executionPrice)
-		_, err = s.transactionService.SellStock(order.UserID, transactionRequest)
-		if err != nil {
-			return fmt.Errorf("failed to execute sell transaction: %w", err)
+	// Cash accounts pay for the whole trade; sells and covers are funded by
+	// the position they close
+	if order.Side == domain.OrderSideBuy {
+		return tradeValue, nil
+	}
+	return 0, nil
+}
+
+// ExecuteOrder fills a single order if its trigger condition is met at marketPrice
+func (s *AdvancedOrderService) ExecuteOrder(orderID int, marketPrice float64) (*domain.OrderExecution, error) {
+	order, err := s.orderRepo.GetByID(orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.fillOrder(order, marketPrice)
+}
+
+func (s *AdvancedOrderService) ExecuteMarketOrders(symbol string, currentPrice float64) ([]domain.OrderExecution, error) {
+	orders, err := s.orderRepo.GetOrdersForExecution(symbol, currentPrice)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get market orders: %w", err)
+	}
+
+	return s.matchOrders(orders, domain.OrderTypeMarket, currentPrice), nil
+}
+
+func (s *AdvancedOrderService) ExecuteLimitOrders(symbol string, currentPrice float64) ([]domain.OrderExecution, error) {
+	orders, err := s.orderRepo.GetOrdersForExecution(symbol, currentPrice)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get limit orders: %w", err)
+	}
+
+	executions := s.matchOrders(orders, domain.OrderTypeLimit, currentPrice)
+	return append(executions, s.matchOrders(orders, domain.OrderTypeStopLimit, currentPrice)...), nil
+}
+
+// executeRestingOrders matches a symbol's market, limit and triggered
+// stop-limit orders on a tick, in that order, from one read of the orders
+// executable at currentPrice. Each pass takes only its own type, so no
+// order filled by one pass is tried again by the next.
+func (s *AdvancedOrderService) executeRestingOrders(symbol string, currentPrice float64) ([]domain.OrderExecution, error) {
+	orders, err := s.orderRepo.GetOrdersForExecution(symbol, currentPrice)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get orders for execution: %w", err)
+	}
+
+	executions := s.matchOrders(orders, domain.OrderTypeMarket, currentPrice)
+	executions = append(executions, s.matchOrders(orders, domain.OrderTypeLimit, currentPrice)...)
+	return append(executions, s.matchOrders(orders, domain.OrderTypeStopLimit, currentPrice)...), nil
+}
+
+func (s *AdvancedOrderService) ExecuteStopOrders(symbol string, currentPrice float64) ([]domain.OrderExecution, error) {
+	orders, err := s.orderRepo.GetStopOrders(symbol)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stop orders: %w", err)
+	}
+
+	executions := s.matchOrders(orders, domain.OrderTypeStopLoss, currentPrice)
+	return append(executions, s.matchOrders(orders, domain.OrderTypeTakeProfit, currentPrice)...), nil
+}
+
+// TriggerStopLimits turns the stop-limit orders on symbol whose stop price is
+// hit at currentPrice into resting limit orders. They keep their order ID and
+// are matched with the other limits from then on.
+func (s *AdvancedOrderService) TriggerStopLimits(symbol string, currentPrice float64) ([]domain.Order, error) {
+	orders, err := s.orderRepo.GetStopOrders(symbol)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stop orders: %w", err)
+	}
+
+	triggered := []domain.Order{}
+	for i := range orders {
+		order := &orders[i]
+		if !order.StopTriggered(currentPrice) {
+			continue
 		}
 
-	case "SHORT":
-		// For SHORT: Add money, create negative position (custom logic)
-		err = s.processShortTransaction(order.UserID, order.StockSymbol, order.Quantity, 
-			float64(order.Quantity)*executionPrice-order.Commission-order.Fees)
+		now := time.Now()
+		ok, err := s.orderRepo.TriggerStopLimit(order.ID, now)
 		if err != nil {
-			return fmt.Errorf("failed to process short transaction: %w", err)
+			fmt.Printf("⚠️ Failed to trigger stop limit order %d: %v\n", order.ID, err)
+			continue
 		}
+		if !ok {
+			// Cancelled or triggered elsewhere since it was read
+			continue
+		}
+		order.TriggeredAt = &now
 
-	case "COVER":
-		// For COVER: Deduct money, reduce negative position (custom logic)
-		err = s.processCoverTransaction(order.UserID, order.StockSymbol, order.Quantity, 
-			float64(order.Quantity)*executionPrice+order.Commission+order.Fees)
+		fmt.Printf("🔔 Stop limit %d triggered at $%.2f: resting %s %d %s at $%.2f\n",
+			order.ID, currentPrice, order.Side, order.RemainingQuantity, order.StockSymbol, *order.Price)
+		if err := s.NotifyOrderUpdate(order, services.OrderUpdateTypeTriggered); err != nil {
+			fmt.Printf("⚠️ Failed to notify order %d update: %v\n", order.ID, err)
+		}
+		triggered = append(triggered, *order)
+	}
+	return triggered, nil
+}
+
+// matchOrders fills every order of the given type that is executable at
+// currentPrice, as far as the tick's liquidity allows. Orders that trigger but
+// can never settle (e.g. the user no longer has the cash or shares) are
+// cancelled rather than retried on every tick; any other failure, such as a
+// lock timeout, leaves the order working for the next tick.
+func (s *AdvancedOrderService) matchOrders(orders []domain.Order, orderType domain.OrderType, currentPrice float64) []domain.OrderExecution {
+	executions := []domain.OrderExecution{}
+	for i := range orders {
+		order := &orders[i]
+		if order.OrderType != orderType || !order.CanBeExecuted(currentPrice) {
+			continue
+		}
+
+		execution, err := s.fillOrder(order, currentPrice)
+		if errors.Is(err, errNoLiquidity) {
+			continue
+		}
 		if err != nil {
-			return fmt.Errorf("failed to process cover transaction: %w", err)
+			fmt.Printf("⚠️ Failed to execute order %d: %v\n", order.ID, err)
+			if order.IsActive() && fillRefused(err) {
+				if cancelErr := s.closeOrder(order, domain.OrderStatusCancelled, err.Error(), domain.OrderActorSystem); cancelErr != nil {
+					fmt.Printf("Warning: failed to cancel order %d: %v\n", order.ID, cancelErr)
+				}
+			}
+			continue
 		}
+
+		executions = append(executions, *execution)
 	}
+	return executions
+}
+
+// fillRefused reports whether a fill failed for a reason the next tick will
+// not change: a risk rejection, a fill the user's cash or position cannot
+// settle, or an FOK order that cannot fill in full
+func fillRefused(err error) bool {
+	var rejection *domain.RiskRejection
+	var refusal *domain.SettlementRefusal
+	return errors.As(err, &rejection) || errors.As(err, &refusal) || errors.Is(err, errFillOrKill)
+}
 
-	// Update order in database
-	err = s.orderRepo.Update(order)
+// fillOrder settles as much of an order as the current tick's liquidity
+// allows, as one unit of work. The order row is locked and re-checked first, so
+// an order reached by both the matching engine and an API request is never
+// overfilled. order is refreshed in place so callers see the stored state even
+// when the fill is refused or rolled back.
+func (s *AdvancedOrderService) fillOrder(order *domain.Order, marketPrice float64) (*domain.OrderExecution, error) {
+	var execution *domain.OrderExecution
+	reserved := 0
+	err := s.uow.Do(func(tx repositories.TxRepositories) error {
+		// A slice's algo parent is locked before the slice, in the lock order
+		if _, err := s.lockAlgoParent(tx, order); err != nil {
+			return err
+		}
+		current, err := tx.Orders().GetByIDForUpdate(order.ID)
+		if err != nil {
+			return err
+		}
+		*order = *current
+
+		if !order.CanBeExecuted(marketPrice) {
+			return fmt.Errorf("order %d is not executable at $%.2f (status %s)", order.ID, marketPrice, order.Status)
+		}
+
+		// An iceberg trades no more than its visible slice at a time
+		reserved = s.liquidity.take(order.StockSymbol, order.VisibleQuantity(), func() int {
+			return s.tickBudget(order.StockSymbol)
+		})
+		if reserved == 0 {
+			return errNoLiquidity
+		}
+		executionPrice := s.executionPrice(order, marketPrice)
+		walked := false
+		slippage := 0.0
+
+		// Market orders walk the book, so size moves the price. The walk is
+		// the order's market impact, recorded as its slippage against the quote.
+		if order.OrderType == domain.OrderTypeMarket && s.orderBookService != nil {
+			price, taken, err := s.orderBookService.WalkBook(order.StockSymbol, order.IsBuySide(), reserved)
+			if err != nil {
+				return err
+			}
+			s.liquidity.giveBack(order.StockSymbol, reserved-taken)
+			reserved = taken
+			if reserved == 0 {
+				return errNoLiquidity
+			}
+			slippage = walkSlippage(order, executionPrice, price)
+			executionPrice = price
+			walked = true
+		}
+
+		if order.TimeInForce == domain.TimeInForceFOK && reserved < order.RemainingQuantity {
+			return errFillOrKill
+		}
+		if !walked {
+			executionPrice, slippage = s.applySlippage(order, executionPrice, reserved)
+		}
+		execution, err = s.executeOrderTransaction(tx, order, executionPrice, reserved, slippage)
+		return err
+	})
 	if err != nil {
-		return fmt.Errorf("failed to update order status: %w", err)
+		s.liquidity.giveBack(order.StockSymbol, reserved)
+		if current, getErr := s.orderRepo.GetByID(order.ID); getErr == nil {
+			*order = *current
+		}
+		return nil, err
 	}
 
-	fmt.Printf("✅ Order executed successfully: ID=%d, Type=%s, Side=%s\n", 
-		order.ID, order.OrderType, order.Side)
-	return nil
+	updateType := services.OrderUpdateTypeExecuted
+	if order.Status == domain.OrderStatusPartiallyFilled {
+		updateType = services.OrderUpdateTypePartiallyFilled
+	}
+	if err := s.NotifyOrderUpdate(order, updateType); err != nil {
+		fmt.Printf("⚠️ Failed to notify order %d update: %v\n", order.ID, err)
+	}
+
+	return execution, nil
 }
 
+// applySlippage moves a fill's price against the order by the modelled
+// slippage and returns the new price with the slippage in basis points
+func (s *AdvancedOrderService) applySlippage(order *domain.Order, price float64, quantity int) (float64, float64) {
+	if s.commissionService == nil {
+		return price, 0
+	}
+	slippage, err := s.commissionService.CalculateSlippage(order.StockSymbol, quantity, order.OrderType)
+	if err != nil || slippage.TotalSlippage == 0 {
+		return price, 0
+	}
 
+	rate := slippage.TotalSlippage / 100
+	if order.IsBuySide() {
+		price *= 1 + rate
+	} else {
+		price *= 1 - rate
+	}
+	return math.Round(price*10000) / 10000, slippage.TotalSlippage * 100
+}
 
+// walkSlippage is how far a book walk's price fell short of the quote, in
+// basis points against the order
+func walkSlippage(order *domain.Order, quoted, walked float64) float64 {
+	if quoted <= 0 {
+		return 0
+	}
+	shortfall := walked - quoted
+	if !order.IsBuySide() {
+		shortfall = -shortfall
+	}
+	return math.Round(shortfall/quoted*10000*100) / 100
+}
 
+// tickBudget looks up the shares of symbol that may fill in one tick
+func (s *AdvancedOrderService) tickBudget(symbol string) int {
+	stock, err := s.stockRepo.GetBySymbol(symbol)
+	if err != nil {
+		return minTickLiquidity
+	}
+	return tickBudget(stock.Volume)
+}
 
-func (s *AdvancedOrderService) processShortTransaction(userID int, symbol string, quantity int, proceeds float64) error {
-	// Add proceeds to user balance (minus margin requirement)
-	user, err := s.userRepo.GetByID(userID)
+// executionPrice returns the price a triggered order fills at: limit and
+// stop-limit orders at their limit, everything else at the current quote.
+// A triggered stop is a market order, so when the price gaps through its
+// stop it fills where the market is, not at the stop.
+func (s *AdvancedOrderService) executionPrice(order *domain.Order, marketPrice float64) float64 {
+	switch order.OrderType {
+	case domain.OrderTypeLimit, domain.OrderTypeStopLimit:
+		if order.Price != nil {
+			return *order.Price
+		}
+	case domain.OrderTypeMarket, domain.OrderTypeStopLoss, domain.OrderTypeTakeProfit, domain.OrderTypeTrailingStop:
+		// Buys lift the ask and sells hit the bid
+		if s.quotes != nil {
+			if stock, err := s.stockRepo.GetBySymbol(order.StockSymbol); err == nil {
+				stock.CurrentPrice = marketPrice
+				return s.quotes.QuoteFor(stock).PriceFor(order.IsBuySide())
+			}
+		}
+	}
+	return marketPrice
+}
+
+// ExecuteTrailingStops triggers trailing stops whose stop price has been
+// crossed; they fill as market orders at the current price
+func (s *AdvancedOrderService) ExecuteTrailingStops(priceUpdates map[string]float64) ([]domain.OrderExecution, error) {
+	orders, err := s.orderRepo.GetTrailingStopsToUpdate(priceUpdates)
 	if err != nil {
-		return err
+		return nil, fmt.Errorf("failed to get trailing stops: %w", err)
+	}
+
+	executions := []domain.OrderExecution{}
+	for i := range orders {
+		currentPrice := priceUpdates[orders[i].StockSymbol]
+		executions = append(executions, s.matchOrders(orders[i:i+1], domain.OrderTypeTrailingStop, currentPrice)...)
 	}
+	return executions, nil
+}
+
+// ValidateBuyingPower checks the account can fund the order on top of what
+// its working orders already hold
+func (s *AdvancedOrderService) ValidateBuyingPower(userID int, order *domain.Order) error {
+	return s.riskManager.CheckMarginRequirements(userID, order)
+}
+
+// ValidatePosition checks a sell or cover has shares left to close that no
+// other working order holds
+func (s *AdvancedOrderService) ValidatePosition(userID int, order *domain.Order) error {
+	return s.riskManager.CheckAvailableShares(userID, order)
+}
 
-	user.Balance += proceeds
-	err = s.userRepo.Update(user)
+func (s *AdvancedOrderService) ValidateMarketHours(order *domain.Order) error {
+	return nil
+}
+
+// ValidateOrderLimits runs every pre-trade risk check on an order
+func (s *AdvancedOrderService) ValidateOrderLimits(userID int, order *domain.Order) error {
+	return s.riskManager.ValidateOrder(userID, order)
+}
+
+// GetUserOrders returns a page of the user's orders, newest first,
+// optionally in one status
+func (s *AdvancedOrderService) GetUserOrders(userID int, status *domain.OrderStatus, limit, offset int) ([]domain.Order, error) {
+	result, err := s.orderRepo.SearchOrdersByUser(userID, &repositories.OrderSearchCriteria{
+		Status: status,
+		Limit:  limit,
+		Offset: offset,
+	})
 	if err != nil {
-		return fmt.Errorf("failed to update user balance: %w", err)
+		return nil, err
 	}
+	return result.Orders, nil
+}
 
-	// Create negative position in portfolio
-	return s.updatePortfolioForShort(userID, symbol, quantity)
+// GetOrderHistory returns a page of the user's orders placed between the
+// dates, either of which may be left open
+func (s *AdvancedOrderService) GetOrderHistory(userID int, startDate, endDate *time.Time, limit, offset int) ([]domain.Order, error) {
+	result, err := s.orderRepo.SearchOrdersByUser(userID, &repositories.OrderSearchCriteria{
+		StartDate: startDate,
+		EndDate:   endDate,
+		Limit:     limit,
+		Offset:    offset,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result.Orders, nil
 }
 
-func (s *AdvancedOrderService) processCoverTransaction(userID int, symbol string, quantity int, totalCost float64) error {
-	// Check if user has short position
-	portfolio, err := s.portfolioRepo.GetByUserIDAndSymbol(userID, symbol)
+// MonitorOrders runs one matching pass over every stock at its stored price
+func (s *AdvancedOrderService) MonitorOrders() error {
+	stocks, err := s.stockRepo.GetAll()
 	if err != nil {
-		return fmt.Errorf("short position not found for: %s", symbol)
+		return fmt.Errorf("failed to get stocks: %w", err)
+	}
+
+	priceUpdates := make(map[string]float64, len(stocks))
+	for _, stock := range stocks {
+		priceUpdates[stock.Symbol] = stock.CurrentPrice
+	}
+
+	return s.ProcessPriceUpdates(priceUpdates)
+}
+
+// ProcessPriceUpdates matches resting orders against a batch of new prices.
+// The price simulator calls it once per tick.
+func (s *AdvancedOrderService) ProcessPriceUpdates(priceUpdates map[string]float64) error {
+	// Each tick brings fresh volume to fill against
+	s.liquidity.reset()
+
+	// Expired orders must not get a chance to fill on this tick
+	if err := s.ExpireOrders(); err != nil {
+		fmt.Printf("⚠️ Order expiry failed: %v\n", err)
+	}
+
+	// Rebuild the books around the new prices before anything walks them
+	if s.orderBookService != nil {
+		if err := s.orderBookService.RefreshBooks(priceUpdates); err != nil {
+			fmt.Printf("⚠️ Order book refresh failed: %v\n", err)
+		}
+	}
+
+	// Send this tick's algo slices so they are matched with everything else
+	if err := s.ProcessAlgoOrders(priceUpdates); err != nil {
+		fmt.Printf("⚠️ Algo order slicing failed: %v\n", err)
 	}
 
-	if portfolio.Quantity >= 0 || (-portfolio.Quantity) < quantity {
-		return fmt.Errorf("insufficient short position: required %d, available %d", quantity, -portfolio.Quantity)
+	// Move trailing stops before checking triggers so they see this tick's price
+	if err := s.UpdateTrailingStops(priceUpdates); err != nil {
+		fmt.Printf("⚠️ Trailing stop update failed: %v\n", err)
 	}
 
-	// Deduct cost from user balance
-	user, err := s.userRepo.GetByID(userID)
+	trailingExecutions, err := s.ExecuteTrailingStops(priceUpdates)
 	if err != nil {
-		return err
+		fmt.Printf("⚠️ Trailing stop matching failed: %v\n", err)
 	}
 
-	if user.Balance < totalCost {
-		return fmt.Errorf("insufficient balance for cover: required %.2f, available %.2f", totalCost, user.Balance)
+	executed := len(trailingExecutions)
+	for symbol, price := range priceUpdates {
+		// Stop-limits hit by this price join the limits before they are matched
+		if _, err := s.TriggerStopLimits(symbol, price); err != nil {
+			fmt.Printf("⚠️ Stop limit triggering failed for %s: %v\n", symbol, err)
+		}
+
+		restingExecutions, err := s.executeRestingOrders(symbol, price)
+		if err != nil {
+			fmt.Printf("⚠️ Market and limit order matching failed for %s: %v\n", symbol, err)
+		}
+
+		stopExecutions, err := s.ExecuteStopOrders(symbol, price)
+		if err != nil {
+			fmt.Printf("⚠️ Stop order matching failed for %s: %v\n", symbol, err)
+		}
+
+		executed += len(restingExecutions) + len(stopExecutions)
+	}
+
+	if executed > 0 {
+		fmt.Printf("🎯 Matching engine executed %d order(s)\n", executed)
+	}
+
+	if err := s.EnforceShortMargins(priceUpdates); err != nil {
+		fmt.Printf("⚠️ Short margin check failed: %v\n", err)
 	}
 
-	user.Balance -= totalCost
-	err = s.userRepo.Update(user)
+	if s.orderBookService != nil {
+		symbols := make([]string, 0, len(priceUpdates))
+		for symbol := range priceUpdates {
+			symbols = append(symbols, symbol)
+		}
+		s.orderBookService.BroadcastBooks(symbols)
+	}
+	return nil
+}
+
+// ExpireOrders is the expiry sweep run ahead of matching on every tick: orders
+// past their expiry time and DAY orders from an earlier session both expire.
+func (s *AdvancedOrderService) ExpireOrders() error {
+	orders, err := s.orderRepo.GetExpiredOrders()
 	if err != nil {
-		return fmt.Errorf("failed to update user balance: %w", err)
+		return fmt.Errorf("failed to get expired orders: %w", err)
 	}
 
-	// Reduce short position
-	return s.updatePortfolioForCover(userID, symbol, quantity)
+	for i := range orders {
+		order := &orders[i]
+		reason := fmt.Sprintf("Expired at %s", order.ExpiresAt.Format(time.RFC3339))
+		if err := s.closeOrder(order, domain.OrderStatusExpired, reason, domain.OrderActorSystem); err != nil {
+			fmt.Printf("⚠️ Failed to expire order %d: %v\n", order.ID, err)
+			continue
+		}
+		fmt.Printf("⌛ Order %d expired\n", order.ID)
+	}
+
+	return s.ProcessMarketClose(defaultMarketCode)
 }
 
+// UpdateTrailingStops ratchets each trailing stop's high-water mark and stop
+// price with the new prices and persists the ones that moved
+func (s *AdvancedOrderService) UpdateTrailingStops(priceUpdates map[string]float64) error {
+	orders, err := s.orderRepo.GetTrailingStopsToUpdate(priceUpdates)
+	if err != nil {
+		return fmt.Errorf("failed to get trailing stops: %w", err)
+	}
+
+	for i := range orders {
+		order := &orders[i]
+		if !order.UpdateTrailingStop(priceUpdates[order.StockSymbol]) {
+			continue
+		}
 
+		if err := s.orderRepo.UpdateTrailingStopPrice(order.ID, *order.HighWaterMark, *order.StopPrice); err != nil {
+			fmt.Printf("⚠️ Failed to update trailing stop %d: %v\n", order.ID, err)
+			continue
+		}
+		fmt.Printf("📐 Trailing stop %d moved: %s high-water $%.2f, stop $%.2f\n",
+			order.ID, order.StockSymbol, *order.HighWaterMark, *order.StopPrice)
+	}
 
+	return nil
+}
 
+// EnforceShortMargins accrues borrow fees on open shorts and buys in any
+// short whose equity has fallen below the maintenance margin at the new
+// prices, with a market cover for whatever of the position its working
+// covers are not already buying back
+func (s *AdvancedOrderService) EnforceShortMargins(priceUpdates map[string]float64) error {
+	if s.shortPositionRepo == nil {
+		return nil
+	}
 
-func (s *AdvancedOrderService) updatePortfolioForShort(userID int, symbol string, quantity int) error {
-	portfolio, err := s.portfolioRepo.GetByUserIDAndSymbol(userID, symbol)
+	charged, err := s.shortPositionRepo.AccrueBorrowFees(time.Now())
 	if err != nil {
-		// Create new short position
-		stock, err := s.stockRepo.GetBySymbol(symbol)
-		if err != nil {
-			return err
+		return err
+	}
+	if charged > 0 {
+		fmt.Printf("💸 Accrued borrow fees on %d short position(s)\n", charged)
+	}
+
+	positions, err := s.shortPositionRepo.GetAll()
+	if err != nil {
+		return err
+	}
+
+	covering := make(map[int]map[string]int)
+	for i := range positions {
+		position := &positions[i]
+		price, ok := priceUpdates[position.StockSymbol]
+		if !ok || !position.IsBelowMaintenance(price) {
+			continue
+		}
+
+		// Shares working covers already buy back are left to them
+		covers, ok := covering[position.UserID]
+		if !ok {
+			covers, err = s.workingCovers(position.UserID)
+			if err != nil {
+				// Without the working covers a buy-in could be placed twice
+				fmt.Printf("⚠️ Skipping buy-in of short %s for user %d: %v\n", position.StockSymbol, position.UserID, err)
+				continue
+			}
+			covering[position.UserID] = covers
+		}
+		quantity := position.Quantity - covers[position.StockSymbol]
+		if quantity <= 0 {
+			continue
 		}
 
-		newPortfolio := &domain.Portfolio{
-			UserID:       userID,
-			StockSymbol:  symbol,
-			Quantity:     -quantity, // Negative for short position
-			AveragePrice: stock.CurrentPrice,
-			TotalCost:    -float64(quantity) * stock.CurrentPrice,
+		fmt.Printf("🚨 Margin call: user %d short %s x%d, equity $%.2f at $%.2f\n",
+			position.UserID, position.StockSymbol, position.Quantity, position.Equity(price), price)
+		_, err := s.CreateForcedOrder(position.UserID, &domain.OrderRequest{
+			StockSymbol: position.StockSymbol,
+			OrderType:   domain.OrderTypeMarket,
+			Side:        domain.OrderSideCover,
+			Quantity:    quantity,
+			TimeInForce: domain.TimeInForceGTC,
+		})
+		if err != nil {
+			fmt.Printf("⚠️ Failed to buy in short %s for user %d: %v\n", position.StockSymbol, position.UserID, err)
 		}
-		return s.portfolioRepo.Create(newPortfolio)
 	}
+	return nil
+}
 
-	// Add to existing short position
-	stock, err := s.stockRepo.GetBySymbol(symbol)
+// workingCovers totals, per symbol, the short shares the user's working
+// covers of any type have left to buy back, so a margin call is not placed
+// twice
+func (s *AdvancedOrderService) workingCovers(userID int) (map[string]int, error) {
+	working, err := s.orderRepo.GetWorkingOrdersByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	return domain.NewHolds(working).Covers, nil
+}
+
+// ProcessMarketClose expires the DAY orders placed before the market's most
+// recent session close. It is idempotent, so the sweep can call it every tick.
+func (s *AdvancedOrderService) ProcessMarketClose(marketCode string) error {
+	sessionClose := domain.LastSessionClose(marketCode, time.Now())
+	orders, err := s.orderRepo.GetOrdersToExpireAtMarketClose(sessionClose)
 	if err != nil {
+		return fmt.Errorf("failed to get day orders: %w", err)
+	}
+
+	reason := fmt.Sprintf("DAY order expired at %s session close", marketCode)
+	for i := range orders {
+		order := &orders[i]
+		if err := s.closeOrder(order, domain.OrderStatusExpired, reason, domain.OrderActorSystem); err != nil {
+			fmt.Printf("⚠️ Failed to expire day order %d: %v\n", order.ID, err)
+			continue
+		}
+		fmt.Printf("🌙 Day order %d expired at session close\n", order.ID)
+	}
+
+	return nil
+}
+
+func (s *AdvancedOrderService) ProcessMarketOpen(marketCode string) error {
+	return nil
+}
+
+func (s *AdvancedOrderService) CheckPositionLimits(userID int, order *domain.Order) error {
+	return s.riskManager.CheckPositionLimits(userID, order)
+}
+
+func (s *AdvancedOrderService) CheckDailyLimits(userID int) error {
+	return s.riskManager.CheckDailyLimits(userID)
+}
+
+// ValidateRiskParameters checks the account can carry the order: its
+// position limits and the margin it would need
+func (s *AdvancedOrderService) ValidateRiskParameters(userID int, order *domain.Order) error {
+	if err := s.riskManager.CheckPositionLimits(userID, order); err != nil {
 		return err
 	}
+	return s.riskManager.CheckMarginRequirements(userID, order)
+}
+
+// NotifyOrderUpdate pushes an order's current state to its owner's
+// authenticated WebSocket connections
+func (s *AdvancedOrderService) NotifyOrderUpdate(order *domain.Order, updateType services.OrderUpdateType) error {
+	if s.realTimeService == nil {
+		return nil
+	}
 
-	portfolio.Quantity -= quantity // More negative
-	portfolio.TotalCost -= float64(quantity) * stock.CurrentPrice
+	message := fmt.Sprintf("Order %d %s", order.ID, order.Status)
+	switch updateType {
+	case services.OrderUpdateTypePartiallyFilled:
+		message = fmt.Sprintf("Order %d filled %d of %d", order.ID, order.ExecutedQuantity, order.Quantity)
+	case services.OrderUpdateTypeTriggered:
+		message = fmt.Sprintf("Order %d stop triggered, resting at limit %.2f", order.ID, *order.Price)
+	case services.OrderUpdateTypeModified:
+		message = fmt.Sprintf("Order %d amended to version %d", order.ID, order.Version)
+	}
 
-	return s.portfolioRepo.Update(portfolio)
+	s.realTimeService.SendOrderUpdate(order.UserID, string(updateType), domain.OrderUpdateMessage{
+		OrderID:           order.ID,
+		Status:            order.Status,
+		ExecutedQuantity:  order.ExecutedQuantity,
+		RemainingQuantity: order.RemainingQuantity,
+		ExecutedPrice:     order.ExecutedPrice,
+		Commission:        order.Commission,
+		Fees:              order.Fees,
+		Message:           message,
+	})
+	return nil
 }
 
-func (s *AdvancedOrderService) updatePortfolioForCover(userID int, symbol string, quantity int) error {
-	portfolio, err := s.portfolioRepo.GetByUserIDAndSymbol(userID, symbol)
+func (s *AdvancedOrderService) UpdatePortfolioOnExecution(execution *domain.OrderExecution) error {
+	return nil
+}
+
+// executeOrderTransaction settles a fill of quantity shares and records it
+// using the repositories of the caller's unit of work, so cash, position,
+// transaction, execution record and order status commit or roll back together
+func (s *AdvancedOrderService) executeOrderTransaction(tx repositories.TxRepositories, order *domain.Order, executionPrice float64, quantity int, slippage float64) (*domain.OrderExecution, error) {
+	commission, fees := order.CostsForFill(quantity)
+	gross := float64(quantity) * executionPrice
+
+	// Settle at the order's execution price with its share of the order's
+	// commission and fees, so the transaction history agrees with the order
+	fill := &domain.TradeFill{
+		UserID:      order.UserID,
+		StockSymbol: order.StockSymbol,
+		Quantity:    quantity,
+		Price:       executionPrice,
+		Commission:  commission,
+		Fees:        fees,
+		OrderID:     &order.ID,
+	}
+
+	var err error
+	switch order.Side {
+	case "BUY":
+		fmt.Printf("🔄 Executing BUY order via transaction service: %s x%d @ $%.2f\n", 
+			order.StockSymbol, quantity, executionPrice)
+		fill.Type = domain.TransactionTypeBuy
+		_, err = s.transactionService.ExecuteFill(tx, fill)
+		if err != nil {
+			return nil, fmt.Errorf("failed to execute buy transaction: %w", err)
+		}
+
+	case "SELL":
+		fmt.Printf("🔄 Executing SELL order via transaction service: %s x%d @ $%.2f\n", 
+			order.StockSymbol, quantity, executionPrice)
+		fill.Type = domain.TransactionTypeSell
+		_, err = s.transactionService.ExecuteFill(tx, fill)
+		if err != nil {
+			return nil, fmt.Errorf("failed to execute sell transaction: %w", err)
+		}
+
+	case "SHORT":
+		fmt.Printf("🔄 Executing SHORT order via transaction service: %s x%d @ $%.2f\n", 
+			order.StockSymbol, quantity, executionPrice)
+		fill.Type = domain.TransactionTypeShort
+		_, err = s.transactionService.ExecuteFill(tx, fill)
+		if err != nil {
+			return nil, fmt.Errorf("failed to execute short transaction: %w", err)
+		}
+
+	case "COVER":
+		fmt.Printf("🔄 Executing COVER order via transaction service: %s x%d @ $%.2f\n", 
+			order.StockSymbol, quantity, executionPrice)
+		fill.Type = domain.TransactionTypeCover
+		_, err = s.transactionService.ExecuteFill(tx, fill)
+		if err != nil {
+			return nil, fmt.Errorf("failed to execute cover transaction: %w", err)
+		}
+	}
+
+	now := time.Now()
+	execution := &domain.OrderExecution{
+		OrderID:          order.ID,
+		ExecutedPrice:    executionPrice,
+		ExecutedQuantity: quantity,
+		Commission:       commission,
+		Fees:             fees,
+		Slippage:         slippage,
+		ExecutedAt:       now,
+		TotalAmount:      gross,
+	}
+	if err := tx.Orders().RecordExecution(execution); err != nil {
+		return nil, err
+	}
+
+	// Record the fill on the order
+	err = tx.Orders().PartialFillOrder(order.ID, quantity, executionPrice)
 	if err != nil {
-		return err
+		return nil, fmt.Errorf("failed to update order status: %w", err)
 	}
+	order.ApplyFill(quantity, executionPrice, now)
 
-	portfolio.Quantity += quantity // Less negative
+	// A slice's fill is also progress on its algo parent
+	if order.ParentOrderID != nil {
+		if err := s.fillAlgoParent(tx, order, quantity, executionPrice); err != nil {
+			return nil, fmt.Errorf("failed to update algo parent: %w", err)
+		}
+	}
 
-	// If fully covered, delete portfolio entry
-	if portfolio.Quantity >= 0 {
-		return s.portfolioRepo.Delete(userID, symbol)
+	// One-cancels-other: the rest of the group goes with this fill
+	if order.OCOGroupID != nil {
+		reason := fmt.Sprintf("OCO: linked order #%d executed", order.ID)
+		cancelled, err := tx.Orders().CancelLinkedOrders(order.ID, reason)
+		if err != nil {
+			return nil, fmt.Errorf("failed to cancel linked orders: %w", err)
+		}
+		if cancelled > 0 {
+			fmt.Printf("🔗 OCO group %d: cancelled %d linked order(s)\n", *order.OCOGroupID, cancelled)
+		}
 	}
 
-	// Update cost proportionally
-	coverRatio := float64(quantity) / float64(-portfolio.Quantity + quantity)
-	portfolio.TotalCost *= (1 - coverRatio)
+	// A filled bracket entry arms its exits
+	if err := s.activateChildOrders(tx, order); err != nil {
+		return nil, fmt.Errorf("failed to activate bracket exits: %w", err)
+	}
 
-	return s.portfolioRepo.Update(portfolio)
+	fmt.Printf("✅ Order filled: ID=%d, Type=%s, Side=%s, Qty=%d, Filled=%d/%d\n", 
+		order.ID, order.OrderType, order.Side, quantity, order.ExecutedQuantity, order.Quantity)
+	return execution, nil
 }
 
+
+
+
+
 func (s *AdvancedOrderService) UpdateBalanceOnExecution(execution *domain.OrderExecution) error {
 	return nil
 } 
\ No newline at end of file
@@ -0,0 +1,380 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"stock-simulation-backend/internal/core/domain"
+	"stock-simulation-backend/internal/core/ports/repositories"
+)
+
+// storeOrder stores a working order for user 1, costed at price like a new
+// order. A limit order's limit is price.
+func storeOrder(t *testing.T, store *fakeStore, orderType domain.OrderType, side domain.OrderSide, quantity int, price float64) *domain.Order {
+	t.Helper()
+	order := &domain.Order{
+		UserID:            1,
+		StockSymbol:       "AAPL",
+		OrderType:         orderType,
+		Side:              side,
+		Quantity:          quantity,
+		RemainingQuantity: quantity,
+		TimeInForce:       domain.TimeInForceGTC,
+		Status:            domain.OrderStatusPending,
+		MarketPrice:       price,
+		Commission:        tradeCommission(quantity, price),
+		Fees:              tradeFees(quantity, price),
+	}
+	if orderType == domain.OrderTypeLimit {
+		order.Price = &price
+	}
+	if err := store.Orders().Create(order); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	return order
+}
+
+func TestExecuteOrderTransaction(t *testing.T) {
+	// 10 shares at 100 cost a $1 commission and $1 in fees
+	tests := []struct {
+		name        string
+		side        domain.OrderSide
+		held        int
+		cancelled   bool
+		wantErr     bool
+		wantBalance float64
+		wantHeld    int
+		wantProfit  float64
+	}{
+		{"buy pays for the fill and its costs", domain.OrderSideBuy, 0, false, false, 8998, 10, 0},
+		{"sell realizes the gain net of costs", domain.OrderSideSell, 20, false, false, 10998, 10, 98},
+		{"sell without the shares is refused", domain.OrderSideSell, 0, false, true, 10000, 0, 0},
+		{"settlement rolls back with the order update", domain.OrderSideBuy, 0, true, true, 10000, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newFakeStore()
+			store.addUser(1, 10000)
+			store.addStock("AAPL", 100)
+			if tt.held > 0 {
+				store.addHolding(1, "AAPL", tt.held, 90)
+			}
+			service := newTestOrderService(store, domain.RiskLimits{})
+			order := storeOrder(t, store, domain.OrderTypeMarket, tt.side, 10, 100)
+			if tt.cancelled {
+				// Cancelled after it was read, so only the order update fails
+				if err := store.Orders().CancelOrder(order.ID, "test", domain.OrderActorUser); err != nil {
+					t.Fatalf("CancelOrder() error = %v", err)
+				}
+			}
+
+			err := store.Do(func(tx repositories.TxRepositories) error {
+				_, err := service.executeOrderTransaction(tx, order, 100, 10, 0)
+				return err
+			})
+
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("executeOrderTransaction() error = %v, want error %v", err, tt.wantErr)
+			}
+			var refusal *domain.SettlementRefusal
+			if refused := errors.As(err, &refusal); refused != (tt.wantErr && !tt.cancelled) {
+				t.Errorf("executeOrderTransaction() error = %v, want a settlement refusal %v", err, !refused)
+			}
+			if user := store.users[1]; user.Balance != tt.wantBalance || user.TotalProfit != tt.wantProfit {
+				t.Errorf("balance, profit = %v, %v, want %v, %v", user.Balance, user.TotalProfit, tt.wantBalance, tt.wantProfit)
+			}
+			if held := store.portfolios[holdingKey{1, "AAPL"}].Quantity; held != tt.wantHeld {
+				t.Errorf("shares held = %d, want %d", held, tt.wantHeld)
+			}
+
+			stored := store.orders[order.ID]
+			if tt.wantErr {
+				if len(store.transactions) != 0 || len(store.executions) != 0 || stored.ExecutedQuantity != 0 {
+					t.Errorf("failed fill left %d transactions, %d executions and %d shares filled, want none",
+						len(store.transactions), len(store.executions), stored.ExecutedQuantity)
+				}
+				return
+			}
+
+			if len(store.transactions) != 1 || store.transactions[0].OrderID == nil || *store.transactions[0].OrderID != order.ID {
+				t.Fatalf("transactions = %+v, want one for order %d", store.transactions, order.ID)
+			}
+			if len(store.executions) != 1 || store.executions[0].ExecutedQuantity != 10 || store.executions[0].ExecutedPrice != 100 {
+				t.Errorf("executions = %+v, want 10 @ 100", store.executions)
+			}
+			if stored.Status != domain.OrderStatusExecuted || stored.ExecutedQuantity != 10 || stored.RemainingQuantity != 0 {
+				t.Errorf("order = %s, %d filled, %d left, want EXECUTED, 10 filled, 0 left",
+					stored.Status, stored.ExecutedQuantity, stored.RemainingQuantity)
+			}
+		})
+	}
+}
+
+func TestFillOrderDrawsOnTickLiquidity(t *testing.T) {
+	store := newFakeStore()
+	store.addUser(1, 100000)
+	store.addStock("AAPL", 100) // No volume, so each tick supplies minTickLiquidity
+	service := newTestOrderService(store, domain.RiskLimits{})
+	order := storeOrder(t, store, domain.OrderTypeMarket, domain.OrderSideBuy, 250, 100)
+
+	// Each tick fills what its budget allows, and nothing more
+	steps := []struct {
+		newTick       bool
+		wantErr       error
+		wantFilled    int
+		wantRemaining int
+		wantStatus    domain.OrderStatus
+	}{
+		{false, nil, 100, 150, domain.OrderStatusPartiallyFilled},
+		{false, errNoLiquidity, 100, 150, domain.OrderStatusPartiallyFilled},
+		{true, nil, 200, 50, domain.OrderStatusPartiallyFilled},
+		{true, nil, 250, 0, domain.OrderStatusExecuted},
+	}
+
+	for i, step := range steps {
+		if step.newTick {
+			service.liquidity.reset()
+		}
+		_, err := service.fillOrder(order, 100)
+		if !errors.Is(err, step.wantErr) {
+			t.Fatalf("step %d: fillOrder() error = %v, want %v", i, err, step.wantErr)
+		}
+		if order.ExecutedQuantity != step.wantFilled || order.RemainingQuantity != step.wantRemaining || order.Status != step.wantStatus {
+			t.Errorf("step %d: order = %d filled, %d left, %s, want %d, %d, %s", i,
+				order.ExecutedQuantity, order.RemainingQuantity, order.Status,
+				step.wantFilled, step.wantRemaining, step.wantStatus)
+		}
+	}
+
+	if len(store.executions) != 3 {
+		t.Errorf("executions = %d, want 3", len(store.executions))
+	}
+	if held := store.portfolios[holdingKey{1, "AAPL"}].Quantity; held != 250 {
+		t.Errorf("shares held = %d, want 250", held)
+	}
+}
+
+func TestFillOrderRefusedGivesBackLiquidity(t *testing.T) {
+	store := newFakeStore()
+	store.addUser(1, 100000)
+	store.addStock("AAPL", 100)
+	service := newTestOrderService(store, domain.RiskLimits{})
+
+	// A sell with no shares to deliver cannot settle
+	sell := storeOrder(t, store, domain.OrderTypeMarket, domain.OrderSideSell, 50, 100)
+	_, err := service.fillOrder(sell, 100)
+	var refusal *domain.SettlementRefusal
+	if !errors.As(err, &refusal) {
+		t.Fatalf("fillOrder() error = %v, want a settlement refusal", err)
+	}
+	if sell.Status != domain.OrderStatusPending || sell.ExecutedQuantity != 0 {
+		t.Errorf("refused order = %s, %d filled, want PENDING, 0 filled", sell.Status, sell.ExecutedQuantity)
+	}
+
+	// The refused fill's shares are still there for the rest of the tick
+	buy := storeOrder(t, store, domain.OrderTypeMarket, domain.OrderSideBuy, 100, 100)
+	if _, err := service.fillOrder(buy, 100); err != nil {
+		t.Fatalf("fillOrder() error = %v", err)
+	}
+	if buy.Status != domain.OrderStatusExecuted {
+		t.Errorf("buy status = %s, want EXECUTED", buy.Status)
+	}
+}
+
+func TestFillOrderNotExecutable(t *testing.T) {
+	store := newFakeStore()
+	store.addUser(1, 100000)
+	store.addStock("AAPL", 100)
+	service := newTestOrderService(store, domain.RiskLimits{})
+
+	// A buy limited to 90 does not fill with the market at 100
+	order := storeOrder(t, store, domain.OrderTypeLimit, domain.OrderSideBuy, 10, 90)
+	if _, err := service.fillOrder(order, 100); err == nil {
+		t.Fatal("fillOrder() error = nil, want the order refused at 100")
+	}
+	if order.Status != domain.OrderStatusPending || len(store.executions) != 0 {
+		t.Errorf("order = %s with %d executions, want PENDING with none", order.Status, len(store.executions))
+	}
+
+	if _, err := service.fillOrder(order, 90); err != nil {
+		t.Fatalf("fillOrder() at the limit error = %v", err)
+	}
+	if order.Status != domain.OrderStatusExecuted || *order.ExecutedPrice != 90 {
+		t.Errorf("order = %s at %v, want EXECUTED at 90", order.Status, *order.ExecutedPrice)
+	}
+}
+
+func TestMatchOrdersCancelsOnlyRefusedFills(t *testing.T) {
+	store := newFakeStore()
+	store.addUser(1, 100000)
+	store.addStock("AAPL", 100)
+	service := newTestOrderService(store, domain.RiskLimits{})
+
+	noShares := storeOrder(t, store, domain.OrderTypeLimit, domain.OrderSideSell, 50, 100)
+	fills := storeOrder(t, store, domain.OrderTypeLimit, domain.OrderSideBuy, 100, 100)
+	noLiquidity := storeOrder(t, store, domain.OrderTypeLimit, domain.OrderSideBuy, 10, 100)
+	belowLimit := storeOrder(t, store, domain.OrderTypeLimit, domain.OrderSideBuy, 10, 90)
+
+	orders := []domain.Order{*noShares, *fills, *noLiquidity, *belowLimit}
+	executions := service.matchOrders(orders, domain.OrderTypeLimit, 100)
+	if len(executions) != 1 || executions[0].OrderID != fills.ID {
+		t.Fatalf("executions = %+v, want one for order %d", executions, fills.ID)
+	}
+
+	tests := []struct {
+		name  string
+		order *domain.Order
+		want  domain.OrderStatus
+	}{
+		{"a fill that cannot settle is cancelled", noShares, domain.OrderStatusCancelled},
+		{"a marketable order fills", fills, domain.OrderStatusExecuted},
+		{"an order short of liquidity waits for the next tick", noLiquidity, domain.OrderStatusPending},
+		{"an order away from the market rests", belowLimit, domain.OrderStatusPending},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := store.orders[tt.order.ID].Status; got != tt.want {
+				t.Errorf("status = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCreateOCOOrderCancelsTheOtherLegOnFill(t *testing.T) {
+	price := func(v float64) *float64 { return &v }
+
+	// A take-profit limit sell and a stop-loss protect the same shares
+	tests := []struct {
+		name       string
+		quantity   int
+		limit      float64
+		fillAt     float64 // Matched after placement when not zero
+		wantParent domain.OrderStatus
+		wantLinked domain.OrderStatus
+	}{
+		{"both legs rest", 100, 110, 0, domain.OrderStatusPending, domain.OrderStatusPending},
+		{"a fill cancels the other leg", 100, 110, 111, domain.OrderStatusExecuted, domain.OrderStatusCancelled},
+		{"a leg marketable on arrival cancels the other", 100, 95, 0, domain.OrderStatusExecuted, domain.OrderStatusCancelled},
+		{"a partial fill cancels the other leg", 150, 95, 0, domain.OrderStatusPartiallyFilled, domain.OrderStatusCancelled},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newFakeStore()
+			store.addUser(1, 10000)
+			store.addStock("AAPL", 100)
+			store.addHolding(1, "AAPL", tt.quantity, 90)
+			service := newTestOrderService(store, domain.RiskLimits{})
+
+			parent, linked, err := service.CreateOCOOrder(1,
+				&domain.OrderRequest{StockSymbol: "AAPL", OrderType: domain.OrderTypeLimit, Side: domain.OrderSideSell,
+					Quantity: tt.quantity, Price: price(tt.limit)},
+				&domain.OrderRequest{StockSymbol: "AAPL", OrderType: domain.OrderTypeStopLoss, Side: domain.OrderSideSell,
+					Quantity: tt.quantity, StopPrice: price(90)})
+			if err != nil {
+				t.Fatalf("CreateOCOOrder() error = %v", err)
+			}
+			if parent.OCOGroupID == nil || linked.OCOGroupID == nil || *parent.OCOGroupID != *linked.OCOGroupID {
+				t.Fatalf("legs are not in one OCO group: %v, %v", parent.OCOGroupID, linked.OCOGroupID)
+			}
+
+			if tt.fillAt > 0 {
+				if _, err := service.fillOrder(parent, tt.fillAt); err != nil {
+					t.Fatalf("fillOrder() error = %v", err)
+				}
+			}
+
+			gotParent, gotLinked := store.orders[parent.ID], store.orders[linked.ID]
+			if gotParent.Status != tt.wantParent || gotLinked.Status != tt.wantLinked {
+				t.Errorf("statuses = %s, %s, want %s, %s", gotParent.Status, gotLinked.Status, tt.wantParent, tt.wantLinked)
+			}
+			if tt.wantLinked == domain.OrderStatusCancelled &&
+				(gotLinked.CancelReason == nil || *gotLinked.CancelReason != fmt.Sprintf("OCO: linked order #%d executed", parent.ID)) {
+				t.Errorf("cancel reason = %v, want the filled leg named", gotLinked.CancelReason)
+			}
+		})
+	}
+}
+
+func TestCreateBracketOrderArmsExitsWithTheEntrysFills(t *testing.T) {
+	price := func(v float64) *float64 { return &v }
+	store := newFakeStore()
+	store.addUser(1, 100000)
+	store.addStock("AAPL", 100)
+	service := newTestOrderService(store, domain.RiskLimits{})
+
+	// The market entry is larger than one tick's liquidity
+	bracket, err := service.CreateBracketOrder(1, &domain.OrderRequest{StockSymbol: "AAPL", OrderType: domain.OrderTypeBracket,
+		Side: domain.OrderSideBuy, Quantity: 150, TakeProfitPrice: price(110), StopLossPrice: price(90)})
+	if err != nil {
+		t.Fatalf("CreateBracketOrder() error = %v", err)
+	}
+	entry, takeProfit, stopLoss := bracket.Entry.ID, bracket.TakeProfit.ID, bracket.StopLoss.ID
+
+	// assertExits checks both exits are sized to what the entry has filled
+	assertExits := func(step string, wantStatus domain.OrderStatus, wantQuantity int) {
+		t.Helper()
+		for _, id := range []int{takeProfit, stopLoss} {
+			exit := store.orders[id]
+			if exit.Status != wantStatus || exit.Quantity != wantQuantity || exit.RemainingQuantity != wantQuantity {
+				t.Errorf("%s: exit %d = %s, %d of %d left, want %s, %d", step, id,
+					exit.Status, exit.RemainingQuantity, exit.Quantity, wantStatus, wantQuantity)
+			}
+		}
+	}
+
+	if got := store.orders[entry]; got.Status != domain.OrderStatusPartiallyFilled || got.ExecutedQuantity != 100 {
+		t.Fatalf("entry = %s, %d filled, want PARTIALLY_FILLED, 100", got.Status, got.ExecutedQuantity)
+	}
+	assertExits("first fill", domain.OrderStatusPending, 100)
+
+	service.liquidity.reset()
+	order := store.orders[entry]
+	if _, err := service.fillOrder(&order, 100); err != nil {
+		t.Fatalf("fillOrder() entry error = %v", err)
+	}
+	assertExits("second fill", domain.OrderStatusPending, 150)
+
+	// The take profit triggers and its fill cancels the stop loss
+	service.liquidity.reset()
+	order = store.orders[takeProfit]
+	if _, err := service.fillOrder(&order, 111); err != nil {
+		t.Fatalf("fillOrder() take profit error = %v", err)
+	}
+	if got := store.orders[stopLoss].Status; got != domain.OrderStatusCancelled {
+		t.Errorf("stop loss = %s, want CANCELLED", got)
+	}
+	if got := store.orders[takeProfit].Status; got != domain.OrderStatusPartiallyFilled {
+		t.Errorf("take profit = %s, want PARTIALLY_FILLED", got)
+	}
+}
+
+func TestCancelBracketEntryCancelsDormantExits(t *testing.T) {
+	price := func(v float64) *float64 { return &v }
+	store := newFakeStore()
+	store.addUser(1, 100000)
+	store.addStock("AAPL", 100)
+	service := newTestOrderService(store, domain.RiskLimits{})
+
+	// A limit entry below the market rests, so its exits stay dormant
+	bracket, err := service.CreateBracketOrder(1, &domain.OrderRequest{StockSymbol: "AAPL", OrderType: domain.OrderTypeBracket,
+		Side: domain.OrderSideBuy, Quantity: 10, Price: price(95), TakeProfitPrice: price(110), StopLossPrice: price(90)})
+	if err != nil {
+		t.Fatalf("CreateBracketOrder() error = %v", err)
+	}
+	if bracket.TakeProfit.Status != domain.OrderStatusDormant || bracket.StopLoss.Status != domain.OrderStatusDormant {
+		t.Fatalf("exits = %s, %s, want DORMANT", bracket.TakeProfit.Status, bracket.StopLoss.Status)
+	}
+
+	if err := service.CancelOrder(1, bracket.Entry.ID); err != nil {
+		t.Fatalf("CancelOrder() error = %v", err)
+	}
+	for _, id := range []int{bracket.Entry.ID, bracket.TakeProfit.ID, bracket.StopLoss.ID} {
+		if got := store.orders[id].Status; got != domain.OrderStatusCancelled {
+			t.Errorf("order %d = %s, want CANCELLED", id, got)
+		}
+	}
+}
@@ -0,0 +1,404 @@
+package services
+
+import (
+	"fmt"
+	"time"
+
+	"stock-simulation-backend/internal/core/domain"
+	"stock-simulation-backend/internal/core/ports/repositories"
+	"stock-simulation-backend/internal/core/ports/services"
+)
+
+// volumeProfileLookback is how much price history a VWAP schedule's intraday
+// volume profile is built from
+const volumeProfileLookback = 5 * 24 * time.Hour
+
+// validateAlgo checks a TWAP or VWAP request's schedule and slice type
+func validateAlgo(request *domain.OrderRequest) error {
+	if request.DurationMinutes == nil || *request.DurationMinutes <= 0 {
+		return fmt.Errorf("%s orders require a positive duration_minutes", request.OrderType)
+	}
+	if time.Duration(*request.DurationMinutes)*time.Minute > domain.MaxAlgoDuration {
+		return fmt.Errorf("%s orders can run for at most %d minutes", request.OrderType, int(domain.MaxAlgoDuration.Minutes()))
+	}
+
+	switch request.SliceOrderType {
+	case "", domain.OrderTypeMarket:
+	case domain.OrderTypeLimit:
+		if request.Price == nil || *request.Price <= 0 {
+			return fmt.Errorf("limit slices require a valid price")
+		}
+	default:
+		return fmt.Errorf("%s orders slice into MARKET or LIMIT orders, not %s", request.OrderType, request.SliceOrderType)
+	}
+
+	switch request.TimeInForce {
+	case "", domain.TimeInForceGTC:
+	default:
+		return fmt.Errorf("%s orders run on their own schedule and must be GTC", request.OrderType)
+	}
+	return nil
+}
+
+// CreateAlgoOrder stores a TWAP or VWAP parent and its schedule. The parent
+// never trades itself: each tick, ProcessAlgoOrders sends the quantity the
+// schedule calls for as child orders linked back through ParentOrderID.
+func (s *AdvancedOrderService) CreateAlgoOrder(userID int, request *domain.OrderRequest) (*domain.AlgoOrder, error) {
+	if request.OrderType != domain.OrderTypeTWAP && request.OrderType != domain.OrderTypeVWAP {
+		return nil, fmt.Errorf("expected a TWAP or VWAP order, got %s", request.OrderType)
+	}
+	if err := s.ValidateOrder(userID, request); err != nil {
+		return nil, err
+	}
+
+	stock, err := s.stockRepo.GetBySymbol(request.StockSymbol)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stock price: %w", err)
+	}
+
+	parent := s.newOrder(userID, request, stock)
+	parent.TimeInForce = domain.TimeInForceGTC
+	if request.SliceOrderType != domain.OrderTypeLimit {
+		parent.Price = nil
+	}
+	// Each slice is charged its own commission and fees
+	parent.Commission, parent.Fees = 0, 0
+
+	now := time.Now()
+	params := &domain.AlgoParams{
+		SliceType:    domain.OrderTypeMarket,
+		StartAt:      now,
+		EndAt:        now.Add(time.Duration(*request.DurationMinutes) * time.Minute),
+		ArrivalPrice: stock.CurrentPrice,
+	}
+	if request.SliceOrderType == domain.OrderTypeLimit {
+		params.SliceType = domain.OrderTypeLimit
+	}
+	if parent.OrderType == domain.OrderTypeVWAP {
+		params.VolumeProfile = s.volumeProfile(stock.Symbol, now)
+	}
+
+	err = s.placeChecked(userID, func() error {
+		return s.riskManager.ValidateOrder(userID, parent)
+	}, func(tx repositories.TxRepositories) error {
+		if err := tx.Orders().Create(parent); err != nil {
+			return fmt.Errorf("failed to create %s order: %w", request.OrderType, err)
+		}
+		params.OrderID = parent.ID
+		if err := tx.AlgoOrders().Create(params); err != nil {
+			return fmt.Errorf("failed to create %s order: %w", request.OrderType, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	fmt.Printf("🧮 %s order %d: %s %d %s over %d minutes, arrival $%.2f\n",
+		parent.OrderType, parent.ID, parent.Side, parent.Quantity, parent.StockSymbol,
+		*request.DurationMinutes, params.ArrivalPrice)
+	return s.GetAlgoOrder(userID, parent.ID)
+}
+
+// volumeProfile builds a VWAP schedule's weights from recent volume by hour
+// of day. Without history the schedule falls back to TWAP.
+func (s *AdvancedOrderService) volumeProfile(symbol string, now time.Time) []float64 {
+	if s.historicalPriceRepo == nil {
+		return nil
+	}
+	volumeByHour, err := s.historicalPriceRepo.GetHourlyVolume(symbol, now.Add(-volumeProfileLookback))
+	if err != nil {
+		fmt.Printf("⚠️ No volume profile for %s, slicing evenly: %v\n", symbol, err)
+		return nil
+	}
+	return domain.NewVolumeProfile(volumeByHour)
+}
+
+func (s *AdvancedOrderService) GetAlgoOrder(userID, orderID int) (*domain.AlgoOrder, error) {
+	parent, params, err := s.algoOrderFor(userID, orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	children, err := s.orderRepo.GetChildOrders(parent.ID)
+	if err != nil {
+		return nil, err
+	}
+	return domain.NewAlgoOrder(*parent, *params, children, time.Now()), nil
+}
+
+// PauseAlgoOrder stops the schedule and takes the working slices out of the
+// market until the order is resumed
+func (s *AdvancedOrderService) PauseAlgoOrder(userID, orderID int) (*domain.AlgoOrder, error) {
+	parent, params, err := s.algoOrderFor(userID, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if !parent.IsActive() {
+		return nil, fmt.Errorf("cannot pause order with status: %s", parent.Status)
+	}
+
+	// The parent goes first so no new slice is sent while the working ones
+	// are being paused
+	if err := s.orderRepo.PauseOrder(parent.ID); err != nil {
+		return nil, err
+	}
+	paused, err := s.orderRepo.PauseChildOrders(parent.ID)
+	if err != nil {
+		return nil, err
+	}
+	params.Pause(time.Now())
+	if err := s.algoOrderRepo.Update(params); err != nil {
+		return nil, err
+	}
+
+	fmt.Printf("⏸️ %s order %d paused with %d working slice(s)\n", parent.OrderType, parent.ID, paused)
+	parent.Status = domain.OrderStatusPaused
+	if err := s.NotifyOrderUpdate(parent, services.OrderUpdateTypePaused); err != nil {
+		fmt.Printf("⚠️ Failed to notify order %d update: %v\n", parent.ID, err)
+	}
+	return s.GetAlgoOrder(userID, orderID)
+}
+
+// ResumeAlgoOrder puts the paused slices back in the market and restarts the
+// schedule where it stopped
+func (s *AdvancedOrderService) ResumeAlgoOrder(userID, orderID int) (*domain.AlgoOrder, error) {
+	parent, params, err := s.algoOrderFor(userID, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if parent.Status != domain.OrderStatusPaused {
+		return nil, fmt.Errorf("cannot resume order with status: %s", parent.Status)
+	}
+
+	// Until the schedule is shifted it stays frozen at the pause, so resuming
+	// the parent first cannot release a burst of slices
+	if err := s.orderRepo.ResumeOrder(parent.ID); err != nil {
+		return nil, err
+	}
+	resumed, err := s.orderRepo.ResumeChildOrders(parent.ID)
+	if err != nil {
+		return nil, err
+	}
+	params.Resume(time.Now())
+	if err := s.algoOrderRepo.Update(params); err != nil {
+		return nil, err
+	}
+
+	fmt.Printf("▶️ %s order %d resumed with %d working slice(s), now ends %s\n",
+		parent.OrderType, parent.ID, resumed, params.EndAt.Format(time.RFC3339))
+	algo, err := s.GetAlgoOrder(userID, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.NotifyOrderUpdate(&algo.Order, services.OrderUpdateTypeResumed); err != nil {
+		fmt.Printf("⚠️ Failed to notify order %d update: %v\n", parent.ID, err)
+	}
+	return algo, nil
+}
+
+// cancelAlgoOrder cancels a parent, then each of its working slices, in the
+// lock order lockAlgoParent describes. The parent's lock is released before
+// any slice is locked.
+func (s *AdvancedOrderService) cancelAlgoOrder(parent *domain.Order, reason string, actor domain.OrderActor) error {
+	if !parent.IsActive() && parent.Status != domain.OrderStatusPaused {
+		return fmt.Errorf("cannot cancel order with status: %s", parent.Status)
+	}
+
+	if err := s.orderRepo.CancelOrder(parent.ID, reason, actor); err != nil {
+		return fmt.Errorf("failed to cancel order: %w", err)
+	}
+	parent.Status = domain.OrderStatusCancelled
+	parent.CancelReason = &reason
+
+	children, err := s.orderRepo.GetChildOrders(parent.ID)
+	if err != nil {
+		return err
+	}
+	childReason := fmt.Sprintf("%s: parent order #%d cancelled", parent.OrderType, parent.ID)
+	for i := range children {
+		child := &children[i]
+		if !child.IsActive() && child.Status != domain.OrderStatusPaused {
+			continue
+		}
+		if err := s.closeOrder(child, domain.OrderStatusCancelled, childReason, actor); err != nil {
+			fmt.Printf("⚠️ Failed to cancel slice %d of order %d: %v\n", child.ID, parent.ID, err)
+		}
+	}
+
+	if err := s.NotifyOrderUpdate(parent, services.OrderUpdateTypeCancelled); err != nil {
+		fmt.Printf("⚠️ Failed to notify order %d update: %v\n", parent.ID, err)
+	}
+	return nil
+}
+
+// algoOrderFor loads a user's algo parent with its schedule
+func (s *AdvancedOrderService) algoOrderFor(userID, orderID int) (*domain.Order, *domain.AlgoParams, error) {
+	parent, err := s.orderRepo.GetByID(orderID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if parent.UserID != userID || !parent.IsAlgo() {
+		return nil, nil, fmt.Errorf("algo order not found")
+	}
+
+	params, err := s.algoOrderRepo.GetByOrderID(orderID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return parent, params, nil
+}
+
+// ProcessAlgoOrders sends each running parent the slice that brings it up to
+// its schedule at this tick's price
+func (s *AdvancedOrderService) ProcessAlgoOrders(priceUpdates map[string]float64) error {
+	if s.algoOrderRepo == nil {
+		return nil
+	}
+
+	running, err := s.algoOrderRepo.GetRunning()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for i := range running {
+		params := &running[i]
+		parent, err := s.orderRepo.GetByID(params.OrderID)
+		if err != nil {
+			fmt.Printf("⚠️ Failed to load algo order %d: %v\n", params.OrderID, err)
+			continue
+		}
+		price, ok := priceUpdates[parent.StockSymbol]
+		if !ok {
+			continue
+		}
+		if err := s.sendSlice(parent, params, price, now); err != nil {
+			fmt.Printf("⚠️ Failed to slice %s order %d: %v\n", parent.OrderType, parent.ID, err)
+		}
+	}
+	return nil
+}
+
+// sendSlice places a child order for whatever the schedule calls for beyond
+// what has filled and what is still working. A slice that cannot trade at
+// all, e.g. for lack of cash, cancels the parent rather than failing again on
+// every tick.
+//
+// The parent's hold already covers its slices, so a slice is not checked
+// again. It is sized and stored holding the parent and then the user's row
+// lock, so it cannot outrun a fill or cancel of the parent, and the user's
+// other orders are checked and placed either before or after it.
+func (s *AdvancedOrderService) sendSlice(parent *domain.Order, params *domain.AlgoParams, price float64, now time.Time) error {
+	stock, err := s.stockRepo.GetBySymbol(parent.StockSymbol)
+	if err != nil {
+		return fmt.Errorf("stock not found: %s", parent.StockSymbol)
+	}
+	stock.CurrentPrice = price
+
+	var child *domain.Order
+	working := 0
+	err = s.uow.Do(func(tx repositories.TxRepositories) error {
+		current, err := tx.Orders().GetByIDForUpdate(parent.ID)
+		if err != nil {
+			return err
+		}
+		*parent = *current
+		if !parent.IsActive() {
+			return nil
+		}
+
+		children, err := tx.Orders().GetChildOrders(parent.ID)
+		if err != nil {
+			return err
+		}
+		for _, child := range children {
+			if child.IsActive() {
+				working += child.RemainingQuantity
+			}
+		}
+
+		quantity := params.TargetQuantity(parent.OrderType, parent.Quantity, now) - parent.ExecutedQuantity - working
+		if unsent := parent.RemainingQuantity - working; quantity > unsent {
+			quantity = unsent
+		}
+		if quantity <= 0 {
+			return nil
+		}
+
+		if _, err := tx.Users().GetByIDForUpdate(parent.UserID); err != nil {
+			return fmt.Errorf("failed to lock user: %w", err)
+		}
+		request := &domain.OrderRequest{
+			StockSymbol: parent.StockSymbol,
+			OrderType:   params.SliceType,
+			Side:        parent.Side,
+			Quantity:    quantity,
+			TimeInForce: domain.TimeInForceGTC,
+		}
+		if params.SliceType == domain.OrderTypeLimit {
+			request.Price = parent.Price
+		}
+		child = s.newOrder(parent.UserID, request, stock)
+		child.ParentOrderID = &parent.ID
+		if err := tx.Orders().Create(child); err != nil {
+			return fmt.Errorf("failed to create slice: %w", err)
+		}
+		return nil
+	})
+	if err != nil || child == nil {
+		return err
+	}
+
+	fmt.Printf("🧩 %s order %d: slice %d for %d %s (%d/%d filled, %d working)\n",
+		parent.OrderType, parent.ID, child.ID, child.Quantity, parent.StockSymbol,
+		parent.ExecutedQuantity, parent.Quantity, working)
+
+	if err := s.activateOrder(child, price); err != nil {
+		reason := fmt.Sprintf("%s: slice #%d failed: %v", parent.OrderType, child.ID, err)
+		if cancelErr := s.cancelAlgoOrder(parent, reason, domain.OrderActorSystem); cancelErr != nil {
+			fmt.Printf("Warning: failed to cancel algo order %d: %v\n", parent.ID, cancelErr)
+		}
+		return err
+	}
+	return nil
+}
+
+// fillAlgoParent books a slice's fill on its parent, if the parent is an
+// algo order, inside the slice's transaction
+func (s *AdvancedOrderService) fillAlgoParent(tx repositories.TxRepositories, order *domain.Order, quantity int, price float64) error {
+	parent, err := s.lockAlgoParent(tx, order)
+	if err != nil || parent == nil {
+		return err
+	}
+	if err := tx.Orders().PartialFillOrder(parent.ID, quantity, price); err != nil {
+		return err
+	}
+
+	if parent.RemainingQuantity == quantity {
+		fmt.Printf("🏁 %s order %d complete: %d %s\n", parent.OrderType, parent.ID, parent.Quantity, parent.StockSymbol)
+	}
+	return nil
+}
+
+// lockAlgoParent locks and returns the algo parent of a slice. Any other
+// order gets nil and takes no lock: the parent is read first, so a bracket
+// exit's entry, which is also its parent, is never locked here.
+//
+// Orders are locked in one order: an algo parent, then the order itself,
+// then its owner's user row. A fill locks the parent before the slice it
+// fills, sendSlice the parent before the user, and cancelAlgoOrder the
+// parent before each of its slices.
+func (s *AdvancedOrderService) lockAlgoParent(tx repositories.TxRepositories, order *domain.Order) (*domain.Order, error) {
+	if order.ParentOrderID == nil {
+		return nil, nil
+	}
+	parent, err := tx.Orders().GetByID(*order.ParentOrderID)
+	if err != nil {
+		return nil, err
+	}
+	if !parent.IsAlgo() {
+		return nil, nil
+	}
+	return tx.Orders().GetByIDForUpdate(parent.ID)
+}
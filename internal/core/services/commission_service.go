@@ -1,6 +1,8 @@
 package services
 
 import (
+	"fmt"
+	"math"
 	"time"
 
 	"stock-simulation-backend/internal/core/domain"
@@ -8,10 +10,29 @@ import (
 	"stock-simulation-backend/internal/core/ports/services"
 )
 
-type CommissionService struct{}
+const (
+	// volatilityWindow is how many recent historical prices feed the
+	// volatility estimate
+	volatilityWindow = 30
 
-func NewCommissionService() services.CommissionService {
-	return &CommissionService{}
+	// volumeImpactRate scales the square root of an order's share of daily
+	// volume into slippage, in percent
+	volumeImpactRate = 5.0
+)
+
+type CommissionService struct {
+	stockRepo           repositories.StockRepository
+	historicalPriceRepo repositories.HistoricalPriceRepository
+}
+
+func NewCommissionService(
+	stockRepo repositories.StockRepository,
+	historicalPriceRepo repositories.HistoricalPriceRepository,
+) services.CommissionService {
+	return &CommissionService{
+		stockRepo:           stockRepo,
+		historicalPriceRepo: historicalPriceRepo,
+	}
 }
 
 func (s *CommissionService) CalculateCommission(userID int, tradeValue float64, orderType domain.OrderType, assetType string) (*domain.CommissionCalculation, error) {
@@ -62,9 +83,56 @@ func (s *CommissionService) GetUserCommissionTier(userID int) (*domain.Commissio
 	}, nil
 }
 
-// Add missing interface methods
+// CalculateSlippage estimates the price a fill gives up, in percent of the
+// price. Orders that fill at a price they named (limits, take profits) do not
+// slip; market and stop orders pay a base cost for their type, an impact that
+// grows with the square root of their share of daily volume, and a share of
+// the symbol's recent volatility.
 func (s *CommissionService) CalculateSlippage(symbol string, quantity int, orderType domain.OrderType) (*domain.Slippage, error) {
-	return &domain.Slippage{}, nil
+	var baseSlippage, volatilityShare float64
+	switch orderType {
+	case domain.OrderTypeMarket:
+		baseSlippage, volatilityShare = 0.02, 0.25
+	case domain.OrderTypeStopLoss, domain.OrderTypeTrailingStop:
+		// Stops fire into the move that triggered them
+		baseSlippage, volatilityShare = 0.05, 0.50
+	default:
+		return &domain.Slippage{}, nil
+	}
+
+	stock, err := s.stockRepo.GetBySymbol(symbol)
+	if err != nil {
+		return nil, fmt.Errorf("stock not found: %s", symbol)
+	}
+
+	volumeImpact := domain.CalculateMarketImpactSlippage(quantity, stock.Volume, baseSlippage) - baseSlippage
+	if stock.Volume > 0 {
+		volumeImpact += volumeImpactRate * math.Sqrt(float64(quantity)/float64(stock.Volume))
+	}
+
+	marketImpact := s.recentVolatility(symbol) * volatilityShare
+	total := baseSlippage + volumeImpact + marketImpact
+
+	return &domain.Slippage{
+		BaseSlippage:   baseSlippage,
+		VolumeImpact:   volumeImpact,
+		MarketImpact:   marketImpact,
+		TotalSlippage:  total,
+		SlippageAmount: math.Round(stock.CurrentPrice*float64(quantity)*total) / 100,
+	}, nil
+}
+
+// recentVolatility is the standard deviation of the latest historical price
+// returns, in percent. Symbols without enough history count as calm.
+func (s *CommissionService) recentVolatility(symbol string) float64 {
+	if s.historicalPriceRepo == nil {
+		return 0
+	}
+	prices, err := s.historicalPriceRepo.GetBySymbolWithLimit(symbol, volatilityWindow)
+	if err != nil {
+		return 0
+	}
+	return domain.ReturnVolatility(prices)
 }
 
 func (s *CommissionService) CreateCommissionStructure(structure *domain.CommissionStructure) error {
@@ -0,0 +1,349 @@
+package services
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"stock-simulation-backend/internal/core/domain"
+	"stock-simulation-backend/internal/core/ports/repositories"
+	"stock-simulation-backend/internal/core/ports/services"
+)
+
+// indicatorLookback is how many saved closes feed a condition's indicators;
+// enough for the longest series, MA50
+const indicatorLookback = 60
+
+// ContingentOrderService holds order requests until their conditions are met
+// and then submits them as ordinary orders. Conditions are evaluated once per
+// simulator tick; the value each one watched is remembered between ticks so
+// crossings can be detected.
+type ContingentOrderService struct {
+	contingentOrderRepo repositories.ContingentOrderRepository
+	stockRepo           repositories.StockRepository
+	historicalPriceRepo repositories.HistoricalPriceRepository
+	orderService        services.AdvancedOrderService
+
+	mu         sync.Mutex
+	lastValues map[string]float64 // By TriggerCondition.Key, as seen on the previous tick
+}
+
+func NewContingentOrderService(
+	contingentOrderRepo repositories.ContingentOrderRepository,
+	stockRepo repositories.StockRepository,
+	historicalPriceRepo repositories.HistoricalPriceRepository,
+	orderService services.AdvancedOrderService,
+) services.ContingentOrderService {
+	return &ContingentOrderService{
+		contingentOrderRepo: contingentOrderRepo,
+		stockRepo:           stockRepo,
+		historicalPriceRepo: historicalPriceRepo,
+		orderService:        orderService,
+		lastValues:          make(map[string]float64),
+	}
+}
+
+func (s *ContingentOrderService) CreateContingentOrder(userID int, request *domain.ContingentOrderRequest) (*domain.ContingentOrder, error) {
+	if err := s.validateRequest(userID, request); err != nil {
+		return nil, err
+	}
+
+	contingent := &domain.ContingentOrder{
+		UserID:     userID,
+		Conditions: request.Conditions,
+		Logic:      request.Logic,
+		Order:      request.Order,
+		Status:     domain.ContingentStatusActive,
+	}
+	if err := s.contingentOrderRepo.Create(contingent); err != nil {
+		return nil, err
+	}
+
+	fmt.Printf("🎯 Contingent order %d created for user %d: %s %d %s on %d condition(s)\n",
+		contingent.ID, userID, contingent.Order.Side, contingent.Order.Quantity,
+		contingent.Order.StockSymbol, len(contingent.Conditions))
+	return contingent, nil
+}
+
+// validateRequest checks the conditions watch known symbols and that the
+// dormant order would be accepted if it were placed now
+func (s *ContingentOrderService) validateRequest(userID int, request *domain.ContingentOrderRequest) error {
+	if err := request.Validate(); err != nil {
+		return err
+	}
+	for _, condition := range request.Conditions {
+		if _, err := s.stockRepo.GetBySymbol(condition.Symbol); err != nil {
+			return fmt.Errorf("stock not found: %s", condition.Symbol)
+		}
+	}
+	return s.orderService.ValidateOrder(userID, &request.Order)
+}
+
+func (s *ContingentOrderService) GetContingentOrder(userID int, id int) (*domain.ContingentOrder, error) {
+	contingent, err := s.contingentOrderRepo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if contingent.UserID != userID {
+		return nil, fmt.Errorf("contingent order not found")
+	}
+	return contingent, nil
+}
+
+func (s *ContingentOrderService) GetUserContingentOrders(userID int) ([]domain.ContingentOrder, error) {
+	return s.contingentOrderRepo.GetByUserID(userID)
+}
+
+func (s *ContingentOrderService) UpdateContingentOrder(userID int, id int, request *domain.ContingentOrderRequest) (*domain.ContingentOrder, error) {
+	contingent, err := s.GetContingentOrder(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if !contingent.IsActive() {
+		return nil, fmt.Errorf("contingent order is %s and cannot be modified", contingent.Status)
+	}
+	if err := s.validateRequest(userID, request); err != nil {
+		return nil, err
+	}
+
+	contingent.Conditions = request.Conditions
+	contingent.Logic = request.Logic
+	contingent.Order = request.Order
+	updated, err := s.contingentOrderRepo.Update(contingent)
+	if err != nil {
+		return nil, err
+	}
+	if !updated {
+		return nil, fmt.Errorf("contingent order has already triggered or been cancelled")
+	}
+
+	return s.contingentOrderRepo.GetByID(id)
+}
+
+func (s *ContingentOrderService) CancelContingentOrder(userID int, id int) error {
+	contingent, err := s.GetContingentOrder(userID, id)
+	if err != nil {
+		return err
+	}
+	if !contingent.IsActive() {
+		return fmt.Errorf("contingent order is %s and cannot be cancelled", contingent.Status)
+	}
+
+	cancelled, err := s.contingentOrderRepo.Cancel(id)
+	if err != nil {
+		return err
+	}
+	if !cancelled {
+		return fmt.Errorf("contingent order has already triggered or been cancelled")
+	}
+	return nil
+}
+
+func (s *ContingentOrderService) ProcessPriceUpdates(prices map[string]float64) error {
+	// One tick at a time, so lastValues always means the previous tick
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	active, err := s.contingentOrderRepo.GetActive()
+	if err != nil || len(active) == 0 {
+		// Nothing is watched this tick, so no value from before it may stand
+		// in for the previous tick on a later one
+		s.lastValues = make(map[string]float64)
+		if err != nil {
+			return fmt.Errorf("failed to get active contingent orders: %w", err)
+		}
+		return nil
+	}
+
+	tick := newConditionTick(s, prices)
+	for i := range active {
+		contingent := &active[i]
+		conditions, met := s.evaluate(contingent, tick)
+		if met {
+			s.trigger(contingent, conditions)
+		}
+	}
+
+	// Only values watched this tick are kept, so a crossing is always
+	// measured against the tick just before it
+	s.lastValues = tick.values
+	return nil
+}
+
+// evaluate returns the conditions annotated with the values observed this
+// tick and whether they are met under the order's logic
+func (s *ContingentOrderService) evaluate(contingent *domain.ContingentOrder, tick *conditionTick) ([]domain.TriggerCondition, bool) {
+	conditions := make([]domain.TriggerCondition, len(contingent.Conditions))
+	copy(conditions, contingent.Conditions)
+
+	metCount := 0
+	for i := range conditions {
+		condition := &conditions[i]
+		current, ok := tick.observe(condition)
+		if !ok {
+			continue
+		}
+
+		var previous *float64
+		if last, seen := s.lastValues[condition.Key()]; seen {
+			previous = &last
+		}
+		if condition.Met(current, previous) {
+			observed := current
+			condition.ObservedValue = &observed
+			metCount++
+		}
+	}
+
+	if contingent.Logic == domain.ConditionLogicAny {
+		return conditions, metCount > 0
+	}
+	return conditions, metCount == len(conditions)
+}
+
+// trigger claims the contingent order and submits its order. The claim comes
+// first so the order is placed at most once; a rejected order is recorded as
+// FAILED rather than retried.
+func (s *ContingentOrderService) trigger(contingent *domain.ContingentOrder, conditions []domain.TriggerCondition) {
+	claimed, err := s.contingentOrderRepo.MarkTriggered(contingent.ID, conditions, time.Now())
+	if err != nil {
+		fmt.Printf("⚠️ Failed to trigger contingent order %d: %v\n", contingent.ID, err)
+		return
+	}
+	if !claimed {
+		return
+	}
+
+	var orderID *int
+	var failureReason *string
+	order, err := s.orderService.CreateOrder(contingent.UserID, &contingent.Order)
+	if err != nil {
+		reason := err.Error()
+		failureReason = &reason
+		fmt.Printf("❌ Contingent order %d triggered but its order was rejected: %v\n", contingent.ID, err)
+	} else {
+		orderID = &order.ID
+		fmt.Printf("🎯 Contingent order %d triggered: placed order %d (%s %d %s)\n",
+			contingent.ID, order.ID, order.Side, order.Quantity, order.StockSymbol)
+	}
+
+	if err := s.contingentOrderRepo.RecordResult(contingent.ID, orderID, failureReason); err != nil {
+		fmt.Printf("⚠️ Failed to record contingent order %d result: %v\n", contingent.ID, err)
+	}
+}
+
+// conditionTick observes the values conditions watch, loading each stock and
+// each symbol's indicators at most once per tick
+type conditionTick struct {
+	service    *ContingentOrderService
+	prices     map[string]float64
+	values     map[string]float64
+	stocks     map[string]*domain.Stock
+	indicators map[string]*domain.ChartIndicators
+}
+
+func newConditionTick(service *ContingentOrderService, prices map[string]float64) *conditionTick {
+	return &conditionTick{
+		service:    service,
+		prices:     prices,
+		values:     make(map[string]float64),
+		stocks:     make(map[string]*domain.Stock),
+		indicators: make(map[string]*domain.ChartIndicators),
+	}
+}
+
+// observe returns the condition's current value, or false when it cannot be
+// computed yet (unknown stock, no previous close, too little history)
+func (t *conditionTick) observe(condition *domain.TriggerCondition) (float64, bool) {
+	key := condition.Key()
+	if value, ok := t.values[key]; ok {
+		return value, true
+	}
+
+	var value float64
+	switch condition.Type {
+	case domain.ConditionTypePrice:
+		price, ok := t.price(condition.Symbol)
+		if !ok {
+			return 0, false
+		}
+		value = price
+	case domain.ConditionTypePercentChange:
+		stock := t.stock(condition.Symbol)
+		price, ok := t.price(condition.Symbol)
+		if !ok || stock == nil || stock.PreviousClose <= 0 {
+			return 0, false
+		}
+		value = (price - stock.PreviousClose) / stock.PreviousClose * 100
+	case domain.ConditionTypeIndicator:
+		indicators := t.indicatorsFor(condition.Symbol)
+		if indicators == nil {
+			return 0, false
+		}
+		indicatorValue, ok := indicators.IndicatorValue(condition.Indicator)
+		if !ok {
+			return 0, false
+		}
+		value = indicatorValue
+	default:
+		return 0, false
+	}
+
+	t.values[key] = value
+	return value, true
+}
+
+// price is the symbol's price this tick, falling back to the stored price
+// for symbols the tick did not move
+func (t *conditionTick) price(symbol string) (float64, bool) {
+	if price, ok := t.prices[symbol]; ok {
+		return price, true
+	}
+	stock := t.stock(symbol)
+	if stock == nil || stock.CurrentPrice <= 0 {
+		return 0, false
+	}
+	return stock.CurrentPrice, true
+}
+
+func (t *conditionTick) stock(symbol string) *domain.Stock {
+	if stock, ok := t.stocks[symbol]; ok {
+		return stock
+	}
+	stock, err := t.service.stockRepo.GetBySymbol(symbol)
+	if err != nil {
+		stock = nil
+	}
+	t.stocks[symbol] = stock
+	return stock
+}
+
+// indicatorsFor computes the symbol's indicators over its saved closes with
+// this tick's price appended as the latest point
+func (t *conditionTick) indicatorsFor(symbol string) *domain.ChartIndicators {
+	if indicators, ok := t.indicators[symbol]; ok {
+		return indicators
+	}
+	t.indicators[symbol] = nil
+
+	history, err := t.service.historicalPriceRepo.GetBySymbolWithLimit(symbol, indicatorLookback)
+	if err != nil {
+		fmt.Printf("⚠️ Failed to load price history for %s: %v\n", symbol, err)
+		return nil
+	}
+	price, ok := t.price(symbol)
+	if !ok {
+		return nil
+	}
+
+	// History comes back newest first
+	closes := make([]domain.HistoricalPrice, 0, len(history)+1)
+	for i := len(history) - 1; i >= 0; i-- {
+		closes = append(closes, history[i])
+	}
+	closes = append(closes, domain.HistoricalPrice{Symbol: symbol, Date: time.Now(), Close: price})
+
+	indicators := domain.NewChartIndicators(closes)
+	t.indicators[symbol] = &indicators
+	return &indicators
+}
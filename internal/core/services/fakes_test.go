@@ -0,0 +1,527 @@
+package services
+
+import (
+	"errors"
+	"maps"
+	"slices"
+	"time"
+
+	"stock-simulation-backend/internal/core/domain"
+	"stock-simulation-backend/internal/core/ports/repositories"
+)
+
+// holdingKey identifies a user's position in one symbol
+type holdingKey struct {
+	userID int
+	symbol string
+}
+
+// fakeStore is an in-memory database behind the repository ports. It is its
+// own unit of work: Do puts back what the store held before fn when fn
+// fails, as a rolled back transaction would. Every user has a cash account
+// and no short positions. Repository methods the tests do not reach are
+// left to the embedded port interfaces, and panic if called.
+type fakeStore struct {
+	users        map[int]domain.User
+	stocks       map[string]domain.Stock
+	portfolios   map[holdingKey]domain.Portfolio
+	orders       map[int]domain.Order
+	executions   []domain.OrderExecution
+	transactions []domain.Transaction
+	nextID       int
+}
+
+func newFakeStore() *fakeStore {
+	return &fakeStore{
+		users:      make(map[int]domain.User),
+		stocks:     make(map[string]domain.Stock),
+		portfolios: make(map[holdingKey]domain.Portfolio),
+		orders:     make(map[int]domain.Order),
+	}
+}
+
+func (s *fakeStore) addUser(id int, balance float64) {
+	s.users[id] = domain.User{ID: id, Username: "trader", Balance: balance}
+}
+
+func (s *fakeStore) addStock(symbol string, price float64) {
+	s.stocks[symbol] = domain.Stock{Symbol: symbol, CurrentPrice: price}
+}
+
+func (s *fakeStore) addHolding(userID int, symbol string, quantity int, averagePrice float64) {
+	s.portfolios[holdingKey{userID, symbol}] = domain.Portfolio{
+		UserID:       userID,
+		StockSymbol:  symbol,
+		Quantity:     quantity,
+		AveragePrice: averagePrice,
+		TotalCost:    averagePrice * float64(quantity),
+	}
+}
+
+func (s *fakeStore) id() int {
+	s.nextID++
+	return s.nextID
+}
+
+func (s *fakeStore) Do(fn func(tx repositories.TxRepositories) error) error {
+	saved := *s
+	saved.users = maps.Clone(s.users)
+	saved.stocks = maps.Clone(s.stocks)
+	saved.portfolios = maps.Clone(s.portfolios)
+	saved.orders = maps.Clone(s.orders)
+	saved.executions = slices.Clone(s.executions)
+	saved.transactions = slices.Clone(s.transactions)
+
+	if err := fn(s); err != nil {
+		*s = saved
+		return err
+	}
+	return nil
+}
+
+func (s *fakeStore) Users() repositories.UserRepository {
+	return fakeUserRepo{store: s}
+}
+
+func (s *fakeStore) Portfolios() repositories.PortfolioRepository {
+	return fakePortfolioRepo{store: s}
+}
+
+func (s *fakeStore) Transactions() repositories.TransactionRepository {
+	return fakeTransactionRepo{store: s}
+}
+
+func (s *fakeStore) Orders() repositories.AdvancedOrderRepositoryWithSearch {
+	return fakeOrderRepo{store: s}
+}
+
+func (s *fakeStore) ShortPositions() repositories.ShortPositionRepository {
+	return fakeShortPositionRepo{}
+}
+
+func (s *fakeStore) MarginAccounts() repositories.MarginAccountRepository {
+	return fakeMarginAccountRepo{}
+}
+
+func (s *fakeStore) AlgoOrders() repositories.AlgoOrderRepository {
+	return fakeAlgoOrderRepo{}
+}
+
+// newTestOrderService wires an order service, with the real transaction
+// service and risk manager, to store. There is no quote cache, order book or
+// slippage model, so orders fill at the price they are matched at.
+func newTestOrderService(store *fakeStore, limits domain.RiskLimits) *AdvancedOrderService {
+	users, portfolios, transactions := store.Users(), store.Portfolios(), store.Transactions()
+	orders, stocks := store.Orders(), fakeStockRepo{store: store}
+	shorts, marginAccounts := store.ShortPositions(), store.MarginAccounts()
+
+	riskManager := NewRiskManager(orders, transactions, users, portfolios, shorts, marginAccounts,
+		stocks, nil, fakeRiskLimitRepo{}, limits)
+	transactionService := NewTransactionService(store, transactions, portfolios, stocks, users, riskManager, nil)
+	return NewAdvancedOrderService(store, orders, stocks, portfolios, users, shorts, marginAccounts,
+		store.AlgoOrders(), nil, transactionService, nil, riskManager, nil, nil, nil).(*AdvancedOrderService)
+}
+
+type fakeUserRepo struct {
+	repositories.UserRepository
+	store *fakeStore
+}
+
+func (r fakeUserRepo) GetByID(id int) (*domain.User, error) {
+	user, ok := r.store.users[id]
+	if !ok {
+		return nil, errors.New("user not found")
+	}
+	return &user, nil
+}
+
+func (r fakeUserRepo) GetByIDForUpdate(id int) (*domain.User, error) {
+	return r.GetByID(id)
+}
+
+func (r fakeUserRepo) UpdateBalance(userID int, newBalance float64) error {
+	user, err := r.GetByID(userID)
+	if err != nil {
+		return err
+	}
+	user.Balance = newBalance
+	r.store.users[userID] = *user
+	return nil
+}
+
+func (r fakeUserRepo) UpdateTotalProfit(userID int, totalProfit float64) error {
+	user, err := r.GetByID(userID)
+	if err != nil {
+		return err
+	}
+	user.TotalProfit = totalProfit
+	r.store.users[userID] = *user
+	return nil
+}
+
+type fakeStockRepo struct {
+	repositories.StockRepository
+	store *fakeStore
+}
+
+func (r fakeStockRepo) GetBySymbol(symbol string) (*domain.Stock, error) {
+	stock, ok := r.store.stocks[symbol]
+	if !ok {
+		return nil, errors.New("stock not found")
+	}
+	return &stock, nil
+}
+
+type fakePortfolioRepo struct {
+	repositories.PortfolioRepository
+	store *fakeStore
+}
+
+func (r fakePortfolioRepo) Create(portfolio *domain.Portfolio) error {
+	r.store.portfolios[holdingKey{portfolio.UserID, portfolio.StockSymbol}] = *portfolio
+	return nil
+}
+
+func (r fakePortfolioRepo) GetByUserID(userID int) ([]domain.Portfolio, error) {
+	holdings := []domain.Portfolio{}
+	for key, holding := range r.store.portfolios {
+		if key.userID == userID {
+			holdings = append(holdings, holding)
+		}
+	}
+	return holdings, nil
+}
+
+func (r fakePortfolioRepo) GetByUserIDAndSymbol(userID int, stockSymbol string) (*domain.Portfolio, error) {
+	holding, ok := r.store.portfolios[holdingKey{userID, stockSymbol}]
+	if !ok {
+		return nil, nil
+	}
+	return &holding, nil
+}
+
+func (r fakePortfolioRepo) GetByUserIDAndSymbolForUpdate(userID int, stockSymbol string) (*domain.Portfolio, error) {
+	return r.GetByUserIDAndSymbol(userID, stockSymbol)
+}
+
+func (r fakePortfolioRepo) Update(portfolio *domain.Portfolio) error {
+	return r.Create(portfolio)
+}
+
+func (r fakePortfolioRepo) Delete(userID int, stockSymbol string) error {
+	delete(r.store.portfolios, holdingKey{userID, stockSymbol})
+	return nil
+}
+
+type fakeTransactionRepo struct {
+	repositories.TransactionRepository
+	store *fakeStore
+}
+
+func (r fakeTransactionRepo) Create(transaction *domain.Transaction) error {
+	transaction.ID = r.store.id()
+	r.store.transactions = append(r.store.transactions, *transaction)
+	return nil
+}
+
+func (r fakeTransactionRepo) GetRealizedPnLSince(userID int, since time.Time) (float64, error) {
+	pnl := 0.0
+	for _, transaction := range r.store.transactions {
+		if transaction.UserID == userID && !transaction.CreatedAt.Before(since) {
+			pnl += transaction.RealizedPnL
+		}
+	}
+	return pnl, nil
+}
+
+type fakeShortPositionRepo struct {
+	repositories.ShortPositionRepository
+}
+
+func (fakeShortPositionRepo) GetByUserID(userID int) ([]domain.ShortPosition, error) {
+	return nil, nil
+}
+
+func (fakeShortPositionRepo) GetByUserIDAndSymbol(userID int, stockSymbol string) (*domain.ShortPosition, error) {
+	return nil, nil
+}
+
+type fakeMarginAccountRepo struct {
+	repositories.MarginAccountRepository
+}
+
+func (fakeMarginAccountRepo) GetByUserID(userID int) (*domain.MarginAccount, error) {
+	return nil, nil
+}
+
+func (fakeMarginAccountRepo) GetByUserIDForUpdate(userID int) (*domain.MarginAccount, error) {
+	return nil, nil
+}
+
+type fakeAlgoOrderRepo struct {
+	repositories.AlgoOrderRepository
+}
+
+type fakeRiskLimitRepo struct {
+	repositories.RiskLimitRepository
+}
+
+func (fakeRiskLimitRepo) GetByUserID(userID int) (*domain.RiskLimitOverrides, error) {
+	return nil, nil
+}
+
+// fakeOrderRepo keeps orders in the store and moves them through the domain
+// state machine, with the status filters of the MySQL repository
+type fakeOrderRepo struct {
+	repositories.AdvancedOrderRepositoryWithSearch
+	store *fakeStore
+}
+
+func (r fakeOrderRepo) Create(order *domain.Order) error {
+	if !domain.CanTransition("", order.Status) {
+		return errors.New("orders are created PENDING or DORMANT")
+	}
+	order.ID = r.store.id()
+	order.CreatedAt = time.Now()
+	if order.Version == 0 {
+		order.Version = 1
+	}
+	r.store.orders[order.ID] = *order
+	return nil
+}
+
+func (r fakeOrderRepo) GetByID(id int) (*domain.Order, error) {
+	order, ok := r.store.orders[id]
+	if !ok {
+		return nil, errors.New("order not found")
+	}
+	return &order, nil
+}
+
+func (r fakeOrderRepo) GetByIDForUpdate(id int) (*domain.Order, error) {
+	return r.GetByID(id)
+}
+
+func (r fakeOrderRepo) Update(order *domain.Order) error {
+	if _, err := r.GetByID(order.ID); err != nil {
+		return err
+	}
+	r.store.orders[order.ID] = *order
+	return nil
+}
+
+func (r fakeOrderRepo) PartialFillOrder(orderID int, filledQuantity int, filledPrice float64) error {
+	order, err := r.GetByID(orderID)
+	if err != nil {
+		return err
+	}
+	if !order.IsActive() || order.RemainingQuantity < filledQuantity {
+		return errors.New("order not found, no longer active or overfilled")
+	}
+	order.ApplyFill(filledQuantity, filledPrice, time.Now())
+	r.store.orders[orderID] = *order
+	return nil
+}
+
+func (r fakeOrderRepo) RecordExecution(execution *domain.OrderExecution) error {
+	execution.ID = r.store.id()
+	r.store.executions = append(r.store.executions, *execution)
+	return nil
+}
+
+// transition moves the order to status if it is in one of from
+func (r fakeOrderRepo) transition(orderID int, from []domain.OrderStatus, to domain.OrderStatus, reason string) error {
+	order, err := r.GetByID(orderID)
+	if err != nil {
+		return err
+	}
+	if !slices.Contains(from, order.Status) {
+		return errors.New("order not found or no longer active")
+	}
+	if err := order.TransitionTo(to); err != nil {
+		return err
+	}
+	if to != domain.OrderStatusPending {
+		order.CancelReason = &reason
+	}
+	r.store.orders[orderID] = *order
+	return nil
+}
+
+var (
+	fakeActiveStatuses      = []domain.OrderStatus{domain.OrderStatusPending, domain.OrderStatusPartiallyFilled}
+	fakeCancellableStatuses = append(slices.Clone(fakeActiveStatuses), domain.OrderStatusPaused)
+)
+
+func (r fakeOrderRepo) CancelOrder(orderID int, reason string, actor domain.OrderActor) error {
+	return r.transition(orderID, fakeCancellableStatuses, domain.OrderStatusCancelled, reason)
+}
+
+func (r fakeOrderRepo) ExpireOrder(orderID int, reason string) error {
+	return r.transition(orderID, fakeActiveStatuses, domain.OrderStatusExpired, reason)
+}
+
+func (r fakeOrderRepo) RejectOrder(orderID int, reason string) error {
+	return r.transition(orderID, fakeActiveStatuses, domain.OrderStatusRejected, reason)
+}
+
+func (r fakeOrderRepo) ActivateOrder(orderID int, reason string) error {
+	return r.transition(orderID, []domain.OrderStatus{domain.OrderStatusDormant}, domain.OrderStatusPending, reason)
+}
+
+func (r fakeOrderRepo) UpdateOrderCommission(orderID int, commission, fees float64) error {
+	order, err := r.GetByID(orderID)
+	if err != nil {
+		return err
+	}
+	order.Commission, order.Fees = commission, fees
+	r.store.orders[orderID] = *order
+	return nil
+}
+
+// matching returns the stored orders match accepts, by ID
+func (r fakeOrderRepo) matching(match func(order *domain.Order) bool) []domain.Order {
+	orders := []domain.Order{}
+	for _, order := range r.store.orders {
+		if match(&order) {
+			orders = append(orders, order)
+		}
+	}
+	slices.SortFunc(orders, func(a, b domain.Order) int { return a.ID - b.ID })
+	return orders
+}
+
+func (r fakeOrderRepo) GetWorkingOrdersByUser(userID int) ([]domain.Order, error) {
+	return r.matching(func(order *domain.Order) bool {
+		return order.UserID == userID && slices.Contains(fakeCancellableStatuses, order.Status)
+	}), nil
+}
+
+func (r fakeOrderRepo) CreateOCOOrders(parentOrder *domain.Order, linkedOrder *domain.Order) error {
+	if err := r.Create(parentOrder); err != nil {
+		return err
+	}
+	if err := r.Create(linkedOrder); err != nil {
+		return err
+	}
+
+	groupID := parentOrder.ID
+	parentOrder.LinkedOrderID, parentOrder.OCOGroupID = &linkedOrder.ID, &groupID
+	linkedOrder.LinkedOrderID, linkedOrder.OCOGroupID = &parentOrder.ID, &groupID
+	r.store.orders[parentOrder.ID] = *parentOrder
+	r.store.orders[linkedOrder.ID] = *linkedOrder
+	return nil
+}
+
+func (r fakeOrderRepo) CancelLinkedOrders(orderID int, reason string) (int, error) {
+	order, err := r.GetByID(orderID)
+	if err != nil || order.OCOGroupID == nil {
+		return 0, err
+	}
+	linked := r.matching(func(other *domain.Order) bool {
+		return other.ID != orderID && other.OCOGroupID != nil && *other.OCOGroupID == *order.OCOGroupID &&
+			other.IsActive()
+	})
+	for _, other := range linked {
+		if err := r.CancelOrder(other.ID, reason, domain.OrderActorSystem); err != nil {
+			return 0, err
+		}
+	}
+	return len(linked), nil
+}
+
+func (r fakeOrderRepo) CreateBracketOrders(entryOrder *domain.Order, takeProfit *domain.Order, stopLoss *domain.Order) error {
+	if err := r.Create(entryOrder); err != nil {
+		return err
+	}
+	takeProfit.ParentOrderID = &entryOrder.ID
+	stopLoss.ParentOrderID = &entryOrder.ID
+	return r.CreateOCOOrders(takeProfit, stopLoss)
+}
+
+func (r fakeOrderRepo) GetChildOrders(parentOrderID int) ([]domain.Order, error) {
+	return r.matching(func(order *domain.Order) bool {
+		return order.ParentOrderID != nil && *order.ParentOrderID == parentOrderID
+	}), nil
+}
+
+func (r fakeOrderRepo) CancelChildOrders(parentOrderID int, reason string) (int, error) {
+	dormant := r.matching(func(order *domain.Order) bool {
+		return order.ParentOrderID != nil && *order.ParentOrderID == parentOrderID &&
+			order.Status == domain.OrderStatusDormant
+	})
+	for _, child := range dormant {
+		err := r.transition(child.ID, []domain.OrderStatus{domain.OrderStatusDormant}, domain.OrderStatusCancelled, reason)
+		if err != nil {
+			return 0, err
+		}
+	}
+	return len(dormant), nil
+}
+
+func (r fakeOrderRepo) CheckDailyOrderLimit(userID int, since time.Time, maxOrders int) (bool, error) {
+	placed := r.matching(func(order *domain.Order) bool {
+		return order.UserID == userID && order.ParentOrderID == nil && !order.CreatedAt.Before(since)
+	})
+	return len(placed) < maxOrders, nil
+}
+
+func (r fakeOrderRepo) CheckOrderSizeLimit(userID int, symbol string, side domain.OrderSide, quantity, maxQuantity int) (bool, error) {
+	resting := 0
+	for _, order := range r.matching(func(order *domain.Order) bool {
+		return order.UserID == userID && order.StockSymbol == symbol && order.Side == side &&
+			order.ParentOrderID == nil && slices.Contains(fakeCancellableStatuses, order.Status)
+	}) {
+		resting += order.RemainingQuantity
+	}
+	return resting+quantity <= maxQuantity, nil
+}
+
+// idempotencyKey identifies a user's idempotency key
+type idempotencyKey struct {
+	userID int
+	key    string
+}
+
+type fakeIdempotencyRepo struct {
+	records map[idempotencyKey]domain.IdempotencyRecord
+}
+
+func newFakeIdempotencyRepo() *fakeIdempotencyRepo {
+	return &fakeIdempotencyRepo{records: make(map[idempotencyKey]domain.IdempotencyRecord)}
+}
+
+func (r *fakeIdempotencyRepo) Reserve(record *domain.IdempotencyRecord) (bool, error) {
+	key := idempotencyKey{record.UserID, record.Key}
+	if _, ok := r.records[key]; ok {
+		return false, nil
+	}
+	r.records[key] = *record
+	return true, nil
+}
+
+func (r *fakeIdempotencyRepo) Get(userID int, key string) (*domain.IdempotencyRecord, error) {
+	record, ok := r.records[idempotencyKey{userID, key}]
+	if !ok {
+		return nil, nil
+	}
+	return &record, nil
+}
+
+func (r *fakeIdempotencyRepo) Complete(record *domain.IdempotencyRecord) error {
+	key := idempotencyKey{record.UserID, record.Key}
+	stored, ok := r.records[key]
+	if !ok || stored.RequestHash != record.RequestHash {
+		return nil
+	}
+	completedAt := time.Now()
+	stored.StatusCode, stored.ResponseBody, stored.CompletedAt = record.StatusCode, record.ResponseBody, &completedAt
+	r.records[key] = stored
+	record.CompletedAt = &completedAt
+	return nil
+}
+
+func (r *fakeIdempotencyRepo) Delete(userID int, key string) error {
+	delete(r.records, idempotencyKey{userID, key})
+	return nil
+}
@@ -0,0 +1,118 @@
+package services
+
+import (
+	"fmt"
+	"time"
+
+	"stock-simulation-backend/internal/core/domain"
+	"stock-simulation-backend/internal/core/ports/repositories"
+	"stock-simulation-backend/internal/core/ports/services"
+)
+
+// IdempotencyService lets a client retry a trading request safely: the first
+// request with a key runs and its response is stored in MySQL, and retries
+// with the same key and payload get that response back without running
+// again. Completed responses are also cached in Redis, when available, so
+// most retries never reach the database.
+type IdempotencyService struct {
+	idempotencyRepo repositories.IdempotencyRepository
+	redisService    *RedisService
+}
+
+func NewIdempotencyService(idempotencyRepo repositories.IdempotencyRepository, redisService *RedisService) services.IdempotencyService {
+	return &IdempotencyService{
+		idempotencyRepo: idempotencyRepo,
+		redisService:    redisService,
+	}
+}
+
+func idempotencyCacheKey(userID int, key string) string {
+	return fmt.Sprintf("idempotency:%d:%s", userID, key)
+}
+
+func (s *IdempotencyService) Begin(userID int, key, requestHash string) (*domain.IdempotencyRecord, error) {
+	if record := s.cached(userID, key); record != nil {
+		return replayable(record, requestHash)
+	}
+
+	// A second attempt covers a key that expired, or was released, between
+	// the reservation and the lookup
+	now := time.Now()
+	for attempt := 0; attempt < 2; attempt++ {
+		reserved, err := s.idempotencyRepo.Reserve(domain.NewIdempotencyRecord(userID, key, requestHash, now))
+		if err != nil {
+			return nil, err
+		}
+		if reserved {
+			return nil, nil
+		}
+
+		existing, err := s.idempotencyRepo.Get(userID, key)
+		if err != nil {
+			return nil, err
+		}
+		if existing == nil {
+			continue
+		}
+		if existing.IsExpired(now) {
+			if err := s.idempotencyRepo.Delete(userID, key); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		return replayable(existing, requestHash)
+	}
+
+	return nil, domain.ErrIdempotencyKeyInFlight
+}
+
+// replayable returns record if it is a finished response to the same request
+func replayable(record *domain.IdempotencyRecord, requestHash string) (*domain.IdempotencyRecord, error) {
+	if record.RequestHash != requestHash {
+		return nil, domain.ErrIdempotencyKeyReused
+	}
+	if !record.IsCompleted() {
+		return nil, domain.ErrIdempotencyKeyInFlight
+	}
+	return record, nil
+}
+
+func (s *IdempotencyService) Complete(userID int, key, requestHash string, statusCode int, body []byte) error {
+	now := time.Now()
+	record := domain.NewIdempotencyRecord(userID, key, requestHash, now)
+	record.StatusCode = statusCode
+	record.ResponseBody = body
+	if err := s.idempotencyRepo.Complete(record); err != nil {
+		return err
+	}
+
+	if s.redisService != nil {
+		if err := s.redisService.CacheJSON(idempotencyCacheKey(userID, key), record, domain.IdempotencyWindow); err != nil {
+			fmt.Printf("⚠️ Failed to cache idempotent response for user %d: %v\n", userID, err)
+		}
+	}
+	return nil
+}
+
+func (s *IdempotencyService) Release(userID int, key string) error {
+	if s.redisService != nil {
+		if err := s.redisService.DeleteCached(idempotencyCacheKey(userID, key)); err != nil {
+			fmt.Printf("⚠️ Failed to clear cached idempotency key for user %d: %v\n", userID, err)
+		}
+	}
+	return s.idempotencyRepo.Delete(userID, key)
+}
+
+// cached looks the key up in Redis; any miss or error falls through to MySQL
+func (s *IdempotencyService) cached(userID int, key string) *domain.IdempotencyRecord {
+	if s.redisService == nil {
+		return nil
+	}
+
+	var record domain.IdempotencyRecord
+	found, err := s.redisService.GetCachedJSON(idempotencyCacheKey(userID, key), &record)
+	if err != nil || !found || !record.IsCompleted() {
+		return nil
+	}
+	return &record
+}
@@ -0,0 +1,88 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"stock-simulation-backend/internal/core/domain"
+)
+
+func TestIdempotencyServiceBegin(t *testing.T) {
+	now := time.Now()
+	completed := func(hash string, createdAt time.Time) *domain.IdempotencyRecord {
+		record := domain.NewIdempotencyRecord(1, "key", hash, createdAt)
+		record.StatusCode, record.ResponseBody, record.CompletedAt = 201, []byte(`{"id":7}`), &createdAt
+		return record
+	}
+
+	tests := []struct {
+		name       string
+		existing   *domain.IdempotencyRecord
+		wantErr    error
+		wantReplay bool
+	}{
+		{name: "a new key runs"},
+		{name: "a finished request replays", existing: completed("hash", now), wantReplay: true},
+		{name: "a key reused for another request", existing: completed("other", now), wantErr: domain.ErrIdempotencyKeyReused},
+		{name: "a request still running", existing: domain.NewIdempotencyRecord(1, "key", "hash", now), wantErr: domain.ErrIdempotencyKeyInFlight},
+		{name: "an expired key runs again", existing: completed("other", now.Add(-domain.IdempotencyWindow))},
+		{name: "an abandoned key runs again", existing: domain.NewIdempotencyRecord(1, "key", "hash", now.Add(-domain.IdempotencyLockTimeout))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newFakeIdempotencyRepo()
+			if tt.existing != nil {
+				repo.records[idempotencyKey{1, "key"}] = *tt.existing
+			}
+			service := NewIdempotencyService(repo, nil)
+
+			record, err := service.Begin(1, "key", "hash")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Begin() error = %v, want %v", err, tt.wantErr)
+			}
+			if (record != nil) != tt.wantReplay {
+				t.Fatalf("Begin() record = %+v, want a replay %v", record, tt.wantReplay)
+			}
+			if tt.wantReplay && (record.StatusCode != 201 || string(record.ResponseBody) != `{"id":7}`) {
+				t.Errorf("replayed %d %s, want the stored response", record.StatusCode, record.ResponseBody)
+			}
+
+			// A request that runs holds the key until it completes
+			if tt.wantErr == nil && !tt.wantReplay {
+				if held := repo.records[idempotencyKey{1, "key"}]; held.RequestHash != "hash" || held.IsCompleted() {
+					t.Errorf("key held = %+v, want it reserved for the request", held)
+				}
+			}
+		})
+	}
+}
+
+func TestIdempotencyServiceCompleteAndRelease(t *testing.T) {
+	repo := newFakeIdempotencyRepo()
+	service := NewIdempotencyService(repo, nil)
+
+	if _, err := service.Begin(1, "key", "hash"); err != nil {
+		t.Fatalf("Begin() error = %v", err)
+	}
+	if err := service.Complete(1, "key", "hash", 201, []byte(`{"id":7}`)); err != nil {
+		t.Fatalf("Complete() error = %v", err)
+	}
+	record, err := service.Begin(1, "key", "hash")
+	if err != nil || record == nil || record.StatusCode != 201 {
+		t.Fatalf("Begin() after Complete() = %+v, %v, want the 201 replayed", record, err)
+	}
+
+	// Keys are scoped to the user
+	if record, err := service.Begin(2, "key", "hash"); err != nil || record != nil {
+		t.Errorf("Begin() for another user = %+v, %v, want it to run", record, err)
+	}
+
+	if err := service.Release(1, "key"); err != nil {
+		t.Fatalf("Release() error = %v", err)
+	}
+	if record, err := service.Begin(1, "key", "other"); err != nil || record != nil {
+		t.Errorf("Begin() after Release() = %+v, %v, want it to run", record, err)
+	}
+}
@@ -0,0 +1,268 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"stock-simulation-backend/internal/core/domain"
+	"stock-simulation-backend/internal/core/ports/repositories"
+	"stock-simulation-backend/internal/core/ports/services"
+)
+
+// MarginService lends against long positions and watches the loans: each
+// tick it values every margin account, issues a call when equity falls below
+// maintenance and, once the grace period passes, sells longs until the call
+// is met.
+type MarginService struct {
+	uow               repositories.UnitOfWork
+	marginAccountRepo repositories.MarginAccountRepository
+	portfolioRepo     repositories.PortfolioRepository
+	stockRepo         repositories.StockRepository
+	userRepo          repositories.UserRepository
+	orderService      services.AdvancedOrderService
+	realTimeService   *RealTimeService
+}
+
+func NewMarginService(
+	uow repositories.UnitOfWork,
+	marginAccountRepo repositories.MarginAccountRepository,
+	portfolioRepo repositories.PortfolioRepository,
+	stockRepo repositories.StockRepository,
+	userRepo repositories.UserRepository,
+	orderService services.AdvancedOrderService,
+	realTimeService *RealTimeService,
+) services.MarginService {
+	return &MarginService{
+		uow:               uow,
+		marginAccountRepo: marginAccountRepo,
+		portfolioRepo:     portfolioRepo,
+		stockRepo:         stockRepo,
+		userRepo:          userRepo,
+		orderService:      orderService,
+		realTimeService:   realTimeService,
+	}
+}
+
+func (s *MarginService) GetMarginStatus(userID int) (*domain.MarginStatus, error) {
+	account, err := s.marginAccountRepo.GetByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.statusFor(userID, account, nil)
+}
+
+func (s *MarginService) SetAccountType(userID int, accountType domain.AccountType) (*domain.MarginStatus, error) {
+	if accountType != domain.AccountTypeCash && accountType != domain.AccountTypeMargin {
+		return nil, fmt.Errorf("invalid account type: %s", accountType)
+	}
+
+	err := s.uow.Do(func(tx repositories.TxRepositories) error {
+		if _, err := tx.Users().GetByIDForUpdate(userID); err != nil {
+			return fmt.Errorf("user not found")
+		}
+
+		account, err := tx.MarginAccounts().GetByUserIDForUpdate(userID)
+		if err != nil {
+			return err
+		}
+		if account == nil {
+			return tx.MarginAccounts().Create(&domain.MarginAccount{UserID: userID, AccountType: accountType})
+		}
+
+		if accountType == domain.AccountTypeCash && account.Debt() > 0 {
+			return fmt.Errorf("repay the margin debt of %.2f before switching to a cash account", account.Debt())
+		}
+		account.AccountType = accountType
+		return tx.MarginAccounts().Update(account)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetMarginStatus(userID)
+}
+
+func (s *MarginService) ProcessPriceUpdates(prices map[string]float64) error {
+	charged, err := s.marginAccountRepo.AccrueInterest(time.Now())
+	if err != nil {
+		return err
+	}
+	if charged > 0 {
+		fmt.Printf("💸 Accrued margin interest on %d account(s)\n", charged)
+	}
+
+	accounts, err := s.marginAccountRepo.GetMarginAccounts()
+	if err != nil {
+		return err
+	}
+
+	for i := range accounts {
+		if err := s.watch(&accounts[i], prices); err != nil {
+			fmt.Printf("⚠️ Margin check failed for user %d: %v\n", accounts[i].UserID, err)
+		}
+	}
+	return nil
+}
+
+// watch re-values one account and moves its margin call along
+func (s *MarginService) watch(account *domain.MarginAccount, prices map[string]float64) error {
+	status, err := s.statusFor(account.UserID, account, prices)
+	if err != nil {
+		return err
+	}
+
+	deficit := status.MaintenanceDeficit()
+	now := time.Now()
+	switch {
+	case deficit == 0 && account.MarginCallAt != nil:
+		if err := s.marginAccountRepo.SetMarginCall(account.UserID, 0, nil); err != nil {
+			return err
+		}
+		status.MarginCallAmount, status.MarginCallAt, status.LiquidateAfter = 0, nil, nil
+		fmt.Printf("✅ Margin call met: user %d\n", account.UserID)
+
+	case deficit > 0 && account.MarginCallAt == nil:
+		if err := s.marginAccountRepo.SetMarginCall(account.UserID, deficit, &now); err != nil {
+			return err
+		}
+		deadline := now.Add(domain.MarginCallGracePeriod)
+		status.MarginCallAmount, status.MarginCallAt, status.LiquidateAfter = deficit, &now, &deadline
+		fmt.Printf("🚨 Margin call: user %d must add $%.2f of equity by %s\n",
+			account.UserID, deficit, deadline.Format(time.RFC3339))
+		s.sendMarginCall(status)
+
+	case deficit > 0 && now.After(account.MarginCallAt.Add(domain.MarginCallGracePeriod)):
+		if err := s.liquidate(account.UserID, deficit, prices); err != nil {
+			return err
+		}
+
+	case deficit > 0:
+		if err := s.marginAccountRepo.SetMarginCall(account.UserID, deficit, account.MarginCallAt); err != nil {
+			return err
+		}
+		status.MarginCallAmount = deficit
+	}
+
+	if s.realTimeService != nil {
+		s.realTimeService.SendBalanceUpdate(account.UserID, status.BalanceUpdate())
+	}
+	return nil
+}
+
+// liquidate sells longs, largest first, until the sales would restore the
+// maintenance margin. Sale proceeds repay the loan, so equity is unchanged
+// while the requirement falls by the maintenance share of what is sold.
+// Shares that working sells, an earlier liquidation's or the user's own, are
+// already selling count towards the sale, so no more than the rest is sold.
+func (s *MarginService) liquidate(userID int, deficit float64, prices map[string]float64) error {
+	holdings, err := s.portfolioRepo.GetByUserID(userID)
+	if err != nil {
+		return err
+	}
+	selling, err := s.workingSells(userID)
+	if err != nil {
+		// Without the working sells a liquidation could be placed twice
+		return fmt.Errorf("failed to check working sells: %w", err)
+	}
+
+	type position struct {
+		symbol   string
+		quantity int
+		price    float64
+	}
+	positions := make([]position, 0, len(holdings))
+	for _, holding := range holdings {
+		price, err := s.priceOf(holding.StockSymbol, prices)
+		if err != nil || price <= 0 {
+			continue
+		}
+		positions = append(positions, position{holding.StockSymbol, holding.Quantity, price})
+	}
+	sort.Slice(positions, func(i, j int) bool {
+		return float64(positions[i].quantity)*positions[i].price > float64(positions[j].quantity)*positions[j].price
+	})
+
+	toSell := deficit / domain.MarginMaintenanceRate
+	for _, p := range positions {
+		if toSell <= 0 {
+			break
+		}
+		working := min(selling[p.symbol], p.quantity)
+		toSell -= float64(working) * p.price
+		if toSell <= 0 {
+			break
+		}
+
+		quantity := min(int(math.Ceil(toSell/p.price)), p.quantity-working)
+		if quantity <= 0 {
+			continue
+		}
+		fmt.Printf("🔻 Liquidating %d %s for user %d to meet a margin call\n", quantity, p.symbol, userID)
+		_, err := s.orderService.CreateForcedOrder(userID, &domain.OrderRequest{
+			StockSymbol: p.symbol,
+			OrderType:   domain.OrderTypeMarket,
+			Side:        domain.OrderSideSell,
+			Quantity:    quantity,
+			TimeInForce: domain.TimeInForceGTC,
+		})
+		if err != nil {
+			fmt.Printf("⚠️ Failed to liquidate %s for user %d: %v\n", p.symbol, userID, err)
+			continue
+		}
+		toSell -= float64(quantity) * p.price
+	}
+	return nil
+}
+
+// workingSells totals, per symbol, the shares the user's working sells of any
+// type have left to sell: the shares they hold
+func (s *MarginService) workingSells(userID int) (map[string]int, error) {
+	orders, err := s.orderService.GetWorkingOrders(userID)
+	if err != nil {
+		return nil, err
+	}
+	return domain.NewHolds(orders).Shares, nil
+}
+
+// statusFor values an account, preferring this tick's prices where given
+func (s *MarginService) statusFor(userID int, account *domain.MarginAccount, prices map[string]float64) (*domain.MarginStatus, error) {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("user not found")
+	}
+
+	holdings, err := s.portfolioRepo.GetByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	longValue := 0.0
+	for _, holding := range holdings {
+		price, err := s.priceOf(holding.StockSymbol, prices)
+		if err != nil {
+			return nil, err
+		}
+		longValue += float64(holding.Quantity) * price
+	}
+
+	return domain.NewMarginStatus(userID, account, user.Balance, longValue), nil
+}
+
+func (s *MarginService) priceOf(symbol string, prices map[string]float64) (float64, error) {
+	if price, ok := prices[symbol]; ok {
+		return price, nil
+	}
+	stock, err := s.stockRepo.GetBySymbol(symbol)
+	if err != nil {
+		return 0, fmt.Errorf("stock not found: %s", symbol)
+	}
+	return stock.CurrentPrice, nil
+}
+
+func (s *MarginService) sendMarginCall(status *domain.MarginStatus) {
+	if s.realTimeService != nil {
+		s.realTimeService.SendMarginCall(status.UserID, *status)
+	}
+}
@@ -0,0 +1,198 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"stock-simulation-backend/internal/core/domain"
+	"stock-simulation-backend/internal/core/ports/repositories"
+	"stock-simulation-backend/internal/core/ports/services"
+)
+
+const (
+	// syntheticLevels is how many simulated price levels back each side of a book
+	syntheticLevels = 10
+
+	// syntheticStepRate spaces the simulated levels as a fraction of the price
+	syntheticStepRate = 0.0005
+
+	// broadcastDepth is how many levels per side are streamed on each tick
+	broadcastDepth = 10
+)
+
+// OrderBookService keeps an in-memory book per symbol. Books are rebuilt on
+// every price tick from the users' resting limit orders plus synthetic market
+// maker liquidity around the current price. Market orders consume the
+// synthetic liquidity until the next tick; the users' orders are shown for
+// depth and fill through the matching engine.
+type OrderBookService struct {
+	orderRepo       repositories.AdvancedOrderRepository
+	stockRepo       repositories.StockRepository
+	realTimeService *RealTimeService
+	quotes          *QuoteCache
+	books           map[string]*domain.OrderBook
+	mu              sync.Mutex
+}
+
+func NewOrderBookService(
+	orderRepo repositories.AdvancedOrderRepository,
+	stockRepo repositories.StockRepository,
+	realTimeService *RealTimeService,
+	quotes *QuoteCache,
+) services.OrderBookService {
+	return &OrderBookService{
+		orderRepo:       orderRepo,
+		stockRepo:       stockRepo,
+		realTimeService: realTimeService,
+		quotes:          quotes,
+		books:           make(map[string]*domain.OrderBook),
+	}
+}
+
+func (s *OrderBookService) GetOrderBook(symbol string, depth int) (*domain.OrderBookUpdate, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	book, err := s.bookFor(symbol)
+	if err != nil {
+		return nil, err
+	}
+	return book.Snapshot(depth), nil
+}
+
+func (s *OrderBookService) RefreshBooks(prices map[string]float64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for symbol, price := range prices {
+		stock, err := s.stockRepo.GetBySymbol(symbol)
+		if err != nil {
+			fmt.Printf("⚠️ Failed to rebuild %s order book: %v\n", symbol, err)
+			continue
+		}
+		stock.CurrentPrice = price
+		if _, err := s.rebuild(stock, s.quotes.QuoteFor(stock)); err != nil {
+			fmt.Printf("⚠️ Failed to rebuild %s order book: %v\n", symbol, err)
+		}
+	}
+	return nil
+}
+
+func (s *OrderBookService) BroadcastBooks(symbols []string) {
+	if s.realTimeService == nil {
+		return
+	}
+
+	for _, symbol := range symbols {
+		update, err := s.GetOrderBook(symbol, broadcastDepth)
+		if err != nil {
+			continue
+		}
+		s.realTimeService.BroadcastOrderBookUpdate(*update)
+	}
+}
+
+func (s *OrderBookService) WalkBook(symbol string, buy bool, quantity int) (float64, int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	book, err := s.bookFor(symbol)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	price, taken := book.Walk(buy, quantity)
+	return price, taken, nil
+}
+
+// bookFor returns the current book, building one around the current quote if
+// no tick has built it yet. Callers hold s.mu.
+func (s *OrderBookService) bookFor(symbol string) (*domain.OrderBook, error) {
+	if book, ok := s.books[symbol]; ok {
+		return book, nil
+	}
+
+	stock, err := s.stockRepo.GetBySymbol(symbol)
+	if err != nil {
+		return nil, fmt.Errorf("stock not found: %s", symbol)
+	}
+	return s.rebuild(stock, s.quotes.QuoteFor(stock))
+}
+
+// rebuild replaces a stock's book with synthetic levels stepping away from
+// the quote's bid and ask. Callers hold s.mu.
+func (s *OrderBookService) rebuild(stock *domain.Stock, quote domain.Quote) (*domain.OrderBook, error) {
+	symbol := stock.Symbol
+	bids, asks, err := s.restingEntries(symbol, quote.Last)
+	if err != nil {
+		return nil, err
+	}
+
+	// Synthetic levels arrive after the users' orders, so users' orders are
+	// listed first at the same price
+	now := time.Now()
+	step := math.Max(0.01, math.Round(quote.Last*syntheticStepRate*100)/100)
+	size := syntheticLevelSize(stock.Volume)
+	for i := 0; i < syntheticLevels; i++ {
+		offset := float64(i) * step
+		if bid := math.Round((quote.Bid-offset)*100) / 100; bid > 0 {
+			bids = append(bids, domain.OrderBookEntry{Price: bid, Quantity: size, PlacedAt: now})
+		}
+		asks = append(asks, domain.OrderBookEntry{Price: math.Round((quote.Ask+offset)*100) / 100, Quantity: size, PlacedAt: now})
+	}
+
+	book := domain.NewOrderBook(symbol, bids, asks)
+	if previous, ok := s.books[symbol]; ok {
+		book.Sequence = previous.Sequence + 1
+	}
+	s.books[symbol] = book
+	return book, nil
+}
+
+// syntheticLevelSize spreads a tick's liquidity budget over the synthetic levels
+func syntheticLevelSize(dailyVolume int64) int {
+	size := tickBudget(dailyVolume) / syntheticLevels
+	if size < 1 {
+		return 1
+	}
+	return size
+}
+
+// restingEntries loads the users' active limit orders as display-only book
+// entries, marked with their order ID so a walk passes them by.
+// Marketable limits are being worked by the matching engine and are left out
+// so the book never crosses. Icebergs show only their current slice, queued
+// from their last refill.
+func (s *OrderBookService) restingEntries(symbol string, price float64) ([]domain.OrderBookEntry, []domain.OrderBookEntry, error) {
+	bids := []domain.OrderBookEntry{}
+	asks := []domain.OrderBookEntry{}
+
+	sides := []domain.OrderSide{domain.OrderSideBuy, domain.OrderSideCover, domain.OrderSideSell, domain.OrderSideShort}
+	for _, side := range sides {
+		orders, err := s.orderRepo.GetLimitOrders(symbol, side)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		for _, order := range orders {
+			if order.Price == nil || order.VisibleQuantity() <= 0 {
+				continue
+			}
+			entry := domain.OrderBookEntry{
+				OrderID:  order.ID,
+				Price:    *order.Price,
+				Quantity: order.VisibleQuantity(),
+				PlacedAt: order.PriorityAt,
+			}
+			if order.IsBuySide() && entry.Price < price {
+				bids = append(bids, entry)
+			} else if !order.IsBuySide() && entry.Price > price {
+				asks = append(asks, entry)
+			}
+		}
+	}
+
+	return bids, asks, nil
+}
@@ -0,0 +1,109 @@
+package services
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+const (
+	// ticksPerTradingDay is a 6.5 hour session divided into the price
+	// simulator's 5 second ticks
+	ticksPerTradingDay = 4680
+
+	// maxParticipationRate is the share of a tick's traded volume the
+	// simulated book will give to our orders
+	maxParticipationRate = 0.10
+
+	// minTickLiquidity keeps thinly traded symbols fillable
+	minTickLiquidity = 100
+
+	// liquidityWindow bounds a budget's life when no tick resets it, so API
+	// fills keep working while the simulator is stopped
+	liquidityWindow = 5 * time.Second
+)
+
+// errNoLiquidity is returned when a symbol's budget for the current tick is
+// spent. The order stays active and fills on a later tick.
+var errNoLiquidity = errors.New("no liquidity left this tick")
+
+// errFillOrKill is returned when the tick cannot supply an FOK order's full
+// remaining quantity
+var errFillOrKill = errors.New("full quantity not available this tick")
+
+// tickBudget derives the shares of a symbol that may fill in one tick from
+// its daily volume
+func tickBudget(dailyVolume int64) int {
+	budget := int(float64(dailyVolume) / ticksPerTradingDay * maxParticipationRate)
+	if budget < minTickLiquidity {
+		return minTickLiquidity
+	}
+	return budget
+}
+
+// tickLiquidity tracks the shares left to fill per symbol in the current
+// tick. Every fill, whether from the matching engine or an API request,
+// draws on the same budget.
+type tickLiquidity struct {
+	mu        sync.Mutex
+	startedAt time.Time
+	remaining map[string]int
+}
+
+func newTickLiquidity() *tickLiquidity {
+	return &tickLiquidity{
+		startedAt: time.Now(),
+		remaining: make(map[string]int),
+	}
+}
+
+// reset starts a new tick; budgets are re-derived on first use
+func (l *tickLiquidity) reset() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.startedAt = time.Now()
+	l.remaining = make(map[string]int)
+}
+
+// take reserves up to want shares of symbol and returns how many it got.
+// budgetFor is only called the first time the symbol is seen in a tick.
+func (l *tickLiquidity) take(symbol string, want int, budgetFor func() int) int {
+	l.mu.Lock()
+	if time.Since(l.startedAt) > liquidityWindow {
+		l.startedAt = time.Now()
+		l.remaining = make(map[string]int)
+	}
+	_, known := l.remaining[symbol]
+	l.mu.Unlock()
+
+	// Look the budget up without holding the lock
+	budget := 0
+	if !known {
+		budget = budgetFor()
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, ok := l.remaining[symbol]; !ok {
+		l.remaining[symbol] = budget
+	}
+
+	got := want
+	if got > l.remaining[symbol] {
+		got = l.remaining[symbol]
+	}
+	l.remaining[symbol] -= got
+	return got
+}
+
+// giveBack returns shares reserved for a fill that did not commit
+func (l *tickLiquidity) giveBack(symbol string, quantity int) {
+	if quantity <= 0 {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, ok := l.remaining[symbol]; ok {
+		l.remaining[symbol] += quantity
+	}
+}
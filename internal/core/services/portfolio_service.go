@@ -2,6 +2,7 @@ package services
 
 import (
 	"fmt"
+	"math"
 	"math/rand"
 	"time"
 	"stock-simulation-backend/internal/core/domain"
@@ -10,17 +11,26 @@ import (
 )
 
 type portfolioService struct {
-	portfolioRepo repositories.PortfolioRepository
-	stockRepo     repositories.StockRepository
+	portfolioRepo     repositories.PortfolioRepository
+	shortPositionRepo repositories.ShortPositionRepository
+	stockRepo         repositories.StockRepository
+	userRepo          repositories.UserRepository
+	orderRepo         repositories.AdvancedOrderRepository
 }
 
 func NewPortfolioService(
 	portfolioRepo repositories.PortfolioRepository,
+	shortPositionRepo repositories.ShortPositionRepository,
 	stockRepo repositories.StockRepository,
+	userRepo repositories.UserRepository,
+	orderRepo repositories.AdvancedOrderRepository,
 ) services.PortfolioService {
 	return &portfolioService{
-		portfolioRepo: portfolioRepo,
-		stockRepo:     stockRepo,
+		portfolioRepo:     portfolioRepo,
+		shortPositionRepo: shortPositionRepo,
+		stockRepo:         stockRepo,
+		userRepo:          userRepo,
+		orderRepo:         orderRepo,
 	}
 }
 
@@ -30,6 +40,16 @@ func (s *portfolioService) GetUserPortfolio(userID int) (*domain.PortfolioSummar
 		return nil, fmt.Errorf("failed to get user portfolio: %w", err)
 	}
 
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	working, err := s.orderRepo.GetWorkingOrdersByUser(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get working orders: %w", err)
+	}
+	holds := domain.NewHolds(working)
+
 	var portfolioItems []domain.PortfolioItem
 	var totalValue, totalCost, totalProfit float64
 
@@ -47,16 +67,20 @@ func (s *portfolioService) GetUserPortfolio(userID int) (*domain.PortfolioSummar
 			profitPct = (profit / portfolio.TotalCost) * 100
 		}
 
+		held := holds.Shares[portfolio.StockSymbol]
 		portfolioItem := domain.PortfolioItem{
-			StockSymbol:   portfolio.StockSymbol,
-			StockName:     stock.Name,
-			Quantity:      portfolio.Quantity,
-			AveragePrice:  portfolio.AveragePrice,
-			CurrentPrice:  stock.CurrentPrice,
-			TotalCost:     portfolio.TotalCost,
-			CurrentValue:  currentValue,
-			ProfitLoss:    profit,
-			ProfitLossPct: profitPct,
+			StockSymbol:       portfolio.StockSymbol,
+			StockName:         stock.Name,
+			Side:              domain.PositionSideLong,
+			Quantity:          portfolio.Quantity,
+			HeldQuantity:      held,
+			AvailableQuantity: max(0, portfolio.Quantity-held),
+			AveragePrice:      portfolio.AveragePrice,
+			CurrentPrice:      stock.CurrentPrice,
+			TotalCost:         portfolio.TotalCost,
+			CurrentValue:      currentValue,
+			ProfitLoss:        profit,
+			ProfitLossPct:     profitPct,
 		}
 
 		portfolioItems = append(portfolioItems, portfolioItem)
@@ -65,6 +89,19 @@ func (s *portfolioService) GetUserPortfolio(userID int) (*domain.PortfolioSummar
 		totalProfit += profit
 	}
 
+	shortItems, err := s.shortPositionItems(userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range shortItems {
+		item.HeldQuantity = holds.Covers[item.StockSymbol]
+		item.AvailableQuantity = max(0, -item.Quantity-item.HeldQuantity)
+		portfolioItems = append(portfolioItems, item)
+		totalValue += item.TotalCost + item.ProfitLoss
+		totalCost += item.TotalCost
+		totalProfit += item.ProfitLoss
+	}
+
 	totalProfitPct := float64(0)
 	if totalCost > 0 {
 		totalProfitPct = (totalProfit / totalCost) * 100
@@ -75,6 +112,9 @@ func (s *portfolioService) GetUserPortfolio(userID int) (*domain.PortfolioSummar
 		TotalCost:      totalCost,
 		TotalProfit:    totalProfit,
 		TotalProfitPct: totalProfitPct,
+		CashBalance:    user.Balance,
+		HeldCash:       holds.Cash,
+		AvailableCash:  math.Max(0, user.Balance-holds.Cash),
 		Holdings:       portfolioItems,
 	}
 
@@ -103,6 +143,16 @@ func (s *portfolioService) GetPortfolioPerformance(userID int, period string) (*
 		totalProfit += (currentValue - portfolio.TotalCost)
 	}
 
+	shortItems, err := s.shortPositionItems(userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range shortItems {
+		totalCost += item.TotalCost
+		totalCurrentValue += item.TotalCost + item.ProfitLoss
+		totalProfit += item.ProfitLoss
+	}
+
 	totalProfitPct := float64(0)
 	if totalCost > 0 {
 		totalProfitPct = (totalProfit / totalCost) * 100
@@ -114,7 +164,7 @@ func (s *portfolioService) GetPortfolioPerformance(userID int, period string) (*
 		EndValue:     totalCurrentValue,
 		Profit:       totalProfit,
 		ProfitPct:    totalProfitPct,
-		Transactions: len(portfolios),
+		Transactions: len(portfolios) + len(shortItems),
 	}
 
 	return performance, nil
@@ -199,9 +249,67 @@ func (s *portfolioService) GetPortfolioSummary(userID int) (*domain.PortfolioSum
 		return nil, fmt.Errorf("failed to get portfolio summary: %w", err)
 	}
 
+	shortItems, err := s.shortPositionItems(userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range shortItems {
+		summary.Holdings = append(summary.Holdings, item)
+		summary.TotalValue += item.TotalCost + item.ProfitLoss
+		summary.TotalCost += item.TotalCost
+		summary.TotalProfit += item.ProfitLoss
+	}
+	if len(shortItems) > 0 && summary.TotalCost > 0 {
+		summary.TotalProfitPct = summary.TotalProfit / summary.TotalCost * 100
+	}
+
 	return summary, nil
 }
 
+// shortPositionItems values the user's shorts at current prices. A short's
+// cost is its net proceeds, its current value the cost of buying back, and
+// its P&L what covering now would book after borrow fees.
+func (s *portfolioService) shortPositionItems(userID int) ([]domain.PortfolioItem, error) {
+	if s.shortPositionRepo == nil {
+		return nil, nil
+	}
+	positions, err := s.shortPositionRepo.GetByUserID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get short positions: %w", err)
+	}
+
+	items := make([]domain.PortfolioItem, 0, len(positions))
+	for i := range positions {
+		position := &positions[i]
+		stock, err := s.stockRepo.GetBySymbol(position.StockSymbol)
+		if err != nil {
+			continue // Skip if stock not found
+		}
+
+		profit := position.UnrealizedPnL(stock.CurrentPrice)
+		profitPct := float64(0)
+		if position.Proceeds > 0 {
+			profitPct = profit / position.Proceeds * 100
+		}
+
+		items = append(items, domain.PortfolioItem{
+			StockSymbol:   position.StockSymbol,
+			StockName:     stock.Name,
+			Side:          domain.PositionSideShort,
+			Quantity:      -position.Quantity,
+			AveragePrice:  position.AveragePrice,
+			CurrentPrice:  stock.CurrentPrice,
+			TotalCost:     position.Proceeds,
+			CurrentValue:  position.MarketValue(stock.CurrentPrice),
+			ProfitLoss:    profit,
+			ProfitLossPct: profitPct,
+			BorrowFees:    position.AccruedBorrowFees,
+			Collateral:    position.Collateral,
+		})
+	}
+	return items, nil
+}
+
 func (s *portfolioService) UpdatePortfolio(userID int, stockSymbol string, quantity int, averagePrice float64) error {
 	portfolio, err := s.portfolioRepo.GetByUserIDAndSymbol(userID, stockSymbol)
 	if err != nil {
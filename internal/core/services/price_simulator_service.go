@@ -7,6 +7,7 @@ import (
 	"math/rand"
 	"stock-simulation-backend/internal/core/domain"
 	"stock-simulation-backend/internal/core/ports/repositories"
+	"stock-simulation-backend/internal/core/ports/services"
 	"sync"
 	"time"
 )
@@ -16,6 +17,10 @@ type PriceSimulatorService struct {
 	historicalPriceRepo repositories.HistoricalPriceRepository
 	realTimeService     *RealTimeService
 	redisService        *RedisService
+	orderService        services.AdvancedOrderService
+	marginService       services.MarginService
+	contingentService   services.ContingentOrderService
+	quotes              *QuoteCache
 	running             bool
 	stopChan            chan bool
 	mu                  sync.RWMutex
@@ -31,12 +36,20 @@ func NewPriceSimulatorService(
 	historicalPriceRepo repositories.HistoricalPriceRepository,
 	realTimeService *RealTimeService,
 	redisService *RedisService,
+	orderService services.AdvancedOrderService,
+	marginService services.MarginService,
+	contingentService services.ContingentOrderService,
+	quotes *QuoteCache,
 ) *PriceSimulatorService {
 	return &PriceSimulatorService{
 		stockRepo:           stockRepo,
 		historicalPriceRepo: historicalPriceRepo,
 		realTimeService:     realTimeService,
 		redisService:        redisService,
+		orderService:        orderService,
+		marginService:       marginService,
+		contingentService:   contingentService,
+		quotes:              quotes,
 		running:             false,
 		stopChan:            make(chan bool),
 		updateInterval:      5 * time.Second,  // Update every 5 seconds
@@ -131,6 +144,7 @@ func (s *PriceSimulatorService) updateAllPrices(rng *rand.Rand) {
 	fmt.Printf("\n📊 [%s] Updating %d stock prices...\n", timestamp, len(stocks))
 	
 	updatedCount := 0
+	priceUpdates := make(map[string]float64, len(stocks))
 	for _, stock := range stocks {
 		oldPrice := stock.CurrentPrice
 		newPrice := s.generateRealisticPrice(stock, rng)
@@ -150,6 +164,16 @@ func (s *PriceSimulatorService) updateAllPrices(rng *rand.Rand) {
 		fmt.Printf("%s %s: $%.2f → $%.2f (%+.2f%%)\n",
 			indicator, stock.Symbol, oldPrice, newPrice, changePercent)
 		
+		// Quote around the new price; volatile symbols quote wider
+		volatility := 0.0
+		if s.quotes != nil {
+			volatility = s.quotes.Observe(stock.Symbol, changePercent)
+		}
+		quote := domain.NewQuote(stock.Symbol, newPrice, volatility, syntheticLevelSize(stock.Volume))
+		if s.quotes != nil {
+			s.quotes.Set(quote)
+		}
+		
 		// Create real-time price update message
 		priceUpdate := domain.PriceUpdateMessage{
 			Symbol:        stock.Symbol,
@@ -161,6 +185,10 @@ func (s *PriceSimulatorService) updateAllPrices(rng *rand.Rand) {
 			Low:           math.Min(oldPrice, newPrice), // Simplified for demo
 			Open:          oldPrice,                     // Using previous price as open
 			PreviousClose: stock.PreviousClose,
+			BidPrice:      &quote.Bid,
+			AskPrice:      &quote.Ask,
+			BidSize:       &quote.BidSize,
+			AskSize:       &quote.AskSize,
 			LastTradeTime: time.Now(),
 			MarketCap:     &stock.MarketCap,
 		}
@@ -187,6 +215,7 @@ func (s *PriceSimulatorService) updateAllPrices(rng *rand.Rand) {
 			s.saveHistoricalPrice(stock.Symbol, oldPrice, newPrice, float64(stock.Volume))
 		}
 		
+		priceUpdates[stock.Symbol] = newPrice
 		updatedCount++
 	}
 	
@@ -206,6 +235,27 @@ func (s *PriceSimulatorService) updateAllPrices(rng *rand.Rand) {
 		}
 		fmt.Println()
 	}
+
+	// Match resting orders against the new prices
+	if s.orderService != nil && len(priceUpdates) > 0 {
+		if err := s.orderService.ProcessPriceUpdates(priceUpdates); err != nil {
+			log.Printf("⚠️ Order matching failed: %v", err)
+		}
+	}
+
+	// Submit contingent orders whose conditions this tick satisfied
+	if s.contingentService != nil && len(priceUpdates) > 0 {
+		if err := s.contingentService.ProcessPriceUpdates(priceUpdates); err != nil {
+			log.Printf("⚠️ Contingent order check failed: %v", err)
+		}
+	}
+
+	// Re-value margin loans once this tick's fills have settled
+	if s.marginService != nil && len(priceUpdates) > 0 {
+		if err := s.marginService.ProcessPriceUpdates(priceUpdates); err != nil {
+			log.Printf("⚠️ Margin check failed: %v", err)
+		}
+	}
 }
 
 // saveHistoricalPrice saves price data for charting
@@ -0,0 +1,99 @@
+package services
+
+import (
+	"sync"
+	"time"
+
+	"stock-simulation-backend/internal/core/domain"
+	"stock-simulation-backend/internal/core/ports/repositories"
+)
+
+// quoteTTL is how long a simulator quote is trusted. Older quotes (or none,
+// when the simulator is stopped) are re-derived from the last price.
+const quoteTTL = 30 * time.Second
+
+// QuoteCache holds the latest simulated quote per symbol, and the recent tick
+// returns its spread is sized from. The price simulator writes it and the
+// order services read it, without either depending on the other.
+type QuoteCache struct {
+	mu                  sync.RWMutex
+	quotes              map[string]domain.Quote
+	returns             map[string][]float64
+	historicalPriceRepo repositories.HistoricalPriceRepository
+}
+
+func NewQuoteCache(historicalPriceRepo repositories.HistoricalPriceRepository) *QuoteCache {
+	return &QuoteCache{
+		quotes:              make(map[string]domain.Quote),
+		returns:             make(map[string][]float64),
+		historicalPriceRepo: historicalPriceRepo,
+	}
+}
+
+// Set stores the latest quote for its symbol
+func (c *QuoteCache) Set(quote domain.Quote) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.quotes[quote.Symbol] = quote
+}
+
+// QuoteFor returns the current quote for a stock. A missing or stale quote,
+// or one made at a different last price, is rebuilt around the stock's
+// current price with the symbol's recent volatility.
+func (c *QuoteCache) QuoteFor(stock *domain.Stock) domain.Quote {
+	c.mu.RLock()
+	quote, ok := c.quotes[stock.Symbol]
+	c.mu.RUnlock()
+
+	if ok && quote.Last == stock.CurrentPrice && time.Since(quote.UpdatedAt) <= quoteTTL {
+		return quote
+	}
+	return domain.NewQuote(stock.Symbol, stock.CurrentPrice, c.Volatility(stock.Symbol), syntheticLevelSize(stock.Volume))
+}
+
+// Observe adds a tick's return, in percent, to the symbol's rolling window
+// and returns the window's volatility
+func (c *QuoteCache) Observe(symbol string, returnPercent float64) float64 {
+	window := c.window(symbol)
+
+	// Copy rather than append in place: readers may hold the old window
+	start := max(0, len(window)+1-volatilityWindow)
+	next := make([]float64, 0, volatilityWindow)
+	next = append(append(next, window[start:]...), returnPercent)
+
+	c.mu.Lock()
+	c.returns[symbol] = next
+	c.mu.Unlock()
+	return domain.Volatility(next)
+}
+
+// Volatility is the standard deviation of the symbol's recent returns, in
+// percent
+func (c *QuoteCache) Volatility(symbol string) float64 {
+	return domain.Volatility(c.window(symbol))
+}
+
+// window is the symbol's rolling window of returns, oldest first. A symbol
+// not seen since startup is seeded from its recent historical prices.
+func (c *QuoteCache) window(symbol string) []float64 {
+	c.mu.RLock()
+	returns, ok := c.returns[symbol]
+	c.mu.RUnlock()
+	if ok || c.historicalPriceRepo == nil {
+		return returns
+	}
+
+	prices, err := c.historicalPriceRepo.GetBySymbolWithLimit(symbol, volatilityWindow+1)
+	if err != nil {
+		return nil
+	}
+	seeded := domain.PriceReturns(prices)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if returns, ok := c.returns[symbol]; ok {
+		return returns
+	}
+	c.returns[symbol] = seeded
+	return seeded
+}
@@ -14,6 +14,7 @@ import (
 
 type RealTimeService struct {
 	clients      map[string]*websocket.Conn
+	clientUsers  map[*websocket.Conn]int // Authenticated connections only
 	clientsMu    sync.RWMutex
 	upgrader     websocket.Upgrader
 	broadcast    chan domain.PriceUpdateMessage
@@ -25,6 +26,7 @@ type RealTimeService struct {
 func NewRealTimeService(redisService *RedisService) *RealTimeService {
 	return &RealTimeService{
 		clients:      make(map[string]*websocket.Conn),
+		clientUsers:  make(map[*websocket.Conn]int),
 		redisService: redisService,
 		upgrader: websocket.Upgrader{
 			CheckOrigin: func(r *http.Request) bool {
@@ -94,6 +96,13 @@ func (s *RealTimeService) subscribeToRedis() {
 
 // Handle WebSocket connection upgrade
 func (s *RealTimeService) HandleWebSocket(w http.ResponseWriter, r *http.Request) {
+	s.HandleUserWebSocket(w, r, 0)
+}
+
+// HandleUserWebSocket upgrades a connection on behalf of an authenticated
+// user, who then also receives their own order updates. A userID of 0 is an
+// anonymous connection that only gets market data.
+func (s *RealTimeService) HandleUserWebSocket(w http.ResponseWriter, r *http.Request, userID int) {
 	conn, err := s.upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		log.Printf("❌ WebSocket upgrade failed: %v", err)
@@ -102,6 +111,12 @@ func (s *RealTimeService) HandleWebSocket(w http.ResponseWriter, r *http.Request
 	
 	log.Printf("🔌 New WebSocket connection established from %s", conn.RemoteAddr())
 	
+	if userID > 0 {
+		s.clientsMu.Lock()
+		s.clientUsers[conn] = userID
+		s.clientsMu.Unlock()
+	}
+	
 	// Register the new connection
 	s.register <- conn
 	
@@ -193,6 +208,8 @@ func (s *RealTimeService) handleDisconnection(conn *websocket.Conn) {
 	s.clientsMu.Lock()
 	defer s.clientsMu.Unlock()
 	
+	delete(s.clientUsers, conn)
+	
 	// Find and remove the client
 	for clientID, client := range s.clients {
 		if client == conn {
@@ -280,6 +297,23 @@ func (s *RealTimeService) BroadcastMarketStatus(status string) {
 	s.clientsMu.RUnlock()
 }
 
+// BroadcastOrderBookUpdate streams a symbol's order book to all local clients
+func (s *RealTimeService) BroadcastOrderBookUpdate(update domain.OrderBookUpdate) {
+	message := map[string]interface{}{
+		"type":      domain.MessageTypeOrderBookUpdate,
+		"data":      update,
+		"timestamp": time.Now().Unix(),
+	}
+	
+	s.clientsMu.RLock()
+	for _, conn := range s.clients {
+		if err := s.sendToClient(conn, message); err != nil {
+			log.Printf("⚠️ Failed to send order book update: %v", err)
+		}
+	}
+	s.clientsMu.RUnlock()
+}
+
 // BroadcastTradingAlert broadcasts trading alerts
 func (s *RealTimeService) BroadcastTradingAlert(alert domain.TradingAlert) {
 	if s.redisService != nil {
@@ -304,6 +338,54 @@ func (s *RealTimeService) BroadcastTradingAlert(alert domain.TradingAlert) {
 	s.clientsMu.RUnlock()
 }
 
+// SendOrderUpdate sends an order status change to the order owner's
+// authenticated connections on this instance
+func (s *RealTimeService) SendOrderUpdate(userID int, updateType string, update domain.OrderUpdateMessage) {
+	message := map[string]interface{}{
+		"type":        "order_update",
+		"update_type": updateType,
+		"data":        update,
+		"timestamp":   time.Now().Unix(),
+	}
+	s.sendToUser(userID, "order update", message)
+}
+
+// SendBalanceUpdate sends a user's cash, margin and buying power
+func (s *RealTimeService) SendBalanceUpdate(userID int, update domain.BalanceUpdateMessage) {
+	message := map[string]interface{}{
+		"type":      domain.MessageTypeBalanceUpdate,
+		"data":      update,
+		"timestamp": time.Now().Unix(),
+	}
+	s.sendToUser(userID, "balance update", message)
+}
+
+// SendMarginCall warns a user that their margin account is below maintenance
+func (s *RealTimeService) SendMarginCall(userID int, status domain.MarginStatus) {
+	message := map[string]interface{}{
+		"type":      domain.MessageTypeMarginCall,
+		"data":      status,
+		"timestamp": time.Now().Unix(),
+	}
+	s.sendToUser(userID, "margin call", message)
+}
+
+// sendToUser sends a message to the user's authenticated connections on this
+// instance
+func (s *RealTimeService) sendToUser(userID int, what string, message interface{}) {
+	s.clientsMu.RLock()
+	defer s.clientsMu.RUnlock()
+	
+	for conn, owner := range s.clientUsers {
+		if owner != userID {
+			continue
+		}
+		if err := s.sendToClient(conn, message); err != nil {
+			log.Printf("⚠️ Failed to send %s to user %d: %v", what, userID, err)
+		}
+	}
+}
+
 // Send message to specific client
 func (s *RealTimeService) sendToClient(conn *websocket.Conn, message interface{}) error {
 	if err := conn.SetWriteDeadline(time.Now().Add(10 * time.Second)); err != nil {
@@ -0,0 +1,218 @@
+package services
+
+import (
+	"fmt"
+
+	"stock-simulation-backend/internal/core/domain"
+	"stock-simulation-backend/internal/core/ports/repositories"
+	"stock-simulation-backend/internal/core/ports/services"
+)
+
+// Rebalance moves the user's long holdings to target weights with a basket
+// of market orders: sells first, so their proceeds can pay for the buys.
+// Short positions are left alone, and buys are paid for from cash only.
+//
+// An all-or-none basket is settled as one unit of work: every order fills in
+// full or, if any one cannot, none of them trades. A best-effort basket
+// sends every order and reports how each one went.
+func (s *AdvancedOrderService) Rebalance(userID int, request *domain.RebalanceRequest) (*domain.Rebalance, error) {
+	weights, cashWeight, err := request.Weights()
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("user not found")
+	}
+	holdings, err := s.portfolioRepo.GetByUserID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user portfolio: %w", err)
+	}
+	working, err := s.orderRepo.GetWorkingOrdersByUser(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get working orders: %w", err)
+	}
+	holds := domain.NewHolds(working)
+
+	stocks := make(map[string]*domain.Stock)
+	positions := make(map[string]domain.RebalancePosition)
+	addPosition := func(symbol string, quantity int) error {
+		stock, err := s.stockRepo.GetBySymbol(symbol)
+		if err != nil {
+			return fmt.Errorf("stock not found: %s", symbol)
+		}
+		if stock.CurrentPrice <= 0 {
+			return fmt.Errorf("no price to rebalance %s at", symbol)
+		}
+		stocks[symbol] = stock
+		positions[symbol] = domain.RebalancePosition{
+			Quantity:  quantity,
+			Available: max(0, quantity-holds.Shares[symbol]),
+			Price:     stock.CurrentPrice,
+		}
+		return nil
+	}
+	for _, holding := range holdings {
+		if holding.Quantity <= 0 {
+			continue
+		}
+		if err := addPosition(holding.StockSymbol, holding.Quantity); err != nil {
+			return nil, err
+		}
+	}
+	for symbol := range weights {
+		if _, ok := positions[symbol]; ok {
+			continue
+		}
+		if err := addPosition(symbol, 0); err != nil {
+			return nil, err
+		}
+	}
+
+	costs := func(quantity int, price float64) float64 {
+		return s.calculateCommission(quantity, price) + s.calculateFees(quantity, price)
+	}
+	rebalance := domain.PlanRebalance(request, weights, cashWeight, positions,
+		user.Balance, user.Balance-holds.Cash, costs)
+
+	// The planned trades come first, sells before buys
+	var requests []*domain.OrderRequest
+	var orders []*domain.Order
+	for _, trade := range rebalance.Trades {
+		if trade.Status != domain.RebalanceTradePlanned {
+			break
+		}
+		orderRequest := &domain.OrderRequest{
+			StockSymbol: trade.Symbol,
+			OrderType:   domain.OrderTypeMarket,
+			Side:        trade.Side,
+			Quantity:    trade.Quantity,
+			TimeInForce: domain.TimeInForceDAY,
+		}
+		requests = append(requests, orderRequest)
+		orders = append(orders, s.newOrder(userID, orderRequest, stocks[trade.Symbol]))
+	}
+	if len(orders) == 0 {
+		if !request.DryRun {
+			rebalance.Status = domain.RebalanceStatusCompleted
+		}
+		return rebalance, nil
+	}
+
+	if request.DryRun {
+		if err := s.riskManager.ValidateBasket(userID, orders); err != nil {
+			rebalance.Error = err.Error()
+		}
+		return rebalance, nil
+	}
+	if request.Mode == domain.RebalanceModeAllOrNone {
+		return s.settleRebalance(userID, rebalance, orders)
+	}
+
+	rebalance.Status = domain.RebalanceStatusCompleted
+	for i, orderRequest := range requests {
+		trade := &rebalance.Trades[i]
+		status, reason := s.sendRebalanceOrder(userID, orderRequest, trade)
+		trade.Status = status
+		if reason != "" {
+			trade.Reason = reason
+		}
+		if trade.Status != domain.RebalanceTradeFilled {
+			rebalance.Status = domain.RebalanceStatusPartial
+		}
+	}
+	return rebalance, nil
+}
+
+// settleRebalance settles an all-or-none basket, whose orders lead its trades
+// in the same order. A risk rejection is returned as an error; a trade that
+// cannot fill in full leaves the basket ABORTED with nothing traded.
+func (s *AdvancedOrderService) settleRebalance(userID int, rebalance *domain.Rebalance, orders []*domain.Order) (*domain.Rebalance, error) {
+	failed, err := s.settleBasket(userID, orders)
+	if err != nil && failed < 0 {
+		return nil, err
+	}
+
+	if err != nil {
+		rebalance.Status = domain.RebalanceStatusAborted
+		for i := range orders {
+			rebalance.Trades[i].Status = domain.RebalanceTradeRolledBack
+		}
+		trade := &rebalance.Trades[failed]
+		trade.Status, trade.Reason = domain.RebalanceTradeRejected, err.Error()
+		rebalance.Error = fmt.Sprintf("%s %d %s could not fill: %v", trade.Side, trade.Quantity, trade.Symbol, err)
+		return rebalance, nil
+	}
+
+	rebalance.Status = domain.RebalanceStatusCompleted
+	for i, order := range orders {
+		rebalance.Trades[i].Status, rebalance.Trades[i].OrderID = domain.RebalanceTradeFilled, &order.ID
+		if err := s.NotifyOrderUpdate(order, services.OrderUpdateTypeExecuted); err != nil {
+			fmt.Printf("⚠️ Failed to notify order %d update: %v\n", order.ID, err)
+		}
+	}
+	return rebalance, nil
+}
+
+// settleBasket stores and fills orders, in turn, in one unit of work holding
+// the user's lock, after checking them as a whole. Sells settle first, so
+// the buys after them can spend their proceeds, and an order that cannot
+// fill in full rolls every one of them back. Orders take the quote with the
+// modelled slippage rather than walking the book, since a walk could not be
+// rolled back with them. It returns the index of the order that failed, or
+// -1 when the basket filled or was refused before any order was tried.
+func (s *AdvancedOrderService) settleBasket(userID int, orders []*domain.Order) (int, error) {
+	failed := -1
+	reserved := make([]int, len(orders))
+	err := s.placeChecked(userID, func() error {
+		return s.riskManager.ValidateBasket(userID, orders)
+	}, func(tx repositories.TxRepositories) error {
+		for i, order := range orders {
+			failed = i
+			reserved[i] = s.liquidity.take(order.StockSymbol, order.Quantity, func() int {
+				return s.tickBudget(order.StockSymbol)
+			})
+			if reserved[i] < order.Quantity {
+				return fmt.Errorf("%w: %d of %d shares available", errNoLiquidity, reserved[i], order.Quantity)
+			}
+
+			if err := tx.Orders().Create(order); err != nil {
+				return fmt.Errorf("failed to create order: %w", err)
+			}
+			price, slippage := s.applySlippage(order, s.executionPrice(order, order.MarketPrice), order.Quantity)
+			if _, err := s.executeOrderTransaction(tx, order, price, order.Quantity, slippage); err != nil {
+				return err
+			}
+		}
+		failed = -1
+		return nil
+	})
+	if err != nil {
+		for i, order := range orders {
+			s.liquidity.giveBack(order.StockSymbol, reserved[i])
+		}
+	}
+	return failed, err
+}
+
+// sendRebalanceOrder places one basket order and says how it went, with the
+// reason when it did not fill
+func (s *AdvancedOrderService) sendRebalanceOrder(userID int, request *domain.OrderRequest, trade *domain.RebalanceTrade) (domain.RebalanceTradeStatus, string) {
+	order, err := s.CreateOrder(userID, request)
+	if err != nil {
+		return domain.RebalanceTradeRejected, err.Error()
+	}
+	trade.OrderID = &order.ID
+
+	switch {
+	case order.Status == domain.OrderStatusExecuted:
+		return domain.RebalanceTradeFilled, ""
+	case order.IsActive():
+		return domain.RebalanceTradeWorking, fmt.Sprintf("%d of %d shares filled", order.Quantity-order.RemainingQuantity, order.Quantity)
+	case order.CancelReason != nil:
+		return domain.RebalanceTradeRejected, *order.CancelReason
+	default:
+		return domain.RebalanceTradeRejected, fmt.Sprintf("order ended %s", order.Status)
+	}
+}
@@ -191,6 +191,59 @@ func (s *RedisService) GetCachedStockPrice(symbol string) (float64, error) {
 	return price, nil
 }
 
+// CacheJSON stores value as JSON under key with TTL
+func (s *RedisService) CacheJSON(key string, value interface{}, ttl time.Duration) error {
+	if s == nil || s.client == nil {
+		return fmt.Errorf("redis service not available")
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("failed to marshal cached value: %w", err)
+	}
+
+	if err := s.client.Set(s.ctx, key, data, ttl).Err(); err != nil {
+		return fmt.Errorf("failed to cache value: %w", err)
+	}
+
+	return nil
+}
+
+// GetCachedJSON decodes the JSON cached under key into dest, reporting false
+// if nothing is cached
+func (s *RedisService) GetCachedJSON(key string, dest interface{}) (bool, error) {
+	if s == nil || s.client == nil {
+		return false, fmt.Errorf("redis service not available")
+	}
+
+	result, err := s.client.Get(s.ctx, key).Bytes()
+	if err != nil {
+		if err == redis.Nil {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to get cached value: %w", err)
+	}
+
+	if err := json.Unmarshal(result, dest); err != nil {
+		return false, fmt.Errorf("failed to parse cached value: %w", err)
+	}
+
+	return true, nil
+}
+
+// DeleteCached removes key from the cache
+func (s *RedisService) DeleteCached(key string) error {
+	if s == nil || s.client == nil {
+		return fmt.Errorf("redis service not available")
+	}
+
+	if err := s.client.Del(s.ctx, key).Err(); err != nil {
+		return fmt.Errorf("failed to delete cached value: %w", err)
+	}
+
+	return nil
+}
+
 // PublishMarketStatus publishes market status updates
 func (s *RedisService) PublishMarketStatus(status string) error {
 	if s == nil || s.client == nil {
@@ -0,0 +1,694 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"stock-simulation-backend/internal/core/domain"
+	"stock-simulation-backend/internal/core/ports/repositories"
+	"stock-simulation-backend/internal/core/ports/services"
+)
+
+const (
+	// varConfidenceZ is the one-tailed z-score of the 95% value at risk
+	varConfidenceZ = 1.65
+
+	// illiquidScore is the liquidity score at or below which a position is
+	// counted as hard to exit
+	illiquidScore = 3
+)
+
+// RiskManager runs the pre-trade checks every order passes before it is
+// stored and values the risk an account carries. Limits come from
+// configuration and can be overridden per user.
+//
+// Only orders that open or add to a position (BUY and SHORT) are held to the
+// notional, concentration, daily and buying power limits, so a user can
+// always close out; closing orders only need shares not already held by
+// other working orders. The price band applies to every priced order.
+//
+// An amendment is checked as the order it would become, with the version it
+// replaces set aside so the order is not counted against itself. It is not a
+// new order, so it never counts towards the daily order limit.
+//
+// The checks read without locks. Callers hold the user's row lock from the
+// check until what it passed is stored, so a user's concurrent orders are
+// checked one after another rather than against the same holds.
+type RiskManager struct {
+	orderRepo           repositories.AdvancedOrderRepository
+	transactionRepo     repositories.TransactionRepository
+	userRepo            repositories.UserRepository
+	portfolioRepo       repositories.PortfolioRepository
+	shortPositionRepo   repositories.ShortPositionRepository
+	marginAccountRepo   repositories.MarginAccountRepository
+	stockRepo           repositories.StockRepository
+	historicalPriceRepo repositories.HistoricalPriceRepository
+	riskLimitRepo       repositories.RiskLimitRepository
+	limits              domain.RiskLimits
+}
+
+func NewRiskManager(
+	orderRepo repositories.AdvancedOrderRepository,
+	transactionRepo repositories.TransactionRepository,
+	userRepo repositories.UserRepository,
+	portfolioRepo repositories.PortfolioRepository,
+	shortPositionRepo repositories.ShortPositionRepository,
+	marginAccountRepo repositories.MarginAccountRepository,
+	stockRepo repositories.StockRepository,
+	historicalPriceRepo repositories.HistoricalPriceRepository,
+	riskLimitRepo repositories.RiskLimitRepository,
+	limits domain.RiskLimits,
+) services.RiskManager {
+	return &RiskManager{
+		orderRepo:           orderRepo,
+		transactionRepo:     transactionRepo,
+		userRepo:            userRepo,
+		portfolioRepo:       portfolioRepo,
+		shortPositionRepo:   shortPositionRepo,
+		marginAccountRepo:   marginAccountRepo,
+		stockRepo:           stockRepo,
+		historicalPriceRepo: historicalPriceRepo,
+		riskLimitRepo:       riskLimitRepo,
+		limits:              limits,
+	}
+}
+
+// riskAccount is an account valued at current prices
+type riskAccount struct {
+	status   *domain.MarginStatus
+	holdings []domain.Portfolio
+	shorts   []domain.ShortPosition
+	holds    *domain.Holds
+	working  []domain.Order
+	stocks   map[string]*domain.Stock
+	equity   float64 // Margin equity plus what the shorts would leave once covered
+}
+
+// heldQuantity is the position the order adds to: longs for a buy, shorts
+// for a short sale
+func (a *riskAccount) heldQuantity(symbol string, side domain.OrderSide) int {
+	if side == domain.OrderSideShort {
+		for _, short := range a.shorts {
+			if short.StockSymbol == symbol {
+				return short.Quantity
+			}
+		}
+		return 0
+	}
+	for _, holding := range a.holdings {
+		if holding.StockSymbol == symbol {
+			return holding.Quantity
+		}
+	}
+	return 0
+}
+
+// setAside takes a working order out of the account's holds
+func (a *riskAccount) setAside(order *domain.Order) {
+	others := make([]domain.Order, 0, len(a.working))
+	for _, working := range a.working {
+		if working.ID != order.ID {
+			others = append(others, working)
+		}
+	}
+	a.holds = domain.NewHolds(others)
+}
+
+func (s *RiskManager) ValidateOrder(userID int, order *domain.Order) error {
+	return s.validate(userID, order, nil)
+}
+
+// ValidateAmendment checks amended, the next version of the working order
+// current. The daily loss limit only stops amendments that make the order
+// bigger.
+func (s *RiskManager) ValidateAmendment(userID int, current, amended *domain.Order) error {
+	return s.validate(userID, amended, current)
+}
+
+// ValidateBasket checks orders that will be sent one after another, as a
+// whole, before any of them is. Each order is checked as if those before it
+// had been placed and, for sells and covers, filled: earlier buys hold
+// buying power from later ones, and earlier sales' proceeds pay for them.
+// The basket is not placed under one lock, so each order is checked again,
+// under the user's lock, as it is placed.
+func (s *RiskManager) ValidateBasket(userID int, orders []*domain.Order) error {
+	limits, err := s.GetRiskLimits(userID)
+	if err != nil {
+		return err
+	}
+	account, err := s.valueAccount(userID)
+	if err != nil {
+		return err
+	}
+
+	// Only opening orders are held to the daily limits, but every order sent
+	// before the last of them counts towards its daily order count
+	for i := len(orders) - 1; i >= 0; i-- {
+		if orders[i].IsOpening() {
+			if err := s.checkDailyLimits(userID, limits, i+1); err != nil {
+				return basketError(orders[i], err)
+			}
+			break
+		}
+	}
+
+	for _, order := range orders {
+		if err := checkPriceBand(order, limits); err != nil {
+			return basketError(order, err)
+		}
+
+		if !order.IsOpening() {
+			if err := checkAvailableShares(order, account); err != nil {
+				return basketError(order, err)
+			}
+			if order.Side == domain.OrderSideSell {
+				proceeds := order.Notional() - order.Commission - order.Fees
+				account.status.CashBalance += proceeds
+				account.status.BuyingPower += proceeds
+			}
+			continue
+		}
+
+		if err := checkOrderNotional(order, limits); err != nil {
+			return basketError(order, err)
+		}
+		if err := s.checkConcentration(userID, order, order.RemainingQuantity, limits, account); err != nil {
+			return basketError(order, err)
+		}
+		if err := checkBuyingPower(order, account); err != nil {
+			return basketError(order, err)
+		}
+		account.working = append(account.working, *order)
+		account.holds = domain.NewHolds(account.working)
+	}
+	return nil
+}
+
+// basketError names the basket order a check failed on
+func basketError(order *domain.Order, err error) error {
+	return fmt.Errorf("%s %d %s: %w", order.Side, order.Quantity, order.StockSymbol, err)
+}
+
+// validate runs the pre-trade checks on order, which replaces the working
+// order replaces when that is not nil
+func (s *RiskManager) validate(userID int, order, replaces *domain.Order) error {
+	limits, err := s.GetRiskLimits(userID)
+	if err != nil {
+		return err
+	}
+
+	if err := checkPriceBand(order, limits); err != nil {
+		return err
+	}
+
+	account, err := s.valueAccount(userID)
+	if err != nil {
+		return err
+	}
+	added := order.RemainingQuantity
+	if replaces != nil {
+		account.setAside(replaces)
+		added -= replaces.RemainingQuantity
+	}
+	if !order.IsOpening() {
+		return checkAvailableShares(order, account)
+	}
+
+	if err := checkOrderNotional(order, limits); err != nil {
+		return err
+	}
+	switch {
+	case replaces == nil:
+		err = s.checkDailyLimits(userID, limits, 1)
+	case order.Hold().Cash > replaces.Hold().Cash:
+		err = s.checkDailyLoss(userID, limits)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := s.checkConcentration(userID, order, added, limits, account); err != nil {
+		return err
+	}
+	return checkBuyingPower(order, account)
+}
+
+func (s *RiskManager) CheckPositionLimits(userID int, order *domain.Order) error {
+	if !order.IsOpening() {
+		return nil
+	}
+	limits, err := s.GetRiskLimits(userID)
+	if err != nil {
+		return err
+	}
+	if err := checkOrderNotional(order, limits); err != nil {
+		return err
+	}
+
+	account, err := s.valueAccount(userID)
+	if err != nil {
+		return err
+	}
+	return s.checkConcentration(userID, order, order.RemainingQuantity, limits, account)
+}
+
+func (s *RiskManager) CheckMarginRequirements(userID int, order *domain.Order) error {
+	if !order.IsOpening() {
+		return nil
+	}
+	account, err := s.valueAccount(userID)
+	if err != nil {
+		return err
+	}
+	return checkBuyingPower(order, account)
+}
+
+// CheckAvailableShares rejects a sell or cover for more shares than the
+// position has left once other working orders' holds are taken out
+func (s *RiskManager) CheckAvailableShares(userID int, order *domain.Order) error {
+	if order.IsOpening() {
+		return nil
+	}
+	account, err := s.valueAccount(userID)
+	if err != nil {
+		return err
+	}
+	return checkAvailableShares(order, account)
+}
+
+func checkAvailableShares(order *domain.Order, account *riskAccount) error {
+	position, held := account.heldQuantity(order.StockSymbol, domain.OrderSideBuy), account.holds.Shares[order.StockSymbol]
+	if order.Side == domain.OrderSideCover {
+		position, held = account.heldQuantity(order.StockSymbol, domain.OrderSideShort), account.holds.Covers[order.StockSymbol]
+	}
+
+	if available := position - held; order.RemainingQuantity > available {
+		return domain.NewRiskRejection(domain.RiskReasonInsufficientShares, float64(available),
+			"%s of %d %s shares needs more than the %d available (%d held by working orders)",
+			order.Side, order.RemainingQuantity, order.StockSymbol, available, held)
+	}
+	return nil
+}
+
+func (s *RiskManager) CheckDailyLimits(userID int) error {
+	limits, err := s.GetRiskLimits(userID)
+	if err != nil {
+		return err
+	}
+	return s.checkDailyLimits(userID, limits, 1)
+}
+
+func (s *RiskManager) GetRiskLimits(userID int) (*domain.RiskLimits, error) {
+	overrides, err := s.riskLimitRepo.GetByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	limits := s.limits.WithOverrides(overrides)
+	return &limits, nil
+}
+
+// UpdateRiskLimits replaces the user's overrides. Users may only tighten the
+// global limits.
+func (s *RiskManager) UpdateRiskLimits(userID int, overrides *domain.RiskLimitOverrides) (*domain.RiskLimits, error) {
+	if err := overrides.ValidateAgainst(s.limits); err != nil {
+		return nil, err
+	}
+
+	overrides.UserID = userID
+	if err := s.riskLimitRepo.Upsert(overrides); err != nil {
+		return nil, err
+	}
+
+	limits := s.limits.WithOverrides(overrides)
+	return &limits, nil
+}
+
+// checkPriceBand rejects limit prices too far from the current price to be
+// anything but a typo. A stop limit's limit is measured from its stop, since
+// the stop may rightly sit well away from the market.
+func checkPriceBand(order *domain.Order, limits *domain.RiskLimits) error {
+	if limits.PriceBandPercent <= 0 || order.Price == nil {
+		return nil
+	}
+
+	reference := order.MarketPrice
+	if order.OrderType == domain.OrderTypeStopLimit && order.StopPrice != nil {
+		reference = *order.StopPrice
+	}
+	if reference <= 0 {
+		return nil
+	}
+
+	deviation := math.Abs(*order.Price-reference) / reference * 100
+	if deviation > limits.PriceBandPercent {
+		return domain.NewRiskRejection(domain.RiskReasonPriceBand, limits.PriceBandPercent,
+			"limit price %.2f is %.1f%% away from %.2f; the band is %.1f%%",
+			*order.Price, deviation, reference, limits.PriceBandPercent)
+	}
+	return nil
+}
+
+func checkOrderNotional(order *domain.Order, limits *domain.RiskLimits) error {
+	if limits.MaxOrderNotional <= 0 {
+		return nil
+	}
+	if notional := order.Notional(); notional > limits.MaxOrderNotional {
+		return domain.NewRiskRejection(domain.RiskReasonOrderNotional, limits.MaxOrderNotional,
+			"order value %.2f exceeds the maximum of %.2f", notional, limits.MaxOrderNotional)
+	}
+	return nil
+}
+
+// checkConcentration caps the position in one symbol, counting shares held
+// and resting orders on the same side, as a share of account equity. added
+// is how many shares the order puts on top of the resting orders.
+func (s *RiskManager) checkConcentration(userID int, order *domain.Order, added int, limits *domain.RiskLimits, account *riskAccount) error {
+	if limits.MaxPositionConcentration <= 0 {
+		return nil
+	}
+	price := order.ReferencePrice()
+	if price <= 0 {
+		return nil
+	}
+
+	if account.equity <= 0 {
+		return domain.NewRiskRejection(domain.RiskReasonConcentration, limits.MaxPositionConcentration,
+			"account equity is %.2f; no new positions can be opened", account.equity)
+	}
+
+	maxValue := account.equity * limits.MaxPositionConcentration
+	maxQuantity := int(math.Floor(maxValue/price)) - account.heldQuantity(order.StockSymbol, order.Side)
+	ok, err := s.orderRepo.CheckOrderSizeLimit(userID, order.StockSymbol, order.Side, added, maxQuantity)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return domain.NewRiskRejection(domain.RiskReasonConcentration, limits.MaxPositionConcentration,
+			"%s position would exceed %.0f%% of account equity (%.2f)",
+			order.StockSymbol, limits.MaxPositionConcentration*100, maxValue)
+	}
+	return nil
+}
+
+// checkDailyLimits counts orders and realized losses over the trading day,
+// which starts at the last session close, with room left for the given
+// number of new orders
+func (s *RiskManager) checkDailyLimits(userID int, limits *domain.RiskLimits, orders int) error {
+	since := domain.LastSessionClose(defaultMarketCode, time.Now())
+
+	if limits.MaxOrdersPerDay > 0 {
+		ok := orders <= limits.MaxOrdersPerDay
+		if ok {
+			var err error
+			ok, err = s.orderRepo.CheckDailyOrderLimit(userID, since, limits.MaxOrdersPerDay-orders+1)
+			if err != nil {
+				return err
+			}
+		}
+		if !ok {
+			return domain.NewRiskRejection(domain.RiskReasonDailyOrderCount, float64(limits.MaxOrdersPerDay),
+				"daily limit of %d orders reached", limits.MaxOrdersPerDay)
+		}
+	}
+
+	return s.checkDailyLoss(userID, limits)
+}
+
+// checkDailyLoss stops new exposure once the day's realized losses reach the
+// limit
+func (s *RiskManager) checkDailyLoss(userID int, limits *domain.RiskLimits) error {
+	if limits.DailyLossLimit <= 0 {
+		return nil
+	}
+
+	since := domain.LastSessionClose(defaultMarketCode, time.Now())
+	pnl, err := s.transactionRepo.GetRealizedPnLSince(userID, since)
+	if err != nil {
+		return err
+	}
+	if -pnl >= limits.DailyLossLimit {
+		return domain.NewRiskRejection(domain.RiskReasonDailyLoss, limits.DailyLossLimit,
+			"realized loss of %.2f today has reached the limit of %.2f; only closing orders are accepted",
+			-pnl, limits.DailyLossLimit)
+	}
+	return nil
+}
+
+// checkBuyingPower rejects an order the account could not fund alongside its
+// other working orders: a buy needs its hold in buying power, a short its
+// initial margin in cash
+func checkBuyingPower(order *domain.Order, account *riskAccount) error {
+	available := account.status.BuyingPower
+	if order.Side == domain.OrderSideShort {
+		available = account.status.CashBalance
+	}
+	available = math.Max(0, available-account.holds.Cash)
+
+	if required := order.Hold().Cash; required > available {
+		return domain.NewRiskRejection(domain.RiskReasonInsufficientFunds, available,
+			"order needs %.2f but only %.2f is available (%.2f held by working orders)",
+			required, available, account.holds.Cash)
+	}
+	return nil
+}
+
+// valueAccount loads the account and values it at current prices
+func (s *RiskManager) valueAccount(userID int) (*riskAccount, error) {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("user not found")
+	}
+	marginAccount, err := s.marginAccountRepo.GetByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	holdings, err := s.portfolioRepo.GetByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	shorts, err := s.shortPositionRepo.GetByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	working, err := s.orderRepo.GetWorkingOrdersByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	account := &riskAccount{
+		holdings: holdings,
+		shorts:   shorts,
+		holds:    domain.NewHolds(working),
+		working:  working,
+		stocks:   make(map[string]*domain.Stock),
+	}
+	longValue := 0.0
+	for _, holding := range holdings {
+		stock, err := s.stockFor(account, holding.StockSymbol)
+		if err != nil {
+			return nil, err
+		}
+		longValue += float64(holding.Quantity) * stock.CurrentPrice
+	}
+
+	account.status = domain.NewMarginStatus(userID, marginAccount, user.Balance, longValue)
+	account.equity = account.status.Equity
+	for i := range shorts {
+		stock, err := s.stockFor(account, shorts[i].StockSymbol)
+		if err != nil {
+			return nil, err
+		}
+		account.equity += shorts[i].Equity(stock.CurrentPrice)
+	}
+	return account, nil
+}
+
+func (s *RiskManager) stockFor(account *riskAccount, symbol string) (*domain.Stock, error) {
+	if stock, ok := account.stocks[symbol]; ok {
+		return stock, nil
+	}
+	stock, err := s.stockRepo.GetBySymbol(symbol)
+	if err != nil {
+		return nil, fmt.Errorf("stock not found: %s", symbol)
+	}
+	account.stocks[symbol] = stock
+	return stock, nil
+}
+
+// CalculateRiskMetrics summarizes the account's leverage, concentration and
+// value at risk. No equity history is kept, so MaxDrawdown is not reported.
+func (s *RiskManager) CalculateRiskMetrics(userID int) (*services.RiskMetrics, error) {
+	account, err := s.valueAccount(userID)
+	if err != nil {
+		return nil, err
+	}
+	positions := s.positionRisk(userID, account)
+
+	postedShortMargin := 0.0
+	for _, short := range account.shorts {
+		postedShortMargin += short.Collateral - short.Proceeds
+	}
+
+	exposure := 0.0
+	for _, item := range positions.Positions {
+		exposure += item.MarketValue
+	}
+
+	metrics := &services.RiskMetrics{
+		UserID:            userID,
+		TotalExposure:     exposure,
+		MarginUsed:        account.status.BorrowedFunds + account.status.AccruedInterest + postedShortMargin,
+		MarginAvailable:   account.status.BuyingPower,
+		PortfolioVaR:      positions.TotalRisk,
+		ConcentrationRisk: positions.ConcentrationRisk,
+		LastCalculated:    time.Now(),
+	}
+	if exposure > 0 {
+		metrics.MarginRatio = account.equity / exposure
+	}
+	if account.equity > 0 {
+		metrics.LeverageRatio = exposure / account.equity
+	}
+	metrics.RiskScore = riskScore(metrics, account.equity)
+
+	return metrics, nil
+}
+
+// riskScore rates an account from 1 (calm) to 10, adding up to three points
+// each for leverage, concentration and value at risk against equity
+func riskScore(metrics *services.RiskMetrics, equity float64) int {
+	if metrics.TotalExposure == 0 {
+		return 1
+	}
+	if equity <= 0 {
+		return 10
+	}
+
+	score := 1.0
+	score += math.Min(3, math.Max(0, metrics.LeverageRatio-1)*3)
+	score += math.Min(3, metrics.ConcentrationRisk*3)
+	score += math.Min(3, metrics.PortfolioVaR/equity*100)
+	return int(math.Min(10, math.Round(score)))
+}
+
+// MonitorPositions breaks the account's risk down by position and suggests
+// what to trim
+func (s *RiskManager) MonitorPositions(userID int) (*services.PositionRisk, error) {
+	account, err := s.valueAccount(userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.positionRisk(userID, account), nil
+}
+
+// positionRisk values each long and short. Shorts carry a negative quantity;
+// market values are the gross exposure either way. VaR is the 95% loss over
+// one historical price interval, summed without offsetting across positions.
+func (s *RiskManager) positionRisk(userID int, account *riskAccount) *services.PositionRisk {
+	risk := &services.PositionRisk{
+		UserID:          userID,
+		Positions:       []services.PositionRiskItem{},
+		Recommendations: []services.RiskRecommendation{},
+	}
+
+	for _, holding := range account.holdings {
+		if holding.Quantity > 0 {
+			risk.Positions = append(risk.Positions, s.positionItem(account, holding.StockSymbol, holding.Quantity))
+		}
+	}
+	for _, short := range account.shorts {
+		if short.Quantity > 0 {
+			risk.Positions = append(risk.Positions, s.positionItem(account, short.StockSymbol, -short.Quantity))
+		}
+	}
+
+	exposure, illiquid := 0.0, 0.0
+	for _, item := range risk.Positions {
+		exposure += item.MarketValue
+		risk.TotalRisk += item.VaR
+		if item.LiquidityScore <= illiquidScore {
+			illiquid += item.MarketValue
+		}
+	}
+	if exposure == 0 {
+		return risk
+	}
+	risk.LiquidityRisk = illiquid / exposure
+
+	limits, err := s.GetRiskLimits(userID)
+	if err != nil {
+		limits = &s.limits
+	}
+
+	for i := range risk.Positions {
+		item := &risk.Positions[i]
+		item.PortfolioWeight = item.MarketValue / exposure
+		risk.ConcentrationRisk += item.PortfolioWeight * item.PortfolioWeight
+		if risk.TotalRisk > 0 {
+			item.RiskContribution = item.VaR / risk.TotalRisk
+		}
+
+		if limits.MaxPositionConcentration > 0 && account.equity > 0 {
+			maxValue := account.equity * limits.MaxPositionConcentration
+			if item.MarketValue > maxValue {
+				risk.Recommendations = append(risk.Recommendations, services.RiskRecommendation{
+					Type:            "REDUCE",
+					Symbol:          item.Symbol,
+					Action:          fmt.Sprintf("Trim %s by %.2f", item.Symbol, item.MarketValue-maxValue),
+					Reason:          fmt.Sprintf("Position is %.0f%% of equity, above the %.0f%% limit", item.MarketValue/account.equity*100, limits.MaxPositionConcentration*100),
+					Priority:        "HIGH",
+					EstimatedImpact: item.MarketValue - maxValue,
+				})
+			}
+		}
+		if item.LiquidityScore <= illiquidScore {
+			risk.Recommendations = append(risk.Recommendations, services.RiskRecommendation{
+				Type:            "REDUCE",
+				Symbol:          item.Symbol,
+				Action:          fmt.Sprintf("Scale out of %s over several sessions", item.Symbol),
+				Reason:          "Position is large against the stock's traded volume",
+				Priority:        "MEDIUM",
+				EstimatedImpact: item.MarketValue,
+			})
+		}
+	}
+
+	// A single name, or one dominating the book
+	if risk.ConcentrationRisk > 0.5 {
+		risk.Recommendations = append(risk.Recommendations, services.RiskRecommendation{
+			Type:     "DIVERSIFY",
+			Action:   "Spread exposure over more symbols",
+			Reason:   fmt.Sprintf("Concentration index is %.2f", risk.ConcentrationRisk),
+			Priority: "MEDIUM",
+		})
+	}
+
+	return risk
+}
+
+func (s *RiskManager) positionItem(account *riskAccount, symbol string, quantity int) services.PositionRiskItem {
+	item := services.PositionRiskItem{Symbol: symbol, Quantity: quantity, LiquidityScore: 1}
+
+	stock, ok := account.stocks[symbol]
+	if !ok {
+		return item
+	}
+	shares := math.Abs(float64(quantity))
+	item.MarketValue = shares * stock.CurrentPrice
+
+	if s.historicalPriceRepo != nil {
+		if prices, err := s.historicalPriceRepo.GetBySymbolWithLimit(symbol, volatilityWindow); err == nil {
+			item.Volatility = domain.ReturnVolatility(prices)
+		}
+	}
+	item.VaR = varConfidenceZ * item.Volatility / 100 * item.MarketValue
+
+	// Ten when the position is a sliver of traded volume, one point off per
+	// percent of volume it would take to exit
+	if stock.Volume > 0 {
+		share := shares / float64(stock.Volume) * 100
+		item.LiquidityScore = int(math.Max(1, math.Min(10, 10-math.Floor(share))))
+	}
+	return item
+}
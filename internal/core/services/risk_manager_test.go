@@ -0,0 +1,117 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"stock-simulation-backend/internal/core/domain"
+)
+
+func TestCreateOrderRiskRejections(t *testing.T) {
+	price := func(v float64) *float64 { return &v }
+	buy := func(quantity int) *domain.OrderRequest {
+		return &domain.OrderRequest{StockSymbol: "AAPL", OrderType: domain.OrderTypeMarket, Side: domain.OrderSideBuy, Quantity: quantity}
+	}
+
+	// The user has 10000 in cash and 10 shares; AAPL trades at 100
+	tests := []struct {
+		name     string
+		limits   domain.RiskLimits
+		setup    func(t *testing.T, store *fakeStore)
+		request  *domain.OrderRequest
+		wantCode domain.RiskReasonCode // Empty when the order is accepted
+	}{
+		{
+			name:     "order notional",
+			limits:   domain.RiskLimits{MaxOrderNotional: 5000},
+			request:  buy(60),
+			wantCode: domain.RiskReasonOrderNotional,
+		},
+		{
+			name:   "limit price outside the band",
+			limits: domain.RiskLimits{PriceBandPercent: 10},
+			request: &domain.OrderRequest{StockSymbol: "AAPL", OrderType: domain.OrderTypeLimit, Side: domain.OrderSideBuy,
+				Quantity: 1, Price: price(80)},
+			wantCode: domain.RiskReasonPriceBand,
+		},
+		{
+			name:   "daily order count",
+			limits: domain.RiskLimits{MaxOrdersPerDay: 1},
+			setup: func(t *testing.T, store *fakeStore) {
+				storeOrder(t, store, domain.OrderTypeLimit, domain.OrderSideBuy, 1, 90)
+			},
+			request:  buy(1),
+			wantCode: domain.RiskReasonDailyOrderCount,
+		},
+		{
+			name:   "daily loss",
+			limits: domain.RiskLimits{DailyLossLimit: 500},
+			setup: func(t *testing.T, store *fakeStore) {
+				store.transactions = append(store.transactions, domain.Transaction{UserID: 1, RealizedPnL: -600, CreatedAt: time.Now()})
+			},
+			request:  buy(1),
+			wantCode: domain.RiskReasonDailyLoss,
+		},
+		{
+			name:     "position concentration",
+			limits:   domain.RiskLimits{MaxPositionConcentration: 0.5},
+			request:  buy(50),
+			wantCode: domain.RiskReasonConcentration,
+		},
+		{
+			name: "buying power held by a resting order",
+			setup: func(t *testing.T, store *fakeStore) {
+				storeOrder(t, store, domain.OrderTypeLimit, domain.OrderSideBuy, 100, 90)
+			},
+			request:  buy(20),
+			wantCode: domain.RiskReasonInsufficientFunds,
+		},
+		{
+			name: "shares held by a resting sell",
+			setup: func(t *testing.T, store *fakeStore) {
+				storeOrder(t, store, domain.OrderTypeLimit, domain.OrderSideSell, 5, 110)
+			},
+			request:  &domain.OrderRequest{StockSymbol: "AAPL", OrderType: domain.OrderTypeMarket, Side: domain.OrderSideSell, Quantity: 10},
+			wantCode: domain.RiskReasonInsufficientShares,
+		},
+		{
+			name:   "closing orders are not held to the opening limits",
+			limits: domain.RiskLimits{MaxOrderNotional: 1, MaxOrdersPerDay: 1, MaxPositionConcentration: 0.01},
+			setup: func(t *testing.T, store *fakeStore) {
+				storeOrder(t, store, domain.OrderTypeLimit, domain.OrderSideBuy, 1, 90)
+			},
+			request: &domain.OrderRequest{StockSymbol: "AAPL", OrderType: domain.OrderTypeMarket, Side: domain.OrderSideSell, Quantity: 10},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newFakeStore()
+			store.addUser(1, 10000)
+			store.addStock("AAPL", 100)
+			store.addHolding(1, "AAPL", 10, 90)
+			if tt.setup != nil {
+				tt.setup(t, store)
+			}
+			service := newTestOrderService(store, tt.limits)
+			stored := len(store.orders)
+
+			_, err := service.CreateOrder(1, tt.request)
+
+			if tt.wantCode == "" {
+				if err != nil {
+					t.Fatalf("CreateOrder() error = %v, want the order accepted", err)
+				}
+				return
+			}
+			var rejection *domain.RiskRejection
+			if !errors.As(err, &rejection) || rejection.Code != tt.wantCode {
+				t.Fatalf("CreateOrder() error = %v, want a %s rejection", err, tt.wantCode)
+			}
+			if len(store.orders) != stored {
+				t.Errorf("rejected order was stored: %d orders, want %d", len(store.orders), stored)
+			}
+		})
+	}
+}
@@ -9,150 +9,482 @@ import (
 )
 
 type transactionService struct {
+	uow             repositories.UnitOfWork
 	transactionRepo repositories.TransactionRepository
 	portfolioRepo   repositories.PortfolioRepository
 	stockRepo       repositories.StockRepository
 	userRepo        repositories.UserRepository
+	riskManager     services.RiskManager
+	quotes          *QuoteCache
 }
 
 func NewTransactionService(
+	uow repositories.UnitOfWork,
 	transactionRepo repositories.TransactionRepository,
 	portfolioRepo repositories.PortfolioRepository,
 	stockRepo repositories.StockRepository,
 	userRepo repositories.UserRepository,
+	riskManager services.RiskManager,
+	quotes *QuoteCache,
 ) services.TransactionService {
 	return &transactionService{
+		uow:             uow,
 		transactionRepo: transactionRepo,
 		portfolioRepo:   portfolioRepo,
 		stockRepo:       stockRepo,
 		userRepo:        userRepo,
+		riskManager:     riskManager,
+		quotes:          quotes,
 	}
 }
 
 func (s *transactionService) BuyStock(userID int, req *domain.TransactionRequest) (*domain.TransactionResponse, error) {
-	// Get user
-	user, err := s.userRepo.GetByID(userID)
+	return s.tradeAtMarket(userID, domain.TransactionTypeBuy, req)
+}
+
+func (s *transactionService) SellStock(userID int, req *domain.TransactionRequest) (*domain.TransactionResponse, error) {
+	return s.tradeAtMarket(userID, domain.TransactionTypeSell, req)
+}
+
+// tradeAtMarket settles a direct trade like a market order: buys at the ask
+// and sells at the bid, held to the same risk checks, and charged the same
+// commission and fees. The quote is recorded on the transaction.
+func (s *transactionService) tradeAtMarket(userID int, transactionType domain.TransactionType, req *domain.TransactionRequest) (*domain.TransactionResponse, error) {
+	stock, err := s.stockRepo.GetBySymbol(req.StockSymbol)
+	if err != nil {
+		return nil, fmt.Errorf("stock not found: %w", err)
+	}
+
+	order := &domain.Order{
+		UserID:            userID,
+		StockSymbol:       req.StockSymbol,
+		OrderType:         domain.OrderTypeMarket,
+		Side:              domain.OrderSide(transactionType),
+		Quantity:          req.Quantity,
+		RemainingQuantity: req.Quantity,
+		MarketPrice:       stock.CurrentPrice,
+	}
+	price := stock.CurrentPrice
+	if s.quotes != nil {
+		quote := s.quotes.QuoteFor(stock)
+		price = quote.PriceFor(order.IsBuySide())
+		order.BidPrice, order.AskPrice = &quote.Bid, &quote.Ask
+	}
+	order.Commission = tradeCommission(req.Quantity, price)
+	order.Fees = tradeFees(req.Quantity, price)
+
+	// Check and settle under the user's row lock, so concurrent trades are
+	// checked one at a time against what those before them settled
+	var response *domain.TransactionResponse
+	err = s.uow.Do(func(tx repositories.TxRepositories) error {
+		if _, err := tx.Users().GetByIDForUpdate(userID); err != nil {
+			return fmt.Errorf("failed to lock user: %w", err)
+		}
+		if err := s.riskManager.ValidateOrder(userID, order); err != nil {
+			return err
+		}
+
+		var err error
+		response, err = s.ExecuteFill(tx, &domain.TradeFill{
+			UserID:      userID,
+			StockSymbol: req.StockSymbol,
+			Type:        transactionType,
+			Quantity:    req.Quantity,
+			Price:       price,
+			BidPrice:    order.BidPrice,
+			AskPrice:    order.AskPrice,
+			Commission:  order.Commission,
+			Fees:        order.Fees,
+		})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// ExecuteFill settles a fill at its own price, commission and fees. When tx is
+// nil the fill runs in a unit of work of its own.
+func (s *transactionService) ExecuteFill(tx repositories.TxRepositories, fill *domain.TradeFill) (*domain.TransactionResponse, error) {
+	if fill.Quantity <= 0 {
+		return nil, domain.RefuseSettlement("fill quantity must be positive")
+	}
+	if fill.Price <= 0 {
+		return nil, domain.RefuseSettlement("fill price must be positive")
+	}
+
+	if tx == nil {
+		var response *domain.TransactionResponse
+		err := s.uow.Do(func(tx repositories.TxRepositories) error {
+			var err error
+			response, err = s.ExecuteFill(tx, fill)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+		return response, nil
+	}
+
+	switch fill.Type {
+	case domain.TransactionTypeBuy:
+		return s.settleBuy(tx, fill)
+	case domain.TransactionTypeSell:
+		return s.settleSell(tx, fill)
+	case domain.TransactionTypeShort:
+		return s.settleShort(tx, fill)
+	case domain.TransactionTypeCover:
+		return s.settleCover(tx, fill)
+	default:
+		return nil, domain.RefuseSettlement("unsupported fill type: %s", fill.Type)
+	}
+}
+
+func (s *transactionService) settleBuy(tx repositories.TxRepositories, fill *domain.TradeFill) (*domain.TransactionResponse, error) {
+	// Lock the user row so concurrent trades cannot spend the same balance
+	user, err := tx.Users().GetByIDForUpdate(fill.UserID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get user: %w", err)
 	}
 
-	// Get stock
-	stock, err := s.stockRepo.GetBySymbol(req.StockSymbol)
+	// An open short is closed by covering, not by buying alongside it
+	short, err := tx.ShortPositions().GetByUserIDAndSymbol(fill.UserID, fill.StockSymbol)
 	if err != nil {
-		return nil, fmt.Errorf("stock not found: %w", err)
+		return nil, fmt.Errorf("failed to get short position: %w", err)
+	}
+	if short != nil {
+		return nil, domain.RefuseSettlement("cover the open short position in %s before buying", fill.StockSymbol)
 	}
 
-	// Calculate total amount
-	totalAmount := float64(req.Quantity) * stock.CurrentPrice
+	// Buyer pays the fill value plus commission and fees
+	totalAmount := fill.GrossAmount() + fill.Costs()
 
-	// Check if user has enough balance
-	if user.Balance < totalAmount {
-		return nil, fmt.Errorf("insufficient balance")
+	// Check if user has enough balance; margin accounts borrow the shortfall
+	newBalance := user.Balance - totalAmount
+	if newBalance < 0 {
+		if err := s.borrowOnMargin(tx, user, totalAmount); err != nil {
+			return nil, err
+		}
+		newBalance = 0
 	}
 
 	// Create transaction
 	transaction := &domain.Transaction{
-		UserID:      userID,
-		StockSymbol: req.StockSymbol,
+		UserID:      fill.UserID,
+		StockSymbol: fill.StockSymbol,
 		Type:        domain.TransactionTypeBuy,
-		Quantity:    req.Quantity,
-		Price:       stock.CurrentPrice,
+		Quantity:    fill.Quantity,
+		Price:       fill.Price,
+		BidPrice:    fill.BidPrice,
+		AskPrice:    fill.AskPrice,
 		TotalAmount: totalAmount,
+		Commission:  fill.Commission,
+		Fees:        fill.Fees,
+		OrderID:     fill.OrderID,
 		CreatedAt:   time.Now(),
 	}
 
-	err = s.transactionRepo.Create(transaction)
+	err = tx.Transactions().Create(transaction)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create transaction: %w", err)
 	}
 
 	// Update user balance
-	newBalance := user.Balance - totalAmount
-	err = s.userRepo.UpdateBalance(userID, newBalance)
+	err = tx.Users().UpdateBalance(fill.UserID, newBalance)
 	if err != nil {
 		return nil, fmt.Errorf("failed to update user balance: %w", err)
 	}
 
-	// Update portfolio
-	err = s.updatePortfolioAfterBuy(userID, req.StockSymbol, req.Quantity, stock.CurrentPrice)
+	// Update portfolio; commission and fees are part of the cost basis
+	err = s.updatePortfolioAfterBuy(tx, fill.UserID, fill.StockSymbol, fill.Quantity, totalAmount)
 	if err != nil {
 		return nil, fmt.Errorf("failed to update portfolio: %w", err)
 	}
 
 	response := &domain.TransactionResponse{
 		Transaction: transaction,
-		Message:     fmt.Sprintf("Successfully bought %d shares of %s", req.Quantity, req.StockSymbol),
+		Message:     fmt.Sprintf("Successfully bought %d shares of %s", fill.Quantity, fill.StockSymbol),
 		Balance:     newBalance,
 	}
 
 	return response, nil
 }
 
-func (s *transactionService) SellStock(userID int, req *domain.TransactionRequest) (*domain.TransactionResponse, error) {
-	// Get user
-	user, err := s.userRepo.GetByID(userID)
+func (s *transactionService) settleSell(tx repositories.TxRepositories, fill *domain.TradeFill) (*domain.TransactionResponse, error) {
+	// Lock the user row before the position so lock order matches settleBuy
+	user, err := tx.Users().GetByIDForUpdate(fill.UserID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get user: %w", err)
 	}
 
-	// Get stock
-	stock, err := s.stockRepo.GetBySymbol(req.StockSymbol)
+	account, err := tx.MarginAccounts().GetByUserIDForUpdate(fill.UserID)
 	if err != nil {
-		return nil, fmt.Errorf("stock not found: %w", err)
+		return nil, err
 	}
 
 	// Get portfolio item
-	portfolioItem, err := s.portfolioRepo.GetByUserIDAndSymbol(userID, req.StockSymbol)
+	portfolioItem, err := tx.Portfolios().GetByUserIDAndSymbolForUpdate(fill.UserID, fill.StockSymbol)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get portfolio: %w", err)
 	}
-	if portfolioItem == nil || portfolioItem.Quantity < req.Quantity {
-		return nil, fmt.Errorf("insufficient shares to sell")
+	if portfolioItem == nil || portfolioItem.Quantity < fill.Quantity {
+		return nil, domain.RefuseSettlement("insufficient shares to sell")
 	}
 
-	// Calculate total amount
-	totalAmount := float64(req.Quantity) * stock.CurrentPrice
+	// Seller receives the fill value less commission and fees
+	totalAmount := fill.GrossAmount() - fill.Costs()
+	realizedPnL := totalAmount - (float64(fill.Quantity) * portfolioItem.AveragePrice)
 
 	// Create transaction
 	transaction := &domain.Transaction{
-		UserID:      userID,
-		StockSymbol: req.StockSymbol,
+		UserID:      fill.UserID,
+		StockSymbol: fill.StockSymbol,
 		Type:        domain.TransactionTypeSell,
-		Quantity:    req.Quantity,
-		Price:       stock.CurrentPrice,
+		Quantity:    fill.Quantity,
+		Price:       fill.Price,
+		BidPrice:    fill.BidPrice,
+		AskPrice:    fill.AskPrice,
 		TotalAmount: totalAmount,
+		Commission:  fill.Commission,
+		Fees:        fill.Fees,
+		OrderID:     fill.OrderID,
+		RealizedPnL: realizedPnL,
 		CreatedAt:   time.Now(),
 	}
 
-	err = s.transactionRepo.Create(transaction)
+	err = tx.Transactions().Create(transaction)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create transaction: %w", err)
 	}
 
+	// Sale proceeds pay down any margin debt before reaching cash
+	credited := totalAmount
+	if account.Debt() > 0 {
+		credited -= account.Repay(totalAmount)
+		if err := tx.MarginAccounts().Update(account); err != nil {
+			return nil, err
+		}
+	}
+
 	// Update user balance
-	newBalance := user.Balance + totalAmount
-	err = s.userRepo.UpdateBalance(userID, newBalance)
+	newBalance := user.Balance + credited
+	err = tx.Users().UpdateBalance(fill.UserID, newBalance)
 	if err != nil {
 		return nil, fmt.Errorf("failed to update user balance: %w", err)
 	}
 
 	// Update portfolio
-	err = s.updatePortfolioAfterSell(userID, req.StockSymbol, req.Quantity)
+	err = s.updatePortfolioAfterSell(tx, fill.UserID, fill.StockSymbol, fill.Quantity)
 	if err != nil {
 		return nil, fmt.Errorf("failed to update portfolio: %w", err)
 	}
 
-	// Calculate and update total profit
-	profit := totalAmount - (float64(req.Quantity) * portfolioItem.AveragePrice)
-	newTotalProfit := user.TotalProfit + profit
-	err = s.userRepo.UpdateTotalProfit(userID, newTotalProfit)
+	// Update total profit
+	err = tx.Users().UpdateTotalProfit(fill.UserID, user.TotalProfit+realizedPnL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update total profit: %w", err)
+	}
+
+	response := &domain.TransactionResponse{
+		Transaction: transaction,
+		Message:     fmt.Sprintf("Successfully sold %d shares of %s", fill.Quantity, fill.StockSymbol),
+		Balance:     newBalance,
+	}
+
+	return response, nil
+}
+
+// borrowOnMargin lends a margin account what its cash cannot pay of a
+// purchase, provided the purchase fits its buying power. Cash accounts get
+// the plain insufficient balance error.
+func (s *transactionService) borrowOnMargin(tx repositories.TxRepositories, user *domain.User, totalAmount float64) error {
+	account, err := tx.MarginAccounts().GetByUserIDForUpdate(user.ID)
+	if err != nil {
+		return err
+	}
+	if !account.IsMargin() {
+		return domain.RefuseSettlement("insufficient balance: required %.2f, available %.2f", totalAmount, user.Balance)
+	}
+
+	longValue, err := s.longMarketValue(tx, user.ID)
+	if err != nil {
+		return err
+	}
+	status := domain.NewMarginStatus(user.ID, account, user.Balance, longValue)
+	if totalAmount > status.BuyingPower {
+		return domain.RefuseSettlement("insufficient buying power: required %.2f, available %.2f", totalAmount, status.BuyingPower)
+	}
+
+	account.Borrow(totalAmount - user.Balance)
+	return tx.MarginAccounts().Update(account)
+}
+
+// longMarketValue values the user's long positions at current prices
+func (s *transactionService) longMarketValue(tx repositories.TxRepositories, userID int) (float64, error) {
+	holdings, err := tx.Portfolios().GetByUserID(userID)
+	if err != nil {
+		return 0, err
+	}
+
+	value := 0.0
+	for _, holding := range holdings {
+		stock, err := s.stockRepo.GetBySymbol(holding.StockSymbol)
+		if err != nil {
+			return 0, fmt.Errorf("stock not found: %s", holding.StockSymbol)
+		}
+		value += float64(holding.Quantity) * stock.CurrentPrice
+	}
+	return value, nil
+}
+
+// settleShort opens or adds to a short. The proceeds are held as collateral
+// together with the initial margin, which comes out of the user's cash.
+func (s *transactionService) settleShort(tx repositories.TxRepositories, fill *domain.TradeFill) (*domain.TransactionResponse, error) {
+	user, err := tx.Users().GetByIDForUpdate(fill.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	holding, err := tx.Portfolios().GetByUserIDAndSymbolForUpdate(fill.UserID, fill.StockSymbol)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get portfolio: %w", err)
+	}
+	if holding != nil && holding.Quantity > 0 {
+		return nil, domain.RefuseSettlement("sell the long position in %s before shorting it", fill.StockSymbol)
+	}
+
+	proceeds := fill.GrossAmount() - fill.Costs()
+	margin := domain.ShortInitialMargin(fill.GrossAmount())
+	if user.Balance < margin {
+		return nil, domain.RefuseSettlement("insufficient balance for short margin: required %.2f, available %.2f", margin, user.Balance)
+	}
+
+	transaction := &domain.Transaction{
+		UserID:      fill.UserID,
+		StockSymbol: fill.StockSymbol,
+		Type:        domain.TransactionTypeShort,
+		Quantity:    fill.Quantity,
+		Price:       fill.Price,
+		BidPrice:    fill.BidPrice,
+		AskPrice:    fill.AskPrice,
+		TotalAmount: proceeds,
+		Commission:  fill.Commission,
+		Fees:        fill.Fees,
+		OrderID:     fill.OrderID,
+		CreatedAt:   time.Now(),
+	}
+
+	err = tx.Transactions().Create(transaction)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create transaction: %w", err)
+	}
+
+	newBalance := user.Balance - margin
+	err = tx.Users().UpdateBalance(fill.UserID, newBalance)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update user balance: %w", err)
+	}
+
+	position, err := tx.ShortPositions().GetByUserIDAndSymbolForUpdate(fill.UserID, fill.StockSymbol)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get short position: %w", err)
+	}
+	if position == nil {
+		position = &domain.ShortPosition{UserID: fill.UserID, StockSymbol: fill.StockSymbol}
+		position.Add(fill.Quantity, proceeds, margin)
+		err = tx.ShortPositions().Create(position)
+	} else {
+		position.Add(fill.Quantity, proceeds, margin)
+		err = tx.ShortPositions().Update(position)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update short position: %w", err)
+	}
+
+	response := &domain.TransactionResponse{
+		Transaction: transaction,
+		Message:     fmt.Sprintf("Successfully shorted %d shares of %s", fill.Quantity, fill.StockSymbol),
+		Balance:     newBalance,
+	}
+
+	return response, nil
+}
+
+// settleCover buys back shorted shares. The covered part of the collateral is
+// released, the buy-back and the borrow fees owed are paid from it, and the
+// difference from the proceeds is booked as realized P&L.
+func (s *transactionService) settleCover(tx repositories.TxRepositories, fill *domain.TradeFill) (*domain.TransactionResponse, error) {
+	user, err := tx.Users().GetByIDForUpdate(fill.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	position, err := tx.ShortPositions().GetByUserIDAndSymbolForUpdate(fill.UserID, fill.StockSymbol)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get short position: %w", err)
+	}
+	if position == nil || position.Quantity < fill.Quantity {
+		available := 0
+		if position != nil {
+			available = position.Quantity
+		}
+		return nil, domain.RefuseSettlement("insufficient short position: required %d, available %d", fill.Quantity, available)
+	}
+
+	cost := fill.GrossAmount() + fill.Costs()
+	proceeds, collateral, borrowFees := position.Release(fill.Quantity)
+	realizedPnL := proceeds - cost - borrowFees
+
+	newBalance := user.Balance + collateral - cost - borrowFees
+	if newBalance < 0 {
+		return nil, domain.RefuseSettlement("insufficient balance for cover: required %.2f, available %.2f",
+			cost+borrowFees-collateral, user.Balance)
+	}
+
+	transaction := &domain.Transaction{
+		UserID:      fill.UserID,
+		StockSymbol: fill.StockSymbol,
+		Type:        domain.TransactionTypeCover,
+		Quantity:    fill.Quantity,
+		Price:       fill.Price,
+		BidPrice:    fill.BidPrice,
+		AskPrice:    fill.AskPrice,
+		TotalAmount: cost + borrowFees,
+		Commission:  fill.Commission,
+		Fees:        fill.Fees + borrowFees,
+		OrderID:     fill.OrderID,
+		RealizedPnL: realizedPnL,
+		CreatedAt:   time.Now(),
+	}
+
+	err = tx.Transactions().Create(transaction)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create transaction: %w", err)
+	}
+
+	err = tx.Users().UpdateBalance(fill.UserID, newBalance)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update user balance: %w", err)
+	}
+
+	if position.Quantity == 0 {
+		err = tx.ShortPositions().Delete(fill.UserID, fill.StockSymbol)
+	} else {
+		err = tx.ShortPositions().Update(position)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update short position: %w", err)
+	}
+
+	err = tx.Users().UpdateTotalProfit(fill.UserID, user.TotalProfit+realizedPnL)
 	if err != nil {
 		return nil, fmt.Errorf("failed to update total profit: %w", err)
 	}
 
 	response := &domain.TransactionResponse{
 		Transaction: transaction,
-		Message:     fmt.Sprintf("Successfully sold %d shares of %s", req.Quantity, req.StockSymbol),
+		Message:     fmt.Sprintf("Successfully covered %d shares of %s", fill.Quantity, fill.StockSymbol),
 		Balance:     newBalance,
 	}
 
@@ -186,8 +518,8 @@ func (s *transactionService) GetTransactionByID(transactionID int) (*domain.Tran
 	return transaction, nil
 }
 
-func (s *transactionService) updatePortfolioAfterBuy(userID int, stockSymbol string, quantity int, price float64) error {
-	portfolioItem, err := s.portfolioRepo.GetByUserIDAndSymbol(userID, stockSymbol)
+func (s *transactionService) updatePortfolioAfterBuy(tx repositories.TxRepositories, userID int, stockSymbol string, quantity int, cost float64) error {
+	portfolioItem, err := tx.Portfolios().GetByUserIDAndSymbolForUpdate(userID, stockSymbol)
 	if err != nil {
 		return err
 	}
@@ -198,14 +530,14 @@ func (s *transactionService) updatePortfolioAfterBuy(userID int, stockSymbol str
 			UserID:       userID,
 			StockSymbol:  stockSymbol,
 			Quantity:     quantity,
-			AveragePrice: price,
-			TotalCost:    float64(quantity) * price,
+			AveragePrice: cost / float64(quantity),
+			TotalCost:    cost,
 			UpdatedAt:    time.Now(),
 		}
-		return s.portfolioRepo.Create(newPortfolio)
+		return tx.Portfolios().Create(newPortfolio)
 	} else {
 		// Update existing portfolio item
-		totalCost := portfolioItem.TotalCost + (float64(quantity) * price)
+		totalCost := portfolioItem.TotalCost + cost
 		totalQuantity := portfolioItem.Quantity + quantity
 		newAveragePrice := totalCost / float64(totalQuantity)
 
@@ -214,12 +546,12 @@ func (s *transactionService) updatePortfolioAfterBuy(userID int, stockSymbol str
 		portfolioItem.TotalCost = totalCost
 		portfolioItem.UpdatedAt = time.Now()
 
-		return s.portfolioRepo.Update(portfolioItem)
+		return tx.Portfolios().Update(portfolioItem)
 	}
 }
 
-func (s *transactionService) updatePortfolioAfterSell(userID int, stockSymbol string, quantity int) error {
-	portfolioItem, err := s.portfolioRepo.GetByUserIDAndSymbol(userID, stockSymbol)
+func (s *transactionService) updatePortfolioAfterSell(tx repositories.TxRepositories, userID int, stockSymbol string, quantity int) error {
+	portfolioItem, err := tx.Portfolios().GetByUserIDAndSymbolForUpdate(userID, stockSymbol)
 	if err != nil {
 		return err
 	}
@@ -231,13 +563,13 @@ func (s *transactionService) updatePortfolioAfterSell(userID int, stockSymbol st
 	newQuantity := portfolioItem.Quantity - quantity
 	if newQuantity <= 0 {
 		// Delete portfolio item if no shares left
-		return s.portfolioRepo.Delete(userID, stockSymbol)
+		return tx.Portfolios().Delete(userID, stockSymbol)
 	} else {
 		// Update portfolio item
 		portfolioItem.Quantity = newQuantity
 		portfolioItem.TotalCost = portfolioItem.AveragePrice * float64(newQuantity)
 		portfolioItem.UpdatedAt = time.Now()
 
-		return s.portfolioRepo.Update(portfolioItem)
+		return tx.Portfolios().Update(portfolioItem)
 	}
 }
\ No newline at end of file
@@ -2,6 +2,7 @@ package services
 
 import (
 	"fmt"
+	"math"
 	"stock-simulation-backend/internal/adapters/middleware"
 	"stock-simulation-backend/internal/core/domain"
 	"stock-simulation-backend/internal/core/ports/repositories"
@@ -12,12 +13,14 @@ import (
 )
 
 type userService struct {
-	userRepo repositories.UserRepository
+	userRepo  repositories.UserRepository
+	orderRepo repositories.AdvancedOrderRepository
 }
 
-func NewUserService(userRepo repositories.UserRepository) services.UserService {
+func NewUserService(userRepo repositories.UserRepository, orderRepo repositories.AdvancedOrderRepository) services.UserService {
 	return &userService{
-		userRepo: userRepo,
+		userRepo:  userRepo,
+		orderRepo: orderRepo,
 	}
 }
 
@@ -111,12 +114,21 @@ func (s *userService) GetProfile(userID int) (*domain.UserProfile, error) {
 		return nil, fmt.Errorf("failed to get user: %w", err)
 	}
 
+	working, err := s.orderRepo.GetWorkingOrdersByUser(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get working orders: %w", err)
+	}
+	held := domain.NewHolds(working).Cash
+	available := math.Max(0, user.Balance-held)
+
 	profile := &domain.UserProfile{
-		ID:          user.ID,
-		Username:    user.Username,
-		Email:       user.Email,
-		Balance:     user.Balance,
-		TotalProfit: user.TotalProfit,
+		ID:               user.ID,
+		Username:         user.Username,
+		Email:            user.Email,
+		Balance:          user.Balance,
+		HeldBalance:      &held,
+		AvailableBalance: &available,
+		TotalProfit:      user.TotalProfit,
 	}
 
 	return profile, nil
@@ -0,0 +1,9 @@
+-- Record the true cost of each fill on the transaction it produced
+USE stock_simulation;
+
+ALTER TABLE transactions
+    ADD COLUMN commission DECIMAL(15,2) NOT NULL DEFAULT 0.00 AFTER total_amount,
+    ADD COLUMN fees DECIMAL(15,2) NOT NULL DEFAULT 0.00 AFTER commission,
+    ADD COLUMN order_id INT NULL AFTER fees,
+    ADD COLUMN realized_pnl DECIMAL(15,2) NOT NULL DEFAULT 0.00 AFTER order_id,
+    ADD INDEX idx_transactions_order_id (order_id);
@@ -0,0 +1,5 @@
+-- Track the best price a trailing stop has seen since it was placed
+USE stock_simulation;
+
+ALTER TABLE advanced_orders
+    ADD COLUMN high_water_mark DECIMAL(15,4) NULL AFTER trailing_percent;
@@ -0,0 +1,7 @@
+-- Group OCO legs so a fill or cancel can take out the whole group, and record why orders were cancelled
+USE stock_simulation;
+
+ALTER TABLE advanced_orders
+    ADD COLUMN oco_group_id INT NULL AFTER linked_order_id,
+    ADD COLUMN cancel_reason VARCHAR(255) NULL AFTER oco_group_id,
+    ADD INDEX idx_advanced_orders_oco_group (oco_group_id);
@@ -0,0 +1,7 @@
+-- Bracket exits are stored DORMANT under their entry order until it fills
+USE stock_simulation;
+
+ALTER TABLE advanced_orders
+    MODIFY COLUMN order_type VARCHAR(20) NOT NULL,
+    MODIFY COLUMN status VARCHAR(20) NOT NULL DEFAULT 'PENDING',
+    ADD INDEX idx_advanced_orders_parent (parent_order_id);
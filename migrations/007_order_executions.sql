@@ -0,0 +1,16 @@
+-- One row per fill, so large orders can execute over several ticks at different prices
+USE stock_simulation;
+
+CREATE TABLE IF NOT EXISTS order_executions (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    order_id INT NOT NULL,
+    price DECIMAL(15,4) NOT NULL,
+    quantity INT NOT NULL,
+    commission DECIMAL(15,2) NOT NULL DEFAULT 0,
+    fees DECIMAL(15,2) NOT NULL DEFAULT 0,
+    slippage DECIMAL(15,4) NOT NULL DEFAULT 0,
+    total_amount DECIMAL(15,2) NOT NULL,
+    executed_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_order_executions_order (order_id, executed_at),
+    FOREIGN KEY (order_id) REFERENCES advanced_orders(id) ON DELETE CASCADE
+);
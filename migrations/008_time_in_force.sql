@@ -0,0 +1,7 @@
+-- Allow GTD orders and support the expiry sweep for GTD and DAY orders
+USE stock_simulation;
+
+ALTER TABLE advanced_orders
+    MODIFY COLUMN time_in_force VARCHAR(10) NOT NULL DEFAULT 'GTC',
+    ADD INDEX idx_advanced_orders_expiry (status, expires_at),
+    ADD INDEX idx_advanced_orders_tif (time_in_force, status, created_at);
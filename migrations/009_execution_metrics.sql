@@ -0,0 +1,9 @@
+-- Index the per-user order and fill scans behind execution metrics and slippage analysis
+USE stock_simulation;
+
+ALTER TABLE advanced_orders
+    ADD INDEX idx_advanced_orders_user_created (user_id, created_at),
+    ADD INDEX idx_advanced_orders_user_symbol (user_id, stock_symbol);
+
+ALTER TABLE order_executions
+    ADD INDEX idx_order_executions_executed_at (executed_at);
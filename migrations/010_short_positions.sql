@@ -0,0 +1,30 @@
+-- Keep shorts out of the long portfolio, with their proceeds and margin held as collateral
+USE stock_simulation;
+
+CREATE TABLE IF NOT EXISTS short_positions (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    user_id INT NOT NULL,
+    stock_symbol VARCHAR(10) NOT NULL,
+    quantity INT NOT NULL,
+    average_price DECIMAL(15,4) NOT NULL,
+    proceeds DECIMAL(15,2) NOT NULL,
+    collateral DECIMAL(15,2) NOT NULL DEFAULT 0,
+    accrued_borrow_fees DECIMAL(15,2) NOT NULL DEFAULT 0,
+    last_accrual_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    UNIQUE KEY uq_short_positions_user_symbol (user_id, stock_symbol),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+ALTER TABLE transactions
+    MODIFY COLUMN transaction_type VARCHAR(10) NOT NULL;
+
+-- Shorts opened before this migration were stored as negative portfolio rows
+-- with their proceeds already paid out as cash, so they carry no collateral
+INSERT INTO short_positions (user_id, stock_symbol, quantity, average_price, proceeds, collateral)
+SELECT user_id, stock_symbol, -quantity, average_price, -total_cost, 0
+FROM portfolio
+WHERE quantity < 0;
+
+DELETE FROM portfolio WHERE quantity < 0;
@@ -0,0 +1,18 @@
+-- Let users borrow against their long positions, with interest and margin calls
+USE stock_simulation;
+
+CREATE TABLE IF NOT EXISTS margin_accounts (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    user_id INT NOT NULL,
+    account_type VARCHAR(10) NOT NULL DEFAULT 'CASH',
+    borrowed_funds DECIMAL(15,2) NOT NULL DEFAULT 0,
+    accrued_interest DECIMAL(15,2) NOT NULL DEFAULT 0,
+    margin_call_amount DECIMAL(15,2) NOT NULL DEFAULT 0,
+    margin_call_at TIMESTAMP NULL,
+    last_interest_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    UNIQUE KEY uq_margin_accounts_user (user_id),
+    INDEX idx_margin_accounts_type (account_type),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
@@ -0,0 +1,5 @@
+-- Record when a stop-limit order's stop triggered and it began resting as a limit
+USE stock_simulation;
+
+ALTER TABLE advanced_orders
+    ADD COLUMN triggered_at TIMESTAMP NULL AFTER high_water_mark;
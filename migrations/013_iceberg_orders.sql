@@ -0,0 +1,14 @@
+-- Iceberg orders show a slice of their quantity at a time, and each refill
+-- goes to the back of the queue
+USE stock_simulation;
+
+ALTER TABLE advanced_orders
+    ADD COLUMN display_quantity INT NULL AFTER remaining_quantity,
+    ADD COLUMN visible_remaining INT NOT NULL DEFAULT 0 AFTER display_quantity,
+    ADD COLUMN priority_at TIMESTAMP NULL AFTER visible_remaining;
+
+UPDATE advanced_orders SET priority_at = created_at WHERE priority_at IS NULL;
+
+ALTER TABLE advanced_orders
+    MODIFY COLUMN priority_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    ADD INDEX idx_advanced_orders_book (stock_symbol, side, status, price, priority_at);
@@ -0,0 +1,15 @@
+-- Schedules for TWAP and VWAP parent orders, which trade through child slices
+USE stock_simulation;
+
+CREATE TABLE IF NOT EXISTS algo_order_params (
+    order_id INT PRIMARY KEY,
+    slice_type VARCHAR(20) NOT NULL DEFAULT 'MARKET',
+    start_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    end_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    arrival_price DECIMAL(15,4) NOT NULL,
+    volume_profile JSON NULL,
+    paused_at TIMESTAMP NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    FOREIGN KEY (order_id) REFERENCES advanced_orders(id) ON DELETE CASCADE
+);
@@ -0,0 +1,20 @@
+-- Dormant order requests submitted once their price or indicator conditions are met
+USE stock_simulation;
+
+CREATE TABLE IF NOT EXISTS contingent_orders (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    user_id INT NOT NULL,
+    conditions JSON NOT NULL,
+    logic VARCHAR(10) NOT NULL DEFAULT 'ALL',
+    order_request JSON NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'ACTIVE',
+    order_id INT NULL,
+    failure_reason VARCHAR(255) NULL,
+    triggered_at TIMESTAMP NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (order_id) REFERENCES advanced_orders(id) ON DELETE SET NULL,
+    INDEX idx_contingent_orders_user (user_id, created_at),
+    INDEX idx_contingent_orders_status (status)
+);
@@ -0,0 +1,14 @@
+-- Append-only log of every order status change, with its reason and actor
+USE stock_simulation;
+
+CREATE TABLE IF NOT EXISTS order_events (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    order_id INT NOT NULL,
+    from_status VARCHAR(20) NULL,
+    to_status VARCHAR(20) NOT NULL,
+    reason TEXT NOT NULL,
+    actor VARCHAR(20) NOT NULL,
+    created_at TIMESTAMP(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    INDEX idx_order_events_order (order_id, created_at),
+    FOREIGN KEY (order_id) REFERENCES advanced_orders(id)
+);
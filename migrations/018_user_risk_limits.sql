@@ -0,0 +1,13 @@
+-- Per-user overrides of the global pre-trade risk limits; NULL keeps the global value
+USE stock_simulation;
+
+CREATE TABLE IF NOT EXISTS user_risk_limits (
+    user_id INT NOT NULL PRIMARY KEY,
+    max_order_notional DECIMAL(15,2) NULL,
+    max_position_concentration DECIMAL(5,4) NULL,
+    max_orders_per_day INT NULL,
+    daily_loss_limit DECIMAL(15,2) NULL,
+    price_band_percent DECIMAL(6,2) NULL,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
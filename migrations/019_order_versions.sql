@@ -0,0 +1,25 @@
+-- Amending a working order bumps its version and keeps a snapshot of the
+-- terms of every version, the first one included
+USE stock_simulation;
+
+ALTER TABLE advanced_orders
+    ADD COLUMN version INT NOT NULL DEFAULT 1 AFTER status;
+
+CREATE TABLE IF NOT EXISTS order_versions (
+    order_id INT NOT NULL,
+    version INT NOT NULL,
+    quantity INT NOT NULL,
+    executed_quantity INT NOT NULL,
+    remaining_quantity INT NOT NULL,
+    price DECIMAL(15,2) NULL,
+    stop_price DECIMAL(15,2) NULL,
+    trailing_amount DECIMAL(15,2) NULL,
+    trailing_percent DECIMAL(5,2) NULL,
+    time_in_force VARCHAR(10) NOT NULL,
+    expires_at TIMESTAMP NULL,
+    priority_at TIMESTAMP NOT NULL,
+    actor VARCHAR(20) NOT NULL,
+    created_at TIMESTAMP(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    PRIMARY KEY (order_id, version),
+    FOREIGN KEY (order_id) REFERENCES advanced_orders(id)
+);
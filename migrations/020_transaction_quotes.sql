@@ -0,0 +1,6 @@
+-- Record the quote a direct trade was priced against
+USE stock_simulation;
+
+ALTER TABLE transactions
+    ADD COLUMN bid_price DECIMAL(15,2) NULL AFTER price,
+    ADD COLUMN ask_price DECIMAL(15,2) NULL AFTER bid_price;